@@ -0,0 +1,20 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// ListAudit pages through the audit log. Administrators only.
+func (c *Client) ListAudit(ctx context.Context, filter AuditFilter) (*AuditList, error) {
+	var list AuditList
+	path := "/audit"
+	if query := filter.values().Encode(); query != "" {
+		path += "?" + query
+	}
+	if err := c.call(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, fmt.Errorf("ListAudit: %w", err)
+	}
+	return &list, nil
+}
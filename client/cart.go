@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+func (c *Client) GetCart(ctx context.Context) (*Cart, error) {
+	var cart Cart
+	if err := c.call(ctx, http.MethodGet, "/cart", nil, &cart); err != nil {
+		return nil, fmt.Errorf("GetCart: %w", err)
+	}
+	return &cart, nil
+}
+
+// AddToCart adds quantity units of the product to the cart; zero adds one.
+func (c *Client) AddToCart(ctx context.Context, productID string, quantity int) (*Cart, error) {
+	var cart Cart
+	if err := c.call(ctx, http.MethodPost, "/cart/items", BasketItem{ProductID: productID, Quantity: quantity}, &cart); err != nil {
+		return nil, fmt.Errorf("AddToCart: %w", err)
+	}
+	return &cart, nil
+}
+
+func (c *Client) SetCartQuantity(ctx context.Context, productID string, quantity int) (*Cart, error) {
+	in := struct {
+		Quantity int `json:"quantity"`
+	}{quantity}
+	var cart Cart
+	if err := c.call(ctx, http.MethodPut, "/cart/items/"+productID, in, &cart); err != nil {
+		return nil, fmt.Errorf("SetCartQuantity: %w", err)
+	}
+	return &cart, nil
+}
+
+func (c *Client) RemoveFromCart(ctx context.Context, productID string) (*Cart, error) {
+	var cart Cart
+	if err := c.call(ctx, http.MethodDelete, "/cart/items/"+productID, nil, &cart); err != nil {
+		return nil, fmt.Errorf("RemoveFromCart: %w", err)
+	}
+	return &cart, nil
+}
+
+func (c *Client) ClearCart(ctx context.Context) error {
+	if err := c.call(ctx, http.MethodDelete, "/cart", nil, nil); err != nil {
+		return fmt.Errorf("ClearCart: %w", err)
+	}
+	return nil
+}
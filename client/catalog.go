@@ -0,0 +1,283 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func (c *Client) ListShops(ctx context.Context) (*ShopList, error) {
+	var list ShopList
+	if err := c.call(ctx, http.MethodGet, "/shops", nil, &list); err != nil {
+		return nil, fmt.Errorf("ListShops: %w", err)
+	}
+	return &list, nil
+}
+
+func (c *Client) AddShop(ctx context.Context, shop Shop) (int, error) {
+	var reply idReply
+	if err := c.call(ctx, http.MethodPost, "/shops", shop, &reply); err != nil {
+		return 0, fmt.Errorf("AddShop: %w", err)
+	}
+	return reply.ID, nil
+}
+
+func (c *Client) EditShop(ctx context.Context, shop Shop) error {
+	if err := c.call(ctx, http.MethodPut, "/shops", shop, nil); err != nil {
+		return fmt.Errorf("EditShop: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) ListCategories(ctx context.Context) (*CategoryList, error) {
+	var list CategoryList
+	if err := c.call(ctx, http.MethodGet, "/categories", nil, &list); err != nil {
+		return nil, fmt.Errorf("ListCategories: %w", err)
+	}
+	return &list, nil
+}
+
+func (c *Client) AddCategory(ctx context.Context, name string) (int, error) {
+	var reply idReply
+	if err := c.call(ctx, http.MethodPost, "/categories", Category{Name: name}, &reply); err != nil {
+		return 0, fmt.Errorf("AddCategory: %w", err)
+	}
+	return reply.ID, nil
+}
+
+func (c *Client) EditCategory(ctx context.Context, category Category) error {
+	if err := c.call(ctx, http.MethodPut, "/categories", category, nil); err != nil {
+		return fmt.Errorf("EditCategory: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) ListProducts(ctx context.Context) (*ProductList, error) {
+	var list ProductList
+	if err := c.call(ctx, http.MethodGet, "/products", nil, &list); err != nil {
+		return nil, fmt.Errorf("ListProducts: %w", err)
+	}
+	return &list, nil
+}
+
+// FilterProducts lists the products matching filter.
+func (c *Client) FilterProducts(ctx context.Context, filter ProductFilter) (*ProductList, error) {
+	var list ProductList
+	path := "/products"
+	if query := filter.values().Encode(); query != "" {
+		path += "?" + query
+	}
+	if err := c.call(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, fmt.Errorf("FilterProducts: %w", err)
+	}
+	return &list, nil
+}
+
+func (c *Client) AddProduct(ctx context.Context, product ProductInput) (*Product, error) {
+	var list ProductList
+	if err := c.call(ctx, http.MethodPost, "/products", product, &list); err != nil {
+		return nil, fmt.Errorf("AddProduct: %w", err)
+	}
+	return first(list), nil
+}
+
+func (c *Client) EditProduct(ctx context.Context, product ProductInput) (*Product, error) {
+	var list ProductList
+	if err := c.call(ctx, http.MethodPut, "/products", product, &list); err != nil {
+		return nil, fmt.Errorf("EditProduct: %w", err)
+	}
+	return first(list), nil
+}
+
+func (c *Client) ProductsByCategory(ctx context.Context, categoryID int) (*ProductList, error) {
+	var list ProductList
+	path := fmt.Sprintf("/categories/%d/products", categoryID)
+	if err := c.call(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, fmt.Errorf("ProductsByCategory: %w", err)
+	}
+	return &list, nil
+}
+
+func (c *Client) ProductsByShop(ctx context.Context, shopID int) (*ProductList, error) {
+	var list ProductList
+	path := fmt.Sprintf("/shops/%d/products", shopID)
+	if err := c.call(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, fmt.Errorf("ProductsByShop: %w", err)
+	}
+	return &list, nil
+}
+
+func (c *Client) SearchProductByName(ctx context.Context, name string) (*Product, error) {
+	var list ProductList
+	if err := c.call(ctx, http.MethodGet, "/search/"+url.PathEscape(name), nil, &list); err != nil {
+		return nil, fmt.Errorf("SearchProductByName: %w", err)
+	}
+	return first(list), nil
+}
+
+func (c *Client) ListPrices(ctx context.Context) (*PriceList, error) {
+	var list PriceList
+	if err := c.call(ctx, http.MethodGet, "/prices", nil, &list); err != nil {
+		return nil, fmt.Errorf("ListPrices: %w", err)
+	}
+	return &list, nil
+}
+
+func (c *Client) AddPrice(ctx context.Context, price PriceInput) (*Price, error) {
+	var added Price
+	if err := c.call(ctx, http.MethodPost, "/prices", price, &added); err != nil {
+		return nil, fmt.Errorf("AddPrice: %w", err)
+	}
+	return &added, nil
+}
+
+func (c *Client) EditPrice(ctx context.Context, price PriceInput) (*Price, error) {
+	var list PriceList
+	if err := c.call(ctx, http.MethodPut, "/prices", price, &list); err != nil {
+		return nil, fmt.Errorf("EditPrice: %w", err)
+	}
+	if len(list.Items) == 0 {
+		return nil, nil
+	}
+	return list.Items[0], nil
+}
+
+// BulkUpdatePrices applies rule to the prices matched by selector. With
+// dryRun nothing is written and the returned batch is only the diff.
+func (c *Client) BulkUpdatePrices(ctx context.Context, rule PriceRule, selector PriceSelector, dryRun bool) (*PriceBatch, error) {
+	request := struct {
+		Rule   PriceRule     `json:"rule"`
+		Select PriceSelector `json:"select"`
+		DryRun bool          `json:"dry_run"`
+	}{rule, selector, dryRun}
+	var batch PriceBatch
+	if err := c.call(ctx, http.MethodPost, "/prices/bulk", request, &batch); err != nil {
+		return nil, fmt.Errorf("BulkUpdatePrices: %w", err)
+	}
+	return &batch, nil
+}
+
+func first(list ProductList) *Product {
+	if len(list.Items) == 0 {
+		return nil
+	}
+	return list.Items[0]
+}
+
+func (c *Client) GetShop(ctx context.Context, shopID int) (*Shop, error) {
+	var shop Shop
+	if err := c.call(ctx, http.MethodGet, fmt.Sprintf("/shops/%d", shopID), nil, &shop); err != nil {
+		return nil, fmt.Errorf("GetShop: %w", err)
+	}
+	return &shop, nil
+}
+
+func (c *Client) GetCategory(ctx context.Context, categoryID int) (*Category, error) {
+	var category Category
+	if err := c.call(ctx, http.MethodGet, fmt.Sprintf("/categories/%d", categoryID), nil, &category); err != nil {
+		return nil, fmt.Errorf("GetCategory: %w", err)
+	}
+	return &category, nil
+}
+
+func (c *Client) GetCategoryByURIName(ctx context.Context, uriName string) (*Category, error) {
+	var category Category
+	if err := c.call(ctx, http.MethodGet, "/categories/uri/"+url.PathEscape(uriName), nil, &category); err != nil {
+		return nil, fmt.Errorf("GetCategoryByURIName: %w", err)
+	}
+	return &category, nil
+}
+
+func (c *Client) GetProduct(ctx context.Context, productID string) (*Product, error) {
+	var product Product
+	if err := c.call(ctx, http.MethodGet, "/products/"+url.PathEscape(productID), nil, &product); err != nil {
+		return nil, fmt.Errorf("GetProduct: %w", err)
+	}
+	return &product, nil
+}
+
+func (c *Client) GetProductBySKU(ctx context.Context, sku string) (*Product, error) {
+	var product Product
+	if err := c.call(ctx, http.MethodGet, "/products/sku/"+url.PathEscape(sku), nil, &product); err != nil {
+		return nil, fmt.Errorf("GetProductBySKU: %w", err)
+	}
+	return &product, nil
+}
+
+// GetProductByURI resolves a storefront URI such as /product/тепловая-3001.
+func (c *Client) GetProductByURI(ctx context.Context, uri string) (*Product, error) {
+	var product Product
+	slug := strings.TrimPrefix(uri, "/product/")
+	if err := c.call(ctx, http.MethodGet, "/product/"+url.PathEscape(slug), nil, &product); err != nil {
+		return nil, fmt.Errorf("GetProductByURI: %w", err)
+	}
+	return &product, nil
+}
+
+func (c *Client) GetPrice(ctx context.Context, priceID int) (*Price, error) {
+	var price Price
+	if err := c.call(ctx, http.MethodGet, fmt.Sprintf("/prices/%d", priceID), nil, &price); err != nil {
+		return nil, fmt.Errorf("GetPrice: %w", err)
+	}
+	return &price, nil
+}
+
+// DeleteShop archives a shop. Archived entities stay restorable until the
+// server purges them.
+func (c *Client) DeleteShop(ctx context.Context, shopID int) error {
+	if err := c.call(ctx, http.MethodDelete, fmt.Sprintf("/shops/%d", shopID), nil, nil); err != nil {
+		return fmt.Errorf("DeleteShop: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) RestoreShop(ctx context.Context, shopID int) error {
+	if err := c.call(ctx, http.MethodPost, fmt.Sprintf("/shops/%d/restore", shopID), nil, nil); err != nil {
+		return fmt.Errorf("RestoreShop: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) DeleteCategory(ctx context.Context, categoryID int) error {
+	if err := c.call(ctx, http.MethodDelete, fmt.Sprintf("/categories/%d", categoryID), nil, nil); err != nil {
+		return fmt.Errorf("DeleteCategory: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) RestoreCategory(ctx context.Context, categoryID int) error {
+	if err := c.call(ctx, http.MethodPost, fmt.Sprintf("/categories/%d/restore", categoryID), nil, nil); err != nil {
+		return fmt.Errorf("RestoreCategory: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) DeleteProduct(ctx context.Context, productID string) error {
+	if err := c.call(ctx, http.MethodDelete, "/products/"+url.PathEscape(productID), nil, nil); err != nil {
+		return fmt.Errorf("DeleteProduct: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) RestoreProduct(ctx context.Context, productID string) error {
+	if err := c.call(ctx, http.MethodPost, "/products/"+url.PathEscape(productID)+"/restore", nil, nil); err != nil {
+		return fmt.Errorf("RestoreProduct: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) DeletePrice(ctx context.Context, priceID int) error {
+	if err := c.call(ctx, http.MethodDelete, fmt.Sprintf("/prices/%d", priceID), nil, nil); err != nil {
+		return fmt.Errorf("DeletePrice: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) RestorePrice(ctx context.Context, priceID int) error {
+	if err := c.call(ctx, http.MethodPost, fmt.Sprintf("/prices/%d/restore", priceID), nil, nil); err != nil {
+		return fmt.Errorf("RestorePrice: %w", err)
+	}
+	return nil
+}
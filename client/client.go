@@ -0,0 +1,239 @@
+// Package client is a typed Go client for the market4 HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRetries = 3
+	defaultBackoff = 200 * time.Millisecond
+	maxBackoff     = 5 * time.Second
+	// tokenLeeway renews the token a little before it actually expires.
+	tokenLeeway = 30 * time.Second
+)
+
+type Client struct {
+	baseURL    string
+	login      string
+	password   string
+	httpClient *http.Client
+	retries    int
+	backoff    time.Duration
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how many times a failed request is repeated and the
+// initial back-off, which doubles on every attempt.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// New returns a client for the API at baseURL, e.g. http://localhost:9999.
+// The credentials are exchanged for a JWT on the first call that needs one.
+func New(baseURL, login, password string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/") + "/api/v1",
+		login:      login,
+		password:   password,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		retries:    defaultRetries,
+		backoff:    defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token returns a valid JWT, requesting a new one when there is none yet or
+// the current one is about to expire.
+func (c *Client) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Add(tokenLeeway).Before(c.expires) {
+		return c.token, nil
+	}
+
+	credentials := struct {
+		Login    string `json:"login"`
+		Password string `json:"password"`
+	}{c.login, c.password}
+	var reply struct {
+		Token string `json:"token"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/auth", credentials, &reply, false); err != nil {
+		return "", fmt.Errorf("Token: %w", err)
+	}
+	c.token = reply.Token
+	c.expires = tokenExpiry(reply.Token)
+	return c.token, nil
+}
+
+func (c *Client) dropToken() {
+	c.mu.Lock()
+	c.token = ""
+	c.mu.Unlock()
+}
+
+// tokenExpiry reads the exp claim without verifying the signature; the
+// server does that. An unreadable token is treated as expiring immediately.
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}
+	}
+	return time.Unix(claims.ExpiresAt, 0)
+}
+
+// call performs an authenticated request. A 401 drops the cached token and
+// repeats the request once with a fresh one.
+func (c *Client) call(ctx context.Context, method, path string, in, out interface{}) error {
+	err := c.do(ctx, method, path, in, out, true)
+	if IsUnauthorized(err) {
+		c.dropToken()
+		err = c.do(ctx, method, path, in, out, true)
+	}
+	return err
+}
+
+// rawBody is sent as is instead of being encoded as JSON.
+type rawBody struct {
+	contentType string
+	data        []byte
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}, authorized bool) error {
+	var body []byte
+	contentType := "application/json"
+	switch v := in.(type) {
+	case nil:
+	case rawBody:
+		body, contentType = v.data, v.contentType
+	default:
+		var err error
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retryAfter time.Duration
+		retryAfter, err = c.attempt(ctx, method, path, contentType, body, out, authorized)
+		if err == nil || attempt >= c.retries || !retryable(method, err) {
+			return err
+		}
+		wait := c.backoff << uint(attempt)
+		if wait > maxBackoff {
+			wait = maxBackoff
+		}
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context,
+	method, path, contentType string,
+	body []byte,
+	out interface{},
+	authorized bool) (time.Duration, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", contentType)
+	}
+	request.Header.Set("Accept", "application/json")
+	if authorized {
+		token, terr := c.Token(ctx)
+		if terr != nil {
+			return 0, terr
+		}
+		request.Header.Set("Authorization", token)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return 0, &transportError{err: err}
+	}
+	defer response.Body.Close()
+
+	if w, ok := out.(io.Writer); ok && response.StatusCode < http.StatusBadRequest {
+		// Part of the body may already be in w, so a failed copy is not
+		// retried.
+		if _, err = io.Copy(w, response.Body); err != nil {
+			return 0, fmt.Errorf("read %s %s: %w", method, path, err)
+		}
+		return 0, nil
+	}
+	payload, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return 0, &transportError{err: err}
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		return retryAfter(response), newError(response.StatusCode, payload)
+	}
+	if out == nil || len(bytes.TrimSpace(payload)) == 0 {
+		return 0, nil
+	}
+	if err = json.Unmarshal(payload, out); err != nil {
+		return 0, fmt.Errorf("decode %s %s: %w", method, path, err)
+	}
+	return 0, nil
+}
+
+func retryAfter(response *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(response.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
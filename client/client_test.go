@@ -0,0 +1,1061 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"market4/internal/api/auth"
+	"market4/internal/api/httpserver"
+	"market4/internal/api/httpserver/md"
+	v1 "market4/internal/api/v1"
+	"market4/internal/audit"
+	"market4/internal/health"
+	"market4/internal/metrics"
+	"market4/internal/payments"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	// The auth middleware loads ./keys/public.key relative to the working
+	// directory, which is the repository root when the binary runs.
+	if err := os.Chdir(".."); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func newTestRouter(t *testing.T) http.Handler {
+	return newTestRouterWith(t, payments.NewFake("test-secret", zap.NewNop()))
+}
+
+func newTestRouterWith(t *testing.T, provider payments.Provider) http.Handler {
+	lg := zap.NewNop()
+	renderer := render.New(render.Options{DisableHTTPErrorRendering: true})
+
+	auditLog := &fakeAudit{}
+	recorder := audit.New(auditLog, fakeTx{}, lg)
+	rawUsers := &fakeUsers{users: map[string]*fakeUser{
+		"user1": {id: 1, password: "user1password", roles: []string{"USER"}},
+		"user2": {id: 2, password: "user1password", roles: []string{"USER", "ADMIN"}},
+		"user3": {id: 3, password: "user1password", roles: []string{"USER", "STAFF"}},
+	}}
+	users := audit.Users(rawUsers, recorder)
+	shops := audit.Shop(&fakeShops{}, recorder)
+	categories := audit.Category(&fakeCategories{}, recorder)
+	rawPrices := &fakePrices{}
+	prices := audit.Price(rawPrices, recorder)
+	rawProducts := &fakeProducts{}
+	products := audit.Product(rawProducts, prices, recorder)
+	promotions := &fakePromotions{}
+	coupons := &fakeCoupons{}
+	carts := &fakeCarts{products: rawProducts, prices: rawPrices}
+	stock := &fakeStock{}
+	orders := &fakeOrders{carts: carts, coupons: coupons, stock: stock, users: rawUsers}
+	cart := v1.NewCart(carts, promotions, fakeCache{}, lg, renderer)
+	order := v1.NewOrder(orders, coupons, products, users, cart, lg, renderer)
+
+	authService := auth.NewAuthService("keys/private.key", "keys/public.key", users, lg)
+	require.NotNil(t, authService)
+
+	counters := metrics.New()
+	router := httpserver.NewRouter(chi.NewRouter(), lg, md.NewAuthenticator(authService.PublicKey(), lg),
+		v1.NewShop(shops, lg, renderer),
+		v1.NewCategory(categories, lg, renderer),
+		v1.NewProduct(products, prices, promotions, counters, time.Minute, lg, renderer),
+		v1.NewStock(stock, lg, renderer),
+		v1.NewPrice(prices, counters, lg, renderer),
+		v1.NewPromotion(promotions, lg, renderer),
+		v1.NewCoupon(coupons, products, prices, lg, renderer),
+		cart,
+		order,
+		v1.NewPayment(&fakePayments{orders: orders}, provider, order, lg, renderer),
+		v1.NewUser(users, lg, renderer),
+		v1.NewAudit(auditLog, lg, renderer),
+		v1.NewAuth(*authService, users, counters, lg, renderer),
+		health.New(time.Second, lg),
+		counters,
+		nil)
+	return &router
+}
+
+func Test_CatalogRoundTrip(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(t))
+	defer server.Close()
+	ctx := context.Background()
+	admin := New(server.URL, "user2", "user1password")
+
+	shopID, err := admin.AddShop(ctx, Shop{Name: "Магазин на диване", Address: "Москва, Останкино"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, shopID)
+
+	categoryID, err := admin.AddCategory(ctx, "Игрушки")
+	require.NoError(t, err)
+
+	product, err := admin.AddProduct(ctx, ProductInput{
+		SKU: "3001", Name: "пушка", Type: "тепловая", Description: "пушка детская",
+		ShopID: shopID, CategoryID: categoryID,
+		Price: &PriceInput{SalePrice: 2000, FactoryPrice: 1000, DiscountPrice: 1600, IsActive: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/product/тепловая-3001", product.URI)
+	require.Len(t, product.Prices, 1)
+	assert.Equal(t, 1600, product.Prices[0].DiscountPrice)
+
+	found, err := admin.SearchProductByName(ctx, "пушка")
+	require.NoError(t, err)
+	assert.Equal(t, product.ID, found.ID)
+
+	shops, err := New(server.URL, "user1", "user1password").ListShops(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, shops.Total)
+
+	byID, err := admin.GetProduct(ctx, product.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "3001", byID.SKU)
+	bySKU, err := admin.GetProductBySKU(ctx, "3001")
+	require.NoError(t, err)
+	assert.Equal(t, product.ID, bySKU.ID)
+	byURI, err := admin.GetProductByURI(ctx, product.URI)
+	require.NoError(t, err)
+	assert.Equal(t, product.ID, byURI.ID)
+
+	shop, err := admin.GetShop(ctx, shopID)
+	require.NoError(t, err)
+	assert.Equal(t, "Магазин на диване", shop.Name)
+	category, err := admin.GetCategoryByURIName(ctx, "Игрушки-1")
+	require.NoError(t, err)
+	assert.Equal(t, categoryID, category.ID)
+	_, err = admin.GetPrice(ctx, 1)
+	require.NoError(t, err)
+}
+
+func Test_TypedErrors(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(t))
+	defer server.Close()
+	ctx := context.Background()
+
+	_, err := New(server.URL, "user2", "wrong").ListShops(ctx)
+	assert.True(t, errors.Is(err, ErrUnauthorized), err)
+
+	_, err = New(server.URL, "user1", "user1password").AddShop(ctx, Shop{Name: "x", Address: "y"})
+	assert.True(t, errors.Is(err, ErrForbidden), err)
+
+	admin := New(server.URL, "user2", "user1password")
+	_, err = admin.AddProduct(ctx, ProductInput{SKU: "3001", Price: &PriceInput{SalePrice: 10, DiscountPrice: 20}})
+	require.True(t, errors.Is(err, ErrBadRequest), err)
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr))
+	assert.Contains(t, apiErr.InvalidParams, InvalidParam{Name: "price.discount_price", Reason: "must not exceed sale_price"})
+
+	_, err = admin.SearchProductByName(ctx, "клюшка")
+	assert.True(t, errors.Is(err, ErrNotFound), err)
+	_, err = admin.GetShop(ctx, 42)
+	assert.True(t, errors.Is(err, ErrNotFound), err)
+	_, err = admin.GetProduct(ctx, "not-a-uuid")
+	assert.True(t, errors.Is(err, ErrBadRequest), err)
+	err = admin.DeleteCategory(ctx, 1)
+	assert.True(t, errors.Is(err, ErrConflict), err)
+}
+
+func Test_SoftDelete(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(t))
+	defer server.Close()
+	ctx := context.Background()
+	admin := New(server.URL, "user2", "user1password")
+
+	shopID, err := admin.AddShop(ctx, Shop{Name: "Магазин на диване", Address: "Москва, Останкино"})
+	require.NoError(t, err)
+	categoryID, err := admin.AddCategory(ctx, "Игрушки")
+	require.NoError(t, err)
+	product, err := admin.AddProduct(ctx, ProductInput{
+		SKU: "3001", Name: "пушка", Type: "тепловая", Description: "пушка детская",
+		ShopID: shopID, CategoryID: categoryID,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, admin.DeleteProduct(ctx, product.ID))
+	_, err = admin.GetProduct(ctx, product.ID)
+	assert.True(t, errors.Is(err, ErrNotFound), err)
+	products, err := admin.ListProducts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, products.Total)
+	err = admin.DeleteProduct(ctx, product.ID)
+	assert.True(t, errors.Is(err, ErrNotFound), "deleting twice: %v", err)
+
+	require.NoError(t, admin.RestoreProduct(ctx, product.ID))
+	_, err = admin.GetProduct(ctx, product.ID)
+	assert.NoError(t, err)
+
+	require.NoError(t, admin.DeleteShop(ctx, shopID))
+	shops, err := admin.ListShops(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, shops.Total)
+	require.NoError(t, admin.RestoreShop(ctx, shopID))
+	err = admin.RestoreShop(ctx, shopID)
+	assert.True(t, errors.Is(err, ErrNotFound), "restoring a live shop: %v", err)
+
+	err = New(server.URL, "user1", "user1password").DeleteShop(ctx, shopID)
+	assert.True(t, errors.Is(err, ErrForbidden), err)
+}
+
+func Test_TokenRefresh(t *testing.T) {
+	var logins int32
+	router := newTestRouter(t)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/api/v1/auth" {
+			atomic.AddInt32(&logins, 1)
+		}
+		router.ServeHTTP(writer, request)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+	c := New(server.URL, "user1", "user1password")
+
+	_, err := c.ListShops(ctx)
+	require.NoError(t, err)
+	_, err = c.ListShops(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&logins), "a valid token is reused")
+
+	// A token the server rejects is replaced transparently.
+	c.mu.Lock()
+	c.token = "garbage"
+	c.mu.Unlock()
+	_, err = c.ListShops(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&logins))
+
+	// So is one that is about to expire.
+	c.mu.Lock()
+	c.expires = time.Now()
+	c.mu.Unlock()
+	_, err = c.ListShops(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&logins))
+}
+
+func Test_Retries(t *testing.T) {
+	var failures int32 = 2
+	router := newTestRouter(t)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/api/v1/auth" && atomic.AddInt32(&failures, -1) >= 0 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		router.ServeHTTP(writer, request)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	c := New(server.URL, "user2", "user1password", WithRetries(3, time.Millisecond))
+	_, err := c.ListShops(ctx)
+	require.NoError(t, err, "GET is retried until the server recovers")
+
+	atomic.StoreInt32(&failures, 1)
+	_, err = c.AddShop(ctx, Shop{Name: "x", Address: "y"})
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode, "POST is not retried")
+
+	atomic.StoreInt32(&failures, 10)
+	_, err = New(server.URL, "user2", "user1password", WithRetries(2, time.Millisecond)).ListShops(ctx)
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode, "retries give up eventually")
+}
+
+func Test_ImportProducts(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(t))
+	defer server.Close()
+	ctx := context.Background()
+	admin := New(server.URL, "user2", "user1password")
+
+	shopID, err := admin.AddShop(ctx, Shop{Name: "Магазин на диване", Address: "Москва, Останкино"})
+	require.NoError(t, err)
+	_, err = admin.AddCategory(ctx, "Игрушки")
+	require.NoError(t, err)
+	_, err = admin.AddProduct(ctx, ProductInput{
+		SKU: "3001", Name: "пушка", Type: "тепловая", Description: "пушка детская", ShopID: shopID, CategoryID: 1,
+	})
+	require.NoError(t, err)
+
+	file := "sku,name,type,description,shop_id,category_id,sale_price,factory_price,discount_price\n" +
+		"3001,пушка,тепловая,пушка водяная,1,1,2000,1000,1600\n" +
+		"3002,мяч,футбольный,мяч кожаный,1,1,,,\n" +
+		"3003,кукла,,кукла тряпичная,1,1,10,5,20\n"
+
+	report, err := admin.ImportProducts(ctx, strings.NewReader(file), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 2, report.Skipped, "one bad row holds back the whole file")
+	assert.Equal(t, "failed", report.Rows[2].Status)
+	assert.ElementsMatch(t, []InvalidParam{
+		{Name: "type", Reason: "is mandatory"},
+		{Name: "price.discount_price", Reason: "must not exceed sale_price"},
+	}, report.Rows[2].InvalidParams)
+
+	report, err = admin.ImportProducts(ctx, strings.NewReader(file), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"updated", "created", "failed"},
+		[]string{report.Rows[0].Status, report.Rows[1].Status, report.Rows[2].Status})
+	product, err := admin.GetProductBySKU(ctx, "3002")
+	require.NoError(t, err)
+	assert.Equal(t, report.Rows[1].ProductID, product.ID)
+
+	_, err = admin.ImportProducts(ctx, strings.NewReader("sku,colour\n3001,red\n"), 0)
+	assert.True(t, errors.Is(err, ErrBadRequest), err)
+}
+
+func Test_ExportProducts(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(t))
+	defer server.Close()
+	ctx := context.Background()
+	admin := New(server.URL, "user2", "user1password")
+	user := New(server.URL, "user1", "user1password")
+
+	for _, sku := range []string{"4001", "4002"} {
+		_, err := admin.AddProduct(ctx, ProductInput{
+			SKU: sku, Name: "самокат " + sku, Type: "детский", Description: "самокат", ShopID: 1, CategoryID: 1,
+		})
+		require.NoError(t, err)
+	}
+	_, err := admin.AddProduct(ctx, ProductInput{
+		SKU: "4003", Name: "велосипед", Type: "горный", Description: "велосипед", ShopID: 1, CategoryID: 1,
+	})
+	require.NoError(t, err)
+
+	list, err := user.FilterProducts(ctx, ProductFilter{Name: "САМОКАТ"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.Total)
+
+	var file bytes.Buffer
+	err = user.ExportProducts(ctx, "csv", ProductFilter{Name: "самокат"}, &file)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(file.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "id,sku,name,type,"), lines[0])
+	assert.Contains(t, lines[1], ",4001,самокат 4001,")
+
+	file.Reset()
+	err = user.ExportProducts(ctx, "xlsx", ProductFilter{}, &file)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(file.Bytes(), []byte("PK")), "xlsx is a zip archive")
+
+	err = user.ExportProducts(ctx, "pdf", ProductFilter{}, &file)
+	assert.True(t, errors.Is(err, ErrBadRequest), err)
+	var apiErr *Error
+	_, err = user.FilterProducts(ctx, ProductFilter{ShopID: -1})
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, []InvalidParam{{Name: "shop_id", Reason: "must be a positive integer"}}, apiErr.InvalidParams)
+}
+
+func Test_BulkUpdatePrices(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(t))
+	defer server.Close()
+	ctx := context.Background()
+	admin := New(server.URL, "user2", "user1password")
+
+	for i, sale := range []int{1000, 2000, 5000} {
+		_, err := admin.AddPrice(ctx, PriceInput{
+			SalePrice: sale, FactoryPrice: sale / 2, DiscountPrice: sale - 100, IsActive: true,
+			ProductID: fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1),
+		})
+		require.NoError(t, err)
+	}
+	cheap := PriceSelector{MaxPrice: 2000}
+
+	preview, err := admin.BulkUpdatePrices(ctx, PriceRule{Op: "percent", Value: -15, Round: "99"}, cheap, true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, preview.Matched)
+	assert.Equal(t, 0, preview.BatchID)
+	require.Len(t, preview.Changes, 2)
+	assert.Equal(t, PriceAmounts{SalePrice: 899, FactoryPrice: 500, DiscountPrice: 900}, preview.Changes[0].New)
+	assert.Equal(t, []InvalidParam{{Name: "discount_price", Reason: "must not exceed sale_price"}}, preview.Changes[0].InvalidParams)
+	prices, err := admin.ListPrices(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, prices.Items[0].SalePrice, "a dry run writes nothing")
+
+	_, err = admin.BulkUpdatePrices(ctx, PriceRule{Op: "percent", Value: -15, Round: "99"}, cheap, false)
+	assert.True(t, errors.Is(err, ErrBadRequest), "an invalid price blocks the whole batch: %v", err)
+
+	batch, err := admin.BulkUpdatePrices(ctx, PriceRule{Op: "amount", Value: 100, Field: "factory_price"}, cheap, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, batch.BatchID)
+	assert.Equal(t, 2, batch.Changed)
+	price, err := admin.GetPrice(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1100, price.FactoryPrice)
+
+	_, err = admin.BulkUpdatePrices(ctx, PriceRule{Op: "set", Value: 1}, PriceSelector{}, true)
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, "select", apiErr.InvalidParams[0].Name)
+
+	user := New(server.URL, "user1", "user1password")
+	_, err = user.BulkUpdatePrices(ctx, PriceRule{Op: "set", Value: 1}, cheap, true)
+	assert.True(t, errors.Is(err, ErrForbidden), err)
+}
+
+func Test_Promotions(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(t))
+	defer server.Close()
+	ctx := context.Background()
+	admin := New(server.URL, "user2", "user1password")
+	user := New(server.URL, "user1", "user1password")
+
+	product, err := admin.AddProduct(ctx, ProductInput{
+		SKU: "5001", Name: "пушка", Type: "тепловая", Description: "пушка детская", ShopID: 1, CategoryID: 1,
+	})
+	require.NoError(t, err)
+	_, err = admin.AddPrice(ctx, PriceInput{SalePrice: 2000, FactoryPrice: 1000, DiscountPrice: 1800, IsActive: true, ProductID: product.ID})
+	require.NoError(t, err)
+
+	now := time.Now()
+	running := Promotion{Type: PromotionPercentage, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), IsActive: true}
+	tenPercent := running
+	tenPercent.Name, tenPercent.Value, tenPercent.Priority, tenPercent.Stackable = "минус 10%", 10, 1, true
+	tenPercent.ProductIDs = []string{product.ID}
+	_, err = admin.AddPromotion(ctx, tenPercent)
+	require.NoError(t, err)
+	hundredOff := running
+	hundredOff.Name, hundredOff.Type, hundredOff.Value, hundredOff.Priority, hundredOff.Stackable = "минус 100", PromotionFixed, 100, 2, true
+	added, err := admin.AddPromotion(ctx, hundredOff)
+	require.NoError(t, err)
+	expired := tenPercent
+	expired.Name, expired.Value, expired.Priority = "прошлая", 90, 9
+	expired.StartsAt, expired.EndsAt = now.Add(-48*time.Hour), now.Add(-24*time.Hour)
+	_, err = admin.AddPromotion(ctx, expired)
+	require.NoError(t, err)
+
+	got, err := user.GetProduct(ctx, product.ID)
+	require.NoError(t, err)
+	require.Len(t, got.Prices, 1)
+	require.NotNil(t, got.Prices[0].EffectivePrice)
+	assert.Equal(t, 1710, *got.Prices[0].EffectivePrice, "100 off first, then 10%")
+	require.Len(t, got.Prices[0].Promotions, 2)
+	assert.Equal(t, "минус 100", got.Prices[0].Promotions[0].Name)
+
+	added.Stackable = false
+	_, err = admin.EditPromotion(ctx, *added)
+	require.NoError(t, err)
+	list, err := user.ListProducts(ctx)
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	assert.Equal(t, 1900, *list.Items[0].Prices[0].EffectivePrice, "an exclusive promotion stands alone")
+
+	require.NoError(t, admin.DeletePromotion(ctx, added.ID))
+	promotions, err := admin.ListPromotions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, promotions.Total)
+	_, err = admin.GetPromotion(ctx, added.ID)
+	assert.True(t, errors.Is(err, ErrNotFound), err)
+
+	invalid := running
+	invalid.Name, invalid.Value = "слишком щедрая", 150
+	_, err = admin.AddPromotion(ctx, invalid)
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, []InvalidParam{{Name: "value", Reason: "must be at most 100"}}, apiErr.InvalidParams)
+
+	_, err = user.ListPromotions(ctx)
+	assert.True(t, errors.Is(err, ErrForbidden), err)
+}
+
+func Test_Coupons(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(t))
+	defer server.Close()
+	ctx := context.Background()
+	admin := New(server.URL, "user2", "user1password")
+	user := New(server.URL, "user1", "user1password")
+
+	toy, err := admin.AddProduct(ctx, ProductInput{
+		SKU: "6001", Name: "пушка", Type: "тепловая", Description: "пушка детская", ShopID: 1, CategoryID: 1,
+	})
+	require.NoError(t, err)
+	_, err = admin.AddPrice(ctx, PriceInput{SalePrice: 2000, FactoryPrice: 1000, DiscountPrice: 1800, IsActive: true, ProductID: toy.ID})
+	require.NoError(t, err)
+	ball, err := admin.AddProduct(ctx, ProductInput{
+		SKU: "6002", Name: "мяч", Type: "футбольный", Description: "мяч кожаный", ShopID: 1, CategoryID: 2,
+	})
+	require.NoError(t, err)
+	_, err = admin.AddPrice(ctx, PriceInput{SalePrice: 500, FactoryPrice: 300, DiscountPrice: 450, IsActive: true, ProductID: ball.ID})
+	require.NoError(t, err)
+
+	now := time.Now()
+	toys := Coupon{
+		Code: " toys10 ", Type: CouponPercentage, Value: 10, MinOrder: 2000,
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), CategoryIDs: []int{1}, IsActive: true,
+	}
+	added, err := admin.AddCoupon(ctx, toys)
+	require.NoError(t, err)
+	assert.Equal(t, "TOYS10", added.Code, "codes are stored upper case")
+	_, err = admin.AddCoupon(ctx, toys)
+	assert.True(t, errors.Is(err, ErrConflict), err)
+
+	check, err := user.ValidateCoupon(ctx, "Toys10", BasketItem{ProductID: toy.ID}, BasketItem{ProductID: ball.ID, Quantity: 2})
+	require.NoError(t, err)
+	assert.True(t, check.Valid, check.Reason)
+	assert.Equal(t, 3000, check.Subtotal)
+	assert.Equal(t, 2000, check.Eligible)
+	assert.Equal(t, 200, check.Discount)
+	assert.Equal(t, 2800, check.Total)
+	require.Len(t, check.Items, 2)
+	assert.Equal(t, CouponCheckItem{ProductID: ball.ID, Quantity: 2, UnitPrice: 500, Eligible: false}, *check.Items[1])
+
+	check, err = user.ValidateCoupon(ctx, "TOYS10", BasketItem{ProductID: ball.ID, Quantity: 4})
+	require.NoError(t, err)
+	assert.False(t, check.Valid)
+	assert.Equal(t, "no item in the basket is eligible", check.Reason)
+	assert.Equal(t, 2000, check.Total)
+
+	added.IsActive = false
+	_, err = admin.EditCoupon(ctx, *added)
+	require.NoError(t, err)
+	check, err = user.ValidateCoupon(ctx, "TOYS10", BasketItem{ProductID: toy.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "coupon is not active", check.Reason)
+
+	check, err = user.ValidateCoupon(ctx, "NOSUCHCODE", BasketItem{ProductID: toy.ID})
+	require.NoError(t, err)
+	assert.False(t, check.Valid)
+	assert.Equal(t, "unknown coupon code", check.Reason)
+
+	_, err = user.ValidateCoupon(ctx, "TOYS10", BasketItem{ProductID: "00000000-0000-0000-0000-000000000099"})
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, []InvalidParam{{Name: "items[0].product_id", Reason: "product is not available"}}, apiErr.InvalidParams)
+
+	coupons, err := admin.ListCoupons(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, coupons.Total)
+	require.NoError(t, admin.DeleteCoupon(ctx, added.ID))
+	_, err = admin.GetCoupon(ctx, added.ID)
+	assert.True(t, errors.Is(err, ErrNotFound), err)
+
+	_, err = user.ListCoupons(ctx)
+	assert.True(t, errors.Is(err, ErrForbidden), err)
+}
+
+func Test_Cart(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(t))
+	defer server.Close()
+	ctx := context.Background()
+	admin := New(server.URL, "user2", "user1password")
+	user := New(server.URL, "user1", "user1password")
+
+	toy, err := admin.AddProduct(ctx, ProductInput{
+		SKU: "7001", Name: "пушка", Type: "тепловая", Description: "пушка детская", ShopID: 1, CategoryID: 1,
+	})
+	require.NoError(t, err)
+	toyPrice, err := admin.AddPrice(ctx, PriceInput{SalePrice: 2000, FactoryPrice: 1000, DiscountPrice: 1800, IsActive: true, ProductID: toy.ID})
+	require.NoError(t, err)
+	ball, err := admin.AddProduct(ctx, ProductInput{
+		SKU: "7002", Name: "мяч", Type: "футбольный", Description: "мяч кожаный", ShopID: 1, CategoryID: 1,
+	})
+	require.NoError(t, err)
+	_, err = admin.AddPrice(ctx, PriceInput{SalePrice: 500, FactoryPrice: 300, DiscountPrice: 450, IsActive: true, ProductID: ball.ID})
+	require.NoError(t, err)
+	unpriced, err := admin.AddProduct(ctx, ProductInput{
+		SKU: "7003", Name: "кубик", Type: "деревянный", Description: "кубик", ShopID: 1, CategoryID: 1,
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	_, err = admin.AddPromotion(ctx, Promotion{Name: "2+1 на мячи", Type: PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1,
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), ProductIDs: []string{ball.ID}, IsActive: true})
+	require.NoError(t, err)
+
+	cart, err := user.GetCart(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, cart.Items)
+
+	_, err = user.AddToCart(ctx, toy.ID, 0)
+	require.NoError(t, err)
+	_, err = user.AddToCart(ctx, ball.ID, 1)
+	require.NoError(t, err)
+	cart, err = user.AddToCart(ctx, ball.ID, 2)
+	require.NoError(t, err)
+	require.Len(t, cart.Items, 2)
+	assert.Equal(t, 3, cart.Items[1].Quantity, "adding again adds up")
+	assert.Equal(t, 4, cart.Count)
+	assert.Equal(t, 3500, cart.Subtotal)
+	assert.Equal(t, 500, cart.Discount, "every third ball is free")
+	assert.Equal(t, 3000, cart.Total)
+	assert.Empty(t, cart.Warnings)
+
+	_, err = user.AddToCart(ctx, unpriced.ID, 1)
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+
+	toyPrice.SalePrice = 2200
+	_, err = admin.EditPrice(ctx, PriceInput{ID: toyPrice.ID, SalePrice: 2200, FactoryPrice: 1000, DiscountPrice: 1800, IsActive: true})
+	require.NoError(t, err)
+	cart, err = user.GetCart(ctx)
+	require.NoError(t, err)
+	require.Len(t, cart.Warnings, 1)
+	assert.Equal(t, CartWarning{ProductID: toy.ID, Code: CartPriceChanged,
+		Message: "the price of пушка has changed from 2000 to 2200", OldPrice: 2000, NewPrice: 2200}, *cart.Warnings[0])
+	assert.Equal(t, 2200, cart.Items[0].UnitPrice, "totals follow the current price")
+
+	cart, err = user.SetCartQuantity(ctx, toy.ID, 2)
+	require.NoError(t, err)
+	assert.Empty(t, cart.Warnings, "changing the quantity accepts the new price")
+	assert.Equal(t, 5900, cart.Subtotal)
+
+	_, err = admin.EditProduct(ctx, ProductInput{SKU: "7002"})
+	require.NoError(t, err)
+	cart, err = user.GetCart(ctx)
+	require.NoError(t, err)
+	require.Len(t, cart.Warnings, 1)
+	assert.Equal(t, CartUnavailable, cart.Warnings[0].Code)
+	assert.False(t, cart.Items[1].Available)
+	assert.Equal(t, 4400, cart.Total, "unavailable items aren't counted")
+
+	other, err := New(server.URL, "user2", "user1password").GetCart(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, other.Items, "carts are per user")
+
+	_, err = user.SetCartQuantity(ctx, toy.ID, 1001)
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, []InvalidParam{{Name: "quantity", Reason: "must be at most 1000"}}, apiErr.InvalidParams)
+
+	cart, err = user.RemoveFromCart(ctx, ball.ID)
+	require.NoError(t, err)
+	assert.Len(t, cart.Items, 1)
+	_, err = user.RemoveFromCart(ctx, ball.ID)
+	assert.True(t, errors.Is(err, ErrNotFound), err)
+
+	require.NoError(t, user.ClearCart(ctx))
+	cart, err = user.GetCart(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, cart.Items)
+}
+
+func Test_Orders(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(t))
+	defer server.Close()
+	ctx := context.Background()
+	admin := New(server.URL, "user2", "user1password")
+	user := New(server.URL, "user1", "user1password")
+
+	toy, err := admin.AddProduct(ctx, ProductInput{
+		SKU: "8001", Name: "пушка", Type: "тепловая", Description: "пушка детская", ShopID: 1, CategoryID: 1,
+	})
+	require.NoError(t, err)
+	toyPrice, err := admin.AddPrice(ctx, PriceInput{SalePrice: 2000, FactoryPrice: 1000, DiscountPrice: 1800, IsActive: true, ProductID: toy.ID})
+	require.NoError(t, err)
+	_, err = admin.SetStock(ctx, 1, toy.ID, 10)
+	require.NoError(t, err)
+	now := time.Now()
+	_, err = admin.AddPromotion(ctx, Promotion{Name: "минус 10%", Type: PromotionPercentage, Value: 10,
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), ProductIDs: []string{toy.ID}, IsActive: true})
+	require.NoError(t, err)
+	_, err = admin.AddCoupon(ctx, Coupon{Code: "ONCE", Type: CouponFixed, Value: 600, PerUserLimit: 1,
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), IsActive: true})
+	require.NoError(t, err)
+
+	_, err = user.Checkout(ctx, "")
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, []InvalidParam{{Name: "cart", Reason: "is empty"}}, apiErr.InvalidParams)
+
+	_, err = user.AddToCart(ctx, toy.ID, 2)
+	require.NoError(t, err)
+	_, err = admin.EditPrice(ctx, PriceInput{ID: toyPrice.ID, SalePrice: 2100, FactoryPrice: 1000, DiscountPrice: 1800, IsActive: true})
+	require.NoError(t, err)
+	_, err = user.Checkout(ctx, "")
+	assert.True(t, errors.Is(err, ErrConflict), "a repriced cart must be reviewed first: %v", err)
+	_, err = user.SetCartQuantity(ctx, toy.ID, 2)
+	require.NoError(t, err)
+
+	_, err = user.Checkout(ctx, "nosuchcode")
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, []InvalidParam{{Name: "coupon_code", Reason: "unknown coupon code"}}, apiErr.InvalidParams)
+
+	order, err := user.Checkout(ctx, "once")
+	require.NoError(t, err)
+	assert.Equal(t, OrderCreated, order.Status)
+	assert.Equal(t, 4200, order.Subtotal)
+	assert.Equal(t, 420, order.Discount)
+	assert.Equal(t, "ONCE", order.CouponCode)
+	assert.Equal(t, 600, order.CouponDiscount)
+	assert.Equal(t, 3180, order.Total)
+	require.Len(t, order.Items, 1)
+	assert.Equal(t, OrderItem{ProductID: toy.ID, SKU: "8001", Name: "пушка", Quantity: 2, UnitPrice: 2100, Total: 3780}, *order.Items[0])
+	cart, err := user.GetCart(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, cart.Items, "ordered items leave the cart")
+
+	_, err = user.AddToCart(ctx, toy.ID, 1)
+	require.NoError(t, err)
+	_, err = user.Checkout(ctx, "ONCE")
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, "coupon has already been used the allowed number of times", apiErr.InvalidParams[0].Reason)
+	second, err := user.Checkout(ctx, "")
+	require.NoError(t, err)
+
+	_, err = user.SetOrderStatus(ctx, order.ID, OrderPaid, "")
+	assert.True(t, errors.Is(err, ErrForbidden), "customers can only cancel: %v", err)
+	for _, status := range []string{OrderPaid, OrderPacked, OrderShipped} {
+		order, err = admin.SetOrderStatus(ctx, order.ID, status, "")
+		require.NoError(t, err, status)
+	}
+	_, err = admin.SetOrderStatus(ctx, order.ID, OrderCancelled, "")
+	assert.True(t, errors.Is(err, ErrConflict), "shipped orders are refunded, not cancelled: %v", err)
+	_, err = user.SetOrderStatus(ctx, order.ID, OrderCancelled, "")
+	assert.True(t, errors.Is(err, ErrConflict), err)
+	_, err = admin.SetOrderStatus(ctx, order.ID, OrderDelivered, "оставлено у двери")
+	require.NoError(t, err)
+
+	order, err = user.GetOrder(ctx, order.ID)
+	require.NoError(t, err)
+	require.Len(t, order.History, 5)
+	last := order.History[4]
+	assert.Equal(t, OrderShipped, last.From)
+	assert.Equal(t, OrderDelivered, last.To)
+	assert.Equal(t, 2, last.ActorID)
+	assert.Equal(t, "оставлено у двери", last.Note)
+
+	second, err = user.SetOrderStatus(ctx, second.ID, OrderCancelled, "передумал")
+	require.NoError(t, err)
+	assert.Equal(t, OrderCancelled, second.Status)
+
+	mine, err := user.ListOrders(ctx, OrderFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, mine.Total)
+	assert.Equal(t, second.ID, mine.Items[0].ID, "newest first")
+	_, err = user.ListOrders(ctx, OrderFilter{UserID: 2})
+	assert.True(t, errors.Is(err, ErrForbidden), err)
+	delivered, err := admin.ListOrders(ctx, OrderFilter{UserID: 1, Status: OrderDelivered})
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered.Total)
+	own, err := admin.ListOrders(ctx, OrderFilter{UserID: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 0, own.Total)
+
+	_, err = admin.AddToCart(ctx, toy.ID, 1)
+	require.NoError(t, err)
+	staffOrder, err := admin.Checkout(ctx, "")
+	require.NoError(t, err)
+	_, err = user.GetOrder(ctx, staffOrder.ID)
+	assert.True(t, errors.Is(err, ErrNotFound), "other customers' orders are hidden: %v", err)
+	assert.Equal(t, []int{1}, staffOrder.ShopIDs)
+
+	staff := New(server.URL, "user3", "user1password")
+	_, err = staff.GetOrder(ctx, staffOrder.ID)
+	assert.True(t, errors.Is(err, ErrNotFound), "staff see the orders of their shops only: %v", err)
+	require.NoError(t, admin.SetStaffShops(ctx, "user3", []int{2}))
+	_, err = staff.SetOrderStatus(ctx, staffOrder.ID, OrderPaid, "")
+	assert.True(t, errors.Is(err, ErrNotFound), err)
+	require.NoError(t, admin.SetStaffShops(ctx, "user3", []int{1}))
+	worked, err := staff.ListOrders(ctx, OrderFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, worked.Total)
+	staffOrder, err = staff.SetOrderStatus(ctx, staffOrder.ID, OrderPaid, "")
+	require.NoError(t, err)
+	assert.Equal(t, OrderPaid, staffOrder.Status)
+	err = user.SetStaffShops(ctx, "user3", []int{1})
+	assert.True(t, errors.Is(err, ErrForbidden), err)
+}
+
+func Test_Payments(t *testing.T) {
+	// The fake provider confirms delayed payments by calling the webhook of
+	// this very server, so its address is needed before the router is built.
+	server := httptest.NewUnstartedServer(nil)
+	webhook := "http://" + server.Listener.Addr().String() + "/api/v1/payments/webhook"
+	fake := payments.NewFake("test-secret", zap.NewNop(), payments.WithWebhook(webhook, 10*time.Millisecond))
+	server.Config.Handler = newTestRouterWith(t, fake)
+	server.Start()
+	defer server.Close()
+	ctx := context.Background()
+	admin := New(server.URL, "user2", "user1password")
+	user := New(server.URL, "user1", "user1password")
+
+	toy, err := admin.AddProduct(ctx, ProductInput{
+		SKU: "9001", Name: "пушка", Type: "тепловая", Description: "пушка детская", ShopID: 1, CategoryID: 1,
+		Price: &PriceInput{SalePrice: 2000, FactoryPrice: 1000, DiscountPrice: 1800, IsActive: true},
+	})
+	require.NoError(t, err)
+	_, err = admin.SetStock(ctx, 1, toy.ID, 10)
+	require.NoError(t, err)
+	checkout := func() *Order {
+		_, err := user.AddToCart(ctx, toy.ID, 1)
+		require.NoError(t, err)
+		order, err := user.Checkout(ctx, "")
+		require.NoError(t, err)
+		return order
+	}
+
+	order := checkout()
+	_, err = admin.PayOrder(ctx, order.ID, "")
+	assert.True(t, errors.Is(err, ErrForbidden), "only the customer pays: %v", err)
+
+	declined, err := user.PayOrder(ctx, order.ID, payments.TokenDecline)
+	require.NoError(t, err)
+	assert.Equal(t, PaymentDeclined, declined.Status)
+	assert.Equal(t, "card declined", declined.Reason)
+	order, err = user.GetOrder(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, OrderCreated, order.Status)
+
+	paid, err := user.PayOrder(ctx, order.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, PaymentCaptured, paid.Status)
+	assert.Equal(t, 2000, paid.Amount)
+	order, err = user.GetOrder(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, OrderPaid, order.Status)
+	assert.Equal(t, "payment "+paid.ID, order.History[len(order.History)-1].Note)
+	_, err = user.PayOrder(ctx, order.ID, "")
+	assert.True(t, errors.Is(err, ErrConflict), "paid orders aren't paid twice: %v", err)
+	list, err := user.ListPayments(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.Total)
+
+	_, err = admin.SetOrderStatus(ctx, order.ID, OrderRefunded, "")
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	_, err = user.RefundOrder(ctx, order.ID, "")
+	assert.True(t, errors.Is(err, ErrForbidden), err)
+	order, err = admin.RefundOrder(ctx, order.ID, "брак")
+	require.NoError(t, err)
+	assert.Equal(t, OrderRefunded, order.Status)
+	list, err = admin.ListPayments(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, PaymentRefunded, list.Items[1].Status)
+
+	delayed := checkout()
+	pending, err := user.PayOrder(ctx, delayed.ID, payments.TokenDelayed)
+	require.NoError(t, err)
+	assert.Equal(t, PaymentPending, pending.Status)
+	require.Eventually(t, func() bool {
+		o, err := user.GetOrder(ctx, delayed.ID)
+		return err == nil && o.Status == OrderPaid
+	}, 5*time.Second, 10*time.Millisecond, "the webhook marks the order paid")
+	list, err = user.ListPayments(ctx, delayed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, PaymentCaptured, list.Items[0].Status)
+
+	body := []byte(`{"payment_id":"` + pending.ID + `","status":"declined"}`)
+	post := func(signature string) int {
+		request, err := http.NewRequest(http.MethodPost, webhook, bytes.NewReader(body))
+		require.NoError(t, err)
+		request.Header.Set(payments.SignatureHeader, signature)
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		response.Body.Close()
+		return response.StatusCode
+	}
+	assert.Equal(t, http.StatusUnauthorized, post("00"))
+	assert.Equal(t, http.StatusNoContent, post(fake.Sign(body)), "settled payments ignore repeated webhooks")
+	list, err = user.ListPayments(ctx, delayed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, PaymentCaptured, list.Items[0].Status)
+}
+
+func Test_StockReservations(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(t))
+	defer server.Close()
+	ctx := context.Background()
+	admin := New(server.URL, "user2", "user1password")
+	user := New(server.URL, "user1", "user1password")
+
+	toy, err := admin.AddProduct(ctx, ProductInput{
+		SKU: "9101", Name: "пушка", Type: "тепловая", Description: "пушка детская", ShopID: 1, CategoryID: 1,
+		Price: &PriceInput{SalePrice: 2000, FactoryPrice: 1000, DiscountPrice: 1800, IsActive: true},
+	})
+	require.NoError(t, err)
+	_, err = admin.SetStock(ctx, 1, toy.ID, 1)
+	require.NoError(t, err)
+	_, err = admin.SetStock(ctx, 2, toy.ID, 2)
+	require.NoError(t, err)
+	stock, err := user.ListStock(ctx, toy.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, stock.Available)
+
+	_, err = user.AddToCart(ctx, toy.ID, 3)
+	require.NoError(t, err)
+	_, err = user.Checkout(ctx, "")
+	assert.True(t, errors.Is(err, ErrConflict), "no shop has three: %v", err)
+	_, err = user.SetCartQuantity(ctx, toy.ID, 2)
+	require.NoError(t, err)
+	first, err := user.Checkout(ctx, "")
+	require.NoError(t, err)
+	assert.NotNil(t, first.ReservedUntil)
+	stock, err = user.ListStock(ctx, toy.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stock.Available)
+	assert.Equal(t, 2, stock.Items[1].Reserved, "held in the shop that had the most")
+
+	_, err = admin.SetStock(ctx, 2, toy.ID, 1)
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode, "stock can't go below what is reserved")
+
+	// Both customers race for the last unit.
+	for _, c := range []*Client{user, admin} {
+		_, err = c.AddToCart(ctx, toy.ID, 1)
+		require.NoError(t, err)
+	}
+	results := make(chan error, 2)
+	orders := make(chan *Order, 2)
+	for _, c := range []*Client{user, admin} {
+		go func(c *Client) {
+			order, err := c.Checkout(ctx, "")
+			if err == nil {
+				orders <- order
+			}
+			results <- err
+		}(c)
+	}
+	var conflicts int
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			assert.True(t, errors.Is(err, ErrConflict), err)
+			conflicts++
+		}
+	}
+	assert.Equal(t, 1, conflicts, "the last unit is sold once")
+	second := <-orders
+
+	_, err = user.SetOrderStatus(ctx, first.ID, OrderCancelled, "")
+	require.NoError(t, err)
+	stock, err = user.ListStock(ctx, toy.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stock.Available, "cancelling releases the reservation")
+
+	_, err = admin.SetOrderStatus(ctx, second.ID, OrderPaid, "")
+	require.NoError(t, err)
+	stock, err = user.ListStock(ctx, toy.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stock.Available)
+	assert.Equal(t, 2, stock.Items[0].Quantity+stock.Items[1].Quantity, "paying deducts the unit")
+	assert.Zero(t, stock.Items[0].Reserved+stock.Items[1].Reserved)
+}
+
+func Test_Metrics(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(t))
+	defer server.Close()
+	ctx := context.Background()
+	admin := New(server.URL, "user2", "user1password")
+
+	shopID, err := admin.AddShop(ctx, Shop{Name: "Магазин", Address: "Москва"})
+	require.NoError(t, err)
+	categoryID, err := admin.AddCategory(ctx, "Игрушки")
+	require.NoError(t, err)
+	product, err := admin.AddProduct(ctx, ProductInput{
+		SKU: "3001", Name: "пушка", Type: "тепловая", Description: "пушка детская",
+		ShopID: shopID, CategoryID: categoryID,
+		Price: &PriceInput{SalePrice: 2000, FactoryPrice: 1000, DiscountPrice: 1600, IsActive: true},
+	})
+	require.NoError(t, err)
+	_, err = admin.GetProduct(ctx, product.ID)
+	require.NoError(t, err)
+	denied, err := http.Post(server.URL+"/api/v1/auth", "application/json",
+		strings.NewReader(`{"login":"user1","password":"wrong password"}`))
+	require.NoError(t, err)
+	denied.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, denied.StatusCode)
+
+	resp, err := http.Get(server.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	for _, line := range []string{
+		`market_http_requests_total{method="GET",route="/api/v1/products/{productID}",status="200"} 1`,
+		`market_http_requests_total{method="POST",route="/api/v1/auth",status="401"} 1`,
+		`market_products_created_total{source="api"} 1`,
+		`market_price_changes_total{source="api"} 1`,
+		`market_logins_total{result="failed"} 1`,
+		`market_logins_total{result="succeeded"} 1`,
+	} {
+		assert.Contains(t, string(body), line)
+	}
+}
+
+func Test_Audit(t *testing.T) {
+	server := httptest.NewServer(newTestRouter(t))
+	defer server.Close()
+	ctx := context.Background()
+	admin := New(server.URL, "user2", "user1password")
+
+	product, err := admin.AddProduct(ctx, ProductInput{
+		SKU: "6001", Name: "юла", Type: "игрушка", Description: "юла детская", ShopID: 1, CategoryID: 1,
+	})
+	require.NoError(t, err)
+	price, err := admin.AddPrice(ctx, PriceInput{SalePrice: 2000, FactoryPrice: 1000, DiscountPrice: 1800, IsActive: true, ProductID: product.ID})
+	require.NoError(t, err)
+	_, err = admin.EditPrice(ctx, PriceInput{ID: price.ID, SalePrice: 2500, FactoryPrice: 1000, DiscountPrice: 1800, IsActive: true, ProductID: product.ID})
+	require.NoError(t, err)
+	auditor, err := admin.AddUser(ctx, "auditor", "auditorpassword", "USER")
+	require.NoError(t, err)
+	require.NoError(t, admin.AddRole(ctx, "auditor", "ADMIN"))
+
+	list, err := admin.ListAudit(ctx, AuditFilter{Entity: "price", EntityID: strconv.Itoa(price.ID)})
+	require.NoError(t, err)
+	require.Equal(t, 2, list.Total)
+	edit, add := list.Items[0], list.Items[1]
+	assert.Equal(t, "update", edit.Action, "newest first")
+	assert.Equal(t, "create", add.Action)
+	assert.Equal(t, 2, edit.ActorID, "the admin from the token")
+	assert.NotEmpty(t, edit.RequestID)
+	assert.NotEqual(t, add.RequestID, edit.RequestID)
+	assert.Empty(t, add.Before)
+	var before, after Price
+	require.NoError(t, json.Unmarshal(edit.Before, &before))
+	require.NoError(t, json.Unmarshal(edit.After, &after))
+	assert.Equal(t, 2000, before.SalePrice)
+	assert.Equal(t, 2500, after.SalePrice)
+
+	byRequest, err := admin.ListAudit(ctx, AuditFilter{RequestID: edit.RequestID})
+	require.NoError(t, err)
+	require.Equal(t, 1, byRequest.Total)
+	assert.Equal(t, edit.ID, byRequest.Items[0].ID)
+
+	roles, err := admin.ListAudit(ctx, AuditFilter{Action: "grant_role"})
+	require.NoError(t, err)
+	require.Equal(t, 1, roles.Total)
+	assert.Equal(t, strconv.Itoa(auditor.ID), roles.Items[0].EntityID)
+	assert.NotContains(t, string(roles.Items[0].Before), "ADMIN")
+	assert.Contains(t, string(roles.Items[0].After), "ADMIN")
+	created, err := admin.ListAudit(ctx, AuditFilter{Entity: "user", Action: "create"})
+	require.NoError(t, err)
+	require.Equal(t, 1, created.Total)
+	assert.NotContains(t, string(created.Items[0].After), "password")
+
+	page, err := admin.ListAudit(ctx, AuditFilter{Limit: 1})
+	require.NoError(t, err)
+	require.Equal(t, 1, page.Total)
+	assert.Equal(t, page.Items[0].ID, page.NextBeforeID)
+	next, err := admin.ListAudit(ctx, AuditFilter{Limit: 1, BeforeID: page.NextBeforeID})
+	require.NoError(t, err)
+	assert.Less(t, next.Items[0].ID, page.Items[0].ID)
+
+	_, err = admin.ListAudit(ctx, AuditFilter{Entity: "order"})
+	var apiErr *Error
+	require.True(t, errors.As(err, &apiErr), err)
+	assert.Equal(t, "entity", apiErr.InvalidParams[0].Name)
+
+	user := New(server.URL, "user1", "user1password")
+	_, err = user.ListAudit(ctx, AuditFilter{})
+	assert.True(t, errors.Is(err, ErrForbidden), err)
+}
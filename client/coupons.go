@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+func (c *Client) ListCoupons(ctx context.Context) (*CouponList, error) {
+	var list CouponList
+	if err := c.call(ctx, http.MethodGet, "/coupons", nil, &list); err != nil {
+		return nil, fmt.Errorf("ListCoupons: %w", err)
+	}
+	return &list, nil
+}
+
+func (c *Client) GetCoupon(ctx context.Context, couponID int) (*Coupon, error) {
+	var coupon Coupon
+	if err := c.call(ctx, http.MethodGet, fmt.Sprintf("/coupons/%d", couponID), nil, &coupon); err != nil {
+		return nil, fmt.Errorf("GetCoupon: %w", err)
+	}
+	return &coupon, nil
+}
+
+func (c *Client) AddCoupon(ctx context.Context, coupon Coupon) (*Coupon, error) {
+	var added Coupon
+	if err := c.call(ctx, http.MethodPost, "/coupons", coupon, &added); err != nil {
+		return nil, fmt.Errorf("AddCoupon: %w", err)
+	}
+	return &added, nil
+}
+
+// EditCoupon replaces the coupon with coupon.ID.
+func (c *Client) EditCoupon(ctx context.Context, coupon Coupon) (*Coupon, error) {
+	var edited Coupon
+	if err := c.call(ctx, http.MethodPut, "/coupons", coupon, &edited); err != nil {
+		return nil, fmt.Errorf("EditCoupon: %w", err)
+	}
+	return &edited, nil
+}
+
+func (c *Client) DeleteCoupon(ctx context.Context, couponID int) error {
+	if err := c.call(ctx, http.MethodDelete, fmt.Sprintf("/coupons/%d", couponID), nil, nil); err != nil {
+		return fmt.Errorf("DeleteCoupon: %w", err)
+	}
+	return nil
+}
+
+// ValidateCoupon checks a code against a basket without redeeming it. A
+// coupon that can't be used is not an error; see CouponCheck.Reason.
+func (c *Client) ValidateCoupon(ctx context.Context, code string, items ...BasketItem) (*CouponCheck, error) {
+	request := struct {
+		Code  string       `json:"code"`
+		Items []BasketItem `json:"items"`
+	}{code, items}
+	var check CouponCheck
+	if err := c.call(ctx, http.MethodPost, "/coupons/validate", request, &check); err != nil {
+		return nil, fmt.Errorf("ValidateCoupon: %w", err)
+	}
+	return &check, nil
+}
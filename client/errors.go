@@ -0,0 +1,110 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+)
+
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Error is returned for every non-2xx response. It carries the problem
+// document sent by the server and matches the Err* values via errors.Is.
+type Error struct {
+	StatusCode    int            `json:"status"`
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Detail        string         `json:"detail"`
+	Instance      string         `json:"instance"`
+	InvalidParams []InvalidParam `json:"invalid_params"`
+}
+
+func newError(status int, body []byte) *Error {
+	e := &Error{}
+	if err := json.Unmarshal(body, e); err != nil || e.Title == "" {
+		e.Title = http.StatusText(status)
+	}
+	e.StatusCode = status
+	return e
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("market4: %d %s", e.StatusCode, e.Title)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	for _, p := range e.InvalidParams {
+		msg += fmt.Sprintf("; %s %s", p.Name, p.Reason)
+	}
+	return msg
+}
+
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	}
+	return false
+}
+
+func IsUnauthorized(err error) bool {
+	return errors.Is(err, ErrUnauthorized)
+}
+
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// retryable reports whether a failed request may be repeated. Rate limited
+// requests were never processed, so they are safe to repeat for any method;
+// other failures are only repeated for idempotent methods.
+func retryable(method string, err error) bool {
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	switch method {
+	case http.MethodGet, http.MethodPut, http.MethodDelete, http.MethodHead:
+	default:
+		return false
+	}
+	var tErr *transportError
+	if errors.As(err, &tErr) {
+		return true
+	}
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
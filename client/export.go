@@ -0,0 +1,19 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ExportProducts writes the products matching filter to w in format, which
+// is csv, jsonl or xlsx. The file is copied to w as it arrives.
+func (c *Client) ExportProducts(ctx context.Context, format string, filter ProductFilter, w io.Writer) error {
+	values := filter.values()
+	values.Set("format", format)
+	if err := c.call(ctx, http.MethodGet, "/export/products?"+values.Encode(), nil, w); err != nil {
+		return fmt.Errorf("ExportProducts: %w", err)
+	}
+	return nil
+}
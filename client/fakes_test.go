@@ -0,0 +1,1208 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"market4/internal/model"
+	"market4/internal/repository"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// In-memory repositories backing the real router in tests.
+
+type fakeShops struct {
+	mu      sync.Mutex
+	shops   []model.Shop
+	deleted map[int]bool
+}
+
+func (f *fakeShops) ListAllShops(ctx context.Context) ([]model.Shop, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	shops := make([]model.Shop, 0)
+	for _, s := range f.shops {
+		if !f.deleted[s.ID] {
+			shops = append(shops, s)
+		}
+	}
+	return shops, nil
+}
+
+func (f *fakeShops) AddShop(ctx context.Context, s *model.Shop) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s.ID = len(f.shops) + 1
+	f.shops = append(f.shops, *s)
+	return s.ID, nil
+}
+
+func (f *fakeShops) EditShop(ctx context.Context, s *model.Shop) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.shops {
+		if f.shops[i].ID == s.ID {
+			f.shops[i] = *s
+			return nil
+		}
+	}
+	return repository.NewNotFoundError(fmt.Sprintf("shop %d", s.ID))
+}
+
+func (f *fakeShops) IfShopExists(ctx context.Context, shopID int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return shopID > 0 && shopID <= len(f.shops) && !f.deleted[shopID]
+}
+
+type fakeCategories struct {
+	mu         sync.Mutex
+	categories []model.Category
+}
+
+func (f *fakeCategories) ListAllCategories(ctx context.Context) ([]model.Category, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]model.Category(nil), f.categories...), nil
+}
+
+func (f *fakeCategories) AddCategory(ctx context.Context, c *model.Category) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, existing := range f.categories {
+		if existing.Name == c.Name {
+			return 0, repository.NewConflictError("category " + c.Name)
+		}
+	}
+	c.ID = len(f.categories) + 1
+	c.URI_name = fmt.Sprintf("%s-%d", c.Name, c.ID)
+	f.categories = append(f.categories, *c)
+	return c.ID, nil
+}
+
+func (f *fakeCategories) EditCategory(ctx context.Context, c *model.Category) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.categories {
+		if f.categories[i].ID == c.ID {
+			f.categories[i].Name = c.Name
+			return nil
+		}
+	}
+	return repository.NewNotFoundError(fmt.Sprintf("category %d", c.ID))
+}
+
+func (f *fakeCategories) IfCategoryExists(ctx context.Context, categoryID int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return categoryID > 0 && categoryID <= len(f.categories)
+}
+
+type fakeProducts struct {
+	mu         sync.Mutex
+	products   []model.Product
+	deleted    map[string]bool
+	categories map[string][]int
+}
+
+func (f *fakeProducts) AddProduct(ctx context.Context, p model.Product, shopID, categoryID int) (model.Product, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p.ID = fmt.Sprintf("00000000-0000-0000-0000-%012d", len(f.products)+1)
+	p.URI = fmt.Sprintf("/product/%s-%s", p.Type, p.SKU)
+	p.Type = ""
+	p.IsActive = true
+	f.products = append(f.products, p)
+	if f.categories == nil {
+		f.categories = make(map[string][]int)
+	}
+	f.categories[p.ID] = []int{categoryID}
+	return p, nil
+}
+
+func (f *fakeProducts) EditProduct(ctx context.Context, p model.Product, shopID, categoryID int) (model.Product, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.products {
+		if f.products[i].SKU == p.SKU {
+			if p.Name != "" {
+				f.products[i].Name = p.Name
+			}
+			f.products[i].IsActive = p.IsActive
+			return f.products[i], nil
+		}
+	}
+	return model.Product{}, repository.NewNotFoundError("product " + p.SKU)
+}
+
+func (f *fakeProducts) ListAllProducts(ctx context.Context) ([]model.Product, error) {
+	return f.ListProducts(ctx, model.ProductFilter{})
+}
+
+// ListProducts applies the name and is_active filters; the fake doesn't
+// track shop and category links.
+func (f *fakeProducts) ListProducts(ctx context.Context, filter model.ProductFilter) ([]model.Product, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	products := make([]model.Product, 0)
+	for _, p := range f.products {
+		if f.deleted[p.ID] {
+			continue
+		}
+		if filter.IsActive != nil && p.IsActive != *filter.IsActive {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(p.Name), strings.ToLower(filter.Name)) {
+			continue
+		}
+		products = append(products, p)
+	}
+	return products, nil
+}
+
+func (f *fakeProducts) IfProductExists(ctx context.Context, productID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.products {
+		if p.ID == productID {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeProducts) SearchProductsByCategory(ctx context.Context, category int) ([]model.Product, error) {
+	return []model.Product{}, nil
+}
+
+func (f *fakeProducts) SearchProductsByName(ctx context.Context, productName string) (model.Product, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.products {
+		if p.Name == productName {
+			return p, nil
+		}
+	}
+	return model.Product{}, nil
+}
+
+func (f *fakeProducts) SearchProductsByShop(ctx context.Context, shopID int) ([]model.Product, error) {
+	return []model.Product{}, nil
+}
+
+type fakePrices struct {
+	mu      sync.Mutex
+	prices  []model.Price
+	batches []model.PriceBatch
+}
+
+func (f *fakePrices) AddPrice(ctx context.Context, p *model.Price) (model.Price, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p.ID = len(f.prices) + 1
+	f.prices = append(f.prices, *p)
+	return *p, nil
+}
+
+func (f *fakePrices) EditPrice(ctx context.Context, p *model.Price) (model.Price, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.prices {
+		if f.prices[i].ID == p.ID {
+			p.ProductID = f.prices[i].ProductID
+			f.prices[i] = *p
+			return *p, nil
+		}
+	}
+	return model.Price{}, repository.NewNotFoundError(fmt.Sprintf("price %d", p.ID))
+}
+
+func (f *fakePrices) ListAllPrices(ctx context.Context) ([]model.Price, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]model.Price(nil), f.prices...), nil
+}
+
+func (f *fakePrices) SearchPriceByProductID(ctx context.Context, productID string) (model.Price, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.prices {
+		if p.ProductID == productID {
+			return p, nil
+		}
+	}
+	return model.Price{}, nil
+}
+
+func (f *fakePrices) EditPriceByProductID(ctx context.Context, p *model.Price) (model.Price, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.prices {
+		if f.prices[i].ProductID == p.ProductID {
+			p.ID = f.prices[i].ID
+			f.prices[i] = *p
+			return *p, nil
+		}
+	}
+	return model.Price{}, repository.NewNotFoundError("price of product " + p.ProductID)
+}
+
+type fakeUser struct {
+	id       int
+	password string
+	roles    []string
+	shops    []int
+}
+
+type fakeUsers struct {
+	mu    sync.Mutex
+	users map[string]*fakeUser
+}
+
+func (f *fakeUsers) AddUser(ctx context.Context, u *model.User) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.users[u.Login]; ok {
+		return nil, repository.NewConflictError("user " + u.Login)
+	}
+	id := len(f.users) + 1
+	f.users[u.Login] = &fakeUser{id: id, password: u.Password, roles: []string{u.Role}}
+	return &model.User{ID: id}, nil
+}
+
+func (f *fakeUsers) EditUser(ctx context.Context, u *model.User) (*model.User, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user, ok := f.users[u.Login]
+	if !ok {
+		return &model.User{}, repository.NewNotFoundError("user " + u.Login)
+	}
+	user.password = u.Password
+	return &model.User{ID: user.id}, nil
+}
+
+func (f *fakeUsers) GetUserRolesByID(ctx context.Context, id int) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if u.id == id {
+			return u.roles, nil
+		}
+	}
+	return []string{}, nil
+}
+
+func (f *fakeUsers) CheckCreds(ctx context.Context, u model.User) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user, ok := f.users[u.Login]
+	return ok && user.password == u.Password
+}
+
+func (f *fakeUsers) GetUserID(ctx context.Context, login string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if user, ok := f.users[login]; ok {
+		return user.id, nil
+	}
+	return 0, nil
+}
+
+func (f *fakeUsers) GetRoleByID(ctx context.Context, roleID int) (string, error) {
+	return "", nil
+}
+
+func (f *fakeUsers) AddRole(ctx context.Context, login, role string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user, ok := f.users[login]
+	if !ok {
+		return repository.NewNotFoundError("user " + login)
+	}
+	user.roles = append(user.roles, role)
+	return nil
+}
+
+func (f *fakeUsers) RemoveRole(ctx context.Context, login, role string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user, ok := f.users[login]
+	if !ok {
+		return repository.NewNotFoundError("user " + login)
+	}
+	roles := user.roles[:0]
+	for _, r := range user.roles {
+		if r != role {
+			roles = append(roles, r)
+		}
+	}
+	user.roles = roles
+	return nil
+}
+
+func (f *fakeUsers) ListUsers(ctx context.Context) ([]model.Account, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	accounts := make([]model.Account, 0, len(f.users))
+	for login, u := range f.users {
+		accounts = append(accounts, model.Account{ID: u.id, Login: login, Roles: u.roles})
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Login < accounts[j].Login })
+	return accounts, nil
+}
+
+func (f *fakeUsers) GetStaffShops(ctx context.Context, userID int) ([]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range f.users {
+		if u.id == userID {
+			return u.shops, nil
+		}
+	}
+	return []int{}, nil
+}
+
+func (f *fakeUsers) SetStaffShops(ctx context.Context, login string, shopIDs []int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user, ok := f.users[login]
+	if !ok {
+		return repository.NewNotFoundError("user " + login)
+	}
+	user.shops = shopIDs
+	return nil
+}
+
+type fakeCache struct{}
+
+func (fakeCache) ToCache(ctx context.Context, key string, value []byte) error {
+	return nil
+}
+
+func (fakeCache) FromCache(ctx context.Context, key string) ([]byte, error) {
+	return nil, nil
+}
+
+func (f *fakeShops) GetShopByID(ctx context.Context, shopID int) (model.Shop, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range f.shops {
+		if s.ID == shopID && !f.deleted[shopID] {
+			return s, nil
+		}
+	}
+	return model.Shop{}, repository.NewNotFoundError(fmt.Sprintf("shop %d", shopID))
+}
+
+func (f *fakeCategories) GetCategoryByID(ctx context.Context, categoryID int) (model.Category, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.categories {
+		if c.ID == categoryID {
+			return c, nil
+		}
+	}
+	return model.Category{}, repository.NewNotFoundError(fmt.Sprintf("category %d", categoryID))
+}
+
+func (f *fakeCategories) GetCategoryByURIName(ctx context.Context, uriName string) (model.Category, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.categories {
+		if c.URI_name == uriName {
+			return c, nil
+		}
+	}
+	return model.Category{}, repository.NewNotFoundError("category " + uriName)
+}
+
+func (f *fakeProducts) find(match func(p model.Product) bool) (model.Product, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.products {
+		if match(p) && !f.deleted[p.ID] {
+			return p, nil
+		}
+	}
+	return model.Product{}, repository.NewNotFoundError("product")
+}
+
+func (f *fakeProducts) GetProductByID(ctx context.Context, productID string) (model.Product, error) {
+	return f.find(func(p model.Product) bool { return p.ID == productID })
+}
+
+func (f *fakeProducts) GetProductBySKU(ctx context.Context, sku string) (model.Product, error) {
+	return f.find(func(p model.Product) bool { return p.SKU == sku })
+}
+
+func (f *fakeProducts) GetProductByURI(ctx context.Context, uri string) (model.Product, error) {
+	return f.find(func(p model.Product) bool { return p.URI == uri })
+}
+
+func (f *fakePrices) GetPriceByID(ctx context.Context, priceID int) (model.Price, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.prices {
+		if p.ID == priceID {
+			return p, nil
+		}
+	}
+	return model.Price{}, repository.NewNotFoundError(fmt.Sprintf("price %d", priceID))
+}
+
+func (f *fakeShops) DeleteShop(ctx context.Context, shopID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if shopID < 1 || shopID > len(f.shops) || f.deleted[shopID] {
+		return repository.NewNotFoundError(fmt.Sprintf("shop %d", shopID))
+	}
+	if f.deleted == nil {
+		f.deleted = map[int]bool{}
+	}
+	f.deleted[shopID] = true
+	return nil
+}
+
+func (f *fakeShops) RestoreShop(ctx context.Context, shopID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.deleted[shopID] {
+		return repository.NewNotFoundError(fmt.Sprintf("archived shop %d", shopID))
+	}
+	delete(f.deleted, shopID)
+	return nil
+}
+
+func (f *fakeCategories) DeleteCategory(ctx context.Context, categoryID int) error {
+	return repository.NewConflictError(fmt.Sprintf("category %d still has products", categoryID))
+}
+
+func (f *fakeCategories) RestoreCategory(ctx context.Context, categoryID int) error {
+	return repository.NewNotFoundError(fmt.Sprintf("archived category %d", categoryID))
+}
+
+func (f *fakeProducts) DeleteProduct(ctx context.Context, productID string) error {
+	if _, err := f.find(func(p model.Product) bool { return p.ID == productID }); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.deleted == nil {
+		f.deleted = map[string]bool{}
+	}
+	f.deleted[productID] = true
+	return nil
+}
+
+func (f *fakeProducts) RestoreProduct(ctx context.Context, productID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.deleted[productID] {
+		return repository.NewNotFoundError("archived product " + productID)
+	}
+	delete(f.deleted, productID)
+	return nil
+}
+
+func (f *fakePrices) DeletePrice(ctx context.Context, priceID int) error {
+	return repository.NewNotFoundError(fmt.Sprintf("price %d", priceID))
+}
+
+func (f *fakePrices) RestorePrice(ctx context.Context, priceID int) error {
+	return repository.NewNotFoundError(fmt.Sprintf("archived price %d", priceID))
+}
+
+func (f *fakeProducts) UpsertProducts(ctx context.Context, rows []model.ProductImport) ([]repository.UpsertResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	results := make([]repository.UpsertResult, len(rows))
+	for i, row := range rows {
+		p := row.Product
+		p.URI = fmt.Sprintf("/product/%s-%s", p.Type, p.SKU)
+		p.Type = ""
+		for j := range f.products {
+			if f.products[j].SKU == p.SKU {
+				p.ID = f.products[j].ID
+				f.products[j] = p
+				delete(f.deleted, p.ID)
+				results[i] = repository.UpsertResult{ProductID: p.ID}
+			}
+		}
+		if p.ID == "" {
+			p.ID = fmt.Sprintf("00000000-0000-0000-0000-%012d", len(f.products)+1)
+			f.products = append(f.products, p)
+			results[i] = repository.UpsertResult{ProductID: p.ID, Created: true}
+		}
+	}
+	return results, nil
+}
+
+func (f *fakeProducts) ExportProducts(ctx context.Context, filter model.ProductFilter, fn func(model.ProductExport) error) error {
+	products, err := f.ListProducts(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for _, p := range products {
+		if err = fn(model.ProductExport{Product: p}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SelectPrices only honours the price range; the fake doesn't know SKUs,
+// shops or categories and reports the product ID as the SKU.
+func (f *fakePrices) SelectPrices(ctx context.Context, selector model.PriceSelector) ([]model.PriceChange, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	changes := make([]model.PriceChange, 0)
+	for _, p := range f.prices {
+		if selector.MinPrice != 0 && p.SalePrice < selector.MinPrice ||
+			selector.MaxPrice != 0 && p.SalePrice > selector.MaxPrice {
+			continue
+		}
+		changes = append(changes, model.PriceChange{PriceID: p.ID, ProductID: p.ProductID, SKU: p.ProductID, Old: p, New: p})
+	}
+	return changes, nil
+}
+
+func (f *fakePrices) ApplyPriceChanges(ctx context.Context, batch model.PriceBatch) (model.PriceBatch, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, change := range batch.Changes {
+		if f.prices[change.PriceID-1] != change.Old {
+			return model.PriceBatch{}, repository.NewConflictError(fmt.Sprintf("price %d has changed since it was read", change.PriceID))
+		}
+	}
+	for _, change := range batch.Changes {
+		f.prices[change.PriceID-1] = change.New
+	}
+	batch.ID = len(f.batches) + 1
+	f.batches = append(f.batches, batch)
+	return batch, nil
+}
+
+// fakePromotions targets products only; shop and category targets never
+// match.
+type fakePromotions struct {
+	mu         sync.Mutex
+	promotions []model.Promotion
+}
+
+func (f *fakePromotions) AddPromotion(ctx context.Context, p model.Promotion) (model.Promotion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p.ID = len(f.promotions) + 1
+	f.promotions = append(f.promotions, p)
+	return p, nil
+}
+
+func (f *fakePromotions) EditPromotion(ctx context.Context, p model.Promotion) (model.Promotion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.promotions {
+		if f.promotions[i].ID == p.ID {
+			f.promotions[i] = p
+			return p, nil
+		}
+	}
+	return model.Promotion{}, repository.NewNotFoundError(fmt.Sprintf("promotion %d", p.ID))
+}
+
+func (f *fakePromotions) GetPromotionByID(ctx context.Context, promotionID int) (model.Promotion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.promotions {
+		if p.ID == promotionID {
+			return p, nil
+		}
+	}
+	return model.Promotion{}, repository.NewNotFoundError(fmt.Sprintf("promotion %d", promotionID))
+}
+
+func (f *fakePromotions) ListPromotions(ctx context.Context) ([]model.Promotion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]model.Promotion{}, f.promotions...), nil
+}
+
+func (f *fakePromotions) DeletePromotion(ctx context.Context, promotionID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, p := range f.promotions {
+		if p.ID == promotionID {
+			f.promotions = append(f.promotions[:i], f.promotions[i+1:]...)
+			return nil
+		}
+	}
+	return repository.NewNotFoundError(fmt.Sprintf("promotion %d", promotionID))
+}
+
+func (f *fakePromotions) ActivePromotions(ctx context.Context, productIDs []string, at time.Time) (map[string][]model.Promotion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := make(map[string][]model.Promotion)
+	for _, productID := range productIDs {
+		for _, p := range f.promotions {
+			if !p.IsActive || at.Before(p.StartsAt) || !at.Before(p.EndsAt) {
+				continue
+			}
+			everything := len(p.ProductIDs) == 0 && len(p.ShopIDs) == 0 && len(p.CategoryIDs) == 0
+			targeted := false
+			for _, id := range p.ProductIDs {
+				targeted = targeted || id == productID
+			}
+			if everything || targeted {
+				result[productID] = append(result[productID], p)
+			}
+		}
+		sort.SliceStable(result[productID], func(i, j int) bool {
+			return result[productID][i].Priority > result[productID][j].Priority
+		})
+	}
+	return result, nil
+}
+
+func (f *fakeProducts) ProductCategories(ctx context.Context, productIDs []string) (map[string][]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := make(map[string][]int)
+	for _, id := range productIDs {
+		if categories, ok := f.categories[id]; ok {
+			result[id] = categories
+		}
+	}
+	return result, nil
+}
+
+type fakeCoupons struct {
+	mu          sync.Mutex
+	coupons     []model.Coupon
+	redemptions map[int][]int
+}
+
+func (f *fakeCoupons) AddCoupon(ctx context.Context, c model.Coupon) (model.Coupon, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, existing := range f.coupons {
+		if existing.Code == c.Code {
+			return model.Coupon{}, repository.NewConflictError(fmt.Sprintf("coupon %q already exists", c.Code))
+		}
+	}
+	c.ID = len(f.coupons) + 1
+	f.coupons = append(f.coupons, c)
+	return c, nil
+}
+
+func (f *fakeCoupons) EditCoupon(ctx context.Context, c model.Coupon) (model.Coupon, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.coupons {
+		if f.coupons[i].ID == c.ID {
+			f.coupons[i] = c
+			return c, nil
+		}
+	}
+	return model.Coupon{}, repository.NewNotFoundError(fmt.Sprintf("coupon %d", c.ID))
+}
+
+func (f *fakeCoupons) find(match func(c model.Coupon) bool, what string) (model.Coupon, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.coupons {
+		if match(c) {
+			c.Used = len(f.redemptions[c.ID])
+			return c, nil
+		}
+	}
+	return model.Coupon{}, repository.NewNotFoundError(what)
+}
+
+func (f *fakeCoupons) GetCouponByID(ctx context.Context, couponID int) (model.Coupon, error) {
+	return f.find(func(c model.Coupon) bool { return c.ID == couponID }, fmt.Sprintf("coupon %d", couponID))
+}
+
+func (f *fakeCoupons) GetCouponByCode(ctx context.Context, code string) (model.Coupon, error) {
+	return f.find(func(c model.Coupon) bool { return c.Code == strings.ToUpper(code) }, fmt.Sprintf("coupon %q", code))
+}
+
+func (f *fakeCoupons) ListCoupons(ctx context.Context) ([]model.Coupon, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]model.Coupon{}, f.coupons...), nil
+}
+
+func (f *fakeCoupons) DeleteCoupon(ctx context.Context, couponID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, c := range f.coupons {
+		if c.ID == couponID {
+			if len(f.redemptions[couponID]) > 0 {
+				return repository.NewConflictError(fmt.Sprintf("coupon %d has been redeemed; deactivate it instead", couponID))
+			}
+			f.coupons = append(f.coupons[:i], f.coupons[i+1:]...)
+			return nil
+		}
+	}
+	return repository.NewNotFoundError(fmt.Sprintf("coupon %d", couponID))
+}
+
+func (f *fakeCoupons) CouponUsage(ctx context.Context, couponID, userID int) (int, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	byUser := 0
+	for _, id := range f.redemptions[couponID] {
+		if id == userID {
+			byUser++
+		}
+	}
+	return len(f.redemptions[couponID]), byUser, nil
+}
+
+type fakeCartItem struct {
+	productID string
+	quantity  int
+	price     int
+}
+
+// fakeCarts prices items through the fake catalog, so deactivating a
+// product or editing its price shows up in the cart as it would live.
+type fakeCarts struct {
+	mu       sync.Mutex
+	products *fakeProducts
+	prices   *fakePrices
+	carts    map[int][]*fakeCartItem
+}
+
+func (f *fakeCarts) current(ctx context.Context, productID string) (model.Product, *int, error) {
+	product, err := f.products.GetProductByID(ctx, productID)
+	if err != nil {
+		return product, nil, err
+	}
+	price, err := f.prices.SearchPriceByProductID(ctx, productID)
+	if err != nil || price.ID == 0 {
+		return product, nil, err
+	}
+	return product, &price.SalePrice, nil
+}
+
+func (f *fakeCarts) GetCart(ctx context.Context, userID int) ([]model.CartItem, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	items := make([]model.CartItem, 0)
+	for _, item := range f.carts[userID] {
+		// Deleted products stay in the cart as unavailable.
+		product, price, _ := f.current(ctx, item.productID)
+		items = append(items, model.CartItem{
+			ProductID:  item.productID,
+			SKU:        product.SKU,
+			Name:       product.Name,
+			Quantity:   item.quantity,
+			AddedPrice: item.price,
+			Price:      price,
+			Available:  product.IsActive,
+		})
+	}
+	return items, nil
+}
+
+func (f *fakeCarts) AddCartItem(ctx context.Context, userID int, productID string, quantity int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	product, price, err := f.current(ctx, productID)
+	if err != nil || !product.IsActive || price == nil {
+		return repository.NewForeignKeyError("product_id", "product is not available")
+	}
+	if f.carts == nil {
+		f.carts = make(map[int][]*fakeCartItem)
+	}
+	for _, item := range f.carts[userID] {
+		if item.productID == productID {
+			item.quantity += quantity
+			item.price = *price
+			return nil
+		}
+	}
+	f.carts[userID] = append(f.carts[userID], &fakeCartItem{productID: productID, quantity: quantity, price: *price})
+	return nil
+}
+
+func (f *fakeCarts) SetCartItemQuantity(ctx context.Context, userID int, productID string, quantity int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, item := range f.carts[userID] {
+		if item.productID == productID {
+			item.quantity = quantity
+			if _, price, err := f.current(ctx, productID); err == nil && price != nil {
+				item.price = *price
+			}
+			return nil
+		}
+	}
+	return repository.NewNotFoundError(fmt.Sprintf("product %s in the cart", productID))
+}
+
+func (f *fakeCarts) RemoveCartItem(ctx context.Context, userID int, productID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, item := range f.carts[userID] {
+		if item.productID == productID {
+			f.carts[userID] = append(f.carts[userID][:i], f.carts[userID][i+1:]...)
+			return nil
+		}
+	}
+	return repository.NewNotFoundError(fmt.Sprintf("product %s in the cart", productID))
+}
+
+func (f *fakeCarts) ClearCart(ctx context.Context, userID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.carts, userID)
+	return nil
+}
+
+type fakeOrders struct {
+	mu      sync.Mutex
+	orders  []model.Order
+	carts   *fakeCarts
+	coupons *fakeCoupons
+	stock   *fakeStock
+	users   *fakeUsers
+}
+
+func (f *fakeOrders) CreateOrder(ctx context.Context, order model.Order) (model.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if order.CouponID != 0 {
+		f.coupons.mu.Lock()
+		if f.coupons.redemptions == nil {
+			f.coupons.redemptions = make(map[int][]int)
+		}
+		f.coupons.redemptions[order.CouponID] = append(f.coupons.redemptions[order.CouponID], order.UserID)
+		f.coupons.mu.Unlock()
+	}
+	order.ID = len(f.orders) + 1
+	shopIDs, err := f.stock.reserve(order.ID, order.Items)
+	if err != nil {
+		return model.Order{}, err
+	}
+	order.ShopIDs = shopIDs
+	order.Status = model.OrderCreated
+	order.Created = time.Now()
+	order.Updated = order.Created
+	reservedUntil := order.Created.Add(15 * time.Minute)
+	order.ReservedUntil = &reservedUntil
+	order.History = []model.OrderTransition{{To: model.OrderCreated, ActorID: order.UserID, Created: order.Created}}
+	f.orders = append(f.orders, order)
+	for _, item := range order.Items {
+		if err := f.carts.RemoveCartItem(ctx, order.UserID, item.ProductID); err != nil {
+			return model.Order{}, err
+		}
+	}
+	return order, nil
+}
+
+func (f *fakeOrders) GetOrder(ctx context.Context, orderID int) (model.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, o := range f.orders {
+		if o.ID == orderID {
+			return o, nil
+		}
+	}
+	return model.Order{}, repository.NewNotFoundError(fmt.Sprintf("order %d", orderID))
+}
+
+func (f *fakeOrders) ListOrders(ctx context.Context, filter model.OrderFilter) ([]model.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	orders := make([]model.Order, 0)
+	for i := len(f.orders) - 1; i >= 0; i-- {
+		o := f.orders[i]
+		if filter.UserID != 0 && o.UserID != filter.UserID || filter.Status != "" && o.Status != filter.Status {
+			continue
+		}
+		if filter.StaffID != 0 && o.UserID != filter.StaffID && !f.staffed(ctx, filter.StaffID, o) {
+			continue
+		}
+		o.History = nil
+		orders = append(orders, o)
+	}
+	return orders, nil
+}
+
+func (f *fakeOrders) staffed(ctx context.Context, userID int, order model.Order) bool {
+	shopIDs, _ := f.users.GetStaffShops(ctx, userID)
+	for _, shopID := range shopIDs {
+		for _, reservedIn := range order.ShopIDs {
+			if shopID == reservedIn {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f *fakeOrders) TransitionOrder(ctx context.Context, orderID int, transition model.OrderTransition) (model.Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.orders {
+		o := &f.orders[i]
+		if o.ID != orderID {
+			continue
+		}
+		if !model.CanTransition(o.Status, transition.To) {
+			return model.Order{}, repository.NewConflictError(fmt.Sprintf("order %d is %s and can't become %s", orderID, o.Status, transition.To))
+		}
+		transition.From, transition.Created = o.Status, time.Now()
+		o.Status, o.Updated = transition.To, transition.Created
+		switch transition.To {
+		case model.OrderPaid:
+			f.stock.settle(orderID, true)
+			o.ReservedUntil = nil
+		case model.OrderCancelled:
+			f.stock.settle(orderID, false)
+			o.ReservedUntil = nil
+		}
+		o.History = append(o.History, transition)
+		if transition.To == model.OrderCancelled && o.CouponID != 0 {
+			f.coupons.mu.Lock()
+			users := f.coupons.redemptions[o.CouponID]
+			for j, userID := range users {
+				if userID == o.UserID {
+					f.coupons.redemptions[o.CouponID] = append(users[:j], users[j+1:]...)
+					break
+				}
+			}
+			f.coupons.mu.Unlock()
+		}
+		return *o, nil
+	}
+	return model.Order{}, repository.NewNotFoundError(fmt.Sprintf("order %d", orderID))
+}
+
+type fakePayments struct {
+	mu       sync.Mutex
+	payments []model.Payment
+	orders   *fakeOrders
+}
+
+func (f *fakePayments) AddPayment(ctx context.Context, p model.Payment) (model.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.orders.GetOrder(ctx, p.OrderID); err != nil {
+		return model.Payment{}, repository.NewForeignKeyError("order_id", "order doesn't exist")
+	}
+	for _, existing := range f.payments {
+		if existing.OrderID == p.OrderID && existing.Status != "declined" {
+			return model.Payment{}, repository.NewConflictError(fmt.Sprintf("order %d already has payment %s", p.OrderID, existing.ID))
+		}
+	}
+	p.Created = time.Now()
+	p.Updated = p.Created
+	f.payments = append(f.payments, p)
+	return p, nil
+}
+
+func (f *fakePayments) GetPayment(ctx context.Context, paymentID string) (model.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.payments {
+		if p.ID == paymentID {
+			return p, nil
+		}
+	}
+	return model.Payment{}, repository.NewNotFoundError("payment " + paymentID)
+}
+
+func (f *fakePayments) ListPayments(ctx context.Context, orderID int) ([]model.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	payments := make([]model.Payment, 0)
+	for _, p := range f.payments {
+		if p.OrderID == orderID {
+			payments = append(payments, p)
+		}
+	}
+	return payments, nil
+}
+
+func (f *fakePayments) SetPaymentStatus(ctx context.Context,
+	paymentID, from, to, reason string,
+	transition *model.OrderTransition) (model.Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.payments {
+		p := &f.payments[i]
+		if p.ID != paymentID {
+			continue
+		}
+		if p.Status != from {
+			return model.Payment{}, repository.NewConflictError(fmt.Sprintf("payment %s is no longer %s", paymentID, from))
+		}
+		if transition != nil {
+			if _, err := f.orders.TransitionOrder(ctx, p.OrderID, *transition); err != nil {
+				return model.Payment{}, err
+			}
+		}
+		p.Status, p.Reason, p.Updated = to, reason, time.Now()
+		return *p, nil
+	}
+	return model.Payment{}, repository.NewNotFoundError("payment " + paymentID)
+}
+
+type fakeStock struct {
+	mu           sync.Mutex
+	stock        []model.Stock
+	reservations map[int][]fakeReservation
+}
+
+type fakeReservation struct {
+	shopID    int
+	productID string
+	quantity  int
+}
+
+func (f *fakeStock) SetStock(ctx context.Context, s model.Stock) (model.Stock, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.stock {
+		existing := &f.stock[i]
+		if existing.ShopID == s.ShopID && existing.ProductID == s.ProductID {
+			if s.Quantity < existing.Reserved {
+				return model.Stock{}, repository.NewValidationError(repository.FieldError{Field: "stock_reserved_check", Reason: "below reserved"})
+			}
+			existing.Quantity, existing.Available, existing.Updated = s.Quantity, s.Quantity-existing.Reserved, time.Now()
+			return *existing, nil
+		}
+	}
+	s.Reserved, s.Available, s.Updated = 0, s.Quantity, time.Now()
+	f.stock = append(f.stock, s)
+	return s, nil
+}
+
+func (f *fakeStock) ListStock(ctx context.Context, productID string) ([]model.Stock, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stock := make([]model.Stock, 0)
+	for _, s := range f.stock {
+		if s.ProductID == productID {
+			stock = append(stock, s)
+		}
+	}
+	return stock, nil
+}
+
+// ExpireReservations has nothing to do: the sweeper doesn't run against the
+// test router.
+func (f *fakeStock) ExpireReservations(ctx context.Context) ([]int, error) {
+	return []int{}, nil
+}
+
+// reserve holds every item in the shop with the most of it left, or
+// nothing at all.
+func (f *fakeStock) reserve(orderID int, items []model.OrderItem) ([]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var held []fakeReservation
+	for _, item := range items {
+		var best *model.Stock
+		for i := range f.stock {
+			s := &f.stock[i]
+			if s.ProductID == item.ProductID && s.Available >= item.Quantity && (best == nil || s.Available > best.Available) {
+				best = s
+			}
+		}
+		if best == nil {
+			for _, r := range held {
+				f.adjust(r, 0, -r.quantity)
+			}
+			return nil, repository.NewConflictError(fmt.Sprintf("not enough stock of %s", item.SKU))
+		}
+		r := fakeReservation{shopID: best.ShopID, productID: item.ProductID, quantity: item.Quantity}
+		f.adjust(r, 0, r.quantity)
+		held = append(held, r)
+	}
+	if f.reservations == nil {
+		f.reservations = make(map[int][]fakeReservation)
+	}
+	f.reservations[orderID] = held
+	reservedIn := make(map[int]bool)
+	for _, r := range held {
+		reservedIn[r.shopID] = true
+	}
+	shopIDs := make([]int, 0, len(reservedIn))
+	for shopID := range reservedIn {
+		shopIDs = append(shopIDs, shopID)
+	}
+	sort.Ints(shopIDs)
+	return shopIDs, nil
+}
+
+// settle frees the order's reserved units, taking them off the stock too
+// when deduct is set.
+func (f *fakeStock) settle(orderID int, deduct bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, r := range f.reservations[orderID] {
+		quantity := 0
+		if deduct {
+			quantity = -r.quantity
+		}
+		f.adjust(r, quantity, -r.quantity)
+	}
+	delete(f.reservations, orderID)
+}
+
+func (f *fakeStock) adjust(r fakeReservation, quantity, reserved int) {
+	for i := range f.stock {
+		s := &f.stock[i]
+		if s.ShopID == r.shopID && s.ProductID == r.productID {
+			s.Quantity += quantity
+			s.Reserved += reserved
+			s.Available = s.Quantity - s.Reserved
+		}
+	}
+}
+
+// fakeTx runs changes as they come; the fakes have nothing to roll back.
+type fakeTx struct{}
+
+func (fakeTx) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type fakeAudit struct {
+	mu      sync.Mutex
+	entries []model.AuditEntry
+}
+
+func (f *fakeAudit) AddAuditEntry(ctx context.Context, e model.AuditEntry) (model.AuditEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	e.ID = int64(len(f.entries) + 1)
+	e.Created = time.Now()
+	f.entries = append(f.entries, e)
+	return e, nil
+}
+
+func (f *fakeAudit) ListAuditEntries(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries := make([]model.AuditEntry, 0)
+	for i := len(f.entries) - 1; i >= 0 && len(entries) < filter.Limit; i-- {
+		e := f.entries[i]
+		if filter.ActorID != 0 && e.ActorID != filter.ActorID ||
+			filter.Action != "" && e.Action != filter.Action ||
+			filter.Entity != "" && e.Entity != filter.Entity ||
+			filter.EntityID != "" && e.EntityID != filter.EntityID ||
+			filter.RequestID != "" && e.RequestID != filter.RequestID ||
+			filter.BeforeID != 0 && e.ID >= filter.BeforeID {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,29 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// ImportProducts uploads a CSV file of products. With batchSize 0 the whole
+// file is written in one transaction, otherwise every batchSize rows are
+// committed separately.
+func (c *Client) ImportProducts(ctx context.Context, csv io.Reader, batchSize int) (*ImportReport, error) {
+	data, err := ioutil.ReadAll(csv)
+	if err != nil {
+		return nil, fmt.Errorf("ImportProducts: %w", err)
+	}
+	path := "/import/products"
+	if batchSize > 0 {
+		path = fmt.Sprintf("%s?batch_size=%d", path, batchSize)
+	}
+	var report ImportReport
+	err = c.call(ctx, http.MethodPost, path, rawBody{contentType: "text/csv", data: data}, &report)
+	if err != nil {
+		return nil, fmt.Errorf("ImportProducts: %w", err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Checkout turns the cart into an order, applying couponCode unless it is
+// empty. It fails with ErrConflict while the cart has warnings.
+func (c *Client) Checkout(ctx context.Context, couponCode string) (*Order, error) {
+	in := struct {
+		CouponCode string `json:"coupon_code,omitempty"`
+	}{couponCode}
+	var order Order
+	if err := c.call(ctx, http.MethodPost, "/orders", in, &order); err != nil {
+		return nil, fmt.Errorf("Checkout: %w", err)
+	}
+	return &order, nil
+}
+
+func (c *Client) ListOrders(ctx context.Context, filter OrderFilter) (*OrderList, error) {
+	var list OrderList
+	path := "/orders"
+	if query := filter.values().Encode(); query != "" {
+		path += "?" + query
+	}
+	if err := c.call(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, fmt.Errorf("ListOrders: %w", err)
+	}
+	return &list, nil
+}
+
+func (c *Client) GetOrder(ctx context.Context, orderID int) (*Order, error) {
+	var order Order
+	if err := c.call(ctx, http.MethodGet, fmt.Sprintf("/orders/%d", orderID), nil, &order); err != nil {
+		return nil, fmt.Errorf("GetOrder: %w", err)
+	}
+	return &order, nil
+}
+
+// SetOrderStatus moves the order to status, recording note in its history.
+func (c *Client) SetOrderStatus(ctx context.Context, orderID int, status, note string) (*Order, error) {
+	in := struct {
+		Status string `json:"status"`
+		Note   string `json:"note,omitempty"`
+	}{status, note}
+	var order Order
+	if err := c.call(ctx, http.MethodPost, fmt.Sprintf("/orders/%d/status", orderID), in, &order); err != nil {
+		return nil, fmt.Errorf("SetOrderStatus: %w", err)
+	}
+	return &order, nil
+}
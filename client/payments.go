@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// PayOrder pays a new order with the payment method token names at the
+// provider. A captured payment means the order is paid; a pending one is
+// confirmed by the provider later, and a declined one may be retried.
+func (c *Client) PayOrder(ctx context.Context, orderID int, token string) (*Payment, error) {
+	in := struct {
+		Token string `json:"token,omitempty"`
+	}{token}
+	var payment Payment
+	if err := c.call(ctx, http.MethodPost, fmt.Sprintf("/orders/%d/payments", orderID), in, &payment); err != nil {
+		return nil, fmt.Errorf("PayOrder: %w", err)
+	}
+	return &payment, nil
+}
+
+func (c *Client) ListPayments(ctx context.Context, orderID int) (*PaymentList, error) {
+	var list PaymentList
+	if err := c.call(ctx, http.MethodGet, fmt.Sprintf("/orders/%d/payments", orderID), nil, &list); err != nil {
+		return nil, fmt.Errorf("ListPayments: %w", err)
+	}
+	return &list, nil
+}
+
+// RefundOrder gives the order's payment back and marks it refunded.
+func (c *Client) RefundOrder(ctx context.Context, orderID int, note string) (*Order, error) {
+	in := struct {
+		Note string `json:"note,omitempty"`
+	}{note}
+	var order Order
+	if err := c.call(ctx, http.MethodPost, fmt.Sprintf("/orders/%d/refund", orderID), in, &order); err != nil {
+		return nil, fmt.Errorf("RefundOrder: %w", err)
+	}
+	return &order, nil
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+func (c *Client) ListPromotions(ctx context.Context) (*PromotionList, error) {
+	var list PromotionList
+	if err := c.call(ctx, http.MethodGet, "/promotions", nil, &list); err != nil {
+		return nil, fmt.Errorf("ListPromotions: %w", err)
+	}
+	return &list, nil
+}
+
+func (c *Client) GetPromotion(ctx context.Context, promotionID int) (*Promotion, error) {
+	var promotion Promotion
+	if err := c.call(ctx, http.MethodGet, fmt.Sprintf("/promotions/%d", promotionID), nil, &promotion); err != nil {
+		return nil, fmt.Errorf("GetPromotion: %w", err)
+	}
+	return &promotion, nil
+}
+
+func (c *Client) AddPromotion(ctx context.Context, promotion Promotion) (*Promotion, error) {
+	var added Promotion
+	if err := c.call(ctx, http.MethodPost, "/promotions", promotion, &added); err != nil {
+		return nil, fmt.Errorf("AddPromotion: %w", err)
+	}
+	return &added, nil
+}
+
+// EditPromotion replaces the promotion with promotion.ID.
+func (c *Client) EditPromotion(ctx context.Context, promotion Promotion) (*Promotion, error) {
+	var edited Promotion
+	if err := c.call(ctx, http.MethodPut, "/promotions", promotion, &edited); err != nil {
+		return nil, fmt.Errorf("EditPromotion: %w", err)
+	}
+	return &edited, nil
+}
+
+func (c *Client) DeletePromotion(ctx context.Context, promotionID int) error {
+	if err := c.call(ctx, http.MethodDelete, fmt.Sprintf("/promotions/%d", promotionID), nil, nil); err != nil {
+		return fmt.Errorf("DeletePromotion: %w", err)
+	}
+	return nil
+}
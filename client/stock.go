@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// SetStock sets how many units of a product a shop has.
+func (c *Client) SetStock(ctx context.Context, shopID int, productID string, quantity int) (*Stock, error) {
+	in := struct {
+		ShopID    int    `json:"shop_id"`
+		ProductID string `json:"product_id"`
+		Quantity  int    `json:"quantity"`
+	}{shopID, productID, quantity}
+	var stock Stock
+	if err := c.call(ctx, http.MethodPut, "/stock", in, &stock); err != nil {
+		return nil, fmt.Errorf("SetStock: %w", err)
+	}
+	return &stock, nil
+}
+
+// ListStock returns the stock of a product in every shop that has it.
+func (c *Client) ListStock(ctx context.Context, productID string) (*StockList, error) {
+	var list StockList
+	if err := c.call(ctx, http.MethodGet, "/products/"+productID+"/stock", nil, &list); err != nil {
+		return nil, fmt.Errorf("ListStock: %w", err)
+	}
+	return &list, nil
+}
@@ -0,0 +1,514 @@
+package client
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// The types below mirror the wire format of the API, including its habit
+// of sending some numbers and booleans as JSON strings.
+
+type Shop struct {
+	ID           int    `json:"id,string"`
+	Name         string `json:"name"`
+	Address      string `json:"address"`
+	WorkingHours string `json:"workingHours"`
+	LON          string `json:"lon"`
+	LAT          string `json:"lat"`
+}
+
+type ShopList struct {
+	Total int     `json:"total"`
+	Items []*Shop `json:"items"`
+}
+
+type Category struct {
+	ID      int    `json:"id,string"`
+	Name    string `json:"name"`
+	URIName string `json:"uri_name"`
+}
+
+type CategoryList struct {
+	Total int         `json:"total"`
+	Items []*Category `json:"items"`
+}
+
+// ProductPrice is a product's price as listed. EffectivePrice is the sale
+// price after the promotions in Promotions.
+type ProductPrice struct {
+	SalePrice      int                 `json:"sale_price"`
+	FactoryPrice   int                 `json:"factory_price"`
+	DiscountPrice  int                 `json:"discount_price"`
+	EffectivePrice *int                `json:"effective_price,omitempty"`
+	Promotions     []*AppliedPromotion `json:"promotions,omitempty"`
+}
+
+type AppliedPromotion struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type Product struct {
+	ID          string          `json:"id,omitempty"`
+	SKU         string          `json:"sku,omitempty"`
+	Name        string          `json:"name,omitempty"`
+	Type        string          `json:"type,omitempty"`
+	URI         string          `json:"uri,omitempty"`
+	Description string          `json:"description,omitempty"`
+	IsActive    bool            `json:"is_active"`
+	Prices      []*ProductPrice `json:"prices,omitempty"`
+}
+
+type ProductList struct {
+	Total int        `json:"total"`
+	Items []*Product `json:"items"`
+}
+
+// ProductInput creates or edits a product. On edit SKU selects the product
+// and empty fields are left unchanged.
+type ProductInput struct {
+	SKU         string      `json:"sku"`
+	Name        string      `json:"name,omitempty"`
+	Type        string      `json:"type,omitempty"`
+	Description string      `json:"description,omitempty"`
+	IsActive    bool        `json:"is_active,string,omitempty"`
+	ShopID      int         `json:"shop_id,string,omitempty"`
+	CategoryID  int         `json:"category_id,string,omitempty"`
+	Price       *PriceInput `json:"price,omitempty"`
+}
+
+type PriceInput struct {
+	ID            int    `json:"id,omitempty,string"`
+	SalePrice     int    `json:"sale_price,string"`
+	FactoryPrice  int    `json:"factory_price,string"`
+	DiscountPrice int    `json:"discount_price,string"`
+	IsActive      bool   `json:"is_active,string"`
+	ProductID     string `json:"product_id,omitempty"`
+}
+
+type Price struct {
+	ID            int    `json:"id,omitempty"`
+	SalePrice     int    `json:"sale_price"`
+	FactoryPrice  int    `json:"factory_price"`
+	DiscountPrice int    `json:"discount_price"`
+	IsActive      bool   `json:"is_active,omitempty"`
+	ProductID     string `json:"product_id"`
+}
+
+// PriceRule is applied by BulkUpdatePrices. Op is set, percent or amount;
+// Round "99" ends the result in 99; Field defaults to sale_price.
+type PriceRule struct {
+	Op    string `json:"op"`
+	Value int    `json:"value"`
+	Round string `json:"round,omitempty"`
+	Field string `json:"field,omitempty"`
+}
+
+// PriceSelector picks the prices a bulk update applies to; set fields are
+// combined with AND and at least one is required.
+type PriceSelector struct {
+	CategoryID int      `json:"category_id,omitempty"`
+	ShopID     int      `json:"shop_id,omitempty"`
+	SKUs       []string `json:"skus,omitempty"`
+	MinPrice   int      `json:"min_price,omitempty"`
+	MaxPrice   int      `json:"max_price,omitempty"`
+}
+
+type PriceAmounts struct {
+	SalePrice     int `json:"sale_price"`
+	FactoryPrice  int `json:"factory_price"`
+	DiscountPrice int `json:"discount_price"`
+}
+
+type PriceChange struct {
+	PriceID       int            `json:"price_id"`
+	ProductID     string         `json:"product_id"`
+	SKU           string         `json:"sku"`
+	Old           PriceAmounts   `json:"old"`
+	New           PriceAmounts   `json:"new"`
+	InvalidParams []InvalidParam `json:"invalid_params,omitempty"`
+}
+
+// PriceBatch is the diff of a bulk update. BatchID is set once it has been
+// applied.
+type PriceBatch struct {
+	BatchID int            `json:"batch_id,omitempty"`
+	DryRun  bool           `json:"dry_run"`
+	Matched int            `json:"matched"`
+	Changed int            `json:"changed"`
+	Invalid int            `json:"invalid"`
+	Changes []*PriceChange `json:"changes"`
+}
+
+type PriceList struct {
+	Total int      `json:"total"`
+	Items []*Price `json:"items"`
+}
+
+type User struct {
+	ID       int    `json:"id,omitempty"`
+	Login    string `json:"login,omitempty"`
+	Password string `json:"password,omitempty"`
+	Role     string `json:"role,omitempty"`
+}
+
+const (
+	RoleAdmin = "ADMIN"
+	RoleUser  = "USER"
+)
+
+type idReply struct {
+	ID int `json:"id,string"`
+}
+
+type ImportRow struct {
+	Row           int            `json:"row"`
+	SKU           string         `json:"sku"`
+	Status        string         `json:"status"`
+	ProductID     string         `json:"product_id,omitempty"`
+	Detail        string         `json:"detail,omitempty"`
+	InvalidParams []InvalidParam `json:"invalid_params,omitempty"`
+}
+
+// ImportReport tells what happened to every row of an import. Status is one
+// of created, updated, failed or skipped; skipped rows were valid but their
+// batch was not written because of another row.
+type ImportReport struct {
+	Total   int          `json:"total"`
+	Created int          `json:"created"`
+	Updated int          `json:"updated"`
+	Failed  int          `json:"failed"`
+	Skipped int          `json:"skipped"`
+	Rows    []*ImportRow `json:"rows"`
+}
+
+// ProductFilter narrows product lists and exports; zero fields don't filter.
+type ProductFilter struct {
+	ShopID     int
+	CategoryID int
+	IsActive   *bool
+	Name       string
+}
+
+func (f ProductFilter) values() url.Values {
+	values := url.Values{}
+	if f.ShopID != 0 {
+		values.Set("shop_id", strconv.Itoa(f.ShopID))
+	}
+	if f.CategoryID != 0 {
+		values.Set("category_id", strconv.Itoa(f.CategoryID))
+	}
+	if f.IsActive != nil {
+		values.Set("is_active", strconv.FormatBool(*f.IsActive))
+	}
+	if f.Name != "" {
+		values.Set("name", f.Name)
+	}
+	return values
+}
+
+// Promotion types.
+const (
+	PromotionPercentage = "percentage"
+	PromotionFixed      = "fixed"
+	PromotionBuyXGetY   = "buy_x_get_y"
+)
+
+// Promotion is a campaign over products, shops and categories; with no
+// targets it covers the whole catalog. EndsAt is exclusive.
+type Promotion struct {
+	ID          int       `json:"id,omitempty"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Value       int       `json:"value,omitempty"`
+	BuyQuantity int       `json:"buy_quantity,omitempty"`
+	GetQuantity int       `json:"get_quantity,omitempty"`
+	Priority    int       `json:"priority"`
+	Stackable   bool      `json:"stackable"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	CategoryIDs []int     `json:"category_ids"`
+	ShopIDs     []int     `json:"shop_ids"`
+	ProductIDs  []string  `json:"product_ids"`
+	IsActive    bool      `json:"is_active"`
+}
+
+type PromotionList struct {
+	Total int          `json:"total"`
+	Items []*Promotion `json:"items"`
+}
+
+// Coupon types.
+const (
+	CouponPercentage = "percentage"
+	CouponFixed      = "fixed"
+)
+
+// Coupon is a redeemable code. Zero limits mean no limit; with CategoryIDs
+// set only items in those categories are discounted. EndsAt is exclusive
+// and Used is read-only.
+type Coupon struct {
+	ID           int       `json:"id,omitempty"`
+	Code         string    `json:"code"`
+	Type         string    `json:"type"`
+	Value        int       `json:"value"`
+	MinOrder     int       `json:"min_order"`
+	UsageLimit   int       `json:"usage_limit"`
+	PerUserLimit int       `json:"per_user_limit"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+	CategoryIDs  []int     `json:"category_ids"`
+	IsActive     bool      `json:"is_active"`
+	Used         int       `json:"used,omitempty"`
+}
+
+type CouponList struct {
+	Total int       `json:"total"`
+	Items []*Coupon `json:"items"`
+}
+
+// BasketItem is a product and quantity of a basket; zero means one.
+type BasketItem struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity,omitempty"`
+}
+
+// CouponCheck is a coupon evaluated against a basket. When Valid is false
+// Reason says why and Discount is zero.
+type CouponCheck struct {
+	Code     string             `json:"code"`
+	Valid    bool               `json:"valid"`
+	Reason   string             `json:"reason,omitempty"`
+	Subtotal int                `json:"subtotal"`
+	Eligible int                `json:"eligible"`
+	Discount int                `json:"discount"`
+	Total    int                `json:"total"`
+	Items    []*CouponCheckItem `json:"items"`
+}
+
+type CouponCheckItem struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int    `json:"unit_price"`
+	Eligible  bool   `json:"eligible"`
+}
+
+// Cart warning codes.
+const (
+	CartUnavailable  = "unavailable"
+	CartNoPrice      = "no_price"
+	CartPriceChanged = "price_changed"
+)
+
+// Cart is the caller's cart at current prices after promotions.
+// Unavailable items are listed but left out of the totals.
+type Cart struct {
+	Items    []*CartItem    `json:"items"`
+	Count    int            `json:"count"`
+	Subtotal int            `json:"subtotal"`
+	Discount int            `json:"discount"`
+	Total    int            `json:"total"`
+	Warnings []*CartWarning `json:"warnings"`
+}
+
+type CartItem struct {
+	ProductID  string              `json:"product_id"`
+	SKU        string              `json:"sku"`
+	Name       string              `json:"name"`
+	Quantity   int                 `json:"quantity"`
+	UnitPrice  int                 `json:"unit_price"`
+	LineTotal  int                 `json:"line_total"`
+	Available  bool                `json:"available"`
+	Promotions []*AppliedPromotion `json:"promotions,omitempty"`
+}
+
+// CartWarning reports an item that changed since it was put into the cart.
+// A price change is acknowledged by adding the item again or changing its
+// quantity.
+type CartWarning struct {
+	ProductID string `json:"product_id"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	OldPrice  int    `json:"old_price,omitempty"`
+	NewPrice  int    `json:"new_price,omitempty"`
+}
+
+// Order statuses.
+const (
+	OrderCreated   = "created"
+	OrderPaid      = "paid"
+	OrderPacked    = "packed"
+	OrderShipped   = "shipped"
+	OrderDelivered = "delivered"
+	OrderCancelled = "cancelled"
+	OrderRefunded  = "refunded"
+)
+
+// Order is a checked-out cart; items keep the names and prices of the
+// moment of checkout. History is only filled in by GetOrder. An unpaid
+// order is cancelled at ReservedUntil, when the stock held for it expires.
+type Order struct {
+	ID             int                `json:"id"`
+	UserID         int                `json:"user_id"`
+	Status         string             `json:"status"`
+	Items          []*OrderItem       `json:"items"`
+	Subtotal       int                `json:"subtotal"`
+	Discount       int                `json:"discount"`
+	CouponCode     string             `json:"coupon_code,omitempty"`
+	CouponDiscount int                `json:"coupon_discount"`
+	Total          int                `json:"total"`
+	Created        time.Time          `json:"created"`
+	Updated        time.Time          `json:"updated"`
+	History        []*OrderTransition `json:"history,omitempty"`
+	ReservedUntil  *time.Time         `json:"reserved_until,omitempty"`
+	ShopIDs        []int              `json:"shop_ids"`
+}
+
+type OrderItem struct {
+	ProductID string `json:"product_id"`
+	SKU       string `json:"sku"`
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int    `json:"unit_price"`
+	Total     int    `json:"total"`
+}
+
+type OrderTransition struct {
+	From    string    `json:"from,omitempty"`
+	To      string    `json:"to"`
+	ActorID int       `json:"actor_id"`
+	Note    string    `json:"note,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+type OrderList struct {
+	Total int      `json:"total"`
+	Items []*Order `json:"items"`
+}
+
+// OrderFilter narrows ListOrders. UserID is for staff only.
+type OrderFilter struct {
+	UserID int
+	Status string
+}
+
+func (f OrderFilter) values() url.Values {
+	values := url.Values{}
+	if f.UserID != 0 {
+		values.Set("user_id", strconv.Itoa(f.UserID))
+	}
+	if f.Status != "" {
+		values.Set("status", f.Status)
+	}
+	return values
+}
+
+// Payment statuses. A pending payment is confirmed by the provider later.
+const (
+	PaymentPending    = "pending"
+	PaymentAuthorized = "authorized"
+	PaymentCaptured   = "captured"
+	PaymentDeclined   = "declined"
+	PaymentRefunded   = "refunded"
+)
+
+type Payment struct {
+	ID       string    `json:"id"`
+	OrderID  int       `json:"order_id"`
+	Provider string    `json:"provider"`
+	Status   string    `json:"status"`
+	Amount   int       `json:"amount"`
+	Reason   string    `json:"reason,omitempty"`
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+}
+
+type PaymentList struct {
+	Total int        `json:"total"`
+	Items []*Payment `json:"items"`
+}
+
+// Stock is how many units of a product a shop has; Reserved are held by
+// unpaid orders.
+type Stock struct {
+	ShopID    int       `json:"shop_id"`
+	ProductID string    `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+	Reserved  int       `json:"reserved"`
+	Available int       `json:"available"`
+	Updated   time.Time `json:"updated"`
+}
+
+type StockList struct {
+	Total     int      `json:"total"`
+	Available int      `json:"available"`
+	Items     []*Stock `json:"items"`
+}
+
+// AuditEntry is one change in the audit log. Before and After hold the
+// entity as JSON; Before is empty for creations, After for deletions.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	ActorID   int             `json:"actor_id,omitempty"`
+	Action    string          `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityID  string          `json:"entity_id"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	Created   time.Time       `json:"created"`
+}
+
+// AuditList is one page of the audit log, newest first. Pass NextBeforeID
+// as AuditFilter.BeforeID for the next page; it is 0 on the last one.
+type AuditList struct {
+	Total        int           `json:"total"`
+	Items        []*AuditEntry `json:"items"`
+	NextBeforeID int64         `json:"next_before_id,omitempty"`
+}
+
+// AuditFilter narrows ListAudit. Zero values don't filter.
+type AuditFilter struct {
+	ActorID   int
+	Action    string
+	Entity    string
+	EntityID  string
+	RequestID string
+	From      time.Time
+	To        time.Time
+	BeforeID  int64
+	Limit     int
+}
+
+func (f AuditFilter) values() url.Values {
+	values := url.Values{}
+	if f.ActorID != 0 {
+		values.Set("actor_id", strconv.Itoa(f.ActorID))
+	}
+	for name, value := range map[string]string{
+		"action":     f.Action,
+		"entity":     f.Entity,
+		"entity_id":  f.EntityID,
+		"request_id": f.RequestID,
+	} {
+		if value != "" {
+			values.Set(name, value)
+		}
+	}
+	if !f.From.IsZero() {
+		values.Set("from", f.From.Format(time.RFC3339))
+	}
+	if !f.To.IsZero() {
+		values.Set("to", f.To.Format(time.RFC3339))
+	}
+	if f.BeforeID != 0 {
+		values.Set("before_id", strconv.FormatInt(f.BeforeID, 10))
+	}
+	if f.Limit != 0 {
+		values.Set("limit", strconv.Itoa(f.Limit))
+	}
+	return values
+}
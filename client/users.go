@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+func (c *Client) AddUser(ctx context.Context, login, password, role string) (*User, error) {
+	var added User
+	user := User{Login: login, Password: password, Role: role}
+	if err := c.call(ctx, http.MethodPost, "/users", user, &added); err != nil {
+		return nil, fmt.Errorf("AddUser: %w", err)
+	}
+	return &added, nil
+}
+
+func (c *Client) SetPassword(ctx context.Context, login, password string) error {
+	user := User{Login: login, Password: password}
+	if err := c.call(ctx, http.MethodPut, "/users", user, nil); err != nil {
+		return fmt.Errorf("SetPassword: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) AddRole(ctx context.Context, login, role string) error {
+	if err := c.call(ctx, http.MethodPut, "/users/addrole", User{Login: login, Role: role}, nil); err != nil {
+		return fmt.Errorf("AddRole: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) SetStaffShops(ctx context.Context, login string, shopIDs []int) error {
+	in := struct {
+		Login   string `json:"login"`
+		ShopIDs []int  `json:"shop_ids"`
+	}{login, shopIDs}
+	if err := c.call(ctx, http.MethodPut, "/users/shops", in, nil); err != nil {
+		return fmt.Errorf("SetStaffShops: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) RemoveRole(ctx context.Context, login, role string) error {
+	if err := c.call(ctx, http.MethodPut, "/users/removerole", User{Login: login, Role: role}, nil); err != nil {
+		return fmt.Errorf("RemoveRole: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	controllers "market4/internal/api/v1"
+	"market4/internal/api/validation"
+	"market4/internal/audit"
+	cache2 "market4/internal/cache"
+	"market4/internal/config"
+	"market4/internal/export"
+	"market4/internal/model"
+	"market4/internal/ratelimit"
+	"market4/internal/repository"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.uber.org/zap"
+)
+
+const adminUsage = `usage: market4 admin COMMAND
+  create-user LOGIN [ROLE...]     create a user with USER and the given roles;
+                                  the password is read from stdin
+  set-password LOGIN              set a password read from stdin
+  grant LOGIN ROLE                grant a role
+  revoke LOGIN ROLE               revoke a role
+  users                           list users and their roles
+  import [-batch-size N] FILE     upsert products from a CSV file, - for stdin
+  export [-format F] [FILE]       export the catalog as csv, jsonl or xlsx
+  flush-cache [-rate-limits]      empty the cache, and the rate limits too`
+
+var accountRoles = []string{string(model.ADMIN), string(model.USER), string(model.STAFF)}
+
+// adminRepos are the repositories the admin commands work through.
+type adminRepos struct {
+	users    repository.Users
+	products repository.Product
+}
+
+// admin runs `market4 admin ...` against the configured database and
+// cache. Changes are recorded in the audit log without an actor.
+func admin(lg *zap.Logger, cfg config.Config, args []string, in io.Reader, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(adminUsage)
+	}
+	ctx := context.Background()
+	if args[0] == "flush-cache" {
+		return flushCache(ctx, cfg.Cache, args[1:], out)
+	}
+
+	poolConfig, err := cfg.Database.PoolConfig()
+	if err != nil {
+		return err
+	}
+	pool, err := pgxpool.ConnectConfig(ctx, poolConfig)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	recorder := audit.New(repository.NewAuditRepository(pool), repository.NewTransactor(pool), lg)
+	shopRepo := repository.NewShopRepository(pool)
+	categoryRepo := repository.NewCategoryRepository(pool)
+	priceRepo := audit.Price(repository.NewPriceRepository(pool), recorder)
+	repos := adminRepos{
+		users:    audit.Users(repository.NewUsersRepo(pool), recorder),
+		products: audit.Product(repository.NewProductRepository(pool, categoryRepo, shopRepo, priceRepo), priceRepo, recorder),
+	}
+	return runAdmin(ctx, lg, repos, args, in, out)
+}
+
+func runAdmin(ctx context.Context, lg *zap.Logger, repos adminRepos, args []string, in io.Reader, out io.Writer) error {
+	command, args := args[0], args[1:]
+	switch {
+	case command == "create-user" && len(args) >= 1:
+		return createUser(ctx, repos.users, args[0], args[1:], in, out)
+	case command == "set-password" && len(args) == 1:
+		return setPassword(ctx, repos.users, args[0], in, out)
+	case (command == "grant" || command == "revoke") && len(args) == 2:
+		return changeRole(ctx, repos.users, command, args[0], args[1], out)
+	case command == "users" && len(args) == 0:
+		return listUsers(ctx, repos.users, out)
+	case command == "import":
+		return importCatalog(ctx, lg, repos.products, args, in, out)
+	case command == "export":
+		return exportCatalog(ctx, repos.products, args, out)
+	}
+	return errors.New(adminUsage)
+}
+
+// readPassword reads the first line of in, so that passwords stay out of
+// the shell history and the process list.
+func readPassword(in io.Reader) (string, error) {
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// createUser creates login with the USER role, which every catalog route
+// requires, and grants roles on top of it.
+func createUser(ctx context.Context, users repository.Users, login string, roles []string, in io.Reader, out io.Writer) error {
+	password, err := readPassword(in)
+	if err != nil {
+		return err
+	}
+	fields := []validation.FieldRules{
+		validation.Field("login", login, validation.Required, validation.MaxLength(64)),
+		validation.Field("password", password, validation.Required, validation.MinLength(6)),
+	}
+	for _, role := range roles {
+		fields = append(fields, validation.Field("role", role, validation.OneOf(accountRoles...)))
+	}
+	if err = validation.Validate(fields...); err != nil {
+		return fmt.Errorf("create-user: %w", err)
+	}
+	if _, err = users.AddUser(ctx, &model.User{Login: login, Password: password, Role: string(model.USER)}); err != nil {
+		return fmt.Errorf("create-user: %w", err)
+	}
+	granted := []string{string(model.USER)}
+	for _, role := range roles {
+		if contains(granted, role) {
+			continue
+		}
+		if err = users.AddRole(ctx, login, role); err != nil {
+			return fmt.Errorf("create-user: %w", err)
+		}
+		granted = append(granted, role)
+	}
+	fmt.Fprintf(out, "created %s with roles %s\n", login, strings.Join(granted, ", "))
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func setPassword(ctx context.Context, users repository.Users, login string, in io.Reader, out io.Writer) error {
+	password, err := readPassword(in)
+	if err != nil {
+		return err
+	}
+	err = validation.Validate(validation.Field("password", password, validation.Required, validation.MinLength(6)))
+	if err != nil {
+		return fmt.Errorf("set-password: %w", err)
+	}
+	if _, err = users.EditUser(ctx, &model.User{Login: login, Password: password}); err != nil {
+		return fmt.Errorf("set-password: %w", err)
+	}
+	fmt.Fprintf(out, "password of %s set\n", login)
+	return nil
+}
+
+func changeRole(ctx context.Context, users repository.Users, command, login, role string, out io.Writer) error {
+	err := validation.Validate(validation.Field("role", role, validation.OneOf(accountRoles...)))
+	if err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+	id, err := users.GetUserID(ctx, login)
+	if err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+	if id == 0 {
+		return fmt.Errorf("%s: %w", command, repository.NewNotFoundError("user "+login))
+	}
+	if command == "grant" {
+		err = users.AddRole(ctx, login, role)
+	} else {
+		err = users.RemoveRole(ctx, login, role)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+	roles, err := users.GetUserRolesByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+	fmt.Fprintf(out, "%s has roles %s\n", login, strings.Join(roles, ", "))
+	return nil
+}
+
+func listUsers(ctx context.Context, users repository.Users, out io.Writer) error {
+	accounts, err := users.ListUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("users: %w", err)
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tLOGIN\tROLES")
+	for _, a := range accounts {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", a.ID, a.Login, strings.Join(a.Roles, ","))
+	}
+	return w.Flush()
+}
+
+// importCatalog upserts products from a CSV file in the format of
+// POST /import/products and prints its report. Failed rows make it fail.
+func importCatalog(ctx context.Context, lg *zap.Logger, products repository.Product, args []string, in io.Reader, out io.Writer) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	batchSize := flags.Int("batch-size", 0, "")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 || *batchSize < 0 {
+		return errors.New(adminUsage)
+	}
+	body := in
+	if name := flags.Arg(0); name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+		defer file.Close()
+		body = file
+	}
+
+	report, err := controllers.ImportCSV(ctx, products, lg, body, *batchSize)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	for _, row := range report.Rows {
+		if row.Detail == "" {
+			continue
+		}
+		fmt.Fprintf(out, "row %d %s: %s", row.Row, row.SKU, row.Detail)
+		for _, field := range row.InvalidParams {
+			fmt.Fprintf(out, "; %s %s", field.Field, field.Reason)
+		}
+		fmt.Fprintln(out)
+	}
+	fmt.Fprintf(out, "%d rows: %d created, %d updated, %d failed, %d skipped\n",
+		report.Total, report.Created, report.Updated, report.Failed, report.Skipped)
+	if report.Failed > 0 || report.Skipped > 0 {
+		return fmt.Errorf("import: %d of %d rows not imported", report.Failed+report.Skipped, report.Total)
+	}
+	return nil
+}
+
+// exportCatalog writes the whole catalog, like GET /export/products without
+// filters, to a file or to out.
+func exportCatalog(ctx context.Context, products repository.Product, args []string, out io.Writer) (err error) {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	format := flags.String("format", export.CSV, "")
+	if err = flags.Parse(args); err != nil || flags.NArg() > 1 {
+		return errors.New(adminUsage)
+	}
+	if name := flags.Arg(0); name != "" && name != "-" {
+		file, err := os.Create(name)
+		if err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+		defer func() {
+			if cerr := file.Close(); err == nil && cerr != nil {
+				err = fmt.Errorf("export: %w", cerr)
+			}
+		}()
+		out = file
+	}
+
+	encoder, err := export.NewEncoder(*format, out)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if err = products.ExportProducts(ctx, model.ProductFilter{}, encoder.Encode); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if err = encoder.Close(); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	return nil
+}
+
+// flushCache empties the Redis database of the cache. The rate limit
+// buckets share it and are kept unless asked for.
+func flushCache(ctx context.Context, cfg config.Cache, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("flush-cache", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	rateLimits := flags.Bool("rate-limits", false, "")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errors.New(adminUsage)
+	}
+	keep := ratelimit.KeyPrefix
+	if *rateLimits {
+		keep = ""
+	}
+
+	pool := cache2.InitCache(cfg.DSN)
+	defer pool.Close()
+	deleted, err := cache2.Flush(ctx, pool, keep)
+	if err != nil {
+		return fmt.Errorf("flush-cache: %w", err)
+	}
+	fmt.Fprintf(out, "deleted %d keys\n", deleted)
+	return nil
+}
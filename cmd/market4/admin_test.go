@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"market4/internal/model"
+	"market4/internal/repository"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type stubUsers struct {
+	repository.Users
+	ids       map[string]int
+	passwords map[string]string
+	roles     map[string][]string
+}
+
+func newStubUsers() *stubUsers {
+	return &stubUsers{ids: map[string]int{}, passwords: map[string]string{}, roles: map[string][]string{}}
+}
+
+func (s *stubUsers) AddUser(ctx context.Context, u *model.User) (*model.User, error) {
+	if _, ok := s.passwords[u.Login]; ok {
+		return nil, repository.NewConflictError("user " + u.Login)
+	}
+	s.ids[u.Login] = len(s.ids) + 1
+	s.passwords[u.Login] = u.Password
+	s.roles[u.Login] = []string{u.Role}
+	return &model.User{ID: s.ids[u.Login]}, nil
+}
+
+func (s *stubUsers) EditUser(ctx context.Context, u *model.User) (*model.User, error) {
+	if _, ok := s.passwords[u.Login]; !ok {
+		return &model.User{}, repository.NewNotFoundError("")
+	}
+	s.passwords[u.Login] = u.Password
+	return &model.User{}, nil
+}
+
+func (s *stubUsers) GetUserID(ctx context.Context, login string) (int, error) {
+	return s.ids[login], nil
+}
+
+func (s *stubUsers) AddRole(ctx context.Context, login, role string) error {
+	s.roles[login] = append(s.roles[login], role)
+	return nil
+}
+
+func (s *stubUsers) GetUserRolesByID(ctx context.Context, id int) ([]string, error) {
+	for login, roles := range s.roles {
+		if s.ids[login] == id {
+			return roles, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *stubUsers) ListUsers(ctx context.Context) ([]model.Account, error) {
+	var accounts []model.Account
+	for login, roles := range s.roles {
+		accounts = append(accounts, model.Account{ID: s.ids[login], Login: login, Roles: roles})
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Login < accounts[j].Login })
+	return accounts, nil
+}
+
+type stubProducts struct {
+	repository.Product
+	upserted []model.ProductImport
+}
+
+func (s *stubProducts) UpsertProducts(ctx context.Context, rows []model.ProductImport) ([]repository.UpsertResult, error) {
+	s.upserted = append(s.upserted, rows...)
+	results := make([]repository.UpsertResult, len(rows))
+	for i := range rows {
+		results[i] = repository.UpsertResult{ProductID: "p" + rows[i].Product.SKU, Created: true}
+	}
+	return results, nil
+}
+
+func (s *stubProducts) ExportProducts(ctx context.Context, filter model.ProductFilter, fn func(model.ProductExport) error) error {
+	return fn(model.ProductExport{Product: model.Product{ID: "p1", SKU: "A-1", Name: "Milk"}})
+}
+
+func runTestAdmin(repos adminRepos, input string, args ...string) (string, error) {
+	var out bytes.Buffer
+	err := runAdmin(context.Background(), zap.NewNop(), repos, args, strings.NewReader(input), &out)
+	return out.String(), err
+}
+
+func Test_Admin_Users(t *testing.T) {
+	users := newStubUsers()
+	repos := adminRepos{users: users}
+
+	out, err := runTestAdmin(repos, "s3cret!\n", "create-user", "root", "ADMIN")
+	require.NoError(t, err)
+	assert.Equal(t, "created root with roles USER, ADMIN\n", out)
+	assert.Equal(t, []string{"USER", "ADMIN"}, users.roles["root"], "an admin can use the catalog routes too")
+	assert.Equal(t, "s3cret!", users.passwords["root"], "the newline isn't part of the password")
+
+	_, err = runTestAdmin(repos, "s3cret!", "create-user", "root", "ADMIN")
+	assert.True(t, errors.Is(err, repository.ErrConflict))
+	_, err = runTestAdmin(repos, "short", "create-user", "bob", "OWNER")
+	assert.EqualError(t, err, "create-user: validation failed; password must be at least 6 characters long; role must be one of ADMIN, USER, STAFF")
+	out, err = runTestAdmin(repos, "s3cret!", "create-user", "alice", "USER")
+	require.NoError(t, err)
+	assert.Equal(t, "created alice with roles USER\n", out)
+
+	out, err = runTestAdmin(repos, "", "grant", "root", "STAFF")
+	require.NoError(t, err)
+	assert.Equal(t, "root has roles USER, ADMIN, STAFF\n", out)
+	_, err = runTestAdmin(repos, "", "grant", "nobody", "STAFF")
+	assert.True(t, errors.Is(err, repository.ErrNotFound))
+
+	_, err = runTestAdmin(repos, "n3w-pass\n", "set-password", "root")
+	require.NoError(t, err)
+	assert.Equal(t, "n3w-pass", users.passwords["root"])
+
+	out, err = runTestAdmin(repos, "", "users")
+	require.NoError(t, err)
+	assert.Equal(t, "ID  LOGIN  ROLES\n2   alice  USER\n1   root   USER,ADMIN,STAFF\n", out)
+}
+
+func Test_Admin_Catalog(t *testing.T) {
+	products := &stubProducts{}
+	repos := adminRepos{products: products}
+
+	file := "sku,name,type,description,shop_id,category_id\nA-1,Milk,food,fresh,1,2\n"
+	out, err := runTestAdmin(repos, file, "import", "-")
+	require.NoError(t, err)
+	assert.Equal(t, "1 rows: 1 created, 0 updated, 0 failed, 0 skipped\n", out)
+	require.Len(t, products.upserted, 1)
+	assert.Equal(t, "A-1", products.upserted[0].Product.SKU)
+
+	out, err = runTestAdmin(repos, file+"B-2,,food,fresh,x,2\n", "import", "-")
+	assert.EqualError(t, err, "import: 2 of 2 rows not imported")
+	assert.Contains(t, out, "row 2 B-2: Bad Request; shop_id must be an integer")
+	assert.Len(t, products.upserted, 1, "a batch with a bad row isn't written")
+
+	out, err = runTestAdmin(repos, "", "export", "-format", "jsonl")
+	require.NoError(t, err)
+	assert.Contains(t, out, `"sku":"A-1"`)
+	_, err = runTestAdmin(repos, "", "export", "-format", "pdf")
+	assert.EqualError(t, err, `export: unknown export format "pdf"`)
+}
+
+func Test_Admin_Usage(t *testing.T) {
+	for _, args := range [][]string{
+		{"create-user"},
+		{"grant", "root"},
+		{"users", "extra"},
+		{"import"},
+		{"import", "-batch-size", "-1", "-"},
+		{"export", "a", "b"},
+		{"drop-database"},
+	} {
+		_, err := runTestAdmin(adminRepos{}, "", args...)
+		assert.EqualError(t, err, adminUsage, "%v", args)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"market4/internal/config"
+	"market4/internal/migrations"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.uber.org/zap"
+)
+
+const migrateUsage = "usage: market4 migrate up|down [steps]|status"
+
+// migrate runs `market4 migrate up|down [steps]|status` against the
+// configured database.
+func migrate(lg *zap.Logger, db config.Database, args []string, out io.Writer) error {
+	if len(args) == 0 || args[0] != "up" && args[0] != "down" && args[0] != "status" {
+		return errors.New(migrateUsage)
+	}
+	steps := 1
+	if args[0] == "down" && len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return fmt.Errorf("migrate down: steps must be a positive number, got %q", args[1])
+		}
+		steps = n
+	}
+
+	poolConfig, err := db.PoolConfig()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.ConnectConfig(ctx, poolConfig)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	migrator, err := migrations.New(pool, lg)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Fprintln(out, "the schema is up to date")
+		}
+		for _, m := range done {
+			fmt.Fprintf(out, "applied %04d_%s\n", m.Version, m.Name)
+		}
+	case "down":
+		done, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Fprintln(out, "nothing to roll back")
+		}
+		for _, m := range done {
+			fmt.Fprintf(out, "rolled back %04d_%s\n", m.Version, m.Name)
+		}
+	case "status":
+		states, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range states {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+	}
+	return nil
+}
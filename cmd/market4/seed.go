@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"market4/internal/config"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// seedSQL is demo data for development. It is never applied by migrations,
+// only by `market4 seed`, and creates no users: those are made with
+// `market4 admin create-user`.
+//
+//go:embed seed.sql
+var seedSQL string
+
+// seed fills a migrated development database with demo shops and
+// categories.
+func seed(db config.Database, out io.Writer) error {
+	poolConfig, err := db.PoolConfig()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.ConnectConfig(ctx, poolConfig)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	err = pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, seedSQL)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("seed: %w", err)
+	}
+	fmt.Fprintln(out, "seeded demo shops and categories")
+	return nil
+}
@@ -0,0 +1,19 @@
+-- демонстрационные магазины и категории для разработки, раньше жившие в
+-- 01_data.sql; повторный запуск ничего не дублирует
+INSERT
+INTO shops (name, address, lon, lat, working_hours)
+SELECT *
+FROM (VALUES ('Магазин на диване', 'Москва, Останкино', '324234' , '5465476', '8 - 20'),
+             ('Магазин для взрослых', 'Ростов, кремль', '12334' , '5465476', '8 - 20'),
+             ('Так себе магазин', 'Одесса, привоз', '8394' , '632542', '10 - 22'),
+             ('Магазин где есть все', 'Краснодар, центр', '86932' , '324234', '0 - 24')) AS demo
+WHERE NOT EXISTS (SELECT 1 FROM shops);
+
+INSERT
+INTO categories (name, uri_name)
+VALUES ('Стройматериалы', 'Стройматериалы-1'),
+       ('Игрушки', 'Игрушки-2'),
+       ('Продукты', 'Продукты-3'),
+       ('Тряпки', 'Тряпки-4'),
+       ('Товаря для дома', 'Товары для дома-5')
+ON CONFLICT DO NOTHING;
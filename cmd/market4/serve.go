@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// serve runs server on listener until ctx is done. It then keeps serving
+// for delay, while readiness reports the shutdown to the load balancer,
+// stops accepting connections and gives the requests in flight up to
+// timeout to finish. Requests still running after that are cut off and
+// reported as an error.
+func serve(ctx context.Context, server *http.Server, listener net.Listener, delay, timeout time.Duration, lg *zap.Logger) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Serve(listener)
+	}()
+	lg.Info("Serve", zap.String("addr", listener.Addr().String()))
+
+	select {
+	case err := <-errs:
+		// The server failed on its own; there is nothing to drain.
+		return err
+	case <-ctx.Done():
+	}
+
+	if delay > 0 {
+		lg.Info("Serve: draining", zap.Duration("delay", delay))
+		select {
+		case err := <-errs:
+			return err
+		case <-time.After(delay):
+		}
+	}
+	lg.Info("Serve: shutting down", zap.Duration("timeout", timeout))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := server.Shutdown(shutdownCtx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		server.Close()
+	}
+	if serr := <-errs; !errors.Is(serr, http.ErrServerClosed) && err == nil {
+		err = serr
+	}
+	return err
+}
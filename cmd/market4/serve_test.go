@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_Serve_DrainsInFlightRequests(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		writer.Write([]byte("done"))
+	})}
+
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, server, listener, 0, 5*time.Second, zap.NewNop())
+	}()
+
+	responses := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			responses <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		responses <- string(body)
+	}()
+	<-started
+	stop()
+
+	assert.Equal(t, "done", <-responses, "the request in flight finishes")
+	assert.NoError(t, <-served)
+	_, err = http.Get("http://" + listener.Addr().String())
+	assert.Error(t, err, "new connections are refused")
+}
+
+func Test_Serve_CutsOffAfterTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	server := &http.Server{Handler: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		close(started)
+		<-release
+	})}
+
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, server, listener, 0, 50*time.Millisecond, zap.NewNop())
+	}()
+	go http.Get("http://" + listener.Addr().String())
+	<-started
+	stop()
+
+	select {
+	case err := <-served:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return")
+	}
+}
+
+func Test_Serve_KeepsServingDuringDelay(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte("ok"))
+	})}
+
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, server, listener, 200*time.Millisecond, time.Second, zap.NewNop())
+	}()
+	stop()
+	time.Sleep(20 * time.Millisecond)
+
+	resp, err := http.Get("http://" + listener.Addr().String())
+	require.NoError(t, err, "still serving while the load balancer catches up")
+	resp.Body.Close()
+	assert.NoError(t, <-served)
+}
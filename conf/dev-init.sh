@@ -0,0 +1,18 @@
+#!/bin/sh
+# Только для docker-compose: миграции, демонстрационные магазины и категории
+# и пользователи user1 (USER) и user2 (USER, ADMIN) с паролем MARKET_DEV_PASSWORD.
+# В рабочем окружении первого администратора создаёт
+#   market4 admin create-user LOGIN ADMIN
+set -e
+
+market=/market/market
+$market migrate up
+$market seed
+users=$($market admin users)
+for account in "user1" "user2 ADMIN"; do
+    set -- $account
+    if ! echo "$users" | grep -q "^[0-9]* *$1 "; then
+        echo "$MARKET_DEV_PASSWORD" | $market admin create-user "$@"
+    fi
+done
+exec $market
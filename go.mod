@@ -4,17 +4,22 @@ go 1.16
 
 require (
 	github.com/DATA-DOG/go-sqlmock v1.5.0 // indirect
-	github.com/dgrijalva/jwt-go v3.2.0+incompatible // indirect
-	github.com/go-chi/chi/v5 v5.0.3 // indirect
+	github.com/dgrijalva/jwt-go v3.2.0+incompatible
+	github.com/go-chi/chi/v5 v5.0.3
 	github.com/go-chi/render v1.0.1 // indirect
-	github.com/gomodule/redigo v1.8.5 // indirect
-	github.com/jackc/pgconn v1.8.1 // indirect
+	github.com/gomodule/redigo v1.8.5
+	github.com/jackc/pgconn v1.8.1
 	github.com/jackc/pgx/v4 v4.11.0
 	github.com/jackc/puddle v1.1.3 // indirect
 	github.com/orlangure/gnomock v0.18.0 // indirect
-	github.com/stretchr/testify v1.7.0
-	github.com/unrolled/render v1.4.1 // indirect
-	go.uber.org/zap v1.19.1 // indirect
+	github.com/prometheus/client_golang v1.12.2
+	github.com/stretchr/testify v1.7.1
+	github.com/unrolled/render v1.4.1
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+	go.uber.org/zap v1.19.1
 	golang.org/x/crypto v0.0.0-20210817164053-32db794688a5
 	golang.org/x/tools v0.1.5 // indirect
 	gopkg.in/yaml.v2 v2.4.0
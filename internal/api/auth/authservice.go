@@ -12,10 +12,6 @@ import (
 	"go.uber.org/zap"
 )
 
-const (
-	PUBLICKEY = "./keys/public.key"
-)
-
 type AuthService struct {
 	privateKey *rsa.PrivateKey
 	publicKey  *rsa.PublicKey
@@ -25,23 +21,23 @@ type AuthService struct {
 func NewAuthService(privateKey, publicKey string, usersRepo repository.Users, lg *zap.Logger) *AuthService {
 	publicKeySource, err := ioutil.ReadFile(publicKey)
 	if err != nil {
-		lg.Error("Auth", zap.Error(err))
+		lg.Error("NewAuthService: read public key", zap.Error(err))
 		return nil
 	}
 	privateKeySource, err := ioutil.ReadFile(privateKey)
 	if err != nil {
-		lg.Error("Auth", zap.Error(err))
+		lg.Error("NewAuthService: read private key", zap.Error(err))
 		return nil
 	}
 
 	k1, err := jwt.ParseRSAPrivateKeyFromPEM(privateKeySource)
 	if err != nil {
-		lg.Error("Auth", zap.Error(err))
+		lg.Error("NewAuthService: parse private key", zap.Error(err))
 		return nil
 	}
 	k2, err := jwt.ParseRSAPublicKeyFromPEM(publicKeySource)
 	if err != nil {
-		lg.Error("Auth", zap.Error(err))
+		lg.Error("NewAuthService: parse public key", zap.Error(err))
 		return nil
 	}
 
@@ -58,6 +54,23 @@ type Payload struct {
 	jwt.StandardClaims
 }
 
+// HasRole reports whether the token grants any of the roles.
+func (p *Payload) HasRole(roles ...string) bool {
+	for _, have := range p.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PublicKey is the key tokens are checked with.
+func (a *AuthService) PublicKey() *rsa.PublicKey {
+	return a.publicKey
+}
+
 func (a *AuthService) GetToken(id int, roles []string) (string, error) {
 	payload := Payload{
 		ID:    id,
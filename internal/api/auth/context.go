@@ -0,0 +1,16 @@
+package auth
+
+import "context"
+
+type payloadKey struct{}
+
+// NewContext returns ctx carrying the verified token payload of the caller.
+func NewContext(ctx context.Context, payload *Payload) context.Context {
+	return context.WithValue(ctx, payloadKey{}, payload)
+}
+
+// FromContext returns the payload stored by NewContext, if any.
+func FromContext(ctx context.Context) (*Payload, bool) {
+	payload, ok := ctx.Value(payloadKey{}).(*Payload)
+	return payload, ok
+}
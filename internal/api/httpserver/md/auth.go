@@ -1,8 +1,10 @@
 package md
 
 import (
-	"io/ioutil"
+	"crypto/rsa"
 	"market4/internal/api/auth"
+	"market4/internal/api/problem"
+	"market4/internal/logging"
 	"market4/internal/model"
 	"net/http"
 
@@ -11,75 +13,80 @@ import (
 	"go.uber.org/zap"
 )
 
-func Auth(role model.UserRole, lg *zap.Logger) func(handler http.Handler) http.Handler {
+// Authenticator checks the tokens of requests against the public key the
+// service was started with.
+type Authenticator struct {
+	publicKey *rsa.PublicKey
+	lg        *zap.Logger
+	renderer  *render.Render
+}
+
+func NewAuthenticator(publicKey *rsa.PublicKey, lg *zap.Logger) *Authenticator {
+	return &Authenticator{publicKey: publicKey, lg: lg, renderer: render.New()}
+}
+
+// Auth lets through requests whose token grants role and puts its claims
+// in the request context.
+func (a *Authenticator) Auth(role model.UserRole) func(handler http.Handler) http.Handler {
 	return func(handler http.Handler) http.Handler {
 		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
-			r := render.New()
-			publicKeySource, err := ioutil.ReadFile(auth.PUBLICKEY)
-			if err != nil {
-				lg.Error("Auth", zap.Error(err))
-				err = r.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-				if err != nil {
-					lg.Error("Auth", zap.Error(err))
-				}
-				return
-			}
-			publicKey, err := jwt.ParseRSAPublicKeyFromPEM(publicKeySource)
-			if err != nil {
-				lg.Error("Auth", zap.Error(err))
-				err = r.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-				if err != nil {
-					lg.Error("Auth", zap.Error(err))
-				}
-				return
-			}
+			lg := logging.FromContext(request.Context(), a.lg)
 			token := request.Header.Get("Authorization")
 			if token == "" {
-				lg.Error("Auth: empty token")
-				err = r.JSON(writer, http.StatusUnauthorized, map[string]string{"Error": "Unauthorized"})
-				if err != nil {
-					lg.Error("Auth", zap.Error(err))
-				}
+				lg.Info("Auth: empty token")
+				a.reject(lg, writer, request, problem.New(http.StatusUnauthorized, ""))
 				return
 			}
-			payload, err := jwt.ParseWithClaims(token, &auth.Payload{}, func(token *jwt.Token) (interface{}, error) {
-				return publicKey, nil
-			})
+			claims, err := a.parse(token)
 			if err != nil {
-				lg.Error("Auth", zap.Error(err))
-				err = r.JSON(writer, http.StatusUnauthorized, map[string]string{"Error": "Unauthorized"})
-				if err != nil {
-					lg.Error("Auth", zap.Error(err))
-				}
-				return
-			}
-			if !payload.Valid {
-				lg.Error("Auth", zap.Error(err))
-				err = r.JSON(writer, http.StatusUnauthorized, map[string]string{"Error": "Unauthorized"})
-				if err != nil {
-					lg.Error("Auth", zap.Error(err))
-				}
-				return
-			}
-			claims, ok := payload.Claims.(*auth.Payload)
-			if !ok {
-				err = r.JSON(writer, http.StatusUnauthorized, map[string]string{"Error": "Unauthorized"})
-				if err != nil {
-					lg.Error("Auth", zap.Error(err))
-				}
+				lg.Info("Auth: invalid token", zap.Error(err))
+				a.reject(lg, writer, request, problem.New(http.StatusUnauthorized, ""))
 				return
 			}
+			logging.SetUser(request.Context(), claims.ID)
 
 			for _, r := range claims.Roles {
 				if r == string(role) {
-					handler.ServeHTTP(writer, request)
+					handler.ServeHTTP(writer, request.WithContext(auth.NewContext(request.Context(), claims)))
 					return
 				}
 			}
-			err = r.JSON(writer, http.StatusUnauthorized, map[string]string{"Error": "Unauthorized"})
-			if err != nil {
-				lg.Error("Auth", zap.Error(err))
-			}
+			a.reject(lg, writer, request, problem.New(http.StatusForbidden, "role "+string(role)+" is required"))
 		})
 	}
 }
+
+// Identify returns the claims of the request's token, if it carries a
+// valid one. Unlike Auth it neither requires a token nor rejects anything.
+func (a *Authenticator) Identify(request *http.Request) (*auth.Payload, bool) {
+	token := request.Header.Get("Authorization")
+	if token == "" {
+		return nil, false
+	}
+	claims, err := a.parse(token)
+	return claims, err == nil
+}
+
+func (a *Authenticator) parse(token string) (*auth.Payload, error) {
+	payload, err := jwt.ParseWithClaims(token, &auth.Payload{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, problem.ErrUnauthorized
+		}
+		return a.publicKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := payload.Claims.(*auth.Payload)
+	if !payload.Valid || !ok {
+		return nil, problem.ErrUnauthorized
+	}
+	return claims, nil
+}
+
+func (a *Authenticator) reject(lg *zap.Logger, writer http.ResponseWriter, request *http.Request, p *problem.Problem) {
+	err := problem.Write(a.renderer, writer, request, p)
+	if err != nil {
+		lg.Error("Auth: write problem", zap.Error(err))
+	}
+}
@@ -0,0 +1,67 @@
+package md
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"market4/internal/api/auth"
+	"market4/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func sign(t *testing.T, key *rsa.PrivateKey, roles ...string) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, auth.Payload{
+		ID:             7,
+		Roles:          roles,
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Minute).Unix()},
+	}).SignedString(key)
+	require.NoError(t, err)
+	return token
+}
+
+func Test_Authenticator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	authn := NewAuthenticator(&key.PublicKey, zap.NewNop())
+
+	var seen *auth.Payload
+	handler := authn.Auth(model.ADMIN)(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		seen, _ = auth.FromContext(request.Context())
+	}))
+	status := func(token string) int {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		if token != "" {
+			request.Header.Set("Authorization", token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, status(sign(t, key, "USER", "ADMIN")))
+	require.NotNil(t, seen)
+	assert.Equal(t, 7, seen.ID)
+	assert.Equal(t, http.StatusForbidden, status(sign(t, key, "USER")))
+	assert.Equal(t, http.StatusUnauthorized, status(sign(t, other, "ADMIN")), "only the configured key is trusted")
+	assert.Equal(t, http.StatusUnauthorized, status(""))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, ok := authn.Identify(request)
+	assert.False(t, ok)
+	request.Header.Set("Authorization", sign(t, other, "ADMIN"))
+	_, ok = authn.Identify(request)
+	assert.False(t, ok)
+	request.Header.Set("Authorization", sign(t, key, "USER"))
+	claims, ok := authn.Identify(request)
+	require.True(t, ok)
+	assert.Equal(t, []string{"USER"}, claims.Roles)
+}
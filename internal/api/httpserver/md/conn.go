@@ -0,0 +1,28 @@
+package md
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+type connKey struct{}
+
+// ConnContext is the http.Server hook that lets handlers reach the
+// connection of their request, see SetWriteDeadline.
+func ConnContext(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, conn)
+}
+
+// SetWriteDeadline moves the write deadline that http.Server set for the
+// request from its WriteTimeout, for responses that stream for longer. The
+// server sets it again for the next request on the connection.
+func SetWriteDeadline(request *http.Request, deadline time.Time) error {
+	conn, ok := request.Context().Value(connKey{}).(net.Conn)
+	if !ok {
+		return errors.New("SetWriteDeadline: the server has no ConnContext")
+	}
+	return conn.SetWriteDeadline(deadline)
+}
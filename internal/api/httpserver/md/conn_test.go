@@ -0,0 +1,44 @@
+package md
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SetWriteDeadline(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/long" {
+			assert.NoError(t, SetWriteDeadline(request, time.Now().Add(time.Minute)))
+		}
+		time.Sleep(200 * time.Millisecond)
+		writer.Write([]byte("done"))
+	}))
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Config.ConnContext = ConnContext
+	server.Start()
+	defer server.Close()
+
+	get := func(path string) (string, error) {
+		response, err := http.Get(server.URL + path)
+		if err != nil {
+			return "", err
+		}
+		defer response.Body.Close()
+		body, err := ioutil.ReadAll(response.Body)
+		return string(body), err
+	}
+	body, err := get("/long")
+	require.NoError(t, err)
+	assert.Equal(t, "done", body, "the handler outlived WriteTimeout")
+	_, err = get("/short")
+	assert.Error(t, err, "the others keep WriteTimeout")
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Error(t, SetWriteDeadline(request, time.Now()), "no ConnContext")
+}
@@ -2,79 +2,180 @@ package httpserver
 
 import (
 	"market4/internal/api/httpserver/md"
+	"market4/internal/api/openapi"
 	v1 "market4/internal/api/v1"
+	"market4/internal/health"
+	"market4/internal/logging"
+	"market4/internal/metrics"
 	"market4/internal/model"
+	"market4/internal/ratelimit"
+	"market4/internal/tracing"
 
 	"go.uber.org/zap"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
 )
 
-type ErrResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-}
-
 func NewRouter(
 	mux *chi.Mux,
 	lg *zap.Logger,
+	authn *md.Authenticator,
 	shopController *v1.Shop,
 	categoryController *v1.Category,
 	productController *v1.Product,
+	stockController *v1.Stock,
 	priceController *v1.Price,
+	promotionController *v1.Promotion,
+	couponController *v1.Coupon,
+	cartController *v1.Cart,
+	orderController *v1.Order,
+	paymentController *v1.Payment,
 	usersController *v1.Users,
-	authController *v1.Auth) chi.Mux {
-	mux.Use(middleware.Logger)
+	auditController *v1.Audit,
+	authController *v1.Auth,
+	healthChecker *health.Health,
+	counters *metrics.Metrics,
+	limiter *ratelimit.Limiter) chi.Mux {
+	mux.Use(tracing.Middleware)
+	mux.Use(logging.Middleware(lg))
+	mux.Use(counters.Middleware)
+	mux.Use(limiter.Middleware)
+	RouterHealth(mux, healthChecker)
+	RouterMetrics(mux, counters)
 	mux.Route("/api/v1", func(router chi.Router) {
-		RouterShop(router, shopController, lg)
-		RouterCategories(router, categoryController, lg)
-		RouterProduct(router, productController, lg)
-		RouterPrice(router, priceController, lg)
-		RouterUser(router, usersController, lg)
+		RouterShop(router, shopController, authn)
+		RouterCategories(router, categoryController, authn)
+		RouterProduct(router, productController, authn)
+		RouterStock(router, stockController, authn)
+		RouterPrice(router, priceController, authn)
+		RouterPromotion(router, promotionController, authn)
+		RouterCoupon(router, couponController, authn)
+		RouterCart(router, cartController, authn)
+		RouterOrder(router, orderController, authn)
+		RouterPayment(router, paymentController, authn)
+		RouterUser(router, usersController, authn)
+		RouterAudit(router, auditController, authn)
 		RouterAuth(router, authController)
+		RouterDocs(router)
 	})
 
 	lg.Info("new router is activated")
 	return *mux
 }
 
-func RouterShop(router chi.Router, shopController *v1.Shop, lg *zap.Logger) chi.Router {
-	router.With(md.Auth(model.USER, lg)).Get("/shops", shopController.ListAllShops)
-	router.With(md.Auth(model.ADMIN, lg)).Post("/shops", shopController.AddShop)
-	router.With(md.Auth(model.ADMIN, lg)).Put("/shops", shopController.EditShop)
+func RouterShop(router chi.Router, shopController *v1.Shop, authn *md.Authenticator) chi.Router {
+	router.With(authn.Auth(model.USER)).Get("/shops", shopController.ListAllShops)
+	router.With(authn.Auth(model.USER)).Get("/shops/{shopID}", shopController.GetShopByID)
+	router.With(authn.Auth(model.ADMIN)).Post("/shops", shopController.AddShop)
+	router.With(authn.Auth(model.ADMIN)).Put("/shops", shopController.EditShop)
+	router.With(authn.Auth(model.ADMIN)).Delete("/shops/{shopID}", shopController.DeleteShop)
+	router.With(authn.Auth(model.ADMIN)).Post("/shops/{shopID}/restore", shopController.RestoreShop)
+	return router
+}
+
+func RouterCategories(router chi.Router, categoryController *v1.Category, authn *md.Authenticator) chi.Router {
+	router.With(authn.Auth(model.USER)).Get("/categories", categoryController.ListAllCategories)
+	router.With(authn.Auth(model.USER)).Get("/categories/{categoryID}", categoryController.GetCategoryByID)
+	router.With(authn.Auth(model.USER)).Get("/categories/uri/{uriName}", categoryController.GetCategoryByURIName)
+	router.With(authn.Auth(model.ADMIN)).Post("/categories", categoryController.AddCategory)
+	router.With(authn.Auth(model.ADMIN)).Put("/categories", categoryController.EditCategory)
+	router.With(authn.Auth(model.ADMIN)).Delete("/categories/{categoryID}", categoryController.DeleteCategory)
+	router.With(authn.Auth(model.ADMIN)).Post("/categories/{categoryID}/restore", categoryController.RestoreCategory)
+	return router
+}
+
+func RouterProduct(router chi.Router, productController *v1.Product, authn *md.Authenticator) chi.Router {
+	router.With(authn.Auth(model.ADMIN)).Post("/products", productController.AddProduct)
+	router.With(authn.Auth(model.ADMIN)).Put("/products", productController.EditProduct)
+	router.With(authn.Auth(model.ADMIN)).Delete("/products/{productID}", productController.DeleteProduct)
+	router.With(authn.Auth(model.ADMIN)).Post("/products/{productID}/restore", productController.RestoreProduct)
+	router.With(authn.Auth(model.USER)).Get("/products", productController.ListAllProducts)
+	router.With(authn.Auth(model.USER)).Get("/products/{productID}", productController.GetProductByID)
+	router.With(authn.Auth(model.USER)).Get("/products/sku/{sku}", productController.GetProductBySKU)
+	router.With(authn.Auth(model.USER)).Get("/product/{slug}", productController.GetProductByURI)
+	router.With(authn.Auth(model.USER)).Get("/categories/{categoryID:.+}/products", productController.SearchProductsByCategory)
+	router.With(authn.Auth(model.USER)).Get("/search/{product_name:.+}", productController.SearchProductByName)
+	router.With(authn.Auth(model.USER)).Get("/shops/{shopID:.+}/products", productController.SearchActiveProductsOfShop)
+	router.With(authn.Auth(model.ADMIN)).Post("/import/products", productController.ImportProducts)
+	router.With(authn.Auth(model.USER)).Get("/export/products", productController.ExportProducts)
+	return router
+}
+
+func RouterStock(router chi.Router, stockController *v1.Stock, authn *md.Authenticator) chi.Router {
+	router.With(authn.Auth(model.USER)).Get("/products/{productID}/stock", stockController.ListStock)
+	router.With(authn.Auth(model.ADMIN)).Put("/stock", stockController.SetStock)
+	return router
+}
+
+func RouterPrice(router chi.Router, priceController *v1.Price, authn *md.Authenticator) chi.Router {
+	router.With(authn.Auth(model.ADMIN)).Post("/prices", priceController.AddPrice)
+	router.With(authn.Auth(model.ADMIN)).Put("/prices", priceController.EditPrice)
+	router.With(authn.Auth(model.ADMIN)).Post("/prices/bulk", priceController.BulkUpdatePrices)
+	router.With(authn.Auth(model.ADMIN)).Delete("/prices/{priceID}", priceController.DeletePrice)
+	router.With(authn.Auth(model.ADMIN)).Post("/prices/{priceID}/restore", priceController.RestorePrice)
+	router.With(authn.Auth(model.USER)).Get("/prices", priceController.ListAllPrices)
+	router.With(authn.Auth(model.USER)).Get("/prices/{priceID}", priceController.GetPriceByID)
+	return router
+}
+
+func RouterPromotion(router chi.Router, promotionController *v1.Promotion, authn *md.Authenticator) chi.Router {
+	router.With(authn.Auth(model.ADMIN)).Get("/promotions", promotionController.ListPromotions)
+	router.With(authn.Auth(model.ADMIN)).Get("/promotions/{promotionID}", promotionController.GetPromotionByID)
+	router.With(authn.Auth(model.ADMIN)).Post("/promotions", promotionController.AddPromotion)
+	router.With(authn.Auth(model.ADMIN)).Put("/promotions", promotionController.EditPromotion)
+	router.With(authn.Auth(model.ADMIN)).Delete("/promotions/{promotionID}", promotionController.DeletePromotion)
+	return router
+}
+
+func RouterCoupon(router chi.Router, couponController *v1.Coupon, authn *md.Authenticator) chi.Router {
+	router.With(authn.Auth(model.ADMIN)).Get("/coupons", couponController.ListCoupons)
+	router.With(authn.Auth(model.ADMIN)).Get("/coupons/{couponID}", couponController.GetCouponByID)
+	router.With(authn.Auth(model.ADMIN)).Post("/coupons", couponController.AddCoupon)
+	router.With(authn.Auth(model.ADMIN)).Put("/coupons", couponController.EditCoupon)
+	router.With(authn.Auth(model.ADMIN)).Delete("/coupons/{couponID}", couponController.DeleteCoupon)
+	router.With(authn.Auth(model.USER)).Post("/coupons/validate", couponController.ValidateCoupon)
 	return router
 }
 
-func RouterCategories(router chi.Router, categoryController *v1.Category, lg *zap.Logger) chi.Router {
-	router.With(md.Auth(model.USER, lg)).Get("/categories", categoryController.ListAllCategories)
-	router.With(md.Auth(model.ADMIN, lg)).Post("/categories", categoryController.AddCategory)
-	router.With(md.Auth(model.ADMIN, lg)).Put("/categories", categoryController.EditCategory)
+func RouterCart(router chi.Router, cartController *v1.Cart, authn *md.Authenticator) chi.Router {
+	router.With(authn.Auth(model.USER)).Get("/cart", cartController.GetCart)
+	router.With(authn.Auth(model.USER)).Delete("/cart", cartController.ClearCart)
+	router.With(authn.Auth(model.USER)).Post("/cart/items", cartController.AddCartItem)
+	router.With(authn.Auth(model.USER)).Put("/cart/items/{productID}", cartController.EditCartItem)
+	router.With(authn.Auth(model.USER)).Delete("/cart/items/{productID}", cartController.RemoveCartItem)
 	return router
 }
 
-func RouterProduct(router chi.Router, productController *v1.Product, lg *zap.Logger) chi.Router {
-	router.With(md.Auth(model.ADMIN, lg)).Post("/products", productController.AddProduct)
-	router.With(md.Auth(model.ADMIN, lg)).Put("/products", productController.EditProduct)
-	router.With(md.Auth(model.USER, lg)).Get("/products", productController.ListAllProducts)
-	router.With(md.Auth(model.USER, lg)).Get("/categories/{categoryID:.+}/products", productController.SearchProductsByCategory)
-	router.With(md.Auth(model.USER, lg)).Get("/search/{product_name:.+}", productController.SearchProductByName)
-	router.With(md.Auth(model.USER, lg)).Get("/shops/{shopID:.+}/products", productController.SearchActiveProductsOfShop)
+func RouterOrder(router chi.Router, orderController *v1.Order, authn *md.Authenticator) chi.Router {
+	router.With(authn.Auth(model.USER)).Get("/orders", orderController.ListOrders)
+	router.With(authn.Auth(model.USER)).Post("/orders", orderController.Checkout)
+	router.With(authn.Auth(model.USER)).Get("/orders/{orderID}", orderController.GetOrder)
+	router.With(authn.Auth(model.USER)).Post("/orders/{orderID}/status", orderController.SetOrderStatus)
 	return router
 }
 
-func RouterPrice(router chi.Router, priceController *v1.Price, lg *zap.Logger) chi.Router {
-	router.With(md.Auth(model.ADMIN, lg)).Post("/prices", priceController.AddPrice)
-	router.With(md.Auth(model.ADMIN, lg)).Put("/prices", priceController.EditPrice)
-	router.With(md.Auth(model.USER, lg)).Get("/prices", priceController.ListAllPrices)
+// RouterPayment serves payments of orders. The webhook is called by the
+// payment provider, which proves itself by signing the body instead of a
+// token.
+func RouterPayment(router chi.Router, paymentController *v1.Payment, authn *md.Authenticator) chi.Router {
+	router.With(authn.Auth(model.USER)).Get("/orders/{orderID}/payments", paymentController.ListPayments)
+	router.With(authn.Auth(model.USER)).Post("/orders/{orderID}/payments", paymentController.PayOrder)
+	router.With(authn.Auth(model.USER)).Post("/orders/{orderID}/refund", paymentController.RefundOrder)
+	router.Post("/payments/webhook", paymentController.Webhook)
 	return router
 }
 
-func RouterUser(router chi.Router, usersController *v1.Users, lg *zap.Logger) chi.Router {
-	router.With(md.Auth(model.ADMIN, lg)).Post("/users", usersController.AddUser)
-	router.With(md.Auth(model.ADMIN, lg)).Put("/users", usersController.EditUser)
-	router.With(md.Auth(model.ADMIN, lg)).Put("/users/addrole", usersController.AddRole)
-	router.With(md.Auth(model.ADMIN, lg)).Put("/users/removerole", usersController.RemoveRole)
+func RouterUser(router chi.Router, usersController *v1.Users, authn *md.Authenticator) chi.Router {
+	router.With(authn.Auth(model.ADMIN)).Post("/users", usersController.AddUser)
+	router.With(authn.Auth(model.ADMIN)).Put("/users", usersController.EditUser)
+	router.With(authn.Auth(model.ADMIN)).Put("/users/addrole", usersController.AddRole)
+	router.With(authn.Auth(model.ADMIN)).Put("/users/removerole", usersController.RemoveRole)
+	router.With(authn.Auth(model.ADMIN)).Put("/users/shops", usersController.SetStaffShops)
+	return router
+}
+
+func RouterAudit(router chi.Router, auditController *v1.Audit, authn *md.Authenticator) chi.Router {
+	router.With(authn.Auth(model.ADMIN)).Get("/audit", auditController.ListAuditEntries)
 	return router
 }
 
@@ -82,3 +183,23 @@ func RouterAuth(router chi.Router, authController *v1.Auth) chi.Router {
 	router.Post("/auth", authController.Token)
 	return router
 }
+
+// RouterHealth serves the probes of the load balancer next to the API,
+// not under it, and without authentication.
+func RouterHealth(router chi.Router, healthChecker *health.Health) chi.Router {
+	router.Get("/healthz", healthChecker.Live)
+	router.Get("/readyz", healthChecker.Ready)
+	return router
+}
+
+// RouterMetrics serves the Prometheus scrape endpoint next to the probes.
+func RouterMetrics(router chi.Router, counters *metrics.Metrics) chi.Router {
+	router.Method("GET", "/metrics", counters.Handler())
+	return router
+}
+
+func RouterDocs(router chi.Router) chi.Router {
+	router.Get("/openapi.json", openapi.ServeSpec)
+	router.Get("/docs", openapi.ServeDocs)
+	return router
+}
@@ -0,0 +1,88 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"market4/internal/api/auth"
+	"market4/internal/api/httpserver/md"
+	"market4/internal/api/openapi"
+	v1 "market4/internal/api/v1"
+	"market4/internal/health"
+	"market4/internal/metrics"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+)
+
+var paramPattern = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+func routerOperations(t *testing.T) map[string]bool {
+	lg := zap.NewNop()
+	renderer := render.New()
+	mux := chi.NewRouter()
+	router := NewRouter(mux, lg, md.NewAuthenticator(nil, lg),
+		v1.NewShop(nil, lg, renderer),
+		v1.NewCategory(nil, lg, renderer),
+		v1.NewProduct(nil, nil, nil, nil, time.Minute, lg, renderer),
+		v1.NewStock(nil, lg, renderer),
+		v1.NewPrice(nil, nil, lg, renderer),
+		v1.NewPromotion(nil, lg, renderer),
+		v1.NewCoupon(nil, nil, nil, lg, renderer),
+		v1.NewCart(nil, nil, nil, lg, renderer),
+		v1.NewOrder(nil, nil, nil, nil, nil, lg, renderer),
+		v1.NewPayment(nil, nil, nil, lg, renderer),
+		v1.NewUser(nil, lg, renderer),
+		v1.NewAudit(nil, lg, renderer),
+		v1.NewAuth(auth.AuthService{}, nil, nil, lg, renderer),
+		health.New(time.Second, lg),
+		metrics.New(),
+		nil)
+
+	operations := make(map[string]bool)
+	err := chi.Walk(&router, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		route = strings.TrimPrefix(route, "/api/v1")
+		route = paramPattern.ReplaceAllString(route, "{$1}")
+		operations[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return operations
+}
+
+func specOperations(t *testing.T) map[string]bool {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openapi.Spec, &spec); err != nil {
+		t.Fatal(err)
+	}
+	operations := make(map[string]bool)
+	for path, methods := range spec.Paths {
+		for method := range methods {
+			operations[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	return operations
+}
+
+func Test_RoutesAreDocumented(t *testing.T) {
+	inSpec := specOperations(t)
+	inRouter := routerOperations(t)
+	for op := range inRouter {
+		if !inSpec[op] {
+			t.Errorf("route %s is registered in the router but missing from openapi.json", op)
+		}
+	}
+	for op := range inSpec {
+		if !inRouter[op] {
+			t.Errorf("operation %s is documented in openapi.json but not registered in the router", op)
+		}
+	}
+}
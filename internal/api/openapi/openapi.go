@@ -0,0 +1,22 @@
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed docs.html
+var docs []byte
+
+func ServeSpec(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "application/json")
+	_, _ = writer.Write(Spec)
+}
+
+func ServeDocs(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = writer.Write(docs)
+}
@@ -0,0 +1,107 @@
+package problem
+
+import (
+	"errors"
+	"market4/internal/repository"
+	"net/http"
+
+	"github.com/unrolled/render"
+)
+
+const ContentType = "application/problem+json"
+
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrUnavailable  = errors.New("service unavailable")
+)
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	Type          string                  `json:"type"`
+	Title         string                  `json:"title"`
+	Status        int                     `json:"status"`
+	Detail        string                  `json:"detail,omitempty"`
+	Instance      string                  `json:"instance,omitempty"`
+	InvalidParams []repository.FieldError `json:"invalid_params,omitempty"`
+}
+
+// BadRequest is ErrBadRequest with the detail the client is shown. err,
+// which may quote parser internals, stays in the chain for the logs only.
+func BadRequest(detail string, err error) error {
+	return &requestError{detail: detail, err: err}
+}
+
+type requestError struct {
+	detail string
+	err    error
+}
+
+func (e *requestError) Error() string {
+	return ErrBadRequest.Error() + ": " + e.err.Error()
+}
+
+func (e *requestError) Is(target error) bool {
+	return target == ErrBadRequest
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+func New(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// FromError maps domain errors onto problem documents. Details come only
+// from BadRequest and repository.Error, whose details are written for
+// clients; anything else becomes a 500 without detail, so internals never
+// leak to clients.
+func FromError(err error) *Problem {
+	var p *Problem
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr):
+		p = New(http.StatusBadRequest, reqErr.detail)
+	case errors.Is(err, ErrBadRequest):
+		p = New(http.StatusBadRequest, "")
+	case errors.Is(err, ErrUnauthorized):
+		p = New(http.StatusUnauthorized, "")
+	case errors.Is(err, ErrForbidden):
+		p = New(http.StatusForbidden, "")
+	case errors.Is(err, ErrUnavailable):
+		p = New(http.StatusServiceUnavailable, "")
+	case errors.Is(err, repository.ErrValidation):
+		p = New(http.StatusBadRequest, "")
+	case errors.Is(err, repository.ErrNotFound):
+		p = New(http.StatusNotFound, "")
+	case errors.Is(err, repository.ErrConflict):
+		p = New(http.StatusConflict, "")
+	case errors.Is(err, repository.ErrForeignKey):
+		p = New(http.StatusUnprocessableEntity, "")
+	default:
+		return New(http.StatusInternalServerError, "")
+	}
+
+	var domainErr *repository.Error
+	if errors.As(err, &domainErr) {
+		p.Detail = domainErr.Detail
+		p.InvalidParams = domainErr.Fields
+	}
+	return p
+}
+
+func Write(renderer *render.Render, writer http.ResponseWriter, request *http.Request, p *Problem) error {
+	if request != nil {
+		p.Instance = request.URL.Path
+	}
+	return renderer.Render(writer, render.JSON{
+		Head: render.Head{ContentType: ContentType, Status: p.Status},
+	}, p)
+}
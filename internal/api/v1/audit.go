@@ -0,0 +1,101 @@
+package v1
+
+import (
+	"encoding/json"
+	"market4/internal/api/validation"
+	"market4/internal/logging"
+	"market4/internal/model"
+	"market4/internal/repository"
+	"market4/internal/views"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+)
+
+type Audit struct {
+	auditRepo repository.Audit
+	lg        *zap.Logger
+	renderer  *render.Render
+}
+
+func NewAudit(auditRepo repository.Audit, lg *zap.Logger, renderer *render.Render) *Audit {
+	return &Audit{auditRepo: auditRepo, lg: lg, renderer: renderer}
+}
+
+// ListAuditEntries pages through the audit log, newest first.
+func (a *Audit) ListAuditEntries(writer http.ResponseWriter, request *http.Request) {
+	filter, err := auditFilter(request)
+	if err != nil {
+		writeError(a.renderer, a.lg, writer, request, "ListAuditEntries", err)
+		return
+	}
+	entries, err := a.auditRepo.ListAuditEntries(request.Context(), filter)
+	if err != nil {
+		writeError(a.renderer, a.lg, writer, request, "ListAuditEntries", err)
+		return
+	}
+	list := views.AuditListDTO{Total: len(entries), Items: make([]*model.AuditEntry, 0, len(entries))}
+	for i := range entries {
+		list.Items = append(list.Items, &entries[i])
+	}
+	if len(entries) > 0 && len(entries) == filter.Limit {
+		list.NextBeforeID = entries[len(entries)-1].ID
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(list)
+	if err != nil {
+		logging.FromContext(request.Context(), a.lg).Error("ListAuditEntries", zap.Error(err))
+	}
+}
+
+func auditFilter(request *http.Request) (model.AuditFilter, error) {
+	query := request.URL.Query()
+	filter := model.AuditFilter{
+		Action:    query.Get("action"),
+		Entity:    query.Get("entity"),
+		EntityID:  query.Get("entity_id"),
+		RequestID: query.Get("request_id"),
+		Limit:     repository.DefaultAuditLimit,
+	}
+	var fieldErrors []repository.FieldError
+	integer := func(name string) int64 {
+		value := query.Get(name)
+		if value == "" {
+			return 0
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 1 {
+			fieldErrors = append(fieldErrors, repository.FieldError{Field: name, Reason: "must be a positive integer"})
+		}
+		return n
+	}
+	moment := func(name string) time.Time {
+		value := query.Get(name)
+		if value == "" {
+			return time.Time{}
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			fieldErrors = append(fieldErrors, repository.FieldError{Field: name, Reason: "must be an RFC 3339 time"})
+		}
+		return t.UTC()
+	}
+	filter.ActorID = int(integer("actor_id"))
+	filter.BeforeID = integer("before_id")
+	if limit := integer("limit"); limit != 0 {
+		filter.Limit = int(limit)
+	}
+	filter.From = moment("from")
+	filter.To = moment("to")
+	if len(fieldErrors) > 0 {
+		return model.AuditFilter{}, repository.NewValidationError(fieldErrors...)
+	}
+	return filter, validation.Validate(
+		validation.Field("action", filter.Action, validation.OneOf(model.AuditActions...)),
+		validation.Field("entity", filter.Entity, validation.OneOf(model.AuditEntities...)),
+		validation.Field("limit", filter.Limit, validation.Max(repository.MaxAuditLimit)),
+	)
+}
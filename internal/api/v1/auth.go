@@ -3,6 +3,8 @@ package v1
 import (
 	"encoding/json"
 	"market4/internal/api/auth"
+	"market4/internal/api/problem"
+	"market4/internal/metrics"
 	"market4/internal/model"
 	"market4/internal/repository"
 	"net/http"
@@ -18,16 +20,19 @@ type Token struct {
 type Auth struct {
 	authService auth.AuthService
 	usersRepo   repository.Users
+	counters    *metrics.Metrics
 	lg          *zap.Logger
 	renderer    *render.Render
 }
 
 func NewAuth(authService auth.AuthService,
 	usersRepo repository.Users,
+	counters *metrics.Metrics,
 	lg *zap.Logger,
 	renderer *render.Render) *Auth {
 	return &Auth{authService: authService,
 		usersRepo: usersRepo,
+		counters:  counters,
 		lg:        lg,
 		renderer:  renderer}
 }
@@ -37,77 +42,50 @@ func (a *Auth) Token(writer http.ResponseWriter, request *http.Request) {
 	writer.Header().Set("Content-Type", "application/json")
 	err := json.NewDecoder(request.Body).Decode(&data)
 	if err != nil {
-		a.lg.Error("Token", zap.Error(err))
-		err = a.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "Bad request"})
-		if err != nil {
-			a.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(a.renderer, a.lg, writer, request, "Token", badRequest(err))
 		return
 	}
-	err = checkMandatoryFields(data.Login, data.Password)
+	err = validateCredentials(data)
 	if err != nil {
-		a.lg.Error("Token or password field is empty")
-		err = a.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "Bad request"})
-		if err != nil {
-			a.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(a.renderer, a.lg, writer, request, "Token", err)
 		return
 	}
 
 	if ok := a.usersRepo.CheckCreds(request.Context(), *data); !ok {
-		err = a.renderer.JSON(writer, http.StatusUnauthorized, map[string]string{"Error": "Unauthorized"})
-		if err != nil {
-			a.lg.Error("Auth", zap.Error(err))
-		}
+		a.counters.Login(false)
+		writeError(a.renderer, a.lg, writer, request, "Token", problem.ErrUnauthorized)
 		return
 	}
 
 	id, err := a.usersRepo.GetUserID(request.Context(), data.Login)
 	if err != nil {
-		a.lg.Error("Token", zap.Error(err))
-		err = a.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			a.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(a.renderer, a.lg, writer, request, "Token", err)
 		return
 	}
 
 	roles, err := a.usersRepo.GetUserRolesByID(request.Context(), id)
 	if err != nil {
-		a.lg.Error("Token", zap.Error(err))
-		err = a.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			a.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(a.renderer, a.lg, writer, request, "Token", err)
 		return
 	}
 
 	if id == 0 || len(roles) == 0 {
-		err = a.renderer.JSON(writer, http.StatusUnauthorized, map[string]string{"Error": "Unauthorized"})
-		if err != nil {
-			a.lg.Error("Auth", zap.Error(err))
-		}
+		a.counters.Login(false)
+		writeError(a.renderer, a.lg, writer, request, "Token", problem.ErrUnauthorized)
 		return
 	}
 
 	var reply Token
 	reply.Token, err = a.authService.GetToken(id, roles)
 	if err != nil {
-		a.lg.Error("Token", zap.Error(err))
-		err = a.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "Bad request"})
-		if err != nil {
-			a.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(a.renderer, a.lg, writer, request, "Token", badRequest(err))
 		return
 	}
+	a.counters.Login(true)
 	writer.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(writer).Encode(reply)
 	if err != nil {
-		a.lg.Error("Token", zap.Error(err))
-		err = a.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			a.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(a.renderer, a.lg, writer, request, "Token", err)
 		return
 	}
 }
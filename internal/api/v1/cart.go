@@ -0,0 +1,291 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"market4/internal/api/auth"
+	"market4/internal/api/problem"
+	"market4/internal/api/validation"
+	"market4/internal/cache"
+	"market4/internal/logging"
+	"market4/internal/model"
+	"market4/internal/pricing"
+	"market4/internal/repository"
+	"market4/internal/views"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+)
+
+// maxCartQuantity matches the CHECK on cart_items.quantity.
+const maxCartQuantity = 1000
+
+// Cart warning codes.
+const (
+	CartUnavailable  = "unavailable"
+	CartNoPrice      = "no_price"
+	CartPriceChanged = "price_changed"
+)
+
+type Cart struct {
+	cartRepo      repository.Cart
+	promotionRepo repository.Promotion
+	store         cache.Cache
+	lg            *zap.Logger
+	renderer      *render.Render
+}
+
+func NewCart(cartRepo repository.Cart, promotionRepo repository.Promotion, store cache.Cache, lg *zap.Logger, renderer *render.Render) *Cart {
+	return &Cart{cartRepo: cartRepo, promotionRepo: promotionRepo, store: store, lg: lg, renderer: renderer}
+}
+
+type CartItemDTO struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+// GetCart serves the caller's cart. Views are cached per user and rebuilt
+// on every change made through this API; price and product changes made
+// elsewhere show up once the cached view expires.
+func (c *Cart) GetCart(writer http.ResponseWriter, request *http.Request) {
+	payload, ok := auth.FromContext(request.Context())
+	if !ok {
+		writeError(c.renderer, c.lg, writer, request, "GetCart", problem.ErrUnauthorized)
+		return
+	}
+	if body, _ := c.store.FromCache(request.Context(), cartKey(payload.ID)); body != nil {
+		writer.Header().Set("Content-Type", "application/json")
+		_, err := writer.Write(body)
+		if err != nil {
+			logging.FromContext(request.Context(), c.lg).Error("GetCart", zap.Error(err))
+		}
+		return
+	}
+	c.writeCart(writer, request, "GetCart", payload.ID)
+}
+
+// AddCartItem adds quantity (one by default) of a product to the cart.
+func (c *Cart) AddCartItem(writer http.ResponseWriter, request *http.Request) {
+	payload, ok := auth.FromContext(request.Context())
+	if !ok {
+		writeError(c.renderer, c.lg, writer, request, "AddCartItem", problem.ErrUnauthorized)
+		return
+	}
+	var data CartItemDTO
+	err := json.NewDecoder(request.Body).Decode(&data)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "AddCartItem", badRequest(err))
+		return
+	}
+	if data.Quantity == 0 {
+		data.Quantity = 1
+	}
+	err = validateCartItem(&data)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "AddCartItem", err)
+		return
+	}
+	err = c.cartRepo.AddCartItem(request.Context(), payload.ID, data.ProductID, data.Quantity)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "AddCartItem", err)
+		return
+	}
+	c.writeCart(writer, request, "AddCartItem", payload.ID)
+}
+
+// EditCartItem sets the quantity of a product already in the cart.
+func (c *Cart) EditCartItem(writer http.ResponseWriter, request *http.Request) {
+	payload, ok := auth.FromContext(request.Context())
+	if !ok {
+		writeError(c.renderer, c.lg, writer, request, "EditCartItem", problem.ErrUnauthorized)
+		return
+	}
+	var data CartItemDTO
+	err := json.NewDecoder(request.Body).Decode(&data)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "EditCartItem", badRequest(err))
+		return
+	}
+	data.ProductID = chi.URLParam(request, "productID")
+	err = validateCartItem(&data)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "EditCartItem", err)
+		return
+	}
+	err = c.cartRepo.SetCartItemQuantity(request.Context(), payload.ID, data.ProductID, data.Quantity)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "EditCartItem", err)
+		return
+	}
+	c.writeCart(writer, request, "EditCartItem", payload.ID)
+}
+
+func (c *Cart) RemoveCartItem(writer http.ResponseWriter, request *http.Request) {
+	payload, ok := auth.FromContext(request.Context())
+	if !ok {
+		writeError(c.renderer, c.lg, writer, request, "RemoveCartItem", problem.ErrUnauthorized)
+		return
+	}
+	productID := chi.URLParam(request, "productID")
+	err := validation.Validate(validation.Field("product_id", productID, validation.Required, validation.UUID))
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "RemoveCartItem", err)
+		return
+	}
+	err = c.cartRepo.RemoveCartItem(request.Context(), payload.ID, productID)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "RemoveCartItem", err)
+		return
+	}
+	c.writeCart(writer, request, "RemoveCartItem", payload.ID)
+}
+
+func (c *Cart) ClearCart(writer http.ResponseWriter, request *http.Request) {
+	payload, ok := auth.FromContext(request.Context())
+	if !ok {
+		writeError(c.renderer, c.lg, writer, request, "ClearCart", problem.ErrUnauthorized)
+		return
+	}
+	err := c.cartRepo.ClearCart(request.Context(), payload.ID)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "ClearCart", err)
+		return
+	}
+	c.refresh(request.Context(), "ClearCart", payload.ID)
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// writeCart builds the user's cart from the database, caches it and writes
+// it out.
+func (c *Cart) writeCart(writer http.ResponseWriter, request *http.Request, op string, userID int) {
+	body, err := c.build(request.Context(), userID)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, op, err)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	_, err = writer.Write(body)
+	if err != nil {
+		logging.FromContext(request.Context(), c.lg).Error(op, zap.Error(err))
+	}
+	err = c.store.ToCache(request.Context(), cartKey(userID), body)
+	if err != nil {
+		logging.FromContext(request.Context(), c.lg).Error(op, zap.Error(err))
+	}
+}
+
+// refresh replaces the cached view after a change that doesn't answer with
+// the cart.
+func (c *Cart) refresh(ctx context.Context, op string, userID int) {
+	body, err := c.build(ctx, userID)
+	if err == nil {
+		err = c.store.ToCache(ctx, cartKey(userID), body)
+	}
+	if err != nil {
+		logging.FromContext(ctx, c.lg).Error(op, zap.Error(err))
+	}
+}
+
+func (c *Cart) build(ctx context.Context, userID int) ([]byte, error) {
+	view, err := c.view(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(view)
+	if err != nil {
+		return nil, err
+	}
+	return append(body, '\n'), nil
+}
+
+// view prices the user's cart straight from the database.
+func (c *Cart) view(ctx context.Context, userID int) (views.CartDTO, error) {
+	items, err := c.cartRepo.GetCart(ctx, userID)
+	if err != nil {
+		return views.CartDTO{}, err
+	}
+	productIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	promotions, err := c.promotionRepo.ActivePromotions(ctx, productIDs, time.Now())
+	if err != nil {
+		return views.CartDTO{}, err
+	}
+	return cartView(items, promotions), nil
+}
+
+func cartKey(userID int) string {
+	return fmt.Sprintf("cart:%d", userID)
+}
+
+// cartView prices the cart at the current sale prices after promotions and
+// warns about every item that can't be bought as it is or whose price has
+// moved since the user last touched it.
+func cartView(items []model.CartItem, promotions map[string][]model.Promotion) views.CartDTO {
+	view := views.CartDTO{
+		Items:    make([]*views.CartItemDTO, 0, len(items)),
+		Warnings: make([]*views.CartWarningDTO, 0),
+	}
+	for _, item := range items {
+		dto := &views.CartItemDTO{
+			ProductID: item.ProductID,
+			SKU:       item.SKU,
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			UnitPrice: item.AddedPrice,
+			Available: item.Available && item.Price != nil,
+		}
+		view.Items = append(view.Items, dto)
+		switch {
+		case !item.Available:
+			view.Warnings = append(view.Warnings, &views.CartWarningDTO{
+				ProductID: item.ProductID,
+				Code:      CartUnavailable,
+				Message:   fmt.Sprintf("%s is no longer available", item.Name),
+			})
+			continue
+		case item.Price == nil:
+			view.Warnings = append(view.Warnings, &views.CartWarningDTO{
+				ProductID: item.ProductID,
+				Code:      CartNoPrice,
+				Message:   fmt.Sprintf("%s has no price at the moment", item.Name),
+			})
+			continue
+		case *item.Price != item.AddedPrice:
+			view.Warnings = append(view.Warnings, &views.CartWarningDTO{
+				ProductID: item.ProductID,
+				Code:      CartPriceChanged,
+				Message:   fmt.Sprintf("the price of %s has changed from %d to %d", item.Name, item.AddedPrice, *item.Price),
+				OldPrice:  item.AddedPrice,
+				NewPrice:  *item.Price,
+			})
+		}
+		dto.UnitPrice = *item.Price
+		quote := pricing.Promote(*item.Price, item.Quantity, promotions[item.ProductID])
+		dto.LineTotal = quote.Total
+		for _, applied := range quote.Applied {
+			dto.Promotions = append(dto.Promotions, &views.AppliedPromotionDTO{
+				ID:   applied.ID,
+				Name: applied.Name,
+				Type: applied.Type,
+			})
+		}
+		view.Count += item.Quantity
+		view.Subtotal += *item.Price * item.Quantity
+		view.Total += quote.Total
+	}
+	view.Discount = view.Subtotal - view.Total
+	return view
+}
+
+func validateCartItem(d *CartItemDTO) error {
+	return validation.Validate(
+		validation.Field("product_id", d.ProductID, validation.Required, validation.UUID),
+		validation.Field("quantity", d.Quantity, validation.Required, validation.Min(1), validation.Max(maxCartQuantity)),
+	)
+}
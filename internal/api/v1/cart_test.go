@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"market4/internal/model"
+	"market4/internal/views"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_cartView(t *testing.T) {
+	price := func(v int) *int { return &v }
+	items := []model.CartItem{
+		{ProductID: "a", Name: "пушка", Quantity: 2, AddedPrice: 1000, Price: price(1000), Available: true},
+		{ProductID: "b", Name: "мяч", Quantity: 1, AddedPrice: 500, Price: price(450), Available: true},
+		{ProductID: "c", Name: "кубик", Quantity: 1, AddedPrice: 300, Available: true},
+		{ProductID: "d", Name: "кукла", Quantity: 5, AddedPrice: 700, Price: price(700)},
+	}
+	promotions := map[string][]model.Promotion{
+		"a": {{ID: 1, Name: "минус 10%", Type: model.PromotionPercentage, Value: 10}},
+	}
+
+	view := cartView(items, promotions)
+	assert.Equal(t, 3, view.Count)
+	assert.Equal(t, 2450, view.Subtotal)
+	assert.Equal(t, 200, view.Discount)
+	assert.Equal(t, 2250, view.Total)
+	assert.Equal(t, []*views.AppliedPromotionDTO{{ID: 1, Name: "минус 10%", Type: model.PromotionPercentage}}, view.Items[0].Promotions)
+	assert.Equal(t, 450, view.Items[1].UnitPrice)
+	assert.False(t, view.Items[2].Available, "an item without a price can't be bought")
+	assert.False(t, view.Items[3].Available)
+
+	codes := make([]string, 0, len(view.Warnings))
+	for _, w := range view.Warnings {
+		codes = append(codes, w.ProductID+":"+w.Code)
+	}
+	assert.Equal(t, []string{"b:price_changed", "c:no_price", "d:unavailable"}, codes)
+	assert.Equal(t, 500, view.Warnings[0].OldPrice)
+	assert.Equal(t, 450, view.Warnings[0].NewPrice)
+
+	empty := cartView(nil, nil)
+	assert.NotNil(t, empty.Items)
+	assert.NotNil(t, empty.Warnings)
+}
+
+func Test_validateCartItem(t *testing.T) {
+	assert.Equal(t, []string{"product_id", "quantity"}, fieldNames(validateCartItem(&CartItemDTO{ProductID: "пушка", Quantity: -1})))
+	assert.Equal(t, []string{"quantity"}, fieldNames(validateCartItem(&CartItemDTO{ProductID: "2800d950-5c62-49e2-a705-c74ba77f57d0"})))
+}
@@ -6,7 +6,9 @@ import (
 	"market4/internal/repository"
 	"market4/internal/views"
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/unrolled/render"
 	"go.uber.org/zap"
 )
@@ -23,32 +25,20 @@ func NewCategory(categoryRepo repository.Category, lg *zap.Logger, renderer *ren
 func (c *Category) ListAllCategories(writer http.ResponseWriter, request *http.Request) {
 	categories, err := c.categoryRepo.ListAllCategories(request.Context())
 	if err != nil {
-		c.lg.Error("ListAllCategories", zap.Error(err))
-		err = c.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			c.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(c.renderer, c.lg, writer, request, "ListAllCategories", err)
 		return
 	}
 
 	categoriesList, err := views.MakeCategoriesList(categories)
 	if err != nil {
-		c.lg.Error("ListAllCategories", zap.Error(err))
-		err = c.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			c.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(c.renderer, c.lg, writer, request, "ListAllCategories", err)
 		return
 	}
 
 	writer.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(writer).Encode(categoriesList)
 	if err != nil {
-		c.lg.Error("ListAllCategories", zap.Error(err))
-		err = c.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			c.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(c.renderer, c.lg, writer, request, "ListAllCategories", err)
 		return
 	}
 }
@@ -57,30 +47,18 @@ func (c *Category) AddCategory(writer http.ResponseWriter, request *http.Request
 	var data *model.Category
 	err := json.NewDecoder(request.Body).Decode(&data)
 	if err != nil {
-		c.lg.Error("AddCategory", zap.Error(err))
-		err = c.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			c.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(c.renderer, c.lg, writer, request, "AddCategory", badRequest(err))
 		return
 	}
-	err = checkMandatoryFields(data.Name)
+	err = validateCategory(data, false)
 	if err != nil {
-		c.lg.Error("field is empty")
-		err = c.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			c.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(c.renderer, c.lg, writer, request, "AddCategory", err)
 		return
 	}
 
 	id, err := c.categoryRepo.AddCategory(request.Context(), data)
 	if err != nil {
-		c.lg.Error("AddCategory", zap.Error(err))
-		err = c.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			c.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(c.renderer, c.lg, writer, request, "AddCategory", err)
 		return
 	}
 
@@ -92,11 +70,7 @@ func (c *Category) AddCategory(writer http.ResponseWriter, request *http.Request
 	writer.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(writer).Encode(reply)
 	if err != nil {
-		c.lg.Error("AddCategory", zap.Error(err))
-		err = c.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			c.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(c.renderer, c.lg, writer, request, "AddCategory", err)
 		return
 	}
 }
@@ -104,38 +78,79 @@ func (c *Category) EditCategory(writer http.ResponseWriter, request *http.Reques
 	var data *model.Category
 	err := json.NewDecoder(request.Body).Decode(&data)
 	if err != nil {
-		c.lg.Error("editCategory", zap.Error(err))
-		err = c.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			c.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(c.renderer, c.lg, writer, request, "editCategory", badRequest(err))
 		return
 	}
-	if data.ID == 0 {
-		c.lg.Error("wrong ID")
-		err = c.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			c.lg.Error("Auth", zap.Error(err))
-		}
+	err = validateCategory(data, true)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "EditCategory", err)
 		return
 	}
-	err = checkMandatoryFields(data.Name)
+
+	err = c.categoryRepo.EditCategory(request.Context(), data)
 	if err != nil {
-		c.lg.Error("field is empty")
-		err = c.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			c.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(c.renderer, c.lg, writer, request, "editCategory", err)
 		return
 	}
+}
 
-	err = c.categoryRepo.EditCategory(request.Context(), data)
+func (c *Category) GetCategoryByID(writer http.ResponseWriter, request *http.Request) {
+	categoryID, err := strconv.Atoi(chi.URLParam(request, "categoryID"))
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "GetCategoryByID", badRequest(err))
+		return
+	}
+	category, err := c.categoryRepo.GetCategoryByID(request.Context(), categoryID)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "GetCategoryByID", err)
+		return
+	}
+	c.writeCategory(writer, "GetCategoryByID", category)
+}
+
+func (c *Category) GetCategoryByURIName(writer http.ResponseWriter, request *http.Request) {
+	category, err := c.categoryRepo.GetCategoryByURIName(request.Context(), chi.URLParam(request, "uriName"))
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "GetCategoryByURIName", err)
+		return
+	}
+	c.writeCategory(writer, "GetCategoryByURIName", category)
+}
+
+func (c *Category) writeCategory(writer http.ResponseWriter, op string, category model.Category) {
+	writer.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(writer).Encode(category)
+	if err != nil {
+		c.lg.Error(op, zap.Error(err))
+	}
+}
+
+// DeleteCategory archives the category; it disappears from listings until
+// restored or purged.
+func (c *Category) DeleteCategory(writer http.ResponseWriter, request *http.Request) {
+	categoryID, err := strconv.Atoi(chi.URLParam(request, "categoryID"))
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "DeleteCategory", badRequest(err))
+		return
+	}
+	err = c.categoryRepo.DeleteCategory(request.Context(), categoryID)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "DeleteCategory", err)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (c *Category) RestoreCategory(writer http.ResponseWriter, request *http.Request) {
+	categoryID, err := strconv.Atoi(chi.URLParam(request, "categoryID"))
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "RestoreCategory", badRequest(err))
+		return
+	}
+	err = c.categoryRepo.RestoreCategory(request.Context(), categoryID)
 	if err != nil {
-		c.lg.Error("editCategory", zap.Error(err))
-		err = c.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			c.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(c.renderer, c.lg, writer, request, "RestoreCategory", err)
 		return
 	}
+	writer.WriteHeader(http.StatusNoContent)
 }
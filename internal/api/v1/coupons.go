@@ -0,0 +1,317 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"market4/internal/api/auth"
+	"market4/internal/api/problem"
+	"market4/internal/api/validation"
+	"market4/internal/logging"
+	"market4/internal/model"
+	"market4/internal/pricing"
+	"market4/internal/repository"
+	"market4/internal/views"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+)
+
+// maxBasketItems bounds a basket checked in one request; every item costs
+// a few queries.
+const maxBasketItems = 100
+
+var couponTypes = []string{model.CouponPercentage, model.CouponFixed}
+
+type Coupon struct {
+	couponRepo  repository.Coupon
+	productRepo repository.Product
+	priceRepo   repository.Price
+	lg          *zap.Logger
+	renderer    *render.Render
+}
+
+func NewCoupon(couponRepo repository.Coupon, productRepo repository.Product, priceRepo repository.Price, lg *zap.Logger, renderer *render.Render) *Coupon {
+	return &Coupon{couponRepo: couponRepo, productRepo: productRepo, priceRepo: priceRepo, lg: lg, renderer: renderer}
+}
+
+type CouponCheckDTO struct {
+	Code  string            `json:"code"`
+	Items []CouponCheckItem `json:"items"`
+}
+
+type CouponCheckItem struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+func (c *Coupon) ListCoupons(writer http.ResponseWriter, request *http.Request) {
+	coupons, err := c.couponRepo.ListCoupons(request.Context())
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "ListCoupons", err)
+		return
+	}
+	list := views.CouponsListDTO{Total: len(coupons), Items: make([]*model.Coupon, 0, len(coupons))}
+	for i := range coupons {
+		list.Items = append(list.Items, &coupons[i])
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(list)
+	if err != nil {
+		logging.FromContext(request.Context(), c.lg).Error("ListCoupons", zap.Error(err))
+	}
+}
+
+func (c *Coupon) GetCouponByID(writer http.ResponseWriter, request *http.Request) {
+	couponID, err := strconv.Atoi(chi.URLParam(request, "couponID"))
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "GetCouponByID", badRequest(err))
+		return
+	}
+	coupon, err := c.couponRepo.GetCouponByID(request.Context(), couponID)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "GetCouponByID", err)
+		return
+	}
+	c.writeCoupon(writer, "GetCouponByID", coupon)
+}
+
+func (c *Coupon) AddCoupon(writer http.ResponseWriter, request *http.Request) {
+	var data model.Coupon
+	err := json.NewDecoder(request.Body).Decode(&data)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "AddCoupon", badRequest(err))
+		return
+	}
+	err = validateCoupon(&data, false)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "AddCoupon", err)
+		return
+	}
+	coupon, err := c.couponRepo.AddCoupon(request.Context(), data)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "AddCoupon", err)
+		return
+	}
+	c.writeCoupon(writer, "AddCoupon", coupon)
+}
+
+// EditCoupon replaces the whole coupon named by id.
+func (c *Coupon) EditCoupon(writer http.ResponseWriter, request *http.Request) {
+	var data model.Coupon
+	err := json.NewDecoder(request.Body).Decode(&data)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "EditCoupon", badRequest(err))
+		return
+	}
+	err = validateCoupon(&data, true)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "EditCoupon", err)
+		return
+	}
+	coupon, err := c.couponRepo.EditCoupon(request.Context(), data)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "EditCoupon", err)
+		return
+	}
+	c.writeCoupon(writer, "EditCoupon", coupon)
+}
+
+func (c *Coupon) DeleteCoupon(writer http.ResponseWriter, request *http.Request) {
+	couponID, err := strconv.Atoi(chi.URLParam(request, "couponID"))
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "DeleteCoupon", badRequest(err))
+		return
+	}
+	err = c.couponRepo.DeleteCoupon(request.Context(), couponID)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "DeleteCoupon", err)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// ValidateCoupon evaluates a code against the caller's basket at current
+// sale prices. A coupon that can't be redeemed is still a 200 answer with
+// valid set to false and the reason; only a malformed basket is an error.
+func (c *Coupon) ValidateCoupon(writer http.ResponseWriter, request *http.Request) {
+	var data CouponCheckDTO
+	err := json.NewDecoder(request.Body).Decode(&data)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "ValidateCoupon", badRequest(err))
+		return
+	}
+	err = validateCouponCheck(&data)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "ValidateCoupon", err)
+		return
+	}
+	payload, ok := auth.FromContext(request.Context())
+	if !ok {
+		writeError(c.renderer, c.lg, writer, request, "ValidateCoupon", problem.ErrUnauthorized)
+		return
+	}
+
+	lines, err := c.basket(request, data.Items)
+	if err != nil {
+		writeError(c.renderer, c.lg, writer, request, "ValidateCoupon", err)
+		return
+	}
+
+	var quote pricing.CouponQuote
+	coupon, err := c.couponRepo.GetCouponByCode(request.Context(), data.Code)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		quote = pricing.EvaluateCoupon(model.Coupon{}, lines, 0, 0, time.Now())
+		quote.Reason = "unknown coupon code"
+	case err != nil:
+		writeError(c.renderer, c.lg, writer, request, "ValidateCoupon", err)
+		return
+	default:
+		used, usedByUser, err := c.couponRepo.CouponUsage(request.Context(), coupon.ID, payload.ID)
+		if err != nil {
+			writeError(c.renderer, c.lg, writer, request, "ValidateCoupon", err)
+			return
+		}
+		quote = pricing.EvaluateCoupon(coupon, lines, used, usedByUser, time.Now())
+	}
+
+	result := views.CouponCheckDTO{
+		Code:     data.Code,
+		Valid:    quote.Reason == "",
+		Reason:   quote.Reason,
+		Subtotal: quote.Subtotal,
+		Eligible: quote.Eligible,
+		Discount: quote.Discount,
+		Total:    quote.Total,
+		Items:    make([]*views.CouponCheckItemDTO, 0, len(quote.Lines)),
+	}
+	for _, line := range quote.Lines {
+		result.Items = append(result.Items, &views.CouponCheckItemDTO{
+			ProductID: line.ProductID,
+			Quantity:  line.Quantity,
+			UnitPrice: line.Unit,
+			Eligible:  line.Eligible,
+		})
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(result)
+	if err != nil {
+		logging.FromContext(request.Context(), c.lg).Error("ValidateCoupon", zap.Error(err))
+	}
+}
+
+// basket prices the items at their current sale price. Products that are
+// gone, inactive or unpriced are reported as field errors all at once.
+func (c *Coupon) basket(request *http.Request, items []CouponCheckItem) ([]pricing.Line, error) {
+	ctx := request.Context()
+	lines := make([]pricing.Line, 0, len(items))
+	var invalid []repository.FieldError
+	productIDs := make([]string, 0, len(items))
+	for i, item := range items {
+		field := fmt.Sprintf("items[%d].product_id", i)
+		product, err := c.productRepo.GetProductByID(ctx, item.ProductID)
+		if errors.Is(err, repository.ErrNotFound) || err == nil && !product.IsActive {
+			invalid = append(invalid, repository.FieldError{Field: field, Reason: "product is not available"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		price, err := c.priceRepo.SearchPriceByProductID(ctx, item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if price.ID == 0 {
+			invalid = append(invalid, repository.FieldError{Field: field, Reason: "product has no price"})
+			continue
+		}
+		lines = append(lines, pricing.Line{ProductID: item.ProductID, Unit: price.SalePrice, Quantity: item.Quantity})
+		productIDs = append(productIDs, item.ProductID)
+	}
+	if len(invalid) > 0 {
+		return nil, repository.NewValidationError(invalid...)
+	}
+	categories, err := c.productRepo.ProductCategories(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range lines {
+		lines[i].CategoryIDs = categories[lines[i].ProductID]
+	}
+	return lines, nil
+}
+
+func (c *Coupon) writeCoupon(writer http.ResponseWriter, op string, coupon model.Coupon) {
+	writer.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(writer).Encode(coupon)
+	if err != nil {
+		c.lg.Error(op, zap.Error(err))
+	}
+}
+
+// normalizeCode makes codes case-insensitive; they are stored upper case.
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func validateCoupon(d *model.Coupon, edit bool) error {
+	d.Code = normalizeCode(d.Code)
+	fields := []validation.FieldRules{
+		validation.Field("code", d.Code, validation.Required, validation.MaxLength(64), validation.Word),
+		validation.Field("type", d.Type, validation.Required, validation.OneOf(couponTypes...)),
+		validation.Field("min_order", d.MinOrder, validation.Min(0)),
+		validation.Field("usage_limit", d.UsageLimit, validation.Min(0)),
+		validation.Field("per_user_limit", d.PerUserLimit, validation.Min(0)),
+		validation.Field("starts_at", d.StartsAt, validation.Required),
+		validation.Field("ends_at", d.EndsAt, validation.Required),
+	}
+	switch d.Type {
+	case model.CouponPercentage:
+		fields = append(fields, validation.Field("value", d.Value, validation.Required, validation.Min(1), validation.Max(100)))
+	case model.CouponFixed:
+		fields = append(fields, validation.Field("value", d.Value, validation.Required, validation.Min(1)))
+	}
+	if edit {
+		fields = append(fields, validation.Field("id", d.ID, validation.Required, validation.Min(1)))
+	}
+	if d.UsageLimit > 0 {
+		fields = append(fields, validation.Field("per_user_limit", d.PerUserLimit,
+			validation.NotAbove("usage_limit", d.UsageLimit)))
+	}
+	for i, id := range d.CategoryIDs {
+		fields = append(fields, validation.Field(fmt.Sprintf("category_ids[%d]", i), id, validation.Required, validation.Min(1)))
+	}
+	err := validation.Validate(fields...)
+	if err != nil {
+		return err
+	}
+	if !d.EndsAt.After(d.StartsAt) {
+		return repository.NewValidationError(repository.FieldError{Field: "ends_at", Reason: "must be after starts_at"})
+	}
+	return nil
+}
+
+// validateCouponCheck also defaults missing quantities to one.
+func validateCouponCheck(d *CouponCheckDTO) error {
+	d.Code = normalizeCode(d.Code)
+	fields := []validation.FieldRules{
+		validation.Field("code", d.Code, validation.Required),
+		validation.Field("items", len(d.Items), validation.Required, validation.Max(maxBasketItems)),
+	}
+	for i := range d.Items {
+		item := &d.Items[i]
+		if item.Quantity == 0 {
+			item.Quantity = 1
+		}
+		fields = append(fields, validation.Prefix(fmt.Sprintf("items[%d]", i),
+			validation.Field("product_id", item.ProductID, validation.Required, validation.UUID),
+			validation.Field("quantity", item.Quantity, validation.Min(1), validation.Max(1000)))...)
+	}
+	return validation.Validate(fields...)
+}
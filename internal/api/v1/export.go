@@ -0,0 +1,116 @@
+package v1
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"market4/internal/api/httpserver/md"
+	"market4/internal/api/validation"
+	"market4/internal/export"
+	"market4/internal/logging"
+	"market4/internal/model"
+	"market4/internal/repository"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ExportProducts streams the catalog, with shop, category and live price,
+// as csv (the default), jsonl or xlsx. Rows are encoded while the
+// repository reads them, so the export never holds the catalog in memory.
+// The export gets exportTimeout instead of the server's WriteTimeout, and
+// its transaction ends with it even if the client reads slowly.
+func (p *Product) ExportProducts(writer http.ResponseWriter, request *http.Request) {
+	format := request.URL.Query().Get("format")
+	if format == "" {
+		format = export.CSV
+	}
+	err := validation.Validate(validation.Field("format", format, validation.OneOf(export.Formats...)))
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "ExportProducts", err)
+		return
+	}
+	filter, err := productFilter(request)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "ExportProducts", err)
+		return
+	}
+
+	deadline := time.Now().Add(p.exportTimeout)
+	if err = md.SetWriteDeadline(request, deadline); err != nil {
+		logging.FromContext(request.Context(), p.lg).Warn("ExportProducts", zap.Error(err))
+	}
+	ctx, cancel := context.WithDeadline(request.Context(), deadline)
+	defer cancel()
+
+	tracked := &trackingWriter{ResponseWriter: writer}
+	buffered := bufio.NewWriter(tracked)
+	encoder, err := export.NewEncoder(format, buffered)
+	if err == nil {
+		writer.Header().Set("Content-Type", export.ContentType(format))
+		writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="products.%s"`, format))
+		err = p.productRepo.ExportProducts(ctx, filter, encoder.Encode)
+	}
+	if err == nil {
+		err = encoder.Close()
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err == nil {
+		return
+	}
+	if !tracked.written {
+		writer.Header().Del("Content-Disposition")
+		writeError(p.renderer, p.lg, writer, request, "ExportProducts", err)
+		return
+	}
+	// Part of the file is already on the wire; aborting the connection is
+	// the only way left to tell the client it is incomplete.
+	logging.FromContext(request.Context(), p.lg).Error("ExportProducts", zap.Error(err))
+	panic(http.ErrAbortHandler)
+}
+
+// productFilter reads the catalog filters shared by the product list and
+// the export.
+func productFilter(request *http.Request) (model.ProductFilter, error) {
+	query := request.URL.Query()
+	filter := model.ProductFilter{Name: query.Get("name")}
+	var fieldErrors []repository.FieldError
+	integer := func(name string) int {
+		value := query.Get(name)
+		if value == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			fieldErrors = append(fieldErrors, repository.FieldError{Field: name, Reason: "must be a positive integer"})
+		}
+		return n
+	}
+	filter.ShopID = integer("shop_id")
+	filter.CategoryID = integer("category_id")
+	if value := query.Get("is_active"); value != "" {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			fieldErrors = append(fieldErrors, repository.FieldError{Field: "is_active", Reason: "must be true or false"})
+		}
+		filter.IsActive = &active
+	}
+	if len(fieldErrors) > 0 {
+		return model.ProductFilter{}, repository.NewValidationError(fieldErrors...)
+	}
+	return filter, nil
+}
+
+type trackingWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *trackingWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
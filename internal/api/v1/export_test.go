@@ -0,0 +1,31 @@
+package v1
+
+import (
+	"errors"
+	"market4/internal/repository"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_productFilter(t *testing.T) {
+	request := httptest.NewRequest("GET", "/products?shop_id=1&category_id=2&is_active=false&name=%D0%BF%D1%83%D1%88", nil)
+	filter, err := productFilter(request)
+	require.NoError(t, err)
+	assert.Equal(t, 1, filter.ShopID)
+	assert.Equal(t, 2, filter.CategoryID)
+	require.NotNil(t, filter.IsActive)
+	assert.False(t, *filter.IsActive)
+	assert.Equal(t, "пуш", filter.Name)
+
+	filter, err = productFilter(httptest.NewRequest("GET", "/products", nil))
+	require.NoError(t, err)
+	assert.Nil(t, filter.IsActive, "no is_active means both")
+
+	_, err = productFilter(httptest.NewRequest("GET", "/products?shop_id=0&is_active=yes", nil))
+	var rerr *repository.Error
+	require.True(t, errors.As(err, &rerr), err)
+	assert.Len(t, rerr.Fields, 2)
+}
@@ -0,0 +1,294 @@
+package v1
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"market4/internal/api/problem"
+	"market4/internal/export"
+	"market4/internal/logging"
+	"market4/internal/metrics"
+	"market4/internal/model"
+	"market4/internal/repository"
+	"market4/internal/views"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const maxImportSize = 10 << 20
+
+const (
+	importCreated = "created"
+	importUpdated = "updated"
+	importFailed  = "failed"
+	importSkipped = "skipped"
+)
+
+var importColumns = []string{"sku", "name", "type", "description", "shop_id", "category_id",
+	"sale_price", "factory_price", "discount_price", "is_active"}
+
+var importRequired = []string{"sku", "name", "type", "description", "shop_id", "category_id"}
+
+type importRow struct {
+	row     int
+	product model.ProductImport
+	err     error
+}
+
+// ImportProducts upserts products by SKU from a CSV body whose header names
+// the columns. By default the whole file is one transaction; batch_size=N
+// commits every N rows on their own, so a bad row only holds back its batch.
+func (p *Product) ImportProducts(writer http.ResponseWriter, request *http.Request) {
+	batchSize := 0
+	if value := request.URL.Query().Get("batch_size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 0 {
+			err = repository.NewValidationError(repository.FieldError{Field: "batch_size", Reason: "must be a non-negative integer"})
+			writeError(p.renderer, p.lg, writer, request, "ImportProducts", err)
+			return
+		}
+		batchSize = size
+	}
+
+	report, err := ImportCSV(request.Context(), p.productRepo, logging.FromContext(request.Context(), p.lg),
+		http.MaxBytesReader(writer, request.Body, maxImportSize), batchSize)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "ImportProducts", err)
+		return
+	}
+	p.counters.ProductsCreated(metrics.SourceImport, report.Created)
+
+	writer.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(report)
+	if err != nil {
+		logging.FromContext(request.Context(), p.lg).Error("ImportProducts", zap.Error(err))
+	}
+}
+
+// ImportCSV upserts the products of a CSV import file through repo and
+// reports on every row. batchSize rows are written per transaction, or the
+// whole file when it is 0. Only a malformed file is an error; failed rows
+// are in the report. `market4 admin import` shares it with ImportProducts.
+func ImportCSV(ctx context.Context, repo repository.Product, lg *zap.Logger, body io.Reader, batchSize int) (views.ImportReportDTO, error) {
+	rows, err := readImport(body)
+	if err != nil {
+		// What is wrong with the file is the client's to see.
+		return views.ImportReportDTO{}, problem.BadRequest(err.Error(), err)
+	}
+	if batchSize == 0 {
+		batchSize = len(rows)
+	}
+
+	report := views.ImportReportDTO{Total: len(rows), Rows: make([]*views.ImportRowDTO, 0, len(rows))}
+	for start := 0; start < len(rows); start += batchSize {
+		end := start + batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		for _, item := range importBatch(ctx, repo, lg, rows[start:end]) {
+			switch item.Status {
+			case importCreated:
+				report.Created++
+			case importUpdated:
+				report.Updated++
+			case importFailed:
+				report.Failed++
+			case importSkipped:
+				report.Skipped++
+			}
+			report.Rows = append(report.Rows, item)
+		}
+	}
+	return report, nil
+}
+
+// importBatch writes one batch in one transaction. Batches with invalid rows
+// aren't sent to the database at all.
+func importBatch(ctx context.Context, repo repository.Product, lg *zap.Logger, rows []importRow) []*views.ImportRowDTO {
+	items := make([]*views.ImportRowDTO, len(rows))
+	products := make([]model.ProductImport, len(rows))
+	invalid := false
+	for i, row := range rows {
+		items[i] = &views.ImportRowDTO{Row: row.row, SKU: row.product.Product.SKU}
+		products[i] = row.product
+		if row.err != nil {
+			invalid = true
+		}
+	}
+	if invalid {
+		for i, row := range rows {
+			setImportStatus(items[i], "", false, row.err)
+		}
+		return items
+	}
+
+	results, err := repo.UpsertProducts(ctx, products)
+	if err != nil {
+		lg.Error("ImportProducts", zap.Error(err))
+	}
+	for i := range items {
+		if err != nil {
+			setImportStatus(items[i], "", false, err)
+			continue
+		}
+		setImportStatus(items[i], results[i].ProductID, results[i].Created, results[i].Err)
+	}
+	return items
+}
+
+// setImportStatus fills in the outcome of a row. A nil err on a row that
+// wasn't written means the row was fine but its batch was not.
+func setImportStatus(item *views.ImportRowDTO, productID string, created bool, err error) {
+	switch {
+	case errors.Is(err, repository.ErrRolledBack):
+		item.Status = importSkipped
+	case err != nil:
+		p := problem.FromError(err)
+		item.Status = importFailed
+		item.Detail = p.Detail
+		if item.Detail == "" {
+			item.Detail = p.Title
+		}
+		item.InvalidParams = p.InvalidParams
+	case productID == "":
+		item.Status = importSkipped
+	case created:
+		item.Status = importCreated
+		item.ProductID = productID
+	default:
+		item.Status = importUpdated
+		item.ProductID = productID
+	}
+}
+
+// readImport parses the CSV body. Errors in the file structure fail the
+// whole import; errors in a row are kept with the row.
+func readImport(body io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !contains(importColumns, name) {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		columns[name] = i
+	}
+	for _, name := range importRequired {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	rows := make([]importRow, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, parseImportRow(len(rows)+1, columns, record))
+	}
+}
+
+func parseImportRow(number int, columns map[string]int, record []string) importRow {
+	cell := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return export.Unescape(strings.TrimSpace(record[i]))
+		}
+		return ""
+	}
+	var parseErrors []repository.FieldError
+	integer := func(name string) int {
+		value := cell(name)
+		if value == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			parseErrors = append(parseErrors, repository.FieldError{Field: name, Reason: "must be an integer"})
+		}
+		return n
+	}
+	boolean := func(name string) bool {
+		value := cell(name)
+		if value == "" {
+			return true
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			parseErrors = append(parseErrors, repository.FieldError{Field: name, Reason: "must be true or false"})
+		}
+		return b
+	}
+
+	d := ProductDTO{
+		SKU:         cell("sku"),
+		Name:        cell("name"),
+		Type:        cell("type"),
+		Description: cell("description"),
+		IsActive:    boolean("is_active"),
+		Shop_ID:     integer("shop_id"),
+		Category_ID: integer("category_id"),
+	}
+	if cell("sale_price") != "" || cell("factory_price") != "" || cell("discount_price") != "" {
+		d.Price = &PriceDTO{
+			SalePrice:     integer("sale_price"),
+			FactoryPrice:  integer("factory_price"),
+			DiscountPrice: integer("discount_price"),
+			IsActive:      true,
+		}
+	}
+
+	row := importRow{row: number, product: model.ProductImport{
+		Product: model.Product{
+			SKU:         d.SKU,
+			Name:        d.Name,
+			Type:        d.Type,
+			Description: d.Description,
+			IsActive:    d.IsActive,
+		},
+		ShopID:     d.Shop_ID,
+		CategoryID: d.Category_ID,
+	}}
+	if d.Price != nil {
+		row.product.Price = &model.Price{
+			SalePrice:     d.Price.SalePrice,
+			FactoryPrice:  d.Price.FactoryPrice,
+			DiscountPrice: d.Price.DiscountPrice,
+			IsActive:      d.Price.IsActive,
+		}
+	}
+
+	if len(parseErrors) > 0 {
+		row.err = repository.NewValidationError(parseErrors...)
+		return row
+	}
+	row.err = validateProduct(&d, false)
+	return row
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
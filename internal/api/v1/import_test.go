@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readImport(t *testing.T) {
+	file := "\ufeffSKU, name,type,description,shop_id,category_id,is_active,sale_price\n" +
+		"3001,пушка,тепловая,\"пушка, детская\",1,2,false,2000\n" +
+		"3002,мяч,футбольный,'-мяч кожаный,1,2\n" +
+		"3003,кукла,тряпичная,кукла,один,2,да,\n"
+	rows, err := readImport(strings.NewReader(file))
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	assert.NoError(t, rows[0].err)
+	assert.Equal(t, 1, rows[0].row)
+	assert.Equal(t, "пушка, детская", rows[0].product.Product.Description)
+	assert.False(t, rows[0].product.Product.IsActive)
+	assert.Equal(t, 2, rows[0].product.CategoryID)
+	require.NotNil(t, rows[0].product.Price)
+	assert.Equal(t, 2000, rows[0].product.Price.SalePrice)
+
+	assert.NoError(t, rows[1].err, "missing trailing cells are empty")
+	assert.Equal(t, "-мяч кожаный", rows[1].product.Product.Description, "escaped cells of an export read back as is")
+	assert.True(t, rows[1].product.Product.IsActive, "products are active by default")
+	assert.Nil(t, rows[1].product.Price)
+
+	assert.ElementsMatch(t, []string{"shop_id", "is_active"}, fieldNames(rows[2].err))
+}
+
+func Test_readImportStructure(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{name: "empty file", file: "", want: "empty file"},
+		{name: "unknown column", file: "sku,colour\n", want: `unknown column "colour"`},
+		{name: "missing column", file: "sku,name,type,description,shop_id\n", want: `missing column "category_id"`},
+		{name: "broken quoting", file: "sku,name,type,description,shop_id,category_id\n\"3001,a,b,c,1,2\n", want: "quote"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readImport(strings.NewReader(tt.file))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"market4/internal/api/auth"
+	"market4/internal/api/problem"
+	"market4/internal/api/validation"
+	"market4/internal/logging"
+	"market4/internal/model"
+	"market4/internal/pricing"
+	"market4/internal/repository"
+	"market4/internal/views"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+)
+
+type Order struct {
+	orderRepo   repository.Order
+	couponRepo  repository.Coupon
+	productRepo repository.Product
+	usersRepo   repository.Users
+	cart        *Cart
+	lg          *zap.Logger
+	renderer    *render.Render
+}
+
+func NewOrder(orderRepo repository.Order,
+	couponRepo repository.Coupon,
+	productRepo repository.Product,
+	usersRepo repository.Users,
+	cart *Cart,
+	lg *zap.Logger,
+	renderer *render.Render) *Order {
+	return &Order{orderRepo: orderRepo,
+		couponRepo:  couponRepo,
+		productRepo: productRepo,
+		usersRepo:   usersRepo,
+		cart:        cart,
+		lg:          lg,
+		renderer:    renderer}
+}
+
+type CheckoutDTO struct {
+	CouponCode string `json:"coupon_code"`
+}
+
+type OrderStatusDTO struct {
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// staffOf tells whether the caller works the order as staff. Admins work
+// every order; STAFF only those reserved in the shops they are assigned to
+// with PUT /users/shops.
+func (o *Order) staffOf(ctx context.Context, payload *auth.Payload, order model.Order) (bool, error) {
+	if payload.HasRole(string(model.ADMIN)) {
+		return true, nil
+	}
+	if !payload.HasRole(string(model.STAFF)) {
+		return false, nil
+	}
+	shopIDs, err := o.usersRepo.GetStaffShops(ctx, payload.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, shopID := range shopIDs {
+		for _, reservedIn := range order.ShopIDs {
+			if shopID == reservedIn {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Checkout turns the caller's cart into an order at the prices shown in
+// it. A cart with warnings has to be reviewed first: the customer must see
+// a new price or a missing product before paying for it.
+func (o *Order) Checkout(writer http.ResponseWriter, request *http.Request) {
+	payload, ok := auth.FromContext(request.Context())
+	if !ok {
+		writeError(o.renderer, o.lg, writer, request, "Checkout", problem.ErrUnauthorized)
+		return
+	}
+	var data CheckoutDTO
+	err := json.NewDecoder(request.Body).Decode(&data)
+	if err != nil && err != io.EOF {
+		writeError(o.renderer, o.lg, writer, request, "Checkout", badRequest(err))
+		return
+	}
+
+	cart, err := o.cart.view(request.Context(), payload.ID)
+	if err != nil {
+		writeError(o.renderer, o.lg, writer, request, "Checkout", err)
+		return
+	}
+	if len(cart.Items) == 0 {
+		writeError(o.renderer, o.lg, writer, request, "Checkout",
+			repository.NewValidationError(repository.FieldError{Field: "cart", Reason: "is empty"}))
+		return
+	}
+	if len(cart.Warnings) > 0 {
+		writeError(o.renderer, o.lg, writer, request, "Checkout",
+			repository.NewConflictError("the cart has changed since it was last reviewed"))
+		return
+	}
+
+	order := model.Order{
+		UserID:   payload.ID,
+		Items:    make([]model.OrderItem, 0, len(cart.Items)),
+		Subtotal: cart.Subtotal,
+		Discount: cart.Discount,
+		Total:    cart.Total,
+	}
+	for _, item := range cart.Items {
+		order.Items = append(order.Items, model.OrderItem{
+			ProductID: item.ProductID,
+			SKU:       item.SKU,
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+			Total:     item.LineTotal,
+		})
+	}
+	if code := normalizeCode(data.CouponCode); code != "" {
+		err = o.redeem(request, &order, code)
+		if err != nil {
+			writeError(o.renderer, o.lg, writer, request, "Checkout", err)
+			return
+		}
+	}
+
+	order, err = o.orderRepo.CreateOrder(request.Context(), order)
+	if err != nil {
+		writeError(o.renderer, o.lg, writer, request, "Checkout", err)
+		return
+	}
+	o.cart.refresh(request.Context(), "Checkout", payload.ID)
+	logging.FromContext(request.Context(), o.lg).Info("Checkout", zap.Int("order", order.ID), zap.Int("user", payload.ID), zap.Int("total", order.Total))
+	o.writeOrder(writer, "Checkout", order)
+}
+
+// redeem applies the coupon to the order after promotions: the coupon sees
+// every item at its promoted line total.
+func (o *Order) redeem(request *http.Request, order *model.Order, code string) error {
+	ctx := request.Context()
+	invalid := func(reason string) error {
+		return repository.NewValidationError(repository.FieldError{Field: "coupon_code", Reason: reason})
+	}
+	coupon, err := o.couponRepo.GetCouponByCode(ctx, code)
+	if errors.Is(err, repository.ErrNotFound) {
+		return invalid("unknown coupon code")
+	}
+	if err != nil {
+		return err
+	}
+	used, usedByUser, err := o.couponRepo.CouponUsage(ctx, coupon.ID, order.UserID)
+	if err != nil {
+		return err
+	}
+	productIDs := make([]string, 0, len(order.Items))
+	for _, item := range order.Items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	categories, err := o.productRepo.ProductCategories(ctx, productIDs)
+	if err != nil {
+		return err
+	}
+	lines := make([]pricing.Line, 0, len(order.Items))
+	for _, item := range order.Items {
+		lines = append(lines, pricing.Line{
+			ProductID:   item.ProductID,
+			CategoryIDs: categories[item.ProductID],
+			Unit:        item.Total,
+			Quantity:    1,
+		})
+	}
+	quote := pricing.EvaluateCoupon(coupon, lines, used, usedByUser, time.Now())
+	if quote.Reason != "" {
+		return invalid(quote.Reason)
+	}
+	order.CouponID = coupon.ID
+	order.CouponCode = coupon.Code
+	order.CouponDiscount = quote.Discount
+	order.Total -= quote.Discount
+	return nil
+}
+
+// ListOrders lists the caller's orders. Admins see every customer's, STAFF
+// also those reserved in the shops they are assigned to; both may narrow
+// them down by user_id.
+func (o *Order) ListOrders(writer http.ResponseWriter, request *http.Request) {
+	payload, ok := auth.FromContext(request.Context())
+	if !ok {
+		writeError(o.renderer, o.lg, writer, request, "ListOrders", problem.ErrUnauthorized)
+		return
+	}
+	filter, err := orderFilter(request)
+	if err != nil {
+		writeError(o.renderer, o.lg, writer, request, "ListOrders", err)
+		return
+	}
+	switch {
+	case payload.HasRole(string(model.ADMIN)):
+	case payload.HasRole(string(model.STAFF)):
+		filter.StaffID = payload.ID
+	default:
+		if filter.UserID != 0 && filter.UserID != payload.ID {
+			writeError(o.renderer, o.lg, writer, request, "ListOrders", problem.ErrForbidden)
+			return
+		}
+		filter.UserID = payload.ID
+	}
+	orders, err := o.orderRepo.ListOrders(request.Context(), filter)
+	if err != nil {
+		writeError(o.renderer, o.lg, writer, request, "ListOrders", err)
+		return
+	}
+	list := views.OrdersListDTO{Total: len(orders), Items: make([]*model.Order, 0, len(orders))}
+	for i := range orders {
+		list.Items = append(list.Items, &orders[i])
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(list)
+	if err != nil {
+		logging.FromContext(request.Context(), o.lg).Error("ListOrders", zap.Error(err))
+	}
+}
+
+func orderFilter(request *http.Request) (model.OrderFilter, error) {
+	var filter model.OrderFilter
+	query := request.URL.Query()
+	var invalid []repository.FieldError
+	if v := query.Get("user_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			invalid = append(invalid, repository.FieldError{Field: "user_id", Reason: "must be a positive integer"})
+		}
+		filter.UserID = id
+	}
+	filter.Status = query.Get("status")
+	if len(invalid) > 0 {
+		return filter, repository.NewValidationError(invalid...)
+	}
+	return filter, validation.Validate(validation.Field("status", filter.Status, validation.OneOf(model.OrderStatuses...)))
+}
+
+// GetOrder returns an order with its history to its customer or to its
+// staff, see staffOf. Other orders are reported as not found.
+func (o *Order) GetOrder(writer http.ResponseWriter, request *http.Request) {
+	order, _, ok := o.visibleOrder(writer, request, "GetOrder")
+	if !ok {
+		return
+	}
+	o.writeOrder(writer, "GetOrder", order)
+}
+
+// SetOrderStatus moves an order along the state machine. Its staff may
+// make any allowed move but refunding; a customer may only cancel their own
+// unpaid order.
+func (o *Order) SetOrderStatus(writer http.ResponseWriter, request *http.Request) {
+	order, payload, ok := o.visibleOrder(writer, request, "SetOrderStatus")
+	if !ok {
+		return
+	}
+	var data OrderStatusDTO
+	err := json.NewDecoder(request.Body).Decode(&data)
+	if err != nil {
+		writeError(o.renderer, o.lg, writer, request, "SetOrderStatus", badRequest(err))
+		return
+	}
+	err = validation.Validate(
+		validation.Field("status", data.Status, validation.Required, validation.OneOf(model.OrderStatuses...)),
+		validation.Field("note", data.Note, validation.MaxLength(1000)),
+	)
+	if err != nil {
+		writeError(o.renderer, o.lg, writer, request, "SetOrderStatus", err)
+		return
+	}
+	if data.Status == model.OrderRefunded {
+		// Refunding gives the money back, which RefundOrder does.
+		writeError(o.renderer, o.lg, writer, request, "SetOrderStatus",
+			repository.NewValidationError(repository.FieldError{Field: "status", Reason: "is set by refunding the order"}))
+		return
+	}
+	staff, err := o.staffOf(request.Context(), payload, order)
+	if err != nil {
+		writeError(o.renderer, o.lg, writer, request, "SetOrderStatus", err)
+		return
+	}
+	if !staff && data.Status != model.OrderCancelled {
+		writeError(o.renderer, o.lg, writer, request, "SetOrderStatus", problem.ErrForbidden)
+		return
+	}
+	order, err = o.orderRepo.TransitionOrder(request.Context(), order.ID, model.OrderTransition{
+		To:      data.Status,
+		ActorID: payload.ID,
+		Note:    data.Note,
+	})
+	if err != nil {
+		writeError(o.renderer, o.lg, writer, request, "SetOrderStatus", err)
+		return
+	}
+	logging.FromContext(request.Context(), o.lg).Info("SetOrderStatus", zap.Int("order", order.ID), zap.String("status", order.Status), zap.Int("actor", payload.ID))
+	o.writeOrder(writer, "SetOrderStatus", order)
+}
+
+// visibleOrder loads the order named in the URL if the caller may see it
+// and writes the error otherwise.
+func (o *Order) visibleOrder(writer http.ResponseWriter, request *http.Request, op string) (model.Order, *auth.Payload, bool) {
+	payload, ok := auth.FromContext(request.Context())
+	if !ok {
+		writeError(o.renderer, o.lg, writer, request, op, problem.ErrUnauthorized)
+		return model.Order{}, nil, false
+	}
+	orderID, err := strconv.Atoi(chi.URLParam(request, "orderID"))
+	if err != nil {
+		writeError(o.renderer, o.lg, writer, request, op, badRequest(err))
+		return model.Order{}, nil, false
+	}
+	order, err := o.orderRepo.GetOrder(request.Context(), orderID)
+	if err == nil && order.UserID != payload.ID {
+		var staff bool
+		staff, err = o.staffOf(request.Context(), payload, order)
+		if err == nil && !staff {
+			err = repository.NewNotFoundError("order " + strconv.Itoa(orderID))
+		}
+	}
+	if err != nil {
+		writeError(o.renderer, o.lg, writer, request, op, err)
+		return model.Order{}, nil, false
+	}
+	return order, payload, true
+}
+
+func (o *Order) writeOrder(writer http.ResponseWriter, op string, order model.Order) {
+	writer.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(writer).Encode(order)
+	if err != nil {
+		o.lg.Error(op, zap.Error(err))
+	}
+}
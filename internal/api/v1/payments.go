@@ -0,0 +1,294 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"market4/internal/api/problem"
+	"market4/internal/api/validation"
+	"market4/internal/logging"
+	"market4/internal/model"
+	"market4/internal/payments"
+	"market4/internal/repository"
+	"market4/internal/views"
+	"net/http"
+	"strconv"
+
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+)
+
+// maxWebhookBody is the largest webhook body that is read.
+const maxWebhookBody = 1 << 20
+
+type Payment struct {
+	paymentRepo repository.Payment
+	provider    payments.Provider
+	order       *Order
+	lg          *zap.Logger
+	renderer    *render.Render
+}
+
+func NewPayment(paymentRepo repository.Payment,
+	provider payments.Provider,
+	order *Order,
+	lg *zap.Logger,
+	renderer *render.Render) *Payment {
+	return &Payment{paymentRepo: paymentRepo,
+		provider: provider,
+		order:    order,
+		lg:       lg,
+		renderer: renderer}
+}
+
+type PayDTO struct {
+	Token string `json:"token"`
+}
+
+type RefundDTO struct {
+	Note string `json:"note"`
+}
+
+// PayOrder pays the caller's new order. An authorized payment is captured
+// at once and the order becomes paid; a payment the provider confirms later
+// is answered with 202 and finished by the webhook.
+func (p *Payment) PayOrder(writer http.ResponseWriter, request *http.Request) {
+	order, payload, ok := p.order.visibleOrder(writer, request, "PayOrder")
+	if !ok {
+		return
+	}
+	if order.UserID != payload.ID {
+		writeError(p.renderer, p.lg, writer, request, "PayOrder", problem.ErrForbidden)
+		return
+	}
+	var data PayDTO
+	err := json.NewDecoder(request.Body).Decode(&data)
+	if err != nil && err != io.EOF {
+		writeError(p.renderer, p.lg, writer, request, "PayOrder", badRequest(err))
+		return
+	}
+	err = validation.Validate(validation.Field("token", data.Token, validation.MaxLength(200)))
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "PayOrder", err)
+		return
+	}
+	if order.Status != model.OrderCreated {
+		writeError(p.renderer, p.lg, writer, request, "PayOrder",
+			repository.NewConflictError(fmt.Sprintf("order %d is %s and can't be paid", order.ID, order.Status)))
+		return
+	}
+
+	ctx := request.Context()
+	result, err := p.provider.Authorize(ctx, payments.Request{
+		Reference: "order-" + strconv.Itoa(order.ID),
+		Amount:    order.Total,
+		Token:     data.Token,
+	})
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "PayOrder", providerError(err))
+		return
+	}
+	payment, err := p.paymentRepo.AddPayment(ctx, model.Payment{
+		ID:       result.PaymentID,
+		OrderID:  order.ID,
+		Provider: p.provider.Name(),
+		Status:   result.Status,
+		Amount:   order.Total,
+		Reason:   result.Reason,
+	})
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "PayOrder", err)
+		return
+	}
+	if payment.Status == payments.StatusAuthorized {
+		payment, err = p.capture(request, payment, payload.ID)
+		if err != nil {
+			writeError(p.renderer, p.lg, writer, request, "PayOrder", err)
+			return
+		}
+	}
+	logging.FromContext(request.Context(), p.lg).Info("PayOrder", zap.Int("order", order.ID), zap.String("payment", payment.ID), zap.String("status", payment.Status))
+	status := http.StatusOK
+	if payment.Status == payments.StatusPending {
+		status = http.StatusAccepted
+	}
+	p.writeJSON(writer, "PayOrder", status, payment)
+}
+
+// providerError answers 503 while no payment provider is configured.
+func providerError(err error) error {
+	if errors.Is(err, payments.ErrDisabled) {
+		return fmt.Errorf("%w: %v", problem.ErrUnavailable, err)
+	}
+	return err
+}
+
+// capture takes an authorized payment and marks its order paid. If the
+// order can't become paid any more, e.g. it was cancelled while the
+// payment was pending, the money is given back.
+func (p *Payment) capture(request *http.Request, payment model.Payment, actorID int) (model.Payment, error) {
+	ctx := request.Context()
+	_, err := p.provider.Capture(ctx, payment.ID, payment.Amount)
+	if err != nil {
+		return payment, err
+	}
+	captured, err := p.paymentRepo.SetPaymentStatus(ctx, payment.ID, payments.StatusAuthorized, payments.StatusCaptured, "",
+		&model.OrderTransition{To: model.OrderPaid, ActorID: actorID, Note: "payment " + payment.ID})
+	if err != nil {
+		if _, rerr := p.provider.Refund(ctx, payment.ID, payment.Amount); rerr != nil {
+			logging.FromContext(request.Context(), p.lg).Error("capture", zap.String("payment", payment.ID), zap.Error(rerr))
+		}
+		return payment, err
+	}
+	return captured, nil
+}
+
+// ListPayments lists the payments of an order to its customer or to its
+// staff.
+func (p *Payment) ListPayments(writer http.ResponseWriter, request *http.Request) {
+	order, _, ok := p.order.visibleOrder(writer, request, "ListPayments")
+	if !ok {
+		return
+	}
+	list, err := p.paymentRepo.ListPayments(request.Context(), order.ID)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "ListPayments", err)
+		return
+	}
+	dto := views.PaymentsListDTO{Total: len(list), Items: make([]*model.Payment, 0, len(list))}
+	for i := range list {
+		dto.Items = append(dto.Items, &list[i])
+	}
+	p.writeJSON(writer, "ListPayments", http.StatusOK, dto)
+}
+
+// RefundOrder gives the captured payment of an order back and marks the
+// order refunded. An order that was paid outside the provider is only
+// marked.
+func (p *Payment) RefundOrder(writer http.ResponseWriter, request *http.Request) {
+	order, payload, ok := p.order.visibleOrder(writer, request, "RefundOrder")
+	if !ok {
+		return
+	}
+	staff, err := p.order.staffOf(request.Context(), payload, order)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "RefundOrder", err)
+		return
+	}
+	if !staff {
+		writeError(p.renderer, p.lg, writer, request, "RefundOrder", problem.ErrForbidden)
+		return
+	}
+	var data RefundDTO
+	err = json.NewDecoder(request.Body).Decode(&data)
+	if err != nil && err != io.EOF {
+		writeError(p.renderer, p.lg, writer, request, "RefundOrder", badRequest(err))
+		return
+	}
+	err = validation.Validate(validation.Field("note", data.Note, validation.MaxLength(1000)))
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "RefundOrder", err)
+		return
+	}
+	if !model.CanTransition(order.Status, model.OrderRefunded) {
+		writeError(p.renderer, p.lg, writer, request, "RefundOrder",
+			repository.NewConflictError(fmt.Sprintf("order %d is %s and can't become %s", order.ID, order.Status, model.OrderRefunded)))
+		return
+	}
+
+	ctx := request.Context()
+	list, err := p.paymentRepo.ListPayments(ctx, order.ID)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "RefundOrder", err)
+		return
+	}
+	transition := model.OrderTransition{To: model.OrderRefunded, ActorID: payload.ID, Note: data.Note}
+	var captured *model.Payment
+	for i := range list {
+		if list[i].Status == payments.StatusCaptured {
+			captured = &list[i]
+		}
+	}
+	if captured == nil {
+		_, err = p.order.orderRepo.TransitionOrder(ctx, order.ID, transition)
+	} else {
+		_, err = p.provider.Refund(ctx, captured.ID, captured.Amount)
+		err = providerError(err)
+		if err == nil {
+			_, err = p.paymentRepo.SetPaymentStatus(ctx, captured.ID, payments.StatusCaptured, payments.StatusRefunded, "", &transition)
+		}
+	}
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "RefundOrder", err)
+		return
+	}
+	order, err = p.order.orderRepo.GetOrder(ctx, order.ID)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "RefundOrder", err)
+		return
+	}
+	logging.FromContext(request.Context(), p.lg).Info("RefundOrder", zap.Int("order", order.ID), zap.Int("actor", payload.ID))
+	p.order.writeOrder(writer, "RefundOrder", order)
+}
+
+// Webhook takes the provider's notifications about pending payments. The
+// body must carry the provider's signature. Notifications about payments
+// that were already settled are acknowledged and ignored, since providers
+// repeat them until they get an answer.
+func (p *Payment) Webhook(writer http.ResponseWriter, request *http.Request) {
+	body, err := ioutil.ReadAll(io.LimitReader(request.Body, maxWebhookBody))
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "Webhook", badRequest(err))
+		return
+	}
+	event, err := p.provider.VerifyWebhook(body, request.Header.Get(payments.SignatureHeader))
+	if errors.Is(err, payments.ErrInvalidSignature) {
+		writeError(p.renderer, p.lg, writer, request, "Webhook", fmt.Errorf("%w: %v", problem.ErrUnauthorized, err))
+		return
+	}
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "Webhook", badRequest(err))
+		return
+	}
+
+	ctx := request.Context()
+	payment, err := p.paymentRepo.GetPayment(ctx, event.PaymentID)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "Webhook", err)
+		return
+	}
+	if payment.Status != payments.StatusPending {
+		logging.FromContext(request.Context(), p.lg).Info("Webhook: already settled", zap.String("payment", payment.ID), zap.String("status", payment.Status))
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+	switch event.Status {
+	case payments.StatusAuthorized:
+		payment, err = p.paymentRepo.SetPaymentStatus(ctx, payment.ID, payments.StatusPending, payments.StatusAuthorized, "", nil)
+		if err == nil {
+			// The provider acts on nobody's behalf.
+			payment, err = p.capture(request, payment, 0)
+		}
+	case payments.StatusDeclined:
+		payment, err = p.paymentRepo.SetPaymentStatus(ctx, payment.ID, payments.StatusPending, payments.StatusDeclined, event.Reason, nil)
+	default:
+		logging.FromContext(request.Context(), p.lg).Info("Webhook: ignored", zap.String("payment", payment.ID), zap.String("status", event.Status))
+	}
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "Webhook", err)
+		return
+	}
+	logging.FromContext(request.Context(), p.lg).Info("Webhook", zap.String("payment", payment.ID), zap.String("status", payment.Status))
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Payment) writeJSON(writer http.ResponseWriter, op string, status int, v interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	err := json.NewEncoder(writer).Encode(v)
+	if err != nil {
+		p.lg.Error(op, zap.Error(err))
+	}
+}
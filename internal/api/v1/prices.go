@@ -2,11 +2,15 @@ package v1
 
 import (
 	"encoding/json"
+	"market4/internal/logging"
+	"market4/internal/metrics"
 	"market4/internal/model"
 	"market4/internal/repository"
 	"market4/internal/views"
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/unrolled/render"
 	"go.uber.org/zap"
 )
@@ -22,26 +26,27 @@ type PriceDTO struct {
 
 type Price struct {
 	priceRepo repository.Price
+	counters  *metrics.Metrics
 	lg        *zap.Logger
 	renderer  *render.Render
 }
 
-func NewPrice(priceRepo repository.Price, lg *zap.Logger, renderer *render.Render) *Price {
-	return &Price{priceRepo: priceRepo, lg: lg, renderer: renderer}
+func NewPrice(priceRepo repository.Price, counters *metrics.Metrics, lg *zap.Logger, renderer *render.Render) *Price {
+	return &Price{priceRepo: priceRepo, counters: counters, lg: lg, renderer: renderer}
 }
 
 func (price *Price) AddPrice(writer http.ResponseWriter, request *http.Request) {
 	var data *PriceDTO
 	err := json.NewDecoder(request.Body).Decode(&data)
 	if err != nil {
-		price.lg.Error("addPrice", zap.Error(err))
-		err = price.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			price.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(price.renderer, price.lg, writer, request, "addPrice", badRequest(err))
+		return
+	}
+	err = validatePrice(data, false)
+	if err != nil {
+		writeError(price.renderer, price.lg, writer, request, "addPrice", err)
 		return
 	}
-
 	var p = model.Price{
 		SalePrice:     data.SalePrice,
 		FactoryPrice:  data.FactoryPrice,
@@ -51,22 +56,15 @@ func (price *Price) AddPrice(writer http.ResponseWriter, request *http.Request)
 	}
 	addedPrice, err := price.priceRepo.AddPrice(request.Context(), &p)
 	if err != nil {
-		price.lg.Error("addPrice", zap.Error(err))
-		err = price.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			price.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(price.renderer, price.lg, writer, request, "addPrice", err)
 		return
 	}
+	price.counters.PricesChanged(metrics.SourceAPI, 1)
 
 	writer.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(writer).Encode(addedPrice)
 	if err != nil {
-		price.lg.Error("addPrice", zap.Error(err))
-		err = price.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			price.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(price.renderer, price.lg, writer, request, "addPrice", err)
 		return
 	}
 }
@@ -75,20 +73,13 @@ func (price *Price) EditPrice(writer http.ResponseWriter, request *http.Request)
 	var data *PriceDTO
 	err := json.NewDecoder(request.Body).Decode(&data)
 	if err != nil {
-		price.lg.Error("EditPrice", zap.Error(err))
-		err = price.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			price.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(price.renderer, price.lg, writer, request, "EditPrice", badRequest(err))
 		return
 	}
 
-	if data.ID == 0 {
-		price.lg.Error("EditPrice: id is empty")
-		err = price.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			price.lg.Error("Auth", zap.Error(err))
-		}
+	err = validatePrice(data, true)
+	if err != nil {
+		writeError(price.renderer, price.lg, writer, request, "EditPrice", err)
 		return
 	}
 
@@ -102,37 +93,23 @@ func (price *Price) EditPrice(writer http.ResponseWriter, request *http.Request)
 	}
 	editedPrice, err := price.priceRepo.EditPrice(request.Context(), &p)
 	if err != nil {
-		price.lg.Error("EditPrice", zap.Error(err))
-		err = price.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			price.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(price.renderer, price.lg, writer, request, "EditPrice", err)
 		return
 	}
+	price.counters.PricesChanged(metrics.SourceAPI, 1)
 
-	if editedPrice.ID == 0 {
-		return
-	}
 	var priceList = make([]model.Price, 0)
 	priceList = append(priceList, editedPrice)
 	result, err := views.MakePricesList(priceList)
 	if err != nil {
-		price.lg.Error("EditPrice", zap.Error(err))
-		err = price.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			price.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(price.renderer, price.lg, writer, request, "EditPrice", err)
 		return
 	}
 
 	writer.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(writer).Encode(result)
 	if err != nil {
-		price.lg.Error("EditPrice", zap.Error(err))
-		err = price.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			price.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(price.renderer, price.lg, writer, request, "EditPrice", err)
 		return
 	}
 }
@@ -140,32 +117,66 @@ func (price *Price) EditPrice(writer http.ResponseWriter, request *http.Request)
 func (price *Price) ListAllPrices(writer http.ResponseWriter, request *http.Request) {
 	prices, err := price.priceRepo.ListAllPrices(request.Context())
 	if err != nil {
-		price.lg.Error("ListAllPrices", zap.Error(err))
-		err = price.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			price.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(price.renderer, price.lg, writer, request, "ListAllPrices", err)
 		return
 	}
 
 	priceList, err := views.MakePricesList(prices)
 	if err != nil {
-		price.lg.Error("ListAllPrices", zap.Error(err))
-		err = price.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			price.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(price.renderer, price.lg, writer, request, "ListAllPrices", err)
 		return
 	}
 
 	writer.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(writer).Encode(priceList)
 	if err != nil {
-		price.lg.Error("ListAllPrices", zap.Error(err))
-		err = price.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			price.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(price.renderer, price.lg, writer, request, "ListAllPrices", err)
+		return
+	}
+}
+
+func (price *Price) GetPriceByID(writer http.ResponseWriter, request *http.Request) {
+	priceID, err := strconv.Atoi(chi.URLParam(request, "priceID"))
+	if err != nil {
+		writeError(price.renderer, price.lg, writer, request, "GetPriceByID", badRequest(err))
+		return
+	}
+	result, err := price.priceRepo.GetPriceByID(request.Context(), priceID)
+	if err != nil {
+		writeError(price.renderer, price.lg, writer, request, "GetPriceByID", err)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(result)
+	if err != nil {
+		logging.FromContext(request.Context(), price.lg).Error("GetPriceByID", zap.Error(err))
+	}
+}
+
+func (p *Price) DeletePrice(writer http.ResponseWriter, request *http.Request) {
+	priceID, err := strconv.Atoi(chi.URLParam(request, "priceID"))
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "DeletePrice", badRequest(err))
+		return
+	}
+	err = p.priceRepo.DeletePrice(request.Context(), priceID)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "DeletePrice", err)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Price) RestorePrice(writer http.ResponseWriter, request *http.Request) {
+	priceID, err := strconv.Atoi(chi.URLParam(request, "priceID"))
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "RestorePrice", badRequest(err))
+		return
+	}
+	err = p.priceRepo.RestorePrice(request.Context(), priceID)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "RestorePrice", err)
 		return
 	}
+	writer.WriteHeader(http.StatusNoContent)
 }
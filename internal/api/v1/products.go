@@ -2,12 +2,16 @@ package v1
 
 import (
 	"encoding/json"
-	"market4/internal/cache"
+	"fmt"
+	"market4/internal/api/validation"
+	"market4/internal/logging"
+	"market4/internal/metrics"
 	"market4/internal/model"
 	"market4/internal/repository"
 	"market4/internal/views"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/unrolled/render"
 	"go.uber.org/zap"
@@ -26,43 +30,41 @@ type ProductDTO struct {
 	Price       *PriceDTO `json:"price,omitempty"`
 }
 type Product struct {
-	productRepo repository.Product
-	priceRepo   repository.Price
-	stock       cache.Cache
-	lg          *zap.Logger
-	renderer    *render.Render
+	productRepo   repository.Product
+	priceRepo     repository.Price
+	promotionRepo repository.Promotion
+	counters      *metrics.Metrics
+	exportTimeout time.Duration
+	lg            *zap.Logger
+	renderer      *render.Render
 }
 
 func NewProduct(productRepo repository.Product,
 	priceRepo repository.Price,
-	stock cache.Cache,
+	promotionRepo repository.Promotion,
+	counters *metrics.Metrics,
+	exportTimeout time.Duration,
 	lg *zap.Logger,
 	renderer *render.Render) *Product {
 	return &Product{productRepo: productRepo,
-		priceRepo: priceRepo,
-		stock:     stock,
-		lg:        lg,
-		renderer:  renderer}
+		priceRepo:     priceRepo,
+		promotionRepo: promotionRepo,
+		counters:      counters,
+		exportTimeout: exportTimeout,
+		lg:            lg,
+		renderer:      renderer}
 }
 func (p *Product) AddProduct(writer http.ResponseWriter, request *http.Request) {
 	var data *ProductDTO
 	writer.Header().Set("Content-Type", "application/json")
 	err := json.NewDecoder(request.Body).Decode(&data)
 	if err != nil {
-		p.lg.Error("addProduct", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "addProduct", badRequest(err))
 		return
 	}
-	err = checkMandatoryFields(data.SKU, data.Name, data.Type, data.Description)
+	err = validateProduct(data, false)
 	if err != nil {
-		p.lg.Error("addProduct: field id empty")
-		err = p.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "addProduct", err)
 		return
 	}
 
@@ -75,13 +77,10 @@ func (p *Product) AddProduct(writer http.ResponseWriter, request *http.Request)
 
 	addedProduct, err := p.productRepo.AddProduct(request.Context(), product, data.Shop_ID, data.Category_ID)
 	if err != nil {
-		p.lg.Error("addProduct", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "addProduct", err)
 		return
 	}
+	p.counters.ProductsCreated(metrics.SourceAPI, 1)
 
 	var editedPrice model.Price
 	if data.Price != nil {
@@ -94,13 +93,10 @@ func (p *Product) AddProduct(writer http.ResponseWriter, request *http.Request)
 		}
 		editedPrice, err = p.priceRepo.AddPrice(request.Context(), &price)
 		if err != nil {
-			p.lg.Error("addProduct", zap.Error(err))
-			err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-			if err != nil {
-				p.lg.Error("Auth", zap.Error(err))
-			}
+			writeError(p.renderer, p.lg, writer, request, "addProduct", err)
 			return
 		}
+		p.counters.PricesChanged(metrics.SourceAPI, 1)
 	}
 
 	var productList = make([]model.Product, 0)
@@ -111,22 +107,14 @@ func (p *Product) AddProduct(writer http.ResponseWriter, request *http.Request)
 
 	result, err := views.MakeProductsListWithPrices(productList, priceList)
 	if err != nil {
-		p.lg.Error("addProduct", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "addProduct", err)
 		return
 	}
 
 	writer.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(writer).Encode(result)
 	if err != nil {
-		p.lg.Error("addProduct", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "addProduct", err)
 		return
 	}
 }
@@ -135,20 +123,12 @@ func (p *Product) EditProduct(writer http.ResponseWriter, request *http.Request)
 	var data *ProductDTO
 	err := json.NewDecoder(request.Body).Decode(&data)
 	if err != nil {
-		p.lg.Error("EditProduct", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "EditProduct", badRequest(err))
 		return
 	}
-	err = checkMandatoryFields(data.SKU)
+	err = validateProduct(data, true)
 	if err != nil {
-		p.lg.Error("EditProduct: SKU field is empty")
-		err = p.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "EditProduct", err)
 		return
 	}
 
@@ -167,11 +147,7 @@ func (p *Product) EditProduct(writer http.ResponseWriter, request *http.Request)
 
 	editedProduct, err := p.productRepo.EditProduct(request.Context(), product, shopID, categoryID)
 	if err != nil {
-		p.lg.Error("EditProduct", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "EditProduct", err)
 		return
 	}
 
@@ -186,11 +162,7 @@ func (p *Product) EditProduct(writer http.ResponseWriter, request *http.Request)
 		}
 		editedPrice, err = p.priceRepo.EditPriceByProductID(request.Context(), &price)
 		if err != nil {
-			p.lg.Error("EditProduct", zap.Error(err))
-			err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-			if err != nil {
-				p.lg.Error("Auth", zap.Error(err))
-			}
+			writeError(p.renderer, p.lg, writer, request, "EditProduct", err)
 			return
 		}
 	}
@@ -203,109 +175,68 @@ func (p *Product) EditProduct(writer http.ResponseWriter, request *http.Request)
 
 	result, err := views.MakeProductsListWithPrices(productList, priceList)
 	if err != nil {
-		p.lg.Error("EditProduct", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "EditProduct", err)
 		return
 	}
 
 	writer.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(writer).Encode(result)
 	if err != nil {
-		p.lg.Error("EditProduct", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "EditProduct", err)
 		return
 	}
 }
 
 func (p *Product) ListAllProducts(writer http.ResponseWriter, request *http.Request) {
-	products, err := p.productRepo.ListAllProducts(request.Context())
+	filter, err := productFilter(request)
 	if err != nil {
-		p.lg.Error("ListAllProducts", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "ListAllProducts", err)
 		return
 	}
 
-	prices, err := p.priceRepo.ListAllPrices(request.Context())
+	products, err := p.productRepo.ListProducts(request.Context(), filter)
 	if err != nil {
-		p.lg.Error("ListAllProducts", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "ListAllProducts", err)
 		return
 	}
 
-	productsList, err := views.MakeProductsListWithPrices(products, prices)
+	prices, err := p.priceRepo.ListAllPrices(request.Context())
 	if err != nil {
-		p.lg.Error("ListAllProducts", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "ListAllProducts", err)
 		return
 	}
 
-	writer.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(writer).Encode(productsList)
+	productsList, err := views.MakeProductsListWithPrices(products, prices)
 	if err != nil {
-		p.lg.Error("ListAllProducts", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "ListAllProducts", err)
 		return
 	}
-}
-func (p *Product) SearchProductsByCategory(writer http.ResponseWriter, request *http.Request) {
-	productsList, err := p.stock.FromCache(request.Context(), request.RequestURI)
+	err = p.promote(request.Context(), productsList.Items...)
 	if err != nil {
-		p.lg.Error("SearchProductsByCategory", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "ListAllProducts", err)
 		return
 	}
-	if productsList != nil {
-		writer.Header().Set("Content-Type", "application/json")
-		_, err = writer.Write(productsList)
-		if err != nil {
-			p.lg.Error("SearchProductsByCategory", zap.Error(err))
-			err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-			if err != nil {
-				p.lg.Error("Auth", zap.Error(err))
-			}
-			return
-		}
+
+	writer.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(productsList)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "ListAllProducts", err)
 		return
 	}
+}
 
+// SearchProductsByCategory lists the products of a category. Like the other
+// lookups it isn't cached, so archiving shows at once.
+func (p *Product) SearchProductsByCategory(writer http.ResponseWriter, request *http.Request) {
 	category, err := strconv.Atoi(chi.URLParam(request, "categoryID"))
 	if err != nil {
-		p.lg.Error("SearchProductsByCategory", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "SearchProductsByCategory", badRequest(err))
 		return
 	}
 
 	products, err := p.productRepo.SearchProductsByCategory(request.Context(), category)
 	if err != nil {
-		p.lg.Error("SearchProductsByCategory", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "SearchProductsByCategory", err)
 		return
 	}
 
@@ -315,75 +246,41 @@ func (p *Product) SearchProductsByCategory(writer http.ResponseWriter, request *
 
 	pList, err := views.MakeProductsList(products)
 	if err != nil {
-		p.lg.Error("SearchProductsByCategory", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "SearchProductsByCategory", err)
 		return
 	}
 
 	writer.Header().Set("Content-Type", "application/json")
 	body, err := json.Marshal(pList)
 	if err != nil {
-		p.lg.Error("SearchProductsByCategory", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "SearchProductsByCategory", err)
 		return
 	}
 
 	_, err = writer.Write(body)
 	if err != nil {
-		p.lg.Error("SearchProductsByCategory", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "SearchProductsByCategory", err)
 		return
 	}
-
-	err = p.stock.ToCache(request.Context(), request.RequestURI, body)
-	if err != nil {
-		p.lg.Error("SearchProductsByCategory", zap.Error(err))
-	}
 }
-func (p *Product) SearchProductByName(writer http.ResponseWriter, request *http.Request) {
-	if result, _ := p.stock.FromCache(request.Context(), request.RequestURI); result != nil {
-		writer.Header().Set("Content-Type", "application/json")
-		_, err := writer.Write(result)
-		if err != nil {
-			p.lg.Error("SearchProductByName", zap.Error(err))
-			err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-			if err != nil {
-				p.lg.Error("Auth", zap.Error(err))
-			}
-			return
-		}
-		return
-	}
 
+// SearchProductByName finds a product by name. It isn't cached either, so
+// archiving and the end of a promotion show at once.
+func (p *Product) SearchProductByName(writer http.ResponseWriter, request *http.Request) {
 	productName := chi.URLParam(request, "product_name")
 	product, err := p.productRepo.SearchProductsByName(request.Context(), productName)
 	if err != nil {
-		p.lg.Error("SearchProductByName", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "SearchProductByName", err)
 		return
 	}
 	if product.ID == "" {
+		err = repository.NewNotFoundError(fmt.Sprintf("product %q", productName))
+		writeError(p.renderer, p.lg, writer, request, "SearchProductByName", err)
 		return
 	}
 	price, err := p.priceRepo.SearchPriceByProductID(request.Context(), product.ID)
 	if err != nil {
-		p.lg.Error("SearchProductByName", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "SearchProductByName", err)
 		return
 	}
 
@@ -395,56 +292,36 @@ func (p *Product) SearchProductByName(writer http.ResponseWriter, request *http.
 
 	result, err := views.MakeProductsListWithPrices(productList, priceList)
 	if err != nil {
-		p.lg.Error("SearchProductByName", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "SearchProductByName", err)
+		return
+	}
+	err = p.promote(request.Context(), result.Items...)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "SearchProductByName", err)
 		return
 	}
 
 	writer.Header().Set("Content-Type", "application/json")
 	body, err := json.Marshal(result)
 	if err != nil {
-		p.lg.Error("SearchProductByName", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "SearchProductByName", err)
 		return
 	}
 	_, err = writer.Write(body)
 	if err != nil {
-		p.lg.Error("SearchProductByName", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "SearchProductByName", err)
 		return
 	}
-
-	err = p.stock.ToCache(request.Context(), request.RequestURI, body)
-	if err != nil {
-		p.lg.Error("SearchProductByName", zap.Error(err))
-	}
 }
 func (p *Product) SearchActiveProductsOfShop(writer http.ResponseWriter, request *http.Request) {
 	shopID, err := strconv.Atoi(chi.URLParam(request, "shopID"))
 	if err != nil {
-		p.lg.Error("SearchActiveProductsOfShop", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "SearchActiveProductsOfShop", badRequest(err))
 		return
 	}
 	products, err := p.productRepo.SearchProductsByShop(request.Context(), shopID)
 	if err != nil {
-		p.lg.Error("SearchActiveProductsOfShop", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "SearchActiveProductsOfShop", err)
 		return
 	}
 
@@ -457,11 +334,7 @@ func (p *Product) SearchActiveProductsOfShop(writer http.ResponseWriter, request
 		if product.IsActive {
 			price, cerr := p.priceRepo.SearchPriceByProductID(request.Context(), product.ID)
 			if cerr != nil {
-				p.lg.Error("SearchActiveProductsOfShop", zap.Error(err))
-				err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-				if err != nil {
-					p.lg.Error("Auth", zap.Error(err))
-				}
+				writeError(p.renderer, p.lg, writer, request, "SearchActiveProductsOfShop", cerr)
 				return
 			}
 			prices = append(prices, price)
@@ -470,22 +343,113 @@ func (p *Product) SearchActiveProductsOfShop(writer http.ResponseWriter, request
 
 	productsList, err := views.MakeProductsListWithPrices(products, prices)
 	if err != nil {
-		p.lg.Error("SearchActiveProductsOfShop", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "SearchActiveProductsOfShop", err)
+		return
+	}
+	err = p.promote(request.Context(), productsList.Items...)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "SearchActiveProductsOfShop", err)
 		return
 	}
 
 	writer.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(writer).Encode(productsList)
 	if err != nil {
-		p.lg.Error("SearchActiveProductsOfShop", zap.Error(err))
-		err = p.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			p.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(p.renderer, p.lg, writer, request, "SearchActiveProductsOfShop", err)
+		return
+	}
+}
+
+func (p *Product) GetProductByID(writer http.ResponseWriter, request *http.Request) {
+	productID := chi.URLParam(request, "productID")
+	err := validation.Validate(validation.Field("productID", productID, validation.UUID))
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "GetProductByID", err)
+		return
+	}
+	product, err := p.productRepo.GetProductByID(request.Context(), productID)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "GetProductByID", err)
+		return
+	}
+	p.writeProduct(writer, request, "GetProductByID", product)
+}
+
+func (p *Product) GetProductBySKU(writer http.ResponseWriter, request *http.Request) {
+	product, err := p.productRepo.GetProductBySKU(request.Context(), chi.URLParam(request, "sku"))
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "GetProductBySKU", err)
+		return
+	}
+	p.writeProduct(writer, request, "GetProductBySKU", product)
+}
+
+// GetProductByURI serves storefront URLs: /product/{type}-{sku} is exactly
+// the uri stored with the product. It isn't cached, so edits, reprices and
+// archiving show at once, like with the other lookups.
+func (p *Product) GetProductByURI(writer http.ResponseWriter, request *http.Request) {
+	uri := "/product/" + chi.URLParam(request, "slug")
+	product, err := p.productRepo.GetProductByURI(request.Context(), uri)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "GetProductByURI", err)
+		return
+	}
+	p.writeProduct(writer, request, "GetProductByURI", product)
+}
+
+// writeProduct replies with the product and its price.
+func (p *Product) writeProduct(writer http.ResponseWriter, request *http.Request, op string, product model.Product) {
+	price, err := p.priceRepo.SearchPriceByProductID(request.Context(), product.ID)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, op, err)
+		return
+	}
+	item := views.MakeProduct(product, price)
+	err = p.promote(request.Context(), item)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, op, err)
+		return
+	}
+	body, err := json.Marshal(item)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, op, err)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	_, err = writer.Write(body)
+	if err != nil {
+		logging.FromContext(request.Context(), p.lg).Error(op, zap.Error(err))
+	}
+}
+
+// DeleteProduct archives the product and its prices; it disappears from
+// listings and searches until restored or purged.
+func (p *Product) DeleteProduct(writer http.ResponseWriter, request *http.Request) {
+	productID := chi.URLParam(request, "productID")
+	err := validation.Validate(validation.Field("productID", productID, validation.UUID))
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "DeleteProduct", err)
+		return
+	}
+	err = p.productRepo.DeleteProduct(request.Context(), productID)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "DeleteProduct", err)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Product) RestoreProduct(writer http.ResponseWriter, request *http.Request) {
+	productID := chi.URLParam(request, "productID")
+	err := validation.Validate(validation.Field("productID", productID, validation.UUID))
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "RestoreProduct", err)
+		return
+	}
+	err = p.productRepo.RestoreProduct(request.Context(), productID)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "RestoreProduct", err)
 		return
 	}
+	writer.WriteHeader(http.StatusNoContent)
 }
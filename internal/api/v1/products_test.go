@@ -0,0 +1,52 @@
+package v1
+
+import (
+	"context"
+	"market4/internal/model"
+	"market4/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+)
+
+type stubProducts struct {
+	repository.Product
+	byCategory map[int][]model.Product
+	searches   int
+}
+
+func (s *stubProducts) SearchProductsByCategory(ctx context.Context, categoryID int) ([]model.Product, error) {
+	s.searches++
+	return s.byCategory[categoryID], nil
+}
+
+func Test_SearchProductsByCategory_NotCached(t *testing.T) {
+	products := &stubProducts{byCategory: map[int][]model.Product{
+		2: {{ID: "2800d950-5c62-49e2-a705-c74ba77f57d0", SKU: "3001", Name: "пушка"}},
+	}}
+	controller := NewProduct(products, nil, nil, nil, time.Minute, zap.NewNop(), render.New())
+	router := chi.NewRouter()
+	router.Get("/products/category/{categoryID}", controller.SearchProductsByCategory)
+	search := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/products/category/2", nil))
+		return recorder
+	}
+
+	recorder := search()
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Contains(t, recorder.Body.String(), `"sku":"3001"`)
+
+	delete(products.byCategory, 2)
+	recorder = search()
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), `"sku":"3001"`, "an archived product disappears at once")
+	assert.Equal(t, 2, products.searches, "every search goes to the database")
+}
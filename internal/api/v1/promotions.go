@@ -0,0 +1,198 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"market4/internal/api/validation"
+	"market4/internal/logging"
+	"market4/internal/model"
+	"market4/internal/pricing"
+	"market4/internal/repository"
+	"market4/internal/views"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+)
+
+var promotionTypes = []string{model.PromotionPercentage, model.PromotionFixed, model.PromotionBuyXGetY}
+
+type Promotion struct {
+	promotionRepo repository.Promotion
+	lg            *zap.Logger
+	renderer      *render.Render
+}
+
+func NewPromotion(promotionRepo repository.Promotion, lg *zap.Logger, renderer *render.Render) *Promotion {
+	return &Promotion{promotionRepo: promotionRepo, lg: lg, renderer: renderer}
+}
+
+func (p *Promotion) ListPromotions(writer http.ResponseWriter, request *http.Request) {
+	promotions, err := p.promotionRepo.ListPromotions(request.Context())
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "ListPromotions", err)
+		return
+	}
+	list := views.PromotionsListDTO{Total: len(promotions), Items: make([]*model.Promotion, 0, len(promotions))}
+	for i := range promotions {
+		list.Items = append(list.Items, &promotions[i])
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(list)
+	if err != nil {
+		logging.FromContext(request.Context(), p.lg).Error("ListPromotions", zap.Error(err))
+	}
+}
+
+func (p *Promotion) GetPromotionByID(writer http.ResponseWriter, request *http.Request) {
+	promotionID, err := strconv.Atoi(chi.URLParam(request, "promotionID"))
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "GetPromotionByID", badRequest(err))
+		return
+	}
+	promotion, err := p.promotionRepo.GetPromotionByID(request.Context(), promotionID)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "GetPromotionByID", err)
+		return
+	}
+	p.writePromotion(writer, "GetPromotionByID", promotion)
+}
+
+func (p *Promotion) AddPromotion(writer http.ResponseWriter, request *http.Request) {
+	var data model.Promotion
+	err := json.NewDecoder(request.Body).Decode(&data)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "AddPromotion", badRequest(err))
+		return
+	}
+	err = validatePromotion(&data, false)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "AddPromotion", err)
+		return
+	}
+	promotion, err := p.promotionRepo.AddPromotion(request.Context(), data)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "AddPromotion", err)
+		return
+	}
+	p.writePromotion(writer, "AddPromotion", promotion)
+}
+
+// EditPromotion replaces the whole promotion named by id.
+func (p *Promotion) EditPromotion(writer http.ResponseWriter, request *http.Request) {
+	var data model.Promotion
+	err := json.NewDecoder(request.Body).Decode(&data)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "EditPromotion", badRequest(err))
+		return
+	}
+	err = validatePromotion(&data, true)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "EditPromotion", err)
+		return
+	}
+	promotion, err := p.promotionRepo.EditPromotion(request.Context(), data)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "EditPromotion", err)
+		return
+	}
+	p.writePromotion(writer, "EditPromotion", promotion)
+}
+
+func (p *Promotion) DeletePromotion(writer http.ResponseWriter, request *http.Request) {
+	promotionID, err := strconv.Atoi(chi.URLParam(request, "promotionID"))
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "DeletePromotion", badRequest(err))
+		return
+	}
+	err = p.promotionRepo.DeletePromotion(request.Context(), promotionID)
+	if err != nil {
+		writeError(p.renderer, p.lg, writer, request, "DeletePromotion", err)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Promotion) writePromotion(writer http.ResponseWriter, op string, promotion model.Promotion) {
+	writer.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(writer).Encode(promotion)
+	if err != nil {
+		p.lg.Error(op, zap.Error(err))
+	}
+}
+
+func validatePromotion(d *model.Promotion, edit bool) error {
+	fields := []validation.FieldRules{
+		validation.Field("name", d.Name, validation.Required, validation.MaxLength(255)),
+		validation.Field("type", d.Type, validation.Required, validation.OneOf(promotionTypes...)),
+		validation.Field("starts_at", d.StartsAt, validation.Required),
+		validation.Field("ends_at", d.EndsAt, validation.Required),
+	}
+	switch d.Type {
+	case model.PromotionPercentage:
+		fields = append(fields, validation.Field("value", d.Value, validation.Required, validation.Min(1), validation.Max(100)))
+	case model.PromotionFixed:
+		fields = append(fields, validation.Field("value", d.Value, validation.Required, validation.Min(1)))
+	case model.PromotionBuyXGetY:
+		fields = append(fields,
+			validation.Field("buy_quantity", d.BuyQuantity, validation.Required, validation.Min(1)),
+			validation.Field("get_quantity", d.GetQuantity, validation.Required, validation.Min(1)))
+	}
+	if edit {
+		fields = append(fields, validation.Field("id", d.ID, validation.Required, validation.Min(1)))
+	}
+	for i, id := range d.CategoryIDs {
+		fields = append(fields, validation.Field(fmt.Sprintf("category_ids[%d]", i), id, validation.Required, validation.Min(1)))
+	}
+	for i, id := range d.ShopIDs {
+		fields = append(fields, validation.Field(fmt.Sprintf("shop_ids[%d]", i), id, validation.Required, validation.Min(1)))
+	}
+	for i, id := range d.ProductIDs {
+		fields = append(fields, validation.Field(fmt.Sprintf("product_ids[%d]", i), id, validation.Required, validation.UUID))
+	}
+	err := validation.Validate(fields...)
+	if err != nil {
+		return err
+	}
+	if !d.EndsAt.After(d.StartsAt) {
+		return repository.NewValidationError(repository.FieldError{Field: "ends_at", Reason: "must be after starts_at"})
+	}
+	return nil
+}
+
+// promote fills in the effective price of every listed price from the
+// promotions running now. Lists are cached for a short while, so a
+// promotion may take up to the cache TTL to show in cached responses.
+func (p *Product) promote(ctx context.Context, items ...*views.Product) error {
+	productIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		if len(item.Prices) > 0 {
+			productIDs = append(productIDs, item.ID)
+		}
+	}
+	if len(productIDs) == 0 {
+		return nil
+	}
+	promotions, err := p.promotionRepo.ActivePromotions(ctx, productIDs, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		for _, price := range item.Prices {
+			quote := pricing.Promote(price.SalePrice, 1, promotions[item.ID])
+			price.EffectivePrice = &quote.Unit
+			for _, applied := range quote.Applied {
+				price.Promotions = append(price.Promotions, &views.AppliedPromotionDTO{
+					ID:   applied.ID,
+					Name: applied.Name,
+					Type: applied.Type,
+				})
+			}
+		}
+	}
+	return nil
+}
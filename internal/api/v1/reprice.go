@@ -0,0 +1,159 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"market4/internal/api/auth"
+	"market4/internal/api/problem"
+	"market4/internal/api/validation"
+	"market4/internal/logging"
+	"market4/internal/metrics"
+	"market4/internal/model"
+	"market4/internal/pricing"
+	"market4/internal/repository"
+	"market4/internal/views"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+type BulkPriceDTO struct {
+	Rule   pricing.Rule        `json:"rule"`
+	Select model.PriceSelector `json:"select"`
+	DryRun bool                `json:"dry_run"`
+}
+
+// BulkUpdatePrices applies one rule to every price the selector matches.
+// With dry_run it only reports the diff. Otherwise the changes are written
+// in one transaction and recorded under the caller's ID, and nothing is
+// written if any new price would be invalid.
+func (price *Price) BulkUpdatePrices(writer http.ResponseWriter, request *http.Request) {
+	var data BulkPriceDTO
+	err := json.NewDecoder(request.Body).Decode(&data)
+	if err != nil {
+		writeError(price.renderer, price.lg, writer, request, "BulkUpdatePrices", badRequest(err))
+		return
+	}
+	err = validateBulkPrice(&data)
+	if err != nil {
+		writeError(price.renderer, price.lg, writer, request, "BulkUpdatePrices", err)
+		return
+	}
+
+	selected, err := price.priceRepo.SelectPrices(request.Context(), data.Select)
+	if err != nil {
+		writeError(price.renderer, price.lg, writer, request, "BulkUpdatePrices", err)
+		return
+	}
+	report := views.PriceBatchDTO{DryRun: data.DryRun, Matched: len(selected), Changes: make([]*views.PriceChangeDTO, 0)}
+	changes := make([]model.PriceChange, 0, len(selected))
+	var invalid []repository.FieldError
+	for _, change := range selected {
+		change.New = data.Rule.Apply(change.Old)
+		if change.New == change.Old {
+			continue
+		}
+		item := &views.PriceChangeDTO{
+			PriceID:   change.PriceID,
+			ProductID: change.ProductID,
+			SKU:       change.SKU,
+			Old:       views.Price{SalePrice: change.Old.SalePrice, FactoryPrice: change.Old.FactoryPrice, DiscountPrice: change.Old.DiscountPrice},
+			New:       views.Price{SalePrice: change.New.SalePrice, FactoryPrice: change.New.FactoryPrice, DiscountPrice: change.New.DiscountPrice},
+		}
+		var domainErr *repository.Error
+		if err = validation.Validate(priceFields(&PriceDTO{
+			SalePrice:     change.New.SalePrice,
+			FactoryPrice:  change.New.FactoryPrice,
+			DiscountPrice: change.New.DiscountPrice,
+		})...); errors.As(err, &domainErr) {
+			item.InvalidParams = domainErr.Fields
+			report.Invalid++
+			for _, f := range domainErr.Fields {
+				invalid = append(invalid, repository.FieldError{Field: change.SKU + "." + f.Field, Reason: f.Reason})
+			}
+		}
+		report.Changes = append(report.Changes, item)
+		changes = append(changes, change)
+	}
+	report.Changed = len(changes)
+
+	if !data.DryRun && len(invalid) > 0 {
+		writeError(price.renderer, price.lg, writer, request, "BulkUpdatePrices", repository.NewValidationError(invalid...))
+		return
+	}
+	if !data.DryRun && len(changes) > 0 {
+		payload, ok := auth.FromContext(request.Context())
+		if !ok {
+			writeError(price.renderer, price.lg, writer, request, "BulkUpdatePrices", problem.ErrUnauthorized)
+			return
+		}
+		rule, err := json.Marshal(struct {
+			Rule   pricing.Rule        `json:"rule"`
+			Select model.PriceSelector `json:"select"`
+		}{data.Rule, data.Select})
+		if err != nil {
+			writeError(price.renderer, price.lg, writer, request, "BulkUpdatePrices", err)
+			return
+		}
+		batch, err := price.priceRepo.ApplyPriceChanges(request.Context(), model.PriceBatch{
+			ActorID: payload.ID,
+			Rule:    string(rule),
+			Changes: changes,
+		})
+		if err != nil {
+			writeError(price.renderer, price.lg, writer, request, "BulkUpdatePrices", err)
+			return
+		}
+		report.BatchID = batch.ID
+		price.counters.PricesChanged(metrics.SourceBulk, len(changes))
+		logging.FromContext(request.Context(), price.lg).Info("BulkUpdatePrices", zap.Int("batch", batch.ID), zap.Int("actor", payload.ID), zap.Int("changed", len(changes)))
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(report)
+	if err != nil {
+		logging.FromContext(request.Context(), price.lg).Error("BulkUpdatePrices", zap.Error(err))
+	}
+}
+
+func validateBulkPrice(d *BulkPriceDTO) error {
+	valueRules := []validation.Rule{}
+	switch d.Rule.Op {
+	case pricing.Set:
+		valueRules = append(valueRules, validation.Min(0))
+	case pricing.Percent:
+		valueRules = append(valueRules, validation.Min(-100))
+	}
+	fields := []validation.FieldRules{
+		validation.Field("rule.op", d.Rule.Op, validation.Required, validation.OneOf(pricing.Ops...)),
+		validation.Field("rule.value", d.Rule.Value, valueRules...),
+		validation.Field("rule.round", d.Rule.Round, validation.OneOf(pricing.Round99)),
+		validation.Field("rule.field", d.Rule.Field, validation.OneOf(pricing.Fields...)),
+		validation.Field("select.category_id", d.Select.CategoryID, validation.Min(1)),
+		validation.Field("select.shop_id", d.Select.ShopID, validation.Min(1)),
+		validation.Field("select.min_price", d.Select.MinPrice, validation.Min(0)),
+		validation.Field("select.max_price", d.Select.MaxPrice, validation.Min(0)),
+	}
+	if d.Select.MaxPrice != 0 {
+		fields = append(fields, validation.Field("select.min_price", d.Select.MinPrice,
+			validation.NotAbove("max_price", d.Select.MaxPrice)))
+	}
+	for i, sku := range d.Select.SKUs {
+		fields = append(fields, validation.Field(fmt.Sprintf("select.skus[%d]", i), sku, validation.Required))
+	}
+	err := validation.Validate(fields...)
+	if err != nil {
+		return err
+	}
+	// An empty selector would reprice the whole catalog; that has to be
+	// asked for by naming a range.
+	if d.Select.CategoryID == 0 && d.Select.ShopID == 0 && len(d.Select.SKUs) == 0 &&
+		d.Select.MinPrice == 0 && d.Select.MaxPrice == 0 {
+		return repository.NewValidationError(repository.FieldError{
+			Field:  "select",
+			Reason: "must set at least one of category_id, shop_id, skus, min_price, max_price",
+		})
+	}
+	return nil
+}
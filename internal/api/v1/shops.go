@@ -2,11 +2,14 @@ package v1
 
 import (
 	"encoding/json"
+	"market4/internal/logging"
 	"market4/internal/model"
 	"market4/internal/repository"
 	"market4/internal/views"
 	"net/http"
+	"strconv"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/unrolled/render"
 	"go.uber.org/zap"
 )
@@ -34,60 +37,37 @@ func (s *Shop) EditShop(writer http.ResponseWriter, request *http.Request) {
 	var data *model.Shop
 	err := json.NewDecoder(request.Body).Decode(&data)
 	if err != nil {
-		s.lg.Error("editShop", zap.Error(err))
-		err = s.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			s.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(s.renderer, s.lg, writer, request, "editShop", badRequest(err))
 		return
 	}
-	if data.ID == 0 {
-		s.lg.Error("editShop", zap.Error(err))
-		err = s.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			s.lg.Error("Auth", zap.Error(err))
-		}
+	err = validateShop(data, true)
+	if err != nil {
+		writeError(s.renderer, s.lg, writer, request, "editShop", err)
 		return
 	}
 
 	err = s.shopRepo.EditShop(request.Context(), data)
 	if err != nil {
-		s.lg.Error("editShop", zap.Error(err))
-		err = s.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			s.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(s.renderer, s.lg, writer, request, "editShop", err)
 		return
 	}
 }
 func (s *Shop) ListAllShops(writer http.ResponseWriter, request *http.Request) {
 	shops, err := s.shopRepo.ListAllShops(request.Context())
 	if err != nil {
-		s.lg.Error("ListAllShops", zap.Error(err))
-		err = s.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			s.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(s.renderer, s.lg, writer, request, "ListAllShops", err)
 		return
 	}
 
 	shopList, err := views.MakeShopList(&shops)
 	if err != nil {
-		s.lg.Error("ListAllShops", zap.Error(err))
-		err = s.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			s.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(s.renderer, s.lg, writer, request, "ListAllShops", err)
 		return
 	}
 	writer.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(writer).Encode(shopList)
 	if err != nil {
-		s.lg.Error("ListAllShops", zap.Error(err))
-		err = s.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			s.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(s.renderer, s.lg, writer, request, "ListAllShops", err)
 		return
 	}
 }
@@ -95,21 +75,17 @@ func (s *Shop) AddShop(writer http.ResponseWriter, request *http.Request) {
 	var data *model.Shop
 	err := json.NewDecoder(request.Body).Decode(&data)
 	if err != nil {
-		s.lg.Error("AddShop", zap.Error(err))
-		err = s.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			s.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(s.renderer, s.lg, writer, request, "AddShop", badRequest(err))
+		return
+	}
+	err = validateShop(data, false)
+	if err != nil {
+		writeError(s.renderer, s.lg, writer, request, "AddShop", err)
 		return
 	}
-
 	id, err := s.shopRepo.AddShop(request.Context(), data)
 	if err != nil {
-		s.lg.Error("AddShop", zap.Error(err))
-		err = s.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			s.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(s.renderer, s.lg, writer, request, "AddShop", err)
 		return
 	}
 
@@ -121,11 +97,55 @@ func (s *Shop) AddShop(writer http.ResponseWriter, request *http.Request) {
 	writer.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(writer).Encode(reply)
 	if err != nil {
-		s.lg.Error("AddShop", zap.Error(err))
-		err = s.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			s.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(s.renderer, s.lg, writer, request, "AddShop", err)
+		return
+	}
+}
+
+func (s *Shop) GetShopByID(writer http.ResponseWriter, request *http.Request) {
+	shopID, err := strconv.Atoi(chi.URLParam(request, "shopID"))
+	if err != nil {
+		writeError(s.renderer, s.lg, writer, request, "GetShopByID", badRequest(err))
+		return
+	}
+	shop, err := s.shopRepo.GetShopByID(request.Context(), shopID)
+	if err != nil {
+		writeError(s.renderer, s.lg, writer, request, "GetShopByID", err)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(shop)
+	if err != nil {
+		logging.FromContext(request.Context(), s.lg).Error("GetShopByID", zap.Error(err))
+	}
+}
+
+// DeleteShop archives the shop; it disappears from listings until restored
+// or purged.
+func (s *Shop) DeleteShop(writer http.ResponseWriter, request *http.Request) {
+	shopID, err := strconv.Atoi(chi.URLParam(request, "shopID"))
+	if err != nil {
+		writeError(s.renderer, s.lg, writer, request, "DeleteShop", badRequest(err))
+		return
+	}
+	err = s.shopRepo.DeleteShop(request.Context(), shopID)
+	if err != nil {
+		writeError(s.renderer, s.lg, writer, request, "DeleteShop", err)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Shop) RestoreShop(writer http.ResponseWriter, request *http.Request) {
+	shopID, err := strconv.Atoi(chi.URLParam(request, "shopID"))
+	if err != nil {
+		writeError(s.renderer, s.lg, writer, request, "RestoreShop", badRequest(err))
+		return
+	}
+	err = s.shopRepo.RestoreShop(request.Context(), shopID)
+	if err != nil {
+		writeError(s.renderer, s.lg, writer, request, "RestoreShop", err)
 		return
 	}
+	writer.WriteHeader(http.StatusNoContent)
 }
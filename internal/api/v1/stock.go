@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"encoding/json"
+	"market4/internal/api/validation"
+	"market4/internal/logging"
+	"market4/internal/model"
+	"market4/internal/repository"
+	"market4/internal/views"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+)
+
+type Stock struct {
+	stockRepo repository.Stock
+	lg        *zap.Logger
+	renderer  *render.Render
+}
+
+func NewStock(stockRepo repository.Stock, lg *zap.Logger, renderer *render.Render) *Stock {
+	return &Stock{stockRepo: stockRepo, lg: lg, renderer: renderer}
+}
+
+// ListStock shows how many units of a product each shop has and how many
+// of them can still be ordered.
+func (s *Stock) ListStock(writer http.ResponseWriter, request *http.Request) {
+	productID := chi.URLParam(request, "productID")
+	err := validation.Validate(validation.Field("productID", productID, validation.UUID))
+	if err != nil {
+		writeError(s.renderer, s.lg, writer, request, "ListStock", err)
+		return
+	}
+	stock, err := s.stockRepo.ListStock(request.Context(), productID)
+	if err != nil {
+		writeError(s.renderer, s.lg, writer, request, "ListStock", err)
+		return
+	}
+	list := views.StockListDTO{Total: len(stock), Items: make([]*model.Stock, 0, len(stock))}
+	for i := range stock {
+		list.Available += stock[i].Available
+		list.Items = append(list.Items, &stock[i])
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(list)
+	if err != nil {
+		logging.FromContext(request.Context(), s.lg).Error("ListStock", zap.Error(err))
+	}
+}
+
+// SetStock sets how many units of a product a shop has, e.g. after a
+// stocktaking. It can't go below what unpaid orders have reserved.
+func (s *Stock) SetStock(writer http.ResponseWriter, request *http.Request) {
+	var data model.Stock
+	err := json.NewDecoder(request.Body).Decode(&data)
+	if err != nil {
+		writeError(s.renderer, s.lg, writer, request, "SetStock", badRequest(err))
+		return
+	}
+	err = validateStock(data)
+	if err != nil {
+		writeError(s.renderer, s.lg, writer, request, "SetStock", err)
+		return
+	}
+	stock, err := s.stockRepo.SetStock(request.Context(), data)
+	if err != nil {
+		writeError(s.renderer, s.lg, writer, request, "SetStock", err)
+		return
+	}
+	logging.FromContext(request.Context(), s.lg).Info("SetStock", zap.Int("shop", stock.ShopID), zap.String("product", stock.ProductID), zap.Int("quantity", stock.Quantity))
+	writer.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(writer).Encode(stock)
+	if err != nil {
+		logging.FromContext(request.Context(), s.lg).Error("SetStock", zap.Error(err))
+	}
+}
+
+func validateStock(d model.Stock) error {
+	return validation.Validate(
+		validation.Field("shop_id", d.ShopID, validation.Required, validation.Min(1)),
+		validation.Field("product_id", d.ProductID, validation.Required, validation.UUID),
+		validation.Field("quantity", d.Quantity, validation.Min(0), validation.Max(1000000)),
+	)
+}
@@ -24,39 +24,23 @@ func (u *Users) AddUser(writer http.ResponseWriter, request *http.Request) {
 	var data *model.User
 	err := json.NewDecoder(request.Body).Decode(&data)
 	if err != nil {
-		u.lg.Error("AddUser", zap.Error(err))
-		err = u.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			u.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(u.renderer, u.lg, writer, request, "AddUser", badRequest(err))
 		return
 	}
-	err = checkMandatoryFields(data.Login, data.Password, data.Role)
+	err = validateUser(data, true)
 	if err != nil {
-		u.lg.Error("AddUser: field is empty")
-		err = u.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			u.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(u.renderer, u.lg, writer, request, "AddUser", err)
 		return
 	}
 	addedUser, err := u.usersRepo.AddUser(request.Context(), data)
 	if err != nil {
-		u.lg.Error("AddUser", zap.Error(err))
-		err = u.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			u.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(u.renderer, u.lg, writer, request, "AddUser", err)
 		return
 	}
 	writer.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(writer).Encode(addedUser)
 	if err != nil {
-		u.lg.Error("AddUser", zap.Error(err))
-		err = u.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			u.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(u.renderer, u.lg, writer, request, "AddUser", err)
 		return
 	}
 }
@@ -65,39 +49,23 @@ func (u *Users) EditUser(writer http.ResponseWriter, request *http.Request) {
 	var data *model.User
 	err := json.NewDecoder(request.Body).Decode(&data)
 	if err != nil {
-		u.lg.Error("EditUser", zap.Error(err))
-		err = u.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			u.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(u.renderer, u.lg, writer, request, "EditUser", badRequest(err))
 		return
 	}
-	err = checkMandatoryFields(data.Login, data.Password)
+	err = validateUser(data, false)
 	if err != nil {
-		u.lg.Error("EditUser: field is empty")
-		err = u.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			u.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(u.renderer, u.lg, writer, request, "EditUser", err)
 		return
 	}
 	editedUser, err := u.usersRepo.EditUser(request.Context(), data)
 	if err != nil {
-		u.lg.Error("EditUser", zap.Error(err))
-		err = u.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			u.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(u.renderer, u.lg, writer, request, "EditUser", err)
 		return
 	}
 	writer.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(writer).Encode(editedUser)
 	if err != nil {
-		u.lg.Error("EditUser", zap.Error(err))
-		err = u.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			u.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(u.renderer, u.lg, writer, request, "EditUser", err)
 		return
 	}
 }
@@ -106,29 +74,17 @@ func (u *Users) AddRole(writer http.ResponseWriter, request *http.Request) {
 	var data *model.User
 	err := json.NewDecoder(request.Body).Decode(&data)
 	if err != nil {
-		u.lg.Error("AddRole", zap.Error(err))
-		err = u.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			u.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(u.renderer, u.lg, writer, request, "AddRole", badRequest(err))
 		return
 	}
-	err = checkMandatoryFields(data.Login, data.Role)
+	err = validateUserRole(data)
 	if err != nil {
-		u.lg.Error("AddRole: field is empty")
-		err = u.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			u.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(u.renderer, u.lg, writer, request, "AddRole", err)
 		return
 	}
 	err = u.usersRepo.AddRole(request.Context(), data.Login, data.Role)
 	if err != nil {
-		u.lg.Error("AddRole", zap.Error(err))
-		err = u.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			u.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(u.renderer, u.lg, writer, request, "AddRole", err)
 		return
 	}
 	writer.WriteHeader(http.StatusOK)
@@ -138,29 +94,45 @@ func (u *Users) RemoveRole(writer http.ResponseWriter, request *http.Request) {
 	var data *model.User
 	err := json.NewDecoder(request.Body).Decode(&data)
 	if err != nil {
-		u.lg.Error("RemoveRole", zap.Error(err))
-		err = u.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			u.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(u.renderer, u.lg, writer, request, "RemoveRole", badRequest(err))
 		return
 	}
-	err = checkMandatoryFields(data.Login, data.Role)
+	err = validateUserRole(data)
 	if err != nil {
-		u.lg.Error("RemoveRole: field is empty")
-		err = u.renderer.JSON(writer, http.StatusBadRequest, map[string]string{"Error": "BadRequest"})
-		if err != nil {
-			u.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(u.renderer, u.lg, writer, request, "RemoveRole", err)
 		return
 	}
 	err = u.usersRepo.RemoveRole(request.Context(), data.Login, data.Role)
 	if err != nil {
-		u.lg.Error("RemoveRole", zap.Error(err))
-		err = u.renderer.JSON(writer, http.StatusInternalServerError, map[string]string{"Error": "InternalServerError"})
-		if err != nil {
-			u.lg.Error("Auth", zap.Error(err))
-		}
+		writeError(u.renderer, u.lg, writer, request, "RemoveRole", err)
+		return
+	}
+	writer.WriteHeader(http.StatusOK)
+}
+
+// StaffShopsDTO names the shops whose orders a STAFF user works.
+type StaffShopsDTO struct {
+	Login   string `json:"login"`
+	ShopIDs []int  `json:"shop_ids"`
+}
+
+// SetStaffShops replaces the shops a user works orders of; see
+// Order.staffOf. An empty list takes them all away.
+func (u *Users) SetStaffShops(writer http.ResponseWriter, request *http.Request) {
+	var data StaffShopsDTO
+	err := json.NewDecoder(request.Body).Decode(&data)
+	if err != nil {
+		writeError(u.renderer, u.lg, writer, request, "SetStaffShops", badRequest(err))
+		return
+	}
+	err = validateStaffShops(&data)
+	if err != nil {
+		writeError(u.renderer, u.lg, writer, request, "SetStaffShops", err)
+		return
+	}
+	err = u.usersRepo.SetStaffShops(request.Context(), data.Login, data.ShopIDs)
+	if err != nil {
+		writeError(u.renderer, u.lg, writer, request, "SetStaffShops", err)
 		return
 	}
 	writer.WriteHeader(http.StatusOK)
@@ -1,12 +1,52 @@
 package v1
 
-import "fmt"
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"market4/internal/api/problem"
+	"market4/internal/logging"
+	"net/http"
+	"strconv"
 
-func checkMandatoryFields(fields ...string) error {
-	for _, field := range fields {
-		if field == "" {
-			return fmt.Errorf("Mandatory field is empty")
-		}
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+)
+
+// badRequest reports a request that can't be read. The client is told what
+// is wrong in its own terms; err, which may quote decoder internals, only
+// goes to the logs.
+func badRequest(err error) error {
+	return problem.BadRequest(requestDetail(err), err)
+}
+
+func requestDetail(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var numErr *strconv.NumError
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return typeErr.Field + " has the wrong type"
+	case errors.As(err, &typeErr), errors.As(err, &syntaxErr),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "the body must be a JSON object"
+	case errors.As(err, &numErr):
+		return "the ID in the path must be a number"
+	}
+	return "the request can't be read"
+}
+
+// writeError logs err under op with the request's logger and replies with the matching problem document.
+func writeError(renderer *render.Render,
+	lg *zap.Logger,
+	writer http.ResponseWriter,
+	request *http.Request,
+	op string,
+	err error) {
+	lg = logging.FromContext(request.Context(), lg)
+	lg.Error(op, zap.Error(err))
+	err = problem.Write(renderer, writer, request, problem.FromError(err))
+	if err != nil {
+		lg.Error(op, zap.Error(err))
 	}
-	return nil
 }
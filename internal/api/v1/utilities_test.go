@@ -0,0 +1,59 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"market4/internal/api/problem"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_badRequest(t *testing.T) {
+	var dto ProductDTO
+	_, atoiErr := strconv.Atoi("abc")
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "wrong field type",
+			err:  json.NewDecoder(strings.NewReader(`{"sku": 1}`)).Decode(&dto),
+			want: "sku has the wrong type",
+		},
+		{
+			name: "malformed body",
+			err:  json.NewDecoder(strings.NewReader(`{"sku": `)).Decode(&dto),
+			want: "the body must be a JSON object",
+		},
+		{
+			name: "empty body",
+			err:  json.NewDecoder(strings.NewReader(``)).Decode(&dto),
+			want: "the body must be a JSON object",
+		},
+		{
+			name: "path id",
+			err:  atoiErr,
+			want: "the ID in the path must be a number",
+		},
+		{
+			name: "anything else",
+			err:  errors.New("unexpected reader state"),
+			want: "the request can't be read",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := badRequest(tt.err)
+			assert.ErrorIs(t, err, problem.ErrBadRequest)
+			assert.ErrorIs(t, err, tt.err)
+			p := problem.FromError(err)
+			assert.Equal(t, http.StatusBadRequest, p.Status)
+			assert.Equal(t, tt.want, p.Detail)
+		})
+	}
+}
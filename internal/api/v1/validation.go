@@ -0,0 +1,111 @@
+package v1
+
+import (
+	"fmt"
+	"market4/internal/api/validation"
+	"market4/internal/model"
+)
+
+// requiredUnless makes a field mandatory on create and optional on edit,
+// where an empty value means "leave unchanged".
+func requiredUnless(edit bool) validation.Rule {
+	if edit {
+		return validation.Optional
+	}
+	return validation.Required
+}
+
+func validateProduct(d *ProductDTO, edit bool) error {
+	required := requiredUnless(edit)
+	fields := []validation.FieldRules{
+		validation.Field("sku", d.SKU, validation.Required, validation.MaxLength(64)),
+		validation.Field("name", d.Name, required, validation.MaxLength(255)),
+		validation.Field("type", d.Type, required, validation.Word, validation.MaxLength(64)),
+		validation.Field("description", d.Description, required),
+		validation.Field("shop_id", d.Shop_ID, required, validation.Min(1)),
+		validation.Field("category_id", d.Category_ID, required, validation.Min(1)),
+	}
+	if d.Price != nil {
+		fields = append(fields, validation.Prefix("price", priceFields(d.Price)...)...)
+	}
+	return validation.Validate(fields...)
+}
+
+// priceFields checks a price payload. Edits overwrite every column, so an
+// omitted sale_price is stored as 0 and discount_price is held against that.
+func priceFields(d *PriceDTO) []validation.FieldRules {
+	return []validation.FieldRules{
+		validation.Field("sale_price", d.SalePrice, validation.Min(0)),
+		validation.Field("factory_price", d.FactoryPrice, validation.Min(0)),
+		validation.Field("discount_price", d.DiscountPrice,
+			validation.Min(0), validation.NotAbove("sale_price", d.SalePrice)),
+	}
+}
+
+func validatePrice(d *PriceDTO, edit bool) error {
+	fields := priceFields(d)
+	if edit {
+		fields = append(fields,
+			validation.Field("id", d.ID, validation.Required, validation.Min(1)),
+			validation.Field("product_id", d.ProductID, validation.UUID))
+	} else {
+		fields = append(fields,
+			validation.Field("product_id", d.ProductID, validation.Required, validation.UUID))
+	}
+	return validation.Validate(fields...)
+}
+
+func validateShop(s *model.Shop, edit bool) error {
+	required := requiredUnless(edit)
+	fields := []validation.FieldRules{
+		validation.Field("name", s.Name, required, validation.MaxLength(255)),
+		validation.Field("address", s.Address, required, validation.MaxLength(255)),
+		validation.Field("workingHours", s.WorkingHours, validation.MaxLength(64)),
+	}
+	if edit {
+		fields = append(fields, validation.Field("id", s.ID, validation.Required, validation.Min(1)))
+	}
+	return validation.Validate(fields...)
+}
+
+func validateCategory(c *model.Category, edit bool) error {
+	fields := []validation.FieldRules{
+		validation.Field("name", c.Name, validation.Required, validation.MaxLength(255)),
+	}
+	if edit {
+		fields = append(fields, validation.Field("id", c.ID, validation.Required, validation.Min(1)))
+	}
+	return validation.Validate(fields...)
+}
+
+var roles = []string{string(model.ADMIN), string(model.USER), string(model.STAFF)}
+
+func validateUser(u *model.User, roleRequired bool) error {
+	return validation.Validate(
+		validation.Field("login", u.Login, validation.Required, validation.MaxLength(64)),
+		validation.Field("password", u.Password, validation.Required, validation.MinLength(6)),
+		validation.Field("role", u.Role, requiredUnless(!roleRequired), validation.OneOf(roles...)),
+	)
+}
+
+func validateUserRole(u *model.User) error {
+	return validation.Validate(
+		validation.Field("login", u.Login, validation.Required),
+		validation.Field("role", u.Role, validation.Required, validation.OneOf(roles...)),
+	)
+}
+
+func validateStaffShops(d *StaffShopsDTO) error {
+	fields := []validation.FieldRules{validation.Field("login", d.Login, validation.Required)}
+	for i, id := range d.ShopIDs {
+		fields = append(fields, validation.Field(fmt.Sprintf("shop_ids[%d]", i), id, validation.Required, validation.Min(1)))
+	}
+	return validation.Validate(fields...)
+}
+
+func validateCredentials(u *model.User) error {
+	return validation.Validate(
+		validation.Field("login", u.Login, validation.Required),
+		validation.Field("password", u.Password, validation.Required),
+	)
+}
@@ -0,0 +1,156 @@
+package v1
+
+import (
+	"errors"
+	"market4/internal/model"
+	"market4/internal/pricing"
+	"market4/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fieldNames(err error) []string {
+	var domainErr *repository.Error
+	if !errors.As(err, &domainErr) {
+		return nil
+	}
+	names := make([]string, 0, len(domainErr.Fields))
+	for _, f := range domainErr.Fields {
+		names = append(names, f.Field)
+	}
+	return names
+}
+
+func Test_validateProduct(t *testing.T) {
+	tests := []struct {
+		name string
+		dto  ProductDTO
+		edit bool
+		want []string
+	}{
+		{
+			name: "valid new product",
+			dto: ProductDTO{SKU: "3001", Name: "пушка", Type: "тепловая", Description: "пушка детская",
+				Shop_ID: 1, Category_ID: 2, Price: &PriceDTO{SalePrice: 2000, FactoryPrice: 1000, DiscountPrice: 1600}},
+			want: []string{},
+		},
+		{
+			name: "empty new product",
+			dto:  ProductDTO{},
+			want: []string{"sku", "name", "type", "description", "shop_id", "category_id"},
+		},
+		{
+			name: "edit needs sku only",
+			dto:  ProductDTO{SKU: "3001"},
+			edit: true,
+			want: []string{},
+		},
+		{
+			name: "bad type and price",
+			dto: ProductDTO{SKU: "3001", Type: "a-b", Shop_ID: -1,
+				Price: &PriceDTO{SalePrice: 100, FactoryPrice: -1, DiscountPrice: 200}},
+			edit: true,
+			want: []string{"type", "shop_id", "price.factory_price", "price.discount_price"},
+		},
+	}
+	for i := range tests {
+		tt := tests[i]
+		t.Run(tt.name, func(t *testing.T) {
+			got := fieldNames(validateProduct(&tt.dto, tt.edit))
+			if len(tt.want) == 0 {
+				assert.Empty(t, got)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_validatePrice(t *testing.T) {
+	assert.Equal(t, []string{"product_id"},
+		fieldNames(validatePrice(&PriceDTO{SalePrice: 10, ProductID: "1"}, false)))
+	assert.Equal(t, []string{"id"},
+		fieldNames(validatePrice(&PriceDTO{SalePrice: 10}, true)))
+	assert.NoError(t, validatePrice(&PriceDTO{SalePrice: 10, ProductID: "9efd8091-67ef-4c97-bb35-7cdfb1680c59"}, false))
+	// Edits overwrite sale_price too, so an omitted one counts as 0.
+	assert.Equal(t, []string{"discount_price"},
+		fieldNames(validatePrice(&PriceDTO{ID: 1, DiscountPrice: 1600}, true)))
+	assert.Equal(t, []string{"discount_price"},
+		fieldNames(validatePrice(&PriceDTO{ID: 1, SalePrice: 1500, DiscountPrice: 1600}, true)))
+	assert.Equal(t, []string{"discount_price"},
+		fieldNames(validatePrice(&PriceDTO{DiscountPrice: 1600, ProductID: "9efd8091-67ef-4c97-bb35-7cdfb1680c59"}, false)))
+	assert.Equal(t, []string{"price.discount_price"},
+		fieldNames(validateProduct(&ProductDTO{SKU: "3001", Price: &PriceDTO{DiscountPrice: 1600}}, true)))
+}
+
+func Test_validateShopAndCategory(t *testing.T) {
+	assert.Equal(t, []string{"name", "address"}, fieldNames(validateShop(&model.Shop{}, false)))
+	assert.Equal(t, []string{"id"}, fieldNames(validateShop(&model.Shop{}, true)))
+	assert.Equal(t, []string{"name", "id"}, fieldNames(validateCategory(&model.Category{}, true)))
+}
+
+func Test_validateUser(t *testing.T) {
+	assert.Equal(t, []string{"password", "role"},
+		fieldNames(validateUser(&model.User{Login: "user7", Password: "123", Role: "EDITOR"}, true)))
+	assert.Equal(t, []string{"role"},
+		fieldNames(validateUser(&model.User{Login: "user7", Password: "user1password"}, true)))
+	assert.NoError(t, validateUser(&model.User{Login: "user7", Password: "user1password"}, false))
+	assert.Equal(t, []string{"role"}, fieldNames(validateUserRole(&model.User{Login: "user7", Role: "root"})))
+	assert.NoError(t, validateUserRole(&model.User{Login: "user7", Role: string(model.STAFF)}))
+	assert.Equal(t, []string{"login", "password"}, fieldNames(validateCredentials(&model.User{})))
+	assert.Equal(t, []string{"login", "shop_ids[1]"}, fieldNames(validateStaffShops(&StaffShopsDTO{ShopIDs: []int{1, -2}})))
+	assert.NoError(t, validateStaffShops(&StaffShopsDTO{Login: "user7", ShopIDs: []int{}}), "an empty list unassigns")
+}
+
+func Test_validatePromotion(t *testing.T) {
+	now := time.Now()
+	offer := model.Promotion{Name: "2+1", Type: model.PromotionBuyXGetY, StartsAt: now, EndsAt: now.Add(time.Hour),
+		ProductIDs: []string{"пушка"}}
+	assert.Equal(t, []string{"buy_quantity", "get_quantity", "id", "product_ids[0]"}, fieldNames(validatePromotion(&offer, true)))
+	offer.BuyQuantity, offer.GetQuantity, offer.ProductIDs = 2, 1, nil
+	assert.NoError(t, validatePromotion(&offer, false))
+	offer.EndsAt = now
+	assert.Equal(t, []string{"ends_at"}, fieldNames(validatePromotion(&offer, false)))
+	assert.Equal(t, []string{"name", "type", "starts_at", "ends_at"}, fieldNames(validatePromotion(&model.Promotion{}, false)))
+}
+
+func Test_validateBulkPrice(t *testing.T) {
+	assert.Equal(t, []string{"select"},
+		fieldNames(validateBulkPrice(&BulkPriceDTO{Rule: pricing.Rule{Op: pricing.Set, Value: 100}})))
+	assert.Equal(t, []string{"rule.op", "rule.round", "select.min_price"},
+		fieldNames(validateBulkPrice(&BulkPriceDTO{
+			Rule:   pricing.Rule{Op: "double", Round: "95"},
+			Select: model.PriceSelector{MinPrice: 500, MaxPrice: 100},
+		})))
+	assert.NoError(t, validateBulkPrice(&BulkPriceDTO{
+		Rule:   pricing.Rule{Op: pricing.Percent, Value: -100},
+		Select: model.PriceSelector{SKUs: []string{"3001"}},
+	}))
+}
+
+func Test_validateCoupon(t *testing.T) {
+	now := time.Now()
+	coupon := model.Coupon{Code: " spring-10 ", Type: model.CouponPercentage, Value: 150, UsageLimit: 5, PerUserLimit: 10,
+		StartsAt: now, EndsAt: now.Add(time.Hour)}
+	assert.Equal(t, []string{"code", "value", "per_user_limit"}, fieldNames(validateCoupon(&coupon, false)))
+	coupon.Code, coupon.Value, coupon.PerUserLimit = " spring10 ", 15, 1
+	assert.NoError(t, validateCoupon(&coupon, false))
+	assert.Equal(t, "SPRING10", coupon.Code)
+	assert.Equal(t, []string{"code", "type", "starts_at", "ends_at"}, fieldNames(validateCoupon(&model.Coupon{}, false)))
+}
+
+func Test_validateCouponCheck(t *testing.T) {
+	check := CouponCheckDTO{Code: "spring10", Items: []CouponCheckItem{{ProductID: "пушка"}, {ProductID: "2800d950-5c62-49e2-a705-c74ba77f57d0", Quantity: -1}}}
+	assert.Equal(t, []string{"items[0].product_id", "items[1].quantity"}, fieldNames(validateCouponCheck(&check)))
+	assert.Equal(t, 1, check.Items[0].Quantity, "quantity defaults to one")
+	assert.Equal(t, []string{"code", "items"}, fieldNames(validateCouponCheck(&CouponCheckDTO{})))
+}
+
+func Test_validateStock(t *testing.T) {
+	assert.Equal(t, []string{"shop_id", "product_id", "quantity"},
+		fieldNames(validateStock(model.Stock{ProductID: "пушка", Quantity: -1})))
+	assert.NoError(t, validateStock(model.Stock{ShopID: 1, ProductID: "2800d950-5c62-49e2-a705-c74ba77f57d0"}),
+		"a shop may run out")
+}
@@ -0,0 +1,157 @@
+package validation
+
+import (
+	"fmt"
+	"market4/internal/repository"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rule checks a single value and returns the reason it is invalid, or an
+// empty string when the value is fine. All rules except Required accept the
+// zero value, so optional fields only need Required to become mandatory.
+type Rule func(value interface{}) string
+
+type FieldRules struct {
+	Name  string
+	Value interface{}
+	Rules []Rule
+}
+
+func Field(name string, value interface{}, rules ...Rule) FieldRules {
+	return FieldRules{Name: name, Value: value, Rules: rules}
+}
+
+// Validate runs every rule of every field and reports all violations at once
+// as a repository validation error. The first failing rule of a field wins.
+func Validate(fields ...FieldRules) error {
+	var violations []repository.FieldError
+	for _, f := range fields {
+		for _, rule := range f.Rules {
+			if reason := rule(f.Value); reason != "" {
+				violations = append(violations, repository.FieldError{Field: f.Name, Reason: reason})
+				break
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return repository.NewValidationError(violations...)
+	}
+	return nil
+}
+
+// Prefix returns fields with their names nested under prefix, e.g. "price.sale_price".
+func Prefix(prefix string, fields ...FieldRules) []FieldRules {
+	nested := make([]FieldRules, 0, len(fields))
+	for _, f := range fields {
+		f.Name = prefix + "." + f.Name
+		nested = append(nested, f)
+	}
+	return nested
+}
+
+func isZero(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	return reflect.ValueOf(value).IsZero()
+}
+
+func Required(value interface{}) string {
+	if isZero(value) {
+		return "is mandatory"
+	}
+	if s, ok := value.(string); ok && strings.TrimSpace(s) == "" {
+		return "is mandatory"
+	}
+	return ""
+}
+
+// Optional does nothing; it stands in for Required where a field is only
+// mandatory for some operations.
+func Optional(value interface{}) string {
+	return ""
+}
+
+func Min(min int) Rule {
+	return func(value interface{}) string {
+		if n, ok := value.(int); ok && !isZero(n) && n < min {
+			return fmt.Sprintf("must be at least %d", min)
+		}
+		return ""
+	}
+}
+
+func Max(max int) Rule {
+	return func(value interface{}) string {
+		if n, ok := value.(int); ok && n > max {
+			return fmt.Sprintf("must be at most %d", max)
+		}
+		return ""
+	}
+}
+
+// NotAbove compares the value against another field of the same payload.
+func NotAbove(field string, limit int) Rule {
+	return func(value interface{}) string {
+		if n, ok := value.(int); ok && n > limit {
+			return fmt.Sprintf("must not exceed %s", field)
+		}
+		return ""
+	}
+}
+
+func MinLength(min int) Rule {
+	return func(value interface{}) string {
+		if s, ok := value.(string); ok && s != "" && utf8.RuneCountInString(s) < min {
+			return fmt.Sprintf("must be at least %d characters long", min)
+		}
+		return ""
+	}
+}
+
+func MaxLength(max int) Rule {
+	return func(value interface{}) string {
+		if s, ok := value.(string); ok && utf8.RuneCountInString(s) > max {
+			return fmt.Sprintf("must be at most %d characters long", max)
+		}
+		return ""
+	}
+}
+
+func OneOf(allowed ...string) Rule {
+	return func(value interface{}) string {
+		s, ok := value.(string)
+		if !ok || s == "" {
+			return ""
+		}
+		for _, a := range allowed {
+			if s == a {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(allowed, ", "))
+	}
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func UUID(value interface{}) string {
+	if s, ok := value.(string); ok && s != "" && !uuidPattern.MatchString(s) {
+		return "must be a valid UUID"
+	}
+	return ""
+}
+
+var wordPattern = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+
+// Word accepts letters, digits and underscores only. Product types go into
+// /product/{type}-{sku}, so a hyphen there would make the URI ambiguous.
+func Word(value interface{}) string {
+	if s, ok := value.(string); ok && s != "" && !wordPattern.MatchString(s) {
+		return "must contain only letters, digits and '_'"
+	}
+	return ""
+}
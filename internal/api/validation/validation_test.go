@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"errors"
+	"market4/internal/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Rules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  Rule
+		value interface{}
+		want  string
+	}{
+		{name: "required empty string", rule: Required, value: "", want: "is mandatory"},
+		{name: "required blank string", rule: Required, value: "  ", want: "is mandatory"},
+		{name: "required zero int", rule: Required, value: 0, want: "is mandatory"},
+		{name: "required nil", rule: Required, value: nil, want: "is mandatory"},
+		{name: "required present", rule: Required, value: "sku", want: ""},
+		{name: "optional empty", rule: Optional, value: "", want: ""},
+		{name: "min below", rule: Min(0), value: -1, want: "must be at least 0"},
+		{name: "min equal", rule: Min(1), value: 1, want: ""},
+		{name: "min skips zero", rule: Min(1), value: 0, want: ""},
+		{name: "max above", rule: Max(100), value: 101, want: "must be at most 100"},
+		{name: "max equal", rule: Max(100), value: 100, want: ""},
+		{name: "not above greater", rule: NotAbove("sale_price", 100), value: 101, want: "must not exceed sale_price"},
+		{name: "not above equal", rule: NotAbove("sale_price", 100), value: 100, want: ""},
+		{name: "min length short", rule: MinLength(6), value: "abc", want: "must be at least 6 characters long"},
+		{name: "min length skips empty", rule: MinLength(6), value: "", want: ""},
+		{name: "max length counts runes", rule: MaxLength(5), value: "пушка", want: ""},
+		{name: "max length long", rule: MaxLength(3), value: "пушка", want: "must be at most 3 characters long"},
+		{name: "one of unknown", rule: OneOf("ADMIN", "USER"), value: "EDITOR", want: "must be one of ADMIN, USER"},
+		{name: "one of known", rule: OneOf("ADMIN", "USER"), value: "USER", want: ""},
+		{name: "uuid invalid", rule: UUID, value: "not-a-uuid", want: "must be a valid UUID"},
+		{name: "uuid valid", rule: UUID, value: "9efd8091-67ef-4c97-bb35-7cdfb1680c59", want: ""},
+		{name: "word with hyphen", rule: Word, value: "тепловая-пушка", want: "must contain only letters, digits and '_'"},
+		{name: "word valid", rule: Word, value: "тепловая_2", want: ""},
+	}
+	for i := range tests {
+		tt := tests[i]
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.rule(tt.value))
+		})
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	err := Validate(
+		Field("sku", "", Required, MaxLength(3)),
+		Field("sale_price", -5, Min(0)),
+		Field("discount_price", 10, Min(0), NotAbove("sale_price", 5)),
+		Field("name", "ok", Required),
+	)
+	assert.True(t, errors.Is(err, repository.ErrValidation))
+
+	var domainErr *repository.Error
+	if assert.True(t, errors.As(err, &domainErr)) {
+		assert.Equal(t, []repository.FieldError{
+			{Field: "sku", Reason: "is mandatory"},
+			{Field: "sale_price", Reason: "must be at least 0"},
+			{Field: "discount_price", Reason: "must not exceed sale_price"},
+		}, domainErr.Fields)
+	}
+
+	assert.NoError(t, Validate(Field("name", "ok", Required)))
+}
+
+func Test_Prefix(t *testing.T) {
+	err := Validate(Prefix("price", Field("sale_price", -1, Min(0)))...)
+	var domainErr *repository.Error
+	if assert.True(t, errors.As(err, &domainErr)) {
+		assert.Equal(t, "price.sale_price", domainErr.Fields[0].Field)
+	}
+}
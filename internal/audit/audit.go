@@ -0,0 +1,72 @@
+// Package audit records who changed what. The repositories of shops,
+// categories, products, prices and users are wrapped so that every
+// successful write appends an entry with the caller from the JWT, the
+// request ID and the entity as it was before and after the change. Rows
+// removed by the archive purge are recorded too.
+//
+// An entry is written in the transaction of its change, so the two commit
+// or roll back together: a change that can't be recorded doesn't happen.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"market4/internal/api/auth"
+	"market4/internal/logging"
+	"market4/internal/model"
+	"market4/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// Recorder appends entries to the audit log.
+type Recorder struct {
+	repo repository.Audit
+	tx   repository.Transactor
+	lg   *zap.Logger
+}
+
+func New(repo repository.Audit, tx repository.Transactor, lg *zap.Logger) *Recorder {
+	return &Recorder{repo: repo, tx: tx, lg: lg}
+}
+
+// change runs fn, which makes a change and records it, in one transaction.
+func (r *Recorder) change(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.tx.InTx(ctx, fn)
+}
+
+// Record appends an entry for a change made in the request ctx belongs to,
+// in the transaction ctx carries. before and after are stored as JSON; nil
+// leaves them empty.
+func (r *Recorder) Record(ctx context.Context, action, entity, entityID string, before, after interface{}) error {
+	entry := model.AuditEntry{
+		Action:    action,
+		Entity:    entity,
+		EntityID:  entityID,
+		RequestID: logging.RequestID(ctx),
+	}
+	if payload, ok := auth.FromContext(ctx); ok {
+		entry.ActorID = payload.ID
+	}
+	var err error
+	if entry.Before, err = marshal(before); err == nil {
+		entry.After, err = marshal(after)
+	}
+	if err == nil {
+		_, err = r.repo.AddAuditEntry(ctx, entry)
+	}
+	if err != nil {
+		logging.FromContext(ctx, r.lg).Error("Audit", zap.String("action", action), zap.String("entity", entity),
+			zap.String("entity_id", entityID), zap.Error(err))
+		return fmt.Errorf("Record: %w", err)
+	}
+	return nil
+}
+
+func marshal(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"market4/internal/api/auth"
+	"market4/internal/model"
+	"market4/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type stubAudit struct {
+	entries []model.AuditEntry
+	err     error
+}
+
+func (s *stubAudit) AddAuditEntry(ctx context.Context, e model.AuditEntry) (model.AuditEntry, error) {
+	if ctx.Err() != nil {
+		return e, ctx.Err()
+	}
+	s.entries = append(s.entries, e)
+	return e, s.err
+}
+
+func (s *stubAudit) ListAuditEntries(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	return s.entries, nil
+}
+
+// stubTx stands in for a transaction; it counts how changes end.
+type stubTx struct {
+	committed, rolledBack int
+}
+
+func (s *stubTx) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := fn(ctx); err != nil {
+		s.rolledBack++
+		return err
+	}
+	s.committed++
+	return nil
+}
+
+type stubShops struct {
+	repository.Shop
+	shop model.Shop
+	err  error
+}
+
+func (s *stubShops) GetShopByID(ctx context.Context, shopID int) (model.Shop, error) {
+	return s.shop, nil
+}
+
+func (s *stubShops) EditShop(ctx context.Context, shop *model.Shop) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.shop = *shop
+	return nil
+}
+
+func Test_Record_ActorAndValues(t *testing.T) {
+	log := &stubAudit{}
+	shops := &stubShops{shop: model.Shop{ID: 1, Name: "old"}}
+	tx := &stubTx{}
+	repo := Shop(shops, New(log, tx, zap.NewNop()))
+	ctx := auth.NewContext(context.Background(), &auth.Payload{ID: 2})
+
+	require.NoError(t, repo.EditShop(ctx, &model.Shop{ID: 1, Name: "new"}))
+	require.Len(t, log.entries, 1)
+	e := log.entries[0]
+	assert.Equal(t, 2, e.ActorID)
+	assert.Equal(t, model.AuditUpdate, e.Action)
+	assert.Equal(t, model.AuditShop, e.Entity)
+	assert.Equal(t, "1", e.EntityID)
+	assert.Contains(t, string(e.Before), `"name":"old"`)
+	assert.Contains(t, string(e.After), `"name":"new"`)
+
+	shops.err = errors.New("conflict")
+	assert.Error(t, repo.EditShop(ctx, &model.Shop{ID: 1}))
+	assert.Len(t, log.entries, 1, "failed writes aren't recorded")
+	assert.Equal(t, stubTx{committed: 1, rolledBack: 1}, *tx)
+}
+
+type stubArchive struct {
+	report repository.PurgeReport
+}
+
+func (s stubArchive) Purge(ctx context.Context, before time.Time) (repository.PurgeReport, error) {
+	return s.report, nil
+}
+
+func Test_Purge_RecordsRows(t *testing.T) {
+	log := &stubAudit{}
+	report := repository.PurgeReport{Shops: 1, Rows: []repository.PurgedRow{
+		{Entity: model.AuditShop, ID: "2", Row: []byte(`{"id": 2, "name": "Магазин"}`)},
+	}}
+	got, err := Archive(stubArchive{report: report}, New(log, &stubTx{}, zap.NewNop())).Purge(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, report, got)
+	require.Len(t, log.entries, 1)
+	e := log.entries[0]
+	assert.Equal(t, model.AuditPurge, e.Action)
+	assert.Equal(t, model.AuditShop, e.Entity)
+	assert.Equal(t, "2", e.EntityID)
+	assert.JSONEq(t, `{"id": 2, "name": "Магазин"}`, string(e.Before))
+	assert.Nil(t, e.After)
+	assert.Zero(t, e.ActorID)
+}
+
+func Test_Record_FailureRollsBack(t *testing.T) {
+	tx := &stubTx{}
+	repo := Shop(&stubShops{}, New(&stubAudit{err: errors.New("connection refused")}, tx, zap.NewNop()))
+	assert.Error(t, repo.EditShop(context.Background(), &model.Shop{ID: 1, Name: "new"}),
+		"an unaudited change is reported")
+	assert.Equal(t, stubTx{rolledBack: 1}, *tx, "and undone with its entry")
+}
@@ -0,0 +1,429 @@
+package audit
+
+import (
+	"context"
+	"market4/internal/model"
+	"market4/internal/repository"
+	"strconv"
+	"time"
+)
+
+// found is the value to record for a lookup: nothing when it failed, e.g.
+// because the entity is archived and so invisible to the repositories.
+func found(v interface{}, err error) interface{} {
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+type shops struct {
+	repository.Shop
+	r *Recorder
+}
+
+// Shop records the changes made through next.
+func Shop(next repository.Shop, r *Recorder) repository.Shop {
+	return shops{Shop: next, r: r}
+}
+
+func (s shops) get(ctx context.Context, shopID int) interface{} {
+	return found(s.Shop.GetShopByID(ctx, shopID))
+}
+
+func (s shops) AddShop(ctx context.Context, shop *model.Shop) (id int, err error) {
+	err = s.r.change(ctx, func(ctx context.Context) error {
+		if id, err = s.Shop.AddShop(ctx, shop); err != nil {
+			return err
+		}
+		return s.r.Record(ctx, model.AuditCreate, model.AuditShop, strconv.Itoa(id), nil, s.get(ctx, id))
+	})
+	return id, err
+}
+
+func (s shops) EditShop(ctx context.Context, shop *model.Shop) error {
+	return s.r.change(ctx, func(ctx context.Context) error {
+		before := s.get(ctx, shop.ID)
+		if err := s.Shop.EditShop(ctx, shop); err != nil {
+			return err
+		}
+		return s.r.Record(ctx, model.AuditUpdate, model.AuditShop, strconv.Itoa(shop.ID), before, s.get(ctx, shop.ID))
+	})
+}
+
+func (s shops) DeleteShop(ctx context.Context, shopID int) error {
+	return s.r.change(ctx, func(ctx context.Context) error {
+		before := s.get(ctx, shopID)
+		if err := s.Shop.DeleteShop(ctx, shopID); err != nil {
+			return err
+		}
+		return s.r.Record(ctx, model.AuditDelete, model.AuditShop, strconv.Itoa(shopID), before, nil)
+	})
+}
+
+func (s shops) RestoreShop(ctx context.Context, shopID int) error {
+	return s.r.change(ctx, func(ctx context.Context) error {
+		if err := s.Shop.RestoreShop(ctx, shopID); err != nil {
+			return err
+		}
+		return s.r.Record(ctx, model.AuditRestore, model.AuditShop, strconv.Itoa(shopID), nil, s.get(ctx, shopID))
+	})
+}
+
+type categories struct {
+	repository.Category
+	r *Recorder
+}
+
+// Category records the changes made through next.
+func Category(next repository.Category, r *Recorder) repository.Category {
+	return categories{Category: next, r: r}
+}
+
+func (c categories) get(ctx context.Context, categoryID int) interface{} {
+	return found(c.Category.GetCategoryByID(ctx, categoryID))
+}
+
+func (c categories) AddCategory(ctx context.Context, category *model.Category) (id int, err error) {
+	err = c.r.change(ctx, func(ctx context.Context) error {
+		if id, err = c.Category.AddCategory(ctx, category); err != nil {
+			return err
+		}
+		return c.r.Record(ctx, model.AuditCreate, model.AuditCategory, strconv.Itoa(id), nil, c.get(ctx, id))
+	})
+	return id, err
+}
+
+func (c categories) EditCategory(ctx context.Context, category *model.Category) error {
+	return c.r.change(ctx, func(ctx context.Context) error {
+		before := c.get(ctx, category.ID)
+		if err := c.Category.EditCategory(ctx, category); err != nil {
+			return err
+		}
+		return c.r.Record(ctx, model.AuditUpdate, model.AuditCategory, strconv.Itoa(category.ID), before, c.get(ctx, category.ID))
+	})
+}
+
+func (c categories) DeleteCategory(ctx context.Context, categoryID int) error {
+	return c.r.change(ctx, func(ctx context.Context) error {
+		before := c.get(ctx, categoryID)
+		if err := c.Category.DeleteCategory(ctx, categoryID); err != nil {
+			return err
+		}
+		return c.r.Record(ctx, model.AuditDelete, model.AuditCategory, strconv.Itoa(categoryID), before, nil)
+	})
+}
+
+func (c categories) RestoreCategory(ctx context.Context, categoryID int) error {
+	return c.r.change(ctx, func(ctx context.Context) error {
+		if err := c.Category.RestoreCategory(ctx, categoryID); err != nil {
+			return err
+		}
+		return c.r.Record(ctx, model.AuditRestore, model.AuditCategory, strconv.Itoa(categoryID), nil, c.get(ctx, categoryID))
+	})
+}
+
+type products struct {
+	repository.Product
+	prices repository.Price
+	r      *Recorder
+}
+
+// Product records the changes made through next. prices is only read, to
+// show the prices that imports set along with the products.
+func Product(next repository.Product, prices repository.Price, r *Recorder) repository.Product {
+	return products{Product: next, prices: prices, r: r}
+}
+
+// imported is a product together with its live price, if any.
+type imported struct {
+	Product model.Product `json:"product"`
+	Price   *model.Price  `json:"price,omitempty"`
+}
+
+func (p products) imported(ctx context.Context, product model.Product, err error) interface{} {
+	if err != nil {
+		return nil
+	}
+	state := imported{Product: product}
+	if price, err := p.prices.SearchPriceByProductID(ctx, product.ID); err == nil {
+		state.Price = &price
+	}
+	return state
+}
+
+func (p products) get(ctx context.Context, productID string) interface{} {
+	return found(p.Product.GetProductByID(ctx, productID))
+}
+
+func (p products) AddProduct(ctx context.Context, product model.Product, shopID int, categoryID int) (added model.Product, err error) {
+	err = p.r.change(ctx, func(ctx context.Context) error {
+		if added, err = p.Product.AddProduct(ctx, product, shopID, categoryID); err != nil {
+			return err
+		}
+		return p.r.Record(ctx, model.AuditCreate, model.AuditProduct, added.ID, nil, added)
+	})
+	return added, err
+}
+
+func (p products) EditProduct(ctx context.Context, product model.Product, shopID int, categoryID int) (edited model.Product, err error) {
+	err = p.r.change(ctx, func(ctx context.Context) error {
+		before := p.get(ctx, product.ID)
+		if edited, err = p.Product.EditProduct(ctx, product, shopID, categoryID); err != nil {
+			return err
+		}
+		return p.r.Record(ctx, model.AuditUpdate, model.AuditProduct, edited.ID, before, edited)
+	})
+	return edited, err
+}
+
+func (p products) DeleteProduct(ctx context.Context, productID string) error {
+	return p.r.change(ctx, func(ctx context.Context) error {
+		before := p.get(ctx, productID)
+		if err := p.Product.DeleteProduct(ctx, productID); err != nil {
+			return err
+		}
+		return p.r.Record(ctx, model.AuditDelete, model.AuditProduct, productID, before, nil)
+	})
+}
+
+func (p products) RestoreProduct(ctx context.Context, productID string) error {
+	return p.r.change(ctx, func(ctx context.Context) error {
+		if err := p.Product.RestoreProduct(ctx, productID); err != nil {
+			return err
+		}
+		return p.r.Record(ctx, model.AuditRestore, model.AuditProduct, productID, nil, p.get(ctx, productID))
+	})
+}
+
+// UpsertProducts records one entry per imported row; a failed import
+// changes nothing and records nothing.
+func (p products) UpsertProducts(ctx context.Context, rows []model.ProductImport) (results []repository.UpsertResult, err error) {
+	err = p.r.change(ctx, func(ctx context.Context) error {
+		before := make([]interface{}, len(rows))
+		for i, row := range rows {
+			product, err := p.Product.GetProductBySKU(ctx, row.Product.SKU)
+			before[i] = p.imported(ctx, product, err)
+		}
+		if results, err = p.Product.UpsertProducts(ctx, rows); err != nil {
+			return err
+		}
+		for _, result := range results {
+			if result.Err != nil {
+				return nil
+			}
+		}
+		for i, result := range results {
+			action := model.AuditUpdate
+			if result.Created {
+				action = model.AuditCreate
+			}
+			product, err := p.Product.GetProductByID(ctx, result.ProductID)
+			err = p.r.Record(ctx, action, model.AuditProduct, result.ProductID, before[i], p.imported(ctx, product, err))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return results, err
+}
+
+type prices struct {
+	repository.Price
+	r *Recorder
+}
+
+// Price records the changes made through next.
+func Price(next repository.Price, r *Recorder) repository.Price {
+	return prices{Price: next, r: r}
+}
+
+func (p prices) get(ctx context.Context, priceID int) interface{} {
+	return found(p.Price.GetPriceByID(ctx, priceID))
+}
+
+func (p prices) AddPrice(ctx context.Context, price *model.Price) (added model.Price, err error) {
+	err = p.r.change(ctx, func(ctx context.Context) error {
+		if added, err = p.Price.AddPrice(ctx, price); err != nil {
+			return err
+		}
+		return p.r.Record(ctx, model.AuditCreate, model.AuditPrice, strconv.Itoa(added.ID), nil, added)
+	})
+	return added, err
+}
+
+func (p prices) EditPrice(ctx context.Context, price *model.Price) (edited model.Price, err error) {
+	err = p.r.change(ctx, func(ctx context.Context) error {
+		before := p.get(ctx, price.ID)
+		if edited, err = p.Price.EditPrice(ctx, price); err != nil {
+			return err
+		}
+		return p.r.Record(ctx, model.AuditUpdate, model.AuditPrice, strconv.Itoa(edited.ID), before, edited)
+	})
+	return edited, err
+}
+
+func (p prices) EditPriceByProductID(ctx context.Context, price *model.Price) (edited model.Price, err error) {
+	err = p.r.change(ctx, func(ctx context.Context) error {
+		before := found(p.Price.SearchPriceByProductID(ctx, price.ProductID))
+		if edited, err = p.Price.EditPriceByProductID(ctx, price); err != nil {
+			return err
+		}
+		return p.r.Record(ctx, model.AuditUpdate, model.AuditPrice, strconv.Itoa(edited.ID), before, edited)
+	})
+	return edited, err
+}
+
+func (p prices) DeletePrice(ctx context.Context, priceID int) error {
+	return p.r.change(ctx, func(ctx context.Context) error {
+		before := p.get(ctx, priceID)
+		if err := p.Price.DeletePrice(ctx, priceID); err != nil {
+			return err
+		}
+		return p.r.Record(ctx, model.AuditDelete, model.AuditPrice, strconv.Itoa(priceID), before, nil)
+	})
+}
+
+func (p prices) RestorePrice(ctx context.Context, priceID int) error {
+	return p.r.change(ctx, func(ctx context.Context) error {
+		if err := p.Price.RestorePrice(ctx, priceID); err != nil {
+			return err
+		}
+		return p.r.Record(ctx, model.AuditRestore, model.AuditPrice, strconv.Itoa(priceID), nil, p.get(ctx, priceID))
+	})
+}
+
+// ApplyPriceChanges records one entry per changed price. The batch already
+// knows the prices before and after, so nothing is read.
+func (p prices) ApplyPriceChanges(ctx context.Context, batch model.PriceBatch) (applied model.PriceBatch, err error) {
+	err = p.r.change(ctx, func(ctx context.Context) error {
+		if applied, err = p.Price.ApplyPriceChanges(ctx, batch); err != nil {
+			return err
+		}
+		for _, change := range applied.Changes {
+			err = p.r.Record(ctx, model.AuditUpdate, model.AuditPrice, strconv.Itoa(change.PriceID), change.Old, change.New)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+type users struct {
+	repository.Users
+	r *Recorder
+}
+
+// Users records the changes made through next. Passwords are never
+// recorded, only that one was set.
+func Users(next repository.Users, r *Recorder) repository.Users {
+	return users{Users: next, r: r}
+}
+
+// account is what the audit log shows of a user.
+type account struct {
+	ID    int      `json:"id"`
+	Login string   `json:"login"`
+	Roles []string `json:"roles"`
+	Shops []int    `json:"shops,omitempty"`
+}
+
+func (u users) get(ctx context.Context, login string) (string, interface{}) {
+	id, err := u.Users.GetUserID(ctx, login)
+	if err != nil || id == 0 {
+		return login, nil
+	}
+	roles, err := u.Users.GetUserRolesByID(ctx, id)
+	if err != nil {
+		return strconv.Itoa(id), nil
+	}
+	shops, err := u.Users.GetStaffShops(ctx, id)
+	if err != nil {
+		return strconv.Itoa(id), nil
+	}
+	return strconv.Itoa(id), account{ID: id, Login: login, Roles: roles, Shops: shops}
+}
+
+func (u users) AddUser(ctx context.Context, user *model.User) (added *model.User, err error) {
+	err = u.r.change(ctx, func(ctx context.Context) error {
+		if added, err = u.Users.AddUser(ctx, user); err != nil {
+			return err
+		}
+		id, after := u.get(ctx, user.Login)
+		return u.r.Record(ctx, model.AuditCreate, model.AuditUser, id, nil, after)
+	})
+	return added, err
+}
+
+func (u users) EditUser(ctx context.Context, user *model.User) (edited *model.User, err error) {
+	err = u.r.change(ctx, func(ctx context.Context) error {
+		if edited, err = u.Users.EditUser(ctx, user); err != nil {
+			return err
+		}
+		return u.r.Record(ctx, model.AuditSetPassword, model.AuditUser, strconv.Itoa(edited.ID), nil, nil)
+	})
+	return edited, err
+}
+
+func (u users) AddRole(ctx context.Context, login string, role string) error {
+	return u.r.change(ctx, func(ctx context.Context) error {
+		_, before := u.get(ctx, login)
+		if err := u.Users.AddRole(ctx, login, role); err != nil {
+			return err
+		}
+		id, after := u.get(ctx, login)
+		return u.r.Record(ctx, model.AuditGrantRole, model.AuditUser, id, before, after)
+	})
+}
+
+func (u users) RemoveRole(ctx context.Context, login string, role string) error {
+	return u.r.change(ctx, func(ctx context.Context) error {
+		_, before := u.get(ctx, login)
+		if err := u.Users.RemoveRole(ctx, login, role); err != nil {
+			return err
+		}
+		id, after := u.get(ctx, login)
+		return u.r.Record(ctx, model.AuditRevokeRole, model.AuditUser, id, before, after)
+	})
+}
+
+func (u users) SetStaffShops(ctx context.Context, login string, shopIDs []int) error {
+	return u.r.change(ctx, func(ctx context.Context) error {
+		_, before := u.get(ctx, login)
+		if err := u.Users.SetStaffShops(ctx, login, shopIDs); err != nil {
+			return err
+		}
+		id, after := u.get(ctx, login)
+		return u.r.Record(ctx, model.AuditSetShops, model.AuditUser, id, before, after)
+	})
+}
+
+type archive struct {
+	repository.Archive
+	r *Recorder
+}
+
+// Archive records the rows purged through next. Purges run without a
+// request, so their entries have neither actor nor request ID.
+func Archive(next repository.Archive, r *Recorder) repository.Archive {
+	return archive{Archive: next, r: r}
+}
+
+func (a archive) Purge(ctx context.Context, before time.Time) (report repository.PurgeReport, err error) {
+	err = a.r.change(ctx, func(ctx context.Context) error {
+		if report, err = a.Archive.Purge(ctx, before); err != nil {
+			return err
+		}
+		for _, row := range report.Rows {
+			err = a.r.Record(ctx, model.AuditPurge, row.Entity, row.ID, row.Row, nil)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return report, err
+}
@@ -1,6 +1,12 @@
 package cache
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrMiss is returned by FromCache when the key isn't cached.
+var ErrMiss = errors.New("cache miss")
 
 type Cache interface {
 	ToCache(ctx context.Context, key string, value []byte) (err error)
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
@@ -22,11 +24,78 @@ func NewRedisCache(pool *redis.Pool) Cache {
 func InitCache(addr string) *redis.Pool {
 	myCache := &redis.Pool{
 		DialContext: func(ctx context.Context) (redis.Conn, error) {
-			return redis.DialURL(addr)
+			// Dial within ctx, so a health check isn't stuck on a dead host.
+			return redis.DialURL(addr, redis.DialContextFunc(func(_ context.Context, network, address string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, address)
+			}))
 		},
 	}
 	return myCache
 }
+
+// Ping checks that Redis answers before ctx is done.
+func Ping(ctx context.Context, pool *redis.Pool) error {
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return fmt.Errorf("Ping: %w", err)
+	}
+	defer conn.Close()
+	timeout := time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+		if timeout <= 0 {
+			return fmt.Errorf("Ping: %w", context.DeadlineExceeded)
+		}
+	}
+	if _, err = redis.DoWithTimeout(conn, timeout, "PING"); err != nil {
+		return fmt.Errorf("Ping: %w", err)
+	}
+	return nil
+}
+
+// Flush deletes the keys of the database, except those starting with keep
+// when it isn't empty, and returns how many it deleted. It walks the keys
+// with SCAN, so Redis keeps serving while it runs.
+func Flush(ctx context.Context, pool *redis.Pool, keep string) (int, error) {
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("Flush: %w", err)
+	}
+	defer conn.Close()
+	deleted := 0
+	cursor := 0
+	for {
+		if err = ctx.Err(); err != nil {
+			return deleted, fmt.Errorf("Flush: %w", err)
+		}
+		values, err := redis.Values(redis.DoWithTimeout(conn, time.Second, "SCAN", cursor, "COUNT", 1000))
+		if err != nil {
+			return deleted, fmt.Errorf("Flush: %w", err)
+		}
+		var keys []string
+		if _, err = redis.Scan(values, &cursor, &keys); err != nil {
+			return deleted, fmt.Errorf("Flush: %w", err)
+		}
+		args := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			if keep == "" || !strings.HasPrefix(key, keep) {
+				args = append(args, key)
+			}
+		}
+		if len(args) > 0 {
+			n, err := redis.Int(redis.DoWithTimeout(conn, time.Second, "DEL", args...))
+			if err != nil {
+				return deleted, fmt.Errorf("Flush: %w", err)
+			}
+			deleted += n
+		}
+		if cursor == 0 {
+			return deleted, nil
+		}
+	}
+}
+
 func (a *apiCache) ToCache(ctx context.Context, key string, value []byte) (err error) {
 	conn, err := a.pool.GetContext(ctx)
 	if err != nil {
@@ -61,6 +130,9 @@ func (a *apiCache) FromCache(ctx context.Context, key string) (value []byte, err
 		return nil, fmt.Errorf("FromCache: %w", err)
 	}
 	value, err = redis.Bytes(reply, nil)
+	if err == redis.ErrNil {
+		return nil, fmt.Errorf("FromCache: %w", ErrMiss)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("FromCache: %w", err)
 	}
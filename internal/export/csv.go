@@ -0,0 +1,47 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"market4/internal/model"
+)
+
+type csvEncoder struct {
+	writer *csv.Writer
+	header bool
+}
+
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{writer: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) writeHeader() error {
+	if e.header {
+		return nil
+	}
+	e.header = true
+	return e.writer.Write(Columns)
+}
+
+func (e *csvEncoder) Encode(row model.ProductExport) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	cells := record(row)
+	values := make([]string, len(cells))
+	for i, cell := range cells {
+		if cell != nil {
+			values[i] = fmt.Sprint(cell)
+		}
+	}
+	return e.writer.Write(values)
+}
+
+func (e *csvEncoder) Close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
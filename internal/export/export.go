@@ -0,0 +1,108 @@
+// Package export encodes catalog rows as CSV, JSON Lines or XLSX while they
+// are read, without holding the whole catalog in memory.
+package export
+
+import (
+	"fmt"
+	"io"
+	"market4/internal/model"
+	"strings"
+)
+
+const (
+	CSV   = "csv"
+	JSONL = "jsonl"
+	XLSX  = "xlsx"
+)
+
+var Formats = []string{CSV, JSONL, XLSX}
+
+// Encoder writes rows one at a time. Close must be called after the last
+// row to complete the document.
+type Encoder interface {
+	Encode(row model.ProductExport) error
+	Close() error
+}
+
+func NewEncoder(format string, w io.Writer) (Encoder, error) {
+	switch format {
+	case CSV:
+		return newCSVEncoder(w), nil
+	case JSONL:
+		return newJSONLEncoder(w), nil
+	case XLSX:
+		return newXLSXEncoder(w)
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+func ContentType(format string) string {
+	switch format {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case JSONL:
+		return "application/x-ndjson"
+	case XLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
+
+// Columns are the CSV and XLSX headers, in the order of record.
+var Columns = []string{"id", "sku", "name", "type", "uri", "description", "is_active",
+	"shop_id", "shop_name", "category_id", "category_name",
+	"sale_price", "factory_price", "discount_price"}
+
+// record flattens a row into cells of type string, int, bool or nil; nil
+// marks an empty cell. Text is escaped for spreadsheets.
+func record(row model.ProductExport) []interface{} {
+	cells := []interface{}{
+		row.Product.ID,
+		Escape(row.Product.SKU),
+		Escape(row.Product.Name),
+		Escape(row.Product.Type),
+		Escape(row.Product.URI),
+		Escape(row.Product.Description),
+		row.Product.IsActive,
+		optional(row.Shop.ID),
+		Escape(row.Shop.Name),
+		optional(row.Category.ID),
+		Escape(row.Category.Name),
+		nil, nil, nil,
+	}
+	if row.Price != nil {
+		cells[11] = row.Price.SalePrice
+		cells[12] = row.Price.FactoryPrice
+		cells[13] = row.Price.DiscountPrice
+	}
+	return cells
+}
+
+func optional(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// formulaStart are the characters that make a spreadsheet evaluate a cell
+// as a formula.
+const formulaStart = "=+-@\t\r"
+
+// Escape keeps text from being run as a formula when a CSV or XLSX export
+// is opened in a spreadsheet: it gets a leading apostrophe, as spreadsheets
+// do themselves for text typed with one.
+func Escape(text string) string {
+	if text != "" && strings.IndexByte(formulaStart, text[0]) >= 0 {
+		return "'" + text
+	}
+	return text
+}
+
+// Unescape undoes Escape, so that an export can be imported back as is.
+func Unescape(text string) string {
+	if len(text) > 1 && text[0] == '\'' && strings.IndexByte(formulaStart, text[1]) >= 0 {
+		return text[1:]
+	}
+	return text
+}
@@ -0,0 +1,138 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"market4/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var rows = []model.ProductExport{
+	{
+		Product:  model.Product{ID: "a1", SKU: "3001", Name: "пушка", Type: "тепловая", URI: "/product/тепловая-3001", Description: `пушка "детская", <большая>`, IsActive: true},
+		Shop:     model.Shop{ID: 1, Name: "Магазин на диване"},
+		Category: model.Category{ID: 2, Name: "Игрушки"},
+		Price:    &model.Price{SalePrice: 2000, FactoryPrice: 1000, DiscountPrice: 1600},
+	},
+	{
+		Product: model.Product{ID: "a2", SKU: "3002", Name: "мяч", Type: "футбольный"},
+	},
+}
+
+func encode(t *testing.T, format string) []byte {
+	return encodeRows(t, format, rows...)
+}
+
+func encodeRows(t *testing.T, format string, rows ...model.ProductExport) []byte {
+	var buf bytes.Buffer
+	encoder, err := NewEncoder(format, &buf)
+	require.NoError(t, err)
+	for _, row := range rows {
+		require.NoError(t, encoder.Encode(row))
+	}
+	require.NoError(t, encoder.Close())
+	return buf.Bytes()
+}
+
+func Test_CSV(t *testing.T) {
+	records, err := csv.NewReader(bytes.NewReader(encode(t, CSV))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, Columns, records[0])
+	assert.Equal(t, []string{"a1", "3001", "пушка", "тепловая", "/product/тепловая-3001", `пушка "детская", <большая>`, "true",
+		"1", "Магазин на диване", "2", "Игрушки", "2000", "1000", "1600"}, records[1])
+	assert.Equal(t, []string{"a2", "3002", "мяч", "футбольный", "", "", "false", "", "", "", "", "", "", ""}, records[2])
+
+	var empty bytes.Buffer
+	encoder, err := NewEncoder(CSV, &empty)
+	require.NoError(t, err)
+	require.NoError(t, encoder.Close())
+	assert.Equal(t, "id,sku,name,type,uri,description,is_active,shop_id,shop_name,category_id,category_name,sale_price,factory_price,discount_price\n",
+		empty.String(), "an empty export still has the header")
+}
+
+func Test_JSONL(t *testing.T) {
+	lines := bytes.Split(bytes.TrimSpace(encode(t, JSONL)), []byte("\n"))
+	require.Len(t, lines, 2)
+	var first, second map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[0], &first))
+	require.NoError(t, json.Unmarshal(lines[1], &second))
+	assert.Equal(t, "Игрушки", first["category_name"])
+	assert.Equal(t, map[string]interface{}{"sale_price": 2000.0, "factory_price": 1000.0, "discount_price": 1600.0}, first["price"])
+	assert.NotContains(t, second, "price")
+	assert.NotContains(t, second, "shop_id")
+}
+
+func Test_XLSX(t *testing.T) {
+	data := encode(t, XLSX)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	parts := map[string][]byte{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		parts[file.Name], err = ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		reader.Close()
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"} {
+		assert.Contains(t, parts, name)
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	require.NoError(t, xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet))
+	require.Len(t, sheet.Rows, 3)
+	assert.Equal(t, "sku", sheet.Rows[0].Cells[1].Inline)
+	cells := sheet.Rows[1].Cells
+	require.Len(t, cells, len(Columns))
+	assert.Equal(t, `пушка "детская", <большая>`, cells[5].Inline)
+	assert.Equal(t, "b", cells[6].Type)
+	assert.Equal(t, "1", cells[6].Value)
+	assert.Equal(t, "2000", cells[11].Value)
+	assert.Equal(t, "", sheet.Rows[2].Cells[11].Value)
+}
+
+func Test_Escape(t *testing.T) {
+	for text, want := range map[string]string{
+		"=HYPERLINK(\"http://evil\")": "'=HYPERLINK(\"http://evil\")",
+		"+1":                          "'+1",
+		"-2+3":                        "'-2+3",
+		"@SUM(A1)":                    "'@SUM(A1)",
+		"\tcmd":                       "'\tcmd",
+		"пушка":                       "пушка",
+		"'quoted":                     "'quoted",
+		"":                            "",
+	} {
+		assert.Equal(t, want, Escape(text), text)
+		assert.Equal(t, text, Unescape(Escape(text)), text)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(encodeRows(t, CSV, model.ProductExport{
+		Product: model.Product{ID: "a3", SKU: "3003", Name: "=1+1", Description: "-скидка"},
+		Shop:    model.Shop{ID: 1, Name: "@магазин"},
+	}))).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, "'=1+1", records[1][2])
+	assert.Equal(t, "'-скидка", records[1][5])
+	assert.Equal(t, "'@магазин", records[1][8])
+}
+
+func Test_NewEncoder(t *testing.T) {
+	_, err := NewEncoder("pdf", &bytes.Buffer{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,68 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"market4/internal/model"
+)
+
+type jsonlPrice struct {
+	SalePrice     int `json:"sale_price"`
+	FactoryPrice  int `json:"factory_price"`
+	DiscountPrice int `json:"discount_price"`
+}
+
+type jsonlRow struct {
+	ID           string      `json:"id"`
+	SKU          string      `json:"sku"`
+	Name         string      `json:"name"`
+	Type         string      `json:"type"`
+	URI          string      `json:"uri"`
+	Description  string      `json:"description"`
+	IsActive     bool        `json:"is_active"`
+	ShopID       int         `json:"shop_id,omitempty"`
+	ShopName     string      `json:"shop_name,omitempty"`
+	CategoryID   int         `json:"category_id,omitempty"`
+	CategoryName string      `json:"category_name,omitempty"`
+	Price        *jsonlPrice `json:"price,omitempty"`
+}
+
+type jsonlEncoder struct {
+	encoder *json.Encoder
+}
+
+func newJSONLEncoder(w io.Writer) *jsonlEncoder {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &jsonlEncoder{encoder: encoder}
+}
+
+// Encode writes the row as one JSON object; json.Encoder ends it with the
+// newline that separates JSON Lines records.
+func (e *jsonlEncoder) Encode(row model.ProductExport) error {
+	line := jsonlRow{
+		ID:           row.Product.ID,
+		SKU:          row.Product.SKU,
+		Name:         row.Product.Name,
+		Type:         row.Product.Type,
+		URI:          row.Product.URI,
+		Description:  row.Product.Description,
+		IsActive:     row.Product.IsActive,
+		ShopID:       row.Shop.ID,
+		ShopName:     row.Shop.Name,
+		CategoryID:   row.Category.ID,
+		CategoryName: row.Category.Name,
+	}
+	if row.Price != nil {
+		line.Price = &jsonlPrice{
+			SalePrice:     row.Price.SalePrice,
+			FactoryPrice:  row.Price.FactoryPrice,
+			DiscountPrice: row.Price.DiscountPrice,
+		}
+	}
+	return e.encoder.Encode(line)
+}
+
+func (e *jsonlEncoder) Close() error {
+	return nil
+}
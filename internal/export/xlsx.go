@@ -0,0 +1,100 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"market4/internal/model"
+	"strconv"
+)
+
+// The smallest package Excel and LibreOffice accept: one workbook with one
+// sheet whose cells are inline strings, so no shared string table has to be
+// built in memory before the sheet can be written.
+var xlsxParts = []struct{ name, body string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="products" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+type xlsxEncoder struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+}
+
+func newXLSXEncoder(w io.Writer) (*xlsxEncoder, error) {
+	archive := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(file, part.body); err != nil {
+			return nil, err
+		}
+	}
+	file, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	e := &xlsxEncoder{archive: archive, sheet: bufio.NewWriter(file)}
+	e.sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	header := make([]interface{}, len(Columns))
+	for i, column := range Columns {
+		header[i] = column
+	}
+	return e, e.writeRow(header)
+}
+
+func (e *xlsxEncoder) Encode(row model.ProductExport) error {
+	return e.writeRow(record(row))
+}
+
+// writeRow relies on bufio.Writer keeping the first write error, which
+// writeRow and Close then report.
+func (e *xlsxEncoder) writeRow(cells []interface{}) error {
+	e.sheet.WriteString("<row>")
+	for _, cell := range cells {
+		switch v := cell.(type) {
+		case nil:
+			e.sheet.WriteString("<c/>")
+		case int:
+			e.sheet.WriteString("<c><v>" + strconv.Itoa(v) + "</v></c>")
+		case bool:
+			value := "0"
+			if v {
+				value = "1"
+			}
+			e.sheet.WriteString(`<c t="b"><v>` + value + "</v></c>")
+		case string:
+			e.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			if err := xml.EscapeText(e.sheet, []byte(v)); err != nil {
+				return err
+			}
+			e.sheet.WriteString("</t></is></c>")
+		}
+	}
+	_, err := e.sheet.WriteString("</row>")
+	return err
+}
+
+func (e *xlsxEncoder) Close() error {
+	e.sheet.WriteString("</sheetData></worksheet>")
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.archive.Close()
+}
@@ -0,0 +1,134 @@
+// Package health answers the load balancer. Liveness only says the process
+// serves HTTP; readiness also checks every dependency and turns false once
+// the service starts shutting down, so no new traffic is routed to it.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	StatusOK           = "ok"
+	StatusUnavailable  = "unavailable"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Check returns an error when a dependency can't be used. It must give up
+// when ctx is done.
+type Check func(ctx context.Context) error
+
+// Dependency is the outcome of one check.
+type Dependency struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status       string                `json:"status"`
+	Dependencies map[string]Dependency `json:"dependencies,omitempty"`
+}
+
+type named struct {
+	name  string
+	check Check
+}
+
+type Health struct {
+	checks       []named
+	timeout      time.Duration
+	shuttingDown int32
+	lg           *zap.Logger
+}
+
+// New returns a Health that gives each check up to timeout.
+func New(timeout time.Duration, lg *zap.Logger) *Health {
+	return &Health{timeout: timeout, lg: lg}
+}
+
+// Add registers a dependency. It is meant to be called before serving.
+func (h *Health) Add(name string, check Check) *Health {
+	h.checks = append(h.checks, named{name: name, check: check})
+	return h
+}
+
+// Shutdown makes the service report not ready from now on.
+func (h *Health) Shutdown() {
+	atomic.StoreInt32(&h.shuttingDown, 1)
+}
+
+// Check runs all checks at once and reports each of them.
+func (h *Health) Check(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Dependencies: make(map[string]Dependency, len(h.checks))}
+	results := make([]Dependency, len(h.checks))
+	var wg sync.WaitGroup
+	for i, c := range h.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+			start := time.Now()
+			err := check(checkCtx)
+			results[i] = Dependency{
+				Status:    StatusOK,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				results[i].Status = StatusUnavailable
+				results[i].Error = err.Error()
+			}
+		}(i, c.check)
+	}
+	wg.Wait()
+	for i, c := range h.checks {
+		report.Dependencies[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	if atomic.LoadInt32(&h.shuttingDown) == 1 {
+		report.Status = StatusShuttingDown
+	}
+	return report
+}
+
+// Live answers as long as the process can serve requests at all.
+func (h *Health) Live(writer http.ResponseWriter, request *http.Request) {
+	h.write(writer, http.StatusOK, Report{Status: StatusOK})
+}
+
+// Ready answers 200 when every dependency is up and 503 otherwise.
+func (h *Health) Ready(writer http.ResponseWriter, request *http.Request) {
+	report := h.Check(request.Context())
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+		failed := make([]string, 0)
+		for name, d := range report.Dependencies {
+			if d.Status != StatusOK {
+				failed = append(failed, name)
+			}
+		}
+		sort.Strings(failed)
+		h.lg.Warn("Ready", zap.String("status", report.Status), zap.Strings("failed", failed))
+	}
+	h.write(writer, status, report)
+}
+
+func (h *Health) write(writer http.ResponseWriter, status int, report Report) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.WriteHeader(status)
+	if err := json.NewEncoder(writer).Encode(report); err != nil {
+		h.lg.Error("Health", zap.Error(err))
+	}
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func up(ctx context.Context) error { return nil }
+
+func down(ctx context.Context) error { return errors.New("connection refused") }
+
+func hangs(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func ready(t *testing.T, h *Health) (int, Report) {
+	recorder := httptest.NewRecorder()
+	h.Ready(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report Report
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	return recorder.Code, report
+}
+
+func Test_Ready(t *testing.T) {
+	h := New(time.Second, zap.NewNop()).Add("postgres", up).Add("redis", up)
+	code, report := ready(t, h)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, StatusOK, report.Dependencies["postgres"].Status)
+	assert.Equal(t, StatusOK, report.Dependencies["redis"].Status)
+}
+
+func Test_Ready_DependencyDown(t *testing.T) {
+	h := New(time.Second, zap.NewNop()).Add("postgres", up).Add("redis", down)
+	code, report := ready(t, h)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, StatusOK, report.Dependencies["postgres"].Status)
+	assert.Equal(t, Dependency{Status: StatusUnavailable, LatencyMS: report.Dependencies["redis"].LatencyMS,
+		Error: "connection refused"}, report.Dependencies["redis"])
+}
+
+func Test_Ready_Timeout(t *testing.T) {
+	h := New(20*time.Millisecond, zap.NewNop()).Add("postgres", hangs)
+	start := time.Now()
+	code, report := ready(t, h)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second), "checks are cut off")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Dependencies["postgres"].Error)
+	assert.GreaterOrEqual(t, report.Dependencies["postgres"].LatencyMS, 20.0)
+}
+
+func Test_Ready_ShuttingDown(t *testing.T) {
+	h := New(time.Second, zap.NewNop()).Add("postgres", up)
+	h.Shutdown()
+	code, report := ready(t, h)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusShuttingDown, report.Status)
+
+	recorder := httptest.NewRecorder()
+	h.Live(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code, "a draining process is still alive")
+}
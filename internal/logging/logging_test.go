@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"context"
+	"market4/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func Test_Middleware_AccessLog(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	router := chi.NewRouter()
+	router.Use(Middleware(zap.New(core)))
+	router.Get("/orders/{orderID}", func(writer http.ResponseWriter, request *http.Request) {
+		SetUser(request.Context(), 7)
+		FromContext(request.Context(), nil).Info("GetOrder")
+		writer.WriteHeader(http.StatusInternalServerError)
+	})
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/orders/3", nil)
+	request.Header.Set(RequestIDHeader, "abc-123")
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, "abc-123", recorder.Header().Get(RequestIDHeader))
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 2)
+	handler, access := entries[0].ContextMap(), entries[1].ContextMap()
+	assert.Equal(t, "abc-123", handler["request_id"])
+	assert.Equal(t, int64(7), handler["user_id"])
+
+	assert.Equal(t, "request", entries[1].Message)
+	assert.Equal(t, zapcore.ErrorLevel, entries[1].Level, "server errors stand out")
+	assert.Equal(t, "abc-123", access["request_id"])
+	assert.Equal(t, int64(7), access["user_id"], "the access log sees the user authenticated further in")
+	assert.Equal(t, "/orders/{orderID}", access["route"])
+	assert.Equal(t, "/orders/3", access["path"])
+	assert.Equal(t, int64(http.StatusInternalServerError), access["status"])
+}
+
+func Test_RequestID(t *testing.T) {
+	assert.Equal(t, "f00d.1_A", requestID("f00d.1_A"))
+	for _, given := range []string{"", "bad id", "evil\r\nheader", strings.Repeat("a", maxRequestIDLength+1)} {
+		id := requestID(given)
+		assert.Len(t, id, 32, "%q is replaced", given)
+	}
+	assert.NotEqual(t, requestID(""), requestID(""))
+}
+
+func Test_FromContext_Fallback(t *testing.T) {
+	fallback := zap.NewNop()
+	assert.Same(t, fallback, FromContext(context.Background(), fallback))
+	assert.Equal(t, "", RequestID(context.Background()))
+	assert.NotPanics(t, func() { SetUser(context.Background(), 1) })
+}
+
+func Test_New(t *testing.T) {
+	lg, err := New(config.Log{Level: "warn", Format: "console"})
+	require.NoError(t, err)
+	assert.False(t, lg.Core().Enabled(zapcore.InfoLevel))
+	assert.True(t, lg.Core().Enabled(zapcore.WarnLevel))
+
+	_, err = New(config.Log{Level: "loud", Format: "json"})
+	assert.Error(t, err)
+}
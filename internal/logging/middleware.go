@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 64
+
+// Middleware gives the request an ID and a logger carrying it, and writes
+// one access log line once the request is served. Server errors are logged
+// at error level, everything else at info.
+func Middleware(lg *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			started := time.Now()
+			e := &entry{id: requestID(request.Header.Get(RequestIDHeader))}
+			fields := []zap.Field{zap.String("request_id", e.id)}
+			if span := trace.SpanContextFromContext(request.Context()); span.IsValid() {
+				fields = append(fields, zap.String("trace_id", span.TraceID().String()))
+			}
+			e.lg = lg.With(fields...)
+			writer.Header().Set(RequestIDHeader, e.id)
+
+			ww := middleware.NewWrapResponseWriter(writer, request.ProtoMajor)
+			// Deferred so that aborted responses are logged as well.
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				level := zapcore.InfoLevel
+				if status >= http.StatusInternalServerError {
+					level = zapcore.ErrorLevel
+				}
+				route := ""
+				if rctx := chi.RouteContext(request.Context()); rctx != nil {
+					route = rctx.RoutePattern()
+				}
+				if ce := e.lg.Check(level, "request"); ce != nil {
+					ce.Write(
+						zap.String("method", request.Method),
+						zap.String("path", request.URL.Path),
+						zap.String("route", route),
+						zap.Int("status", status),
+						zap.Int("bytes", ww.BytesWritten()),
+						zap.Duration("duration", time.Since(started)),
+						zap.String("remote", request.RemoteAddr),
+						zap.String("user_agent", request.UserAgent()),
+					)
+				}
+			}()
+			next.ServeHTTP(ww, request.WithContext(context.WithValue(request.Context(), entryKey{}, e)))
+		})
+	}
+}
+
+// requestID keeps the caller's ID when it is short and plain enough to be
+// logged and echoed safely, and makes a new one otherwise.
+func requestID(given string) string {
+	if given != "" && len(given) <= maxRequestIDLength {
+		plain := true
+		for _, r := range given {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+				plain = false
+				break
+			}
+		}
+		if plain {
+			return given
+		}
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"market4/internal/cache"
+)
+
+type countingCache struct {
+	next cache.Cache
+	m    *Metrics
+}
+
+// Cache wraps next and counts its hits, misses and errors.
+func (m *Metrics) Cache(next cache.Cache) cache.Cache {
+	return &countingCache{next: next, m: m}
+}
+
+func (c *countingCache) ToCache(ctx context.Context, key string, value []byte) error {
+	err := c.next.ToCache(ctx, key, value)
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	c.m.cacheOps.WithLabelValues("set", result).Inc()
+	return err
+}
+
+func (c *countingCache) FromCache(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.next.FromCache(ctx, key)
+	result := "hit"
+	switch {
+	case errors.Is(err, cache.ErrMiss), err == nil && value == nil:
+		result = "miss"
+	case err != nil:
+		result = "error"
+	}
+	c.m.cacheOps.WithLabelValues("get", result).Inc()
+	return value, err
+}
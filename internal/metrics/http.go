@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatched labels requests that no route matched, so that scanners
+// probing random paths can't blow up the number of series.
+const unmatched = "unmatched"
+
+// Middleware counts requests and measures their latency. It labels them
+// with the chi route pattern, e.g. /api/v1/products/{productID}, rather
+// than the path, so it must wrap the router the routes are mounted on.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(writer, request.ProtoMajor)
+		next.ServeHTTP(ww, request)
+
+		route := unmatched
+		if rctx := chi.RouteContext(request.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		m.requests.WithLabelValues(request.Method, route, strconv.Itoa(status)).Inc()
+		m.duration.WithLabelValues(request.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,115 @@
+// Package metrics exposes the service's Prometheus metrics: HTTP traffic
+// per route, the database pool, the cache and a few business counters. All
+// of them live in the registry of a Metrics value rather than the global
+// one, so tests can build as many as they like.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "market"
+
+// Sources of created products and changed prices.
+const (
+	SourceAPI    = "api"
+	SourceImport = "import"
+	SourceBulk   = "bulk"
+)
+
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	duration        *prometheus.HistogramVec
+	cacheOps        *prometheus.CounterVec
+	productsCreated *prometheus.CounterVec
+	pricesChanged   *prometheus.CounterVec
+	logins          *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by method, route pattern and status.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		cacheOps: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "operations_total",
+			Help:      "Cache reads by result (hit, miss, error) and writes by result (ok, error).",
+		}, []string{"operation", "result"}),
+		productsCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "products_created_total",
+			Help:      "Products created, by source (api, import).",
+		}, []string{"source"}),
+		pricesChanged: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "price_changes_total",
+			Help:      "Prices added or changed, by source (api, bulk).",
+		}, []string{"source"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Token requests by result (succeeded, failed).",
+		}, []string{"result"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.duration, m.cacheOps,
+		m.productsCreated, m.pricesChanged, m.logins,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// The business counters below may be called on a nil *Metrics, which
+// counts nothing, so controllers built without metrics keep working.
+
+// ProductsCreated counts n new products.
+func (m *Metrics) ProductsCreated(source string, n int) {
+	if m == nil || n == 0 {
+		return
+	}
+	m.productsCreated.WithLabelValues(source).Add(float64(n))
+}
+
+// PricesChanged counts n added or changed prices.
+func (m *Metrics) PricesChanged(source string, n int) {
+	if m == nil || n == 0 {
+		return
+	}
+	m.pricesChanged.WithLabelValues(source).Add(float64(n))
+}
+
+// Login counts a token request.
+func (m *Metrics) Login(succeeded bool) {
+	if m == nil {
+		return
+	}
+	result := "failed"
+	if succeeded {
+		result = "succeeded"
+	}
+	m.logins.WithLabelValues(result).Inc()
+}
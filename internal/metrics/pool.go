@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// poolCollector reads the pool statistics on every scrape instead of
+// keeping gauges up to date.
+type poolCollector struct {
+	stat func() *pgxpool.Stat
+
+	acquiredConns     *prometheus.Desc
+	idleConns         *prometheus.Desc
+	constructingConns *prometheus.Desc
+	totalConns        *prometheus.Desc
+	maxConns          *prometheus.Desc
+	acquires          *prometheus.Desc
+	acquireSeconds    *prometheus.Desc
+	canceledAcquires  *prometheus.Desc
+	emptyAcquires     *prometheus.Desc
+}
+
+func poolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+}
+
+// RegisterPool exports the statistics of the database pool.
+func (m *Metrics) RegisterPool(pool *pgxpool.Pool) error {
+	return m.registry.Register(newPoolCollector(pool.Stat))
+}
+
+func newPoolCollector(stat func() *pgxpool.Stat) *poolCollector {
+	return &poolCollector{
+		stat:              stat,
+		acquiredConns:     poolDesc("acquired_conns", "Connections in use."),
+		idleConns:         poolDesc("idle_conns", "Idle connections."),
+		constructingConns: poolDesc("constructing_conns", "Connections being opened."),
+		totalConns:        poolDesc("total_conns", "All connections of the pool."),
+		maxConns:          poolDesc("max_conns", "The most connections the pool opens."),
+		acquires:          poolDesc("acquires_total", "Connections handed out."),
+		acquireSeconds:    poolDesc("acquire_seconds_total", "Time spent waiting for connections."),
+		canceledAcquires:  poolDesc("canceled_acquires_total", "Waits for a connection given up by the caller."),
+		emptyAcquires:     poolDesc("empty_acquires_total", "Connections handed out after waiting because none was idle."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+	gauge(c.acquiredConns, float64(s.AcquiredConns()))
+	gauge(c.idleConns, float64(s.IdleConns()))
+	gauge(c.constructingConns, float64(s.ConstructingConns()))
+	gauge(c.totalConns, float64(s.TotalConns()))
+	gauge(c.maxConns, float64(s.MaxConns()))
+	counter(c.acquires, float64(s.AcquireCount()))
+	counter(c.acquireSeconds, s.AcquireDuration().Seconds())
+	counter(c.canceledAcquires, float64(s.CanceledAcquireCount()))
+	counter(c.emptyAcquires, float64(s.EmptyAcquireCount()))
+}
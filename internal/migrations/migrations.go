@@ -0,0 +1,255 @@
+// Package migrations evolves the database schema with the SQL files
+// embedded in the binary. Every migration is a pair of files,
+// NNNN_name.up.sql and NNNN_name.down.sql, applied in version order. The
+// applied versions are kept in schema_migrations, and an advisory lock makes
+// replicas that start together wait for each other instead of racing.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.uber.org/zap"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID is the advisory lock key: "market4" in ASCII.
+const lockID = 0x6d61726b657434
+
+const createTable = "CREATE TABLE IF NOT EXISTS schema_migrations (" +
+	"version BIGINT PRIMARY KEY, " +
+	"name TEXT NOT NULL, " +
+	"applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// State is a migration and when it was applied; AppliedAt is nil for a
+// pending one.
+type State struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// parse reads the migrations in dir of fsys. Every version must have both
+// files and one name.
+func parse(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migrations: unexpected file %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		body, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migrations: version %d is both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migrations: %04d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// pending returns the migrations that aren't applied yet, oldest first.
+func pending(migrations []Migration, applied map[int]time.Time) []Migration {
+	var result []Migration
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// rollback returns up to steps applied migrations, newest first. An applied
+// version the binary doesn't know can't be rolled back and stops the way.
+func rollback(migrations []Migration, applied map[int]time.Time, steps int) ([]Migration, error) {
+	known := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+	versions := make([]int, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	var result []Migration
+	for _, v := range versions {
+		if len(result) == steps {
+			break
+		}
+		m, ok := known[v]
+		if !ok {
+			return nil, fmt.Errorf("migrations: version %d is applied but unknown to this binary", v)
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+	lg         *zap.Logger
+}
+
+// New returns a migrator for the migrations embedded in the binary.
+func New(pool *pgxpool.Pool, lg *zap.Logger) (*Migrator, error) {
+	migrations, err := parse(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{pool: pool, migrations: migrations, lg: lg}, nil
+}
+
+// Up applies every pending migration, each in its own transaction, and
+// returns the applied ones.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn, applied map[int]time.Time) error {
+		for _, migration := range pending(m.migrations, applied) {
+			err := conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)",
+					migration.Version, migration.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("%04d_%s up: %w", migration.Version, migration.Name, err)
+			}
+			m.lg.Info("Migrate", zap.Int("version", migration.Version), zap.String("name", migration.Name), zap.String("direction", "up"))
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the steps newest migrations and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn, applied map[int]time.Time) error {
+		migrations, err := rollback(m.migrations, applied, steps)
+		if err != nil {
+			return err
+		}
+		for _, migration := range migrations {
+			err = conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("%04d_%s down: %w", migration.Version, migration.Name, err)
+			}
+			m.lg.Info("Migrate", zap.Int("version", migration.Version), zap.String("name", migration.Name), zap.String("direction", "down"))
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists every known migration with the moment it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]State, error) {
+	var states []State
+	err := m.locked(ctx, func(conn *pgxpool.Conn, applied map[int]time.Time) error {
+		for _, migration := range m.migrations {
+			state := State{Migration: migration}
+			if at, ok := applied[migration.Version]; ok {
+				state.AppliedAt = &at
+			}
+			states = append(states, state)
+		}
+		return nil
+	})
+	return states, err
+}
+
+// locked runs fn on one connection that holds the advisory lock. The lock
+// belongs to the session, so it has to be taken and released on the same
+// connection, and it is released even if fn fails half way.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn, applied map[int]time.Time) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	if _, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", int64(lockID)); err != nil {
+		return err
+	}
+	defer func() {
+		// The context may be cancelled by now; the lock must go anyway.
+		if _, uerr := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", int64(lockID)); uerr != nil {
+			m.lg.Error("Migrate", zap.Error(uerr))
+		}
+	}()
+
+	if _, err = conn.Exec(ctx, createTable); err != nil {
+		return err
+	}
+	applied := make(map[int]time.Time)
+	rows, err := conn.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err = rows.Scan(&version, &at); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[version] = at
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	return fn(conn, applied)
+}
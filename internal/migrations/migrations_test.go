@@ -0,0 +1,80 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_EmbeddedMigrations(t *testing.T) {
+	migrations, err := parse(files, "sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version, "versions have no gaps")
+	}
+	assert.Equal(t, "initial", migrations[0].Name)
+	assert.True(t, strings.Contains(migrations[0].Up, "CREATE TABLE IF NOT EXISTS products"))
+	assert.False(t, strings.Contains(migrations[0].Up, "deleted_at"), "the baseline is the schema before migrations")
+	for _, m := range migrations {
+		assert.False(t, strings.Contains(m.Up, "INSERT\nINTO users"), "%s: demo users belong to the dev seed", m.Name)
+	}
+}
+
+func Test_parse(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_stock.up.sql":     {Data: []byte("CREATE TABLE stock ();")},
+		"sql/0002_stock.down.sql":   {Data: []byte("DROP TABLE stock;")},
+		"sql/0001_initial.up.sql":   {Data: []byte("CREATE TABLE orders ();")},
+		"sql/0001_initial.down.sql": {Data: []byte("DROP TABLE orders;")},
+	}
+	migrations, err := parse(fsys, "sql")
+	require.NoError(t, err)
+	assert.Equal(t, []Migration{
+		{Version: 1, Name: "initial", Up: "CREATE TABLE orders ();", Down: "DROP TABLE orders;"},
+		{Version: 2, Name: "stock", Up: "CREATE TABLE stock ();", Down: "DROP TABLE stock;"},
+	}, migrations)
+
+	tests := []struct {
+		name  string
+		fsys  fstest.MapFS
+		error string
+	}{
+		{"no down", fstest.MapFS{"sql/0001_initial.up.sql": {Data: []byte("SELECT 1;")}}, "needs both"},
+		{"two names", fstest.MapFS{
+			"sql/0001_initial.up.sql": {Data: []byte("SELECT 1;")},
+			"sql/0001_other.down.sql": {Data: []byte("SELECT 1;")},
+		}, "is both"},
+		{"stray file", fstest.MapFS{"sql/README.md": {Data: []byte("")}}, "unexpected file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(tt.fsys, "sql")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.error)
+		})
+	}
+}
+
+func Test_pendingAndRollback(t *testing.T) {
+	migrations := []Migration{{Version: 1, Name: "initial"}, {Version: 2, Name: "stock"}, {Version: 3, Name: "payments"}}
+	now := time.Now()
+	applied := map[int]time.Time{1: now, 2: now}
+
+	assert.Equal(t, []Migration{{Version: 3, Name: "payments"}}, pending(migrations, applied))
+	assert.Empty(t, pending(migrations, map[int]time.Time{1: now, 2: now, 3: now}))
+
+	back, err := rollback(migrations, applied, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []Migration{{Version: 2, Name: "stock"}}, back, "the newest goes first")
+	back, err = rollback(migrations, applied, 10)
+	require.NoError(t, err)
+	assert.Len(t, back, 2)
+
+	_, err = rollback(migrations, map[int]time.Time{1: now, 4: now}, 1)
+	assert.Error(t, err, "a newer binary applied version 4")
+}
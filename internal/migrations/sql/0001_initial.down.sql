@@ -0,0 +1 @@
+DROP TABLE IF EXISTS userroles, users, roles, productshop, shops, productcategory, categories, prices, products;
@@ -1,6 +1,9 @@
-CREATE EXTENSION pgcrypto;
+-- схема, с которой сервис жил до миграций; IF NOT EXISTS позволяет принять
+-- базу, созданную прежним 00_schema.sql, а всё, что появилось позже, добавляют
+-- следующие миграции
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
 -- товары
-CREATE TABLE products
+CREATE TABLE IF NOT EXISTS products
 (
     id          UUID DEFAULT gen_random_uuid() PRIMARY KEY,
     sku         TEXT NOT NULL,
@@ -12,7 +15,7 @@ CREATE TABLE products
     updated  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE TABLE prices
+CREATE TABLE IF NOT EXISTS prices
 (
     id              BIGSERIAL PRIMARY KEY,
     sale_price      INTEGER NOT NULL,
@@ -24,7 +27,7 @@ CREATE TABLE prices
     updated         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE TABLE categories
+CREATE TABLE IF NOT EXISTS categories
 (
     id          BIGSERIAL PRIMARY KEY,
     name        TEXT NOT NULL UNIQUE,
@@ -33,14 +36,14 @@ CREATE TABLE categories
     updated     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE TABLE productcategory
+CREATE TABLE IF NOT EXISTS productcategory
 (
     category_id  BIGINT NOT NULL REFERENCES categories,
     product_id UUID NOT NULL REFERENCES products,
     PRIMARY KEY (category_id, product_id)
 );
 
-CREATE TABLE shops
+CREATE TABLE IF NOT EXISTS shops
 (
     id              BIGSERIAL PRIMARY KEY,
     name            TEXT NOT NULL,
@@ -52,36 +55,36 @@ CREATE TABLE shops
     updated         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE TABLE productshop
+CREATE TABLE IF NOT EXISTS productshop
 (
     shop_id BIGINT NOT NULL REFERENCES shops,
     product_id UUID NOT NULL REFERENCES products,
     PRIMARY KEY (shop_id, product_id)
 );
 
--- CREATE TABLE presence
--- (
---     shop_id BIGINT NOT NULL REFERENCES shops,
---     product_id UUID NOT NULL REFERENCES products,
---     PRIMARY KEY (shop_id, product_id)
--- );
-
-CREATE TABLE roles
+CREATE TABLE IF NOT EXISTS roles
 (
     id BIGSERIAL PRIMARY KEY,
     name TEXT NOT NULL UNIQUE
 );
 
-CREATE TABLE users
+CREATE TABLE IF NOT EXISTS users
 (
     id BIGSERIAL PRIMARY KEY,
     login TEXT NOT NULL UNIQUE,
     password TEXT NOT NULL
 );
 
-CREATE TABLE userroles
+CREATE TABLE IF NOT EXISTS userroles
 (
     user_id BIGINT NOT NULL REFERENCES users,
     role_id BIGINT NOT NULL REFERENCES roles,
     PRIMARY KEY (user_id, role_id)
 );
+
+-- роли — справочник, без них не работает авторизация
+INSERT
+INTO roles (name)
+VALUES ('USER'),
+       ('ADMIN')
+ON CONFLICT (name) DO NOTHING;
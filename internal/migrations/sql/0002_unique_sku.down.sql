@@ -0,0 +1 @@
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_sku_key;
@@ -0,0 +1,10 @@
+-- артикул однозначно определяет товар; ограничение называется так же, как
+-- его назвал бы UNIQUE в CREATE TABLE, поэтому повторно не создаётся
+DO
+$$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'products_sku_key') THEN
+        ALTER TABLE products ADD CONSTRAINT products_sku_key UNIQUE (sku);
+    END IF;
+END;
+$$;
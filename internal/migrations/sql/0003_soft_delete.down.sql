@@ -0,0 +1,4 @@
+ALTER TABLE shops DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE categories DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE prices DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE products DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,5 @@
+-- мягкое удаление: архивные записи помечены deleted_at и скрыты из выдачи
+ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE prices ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE shops ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
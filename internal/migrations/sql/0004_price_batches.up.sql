@@ -0,0 +1,21 @@
+-- массовые изменения цен: кто, каким правилом и что было до и после
+CREATE TABLE IF NOT EXISTS price_batches
+(
+    id          BIGSERIAL PRIMARY KEY,
+    changed_by  BIGINT NOT NULL,
+    rule        JSONB NOT NULL,
+    created     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS price_history
+(
+    batch_id            BIGINT NOT NULL REFERENCES price_batches,
+    price_id            BIGINT NOT NULL,
+    old_sale_price      INTEGER NOT NULL,
+    old_factory_price   INTEGER NOT NULL,
+    old_discount_price  INTEGER NOT NULL,
+    sale_price          INTEGER NOT NULL,
+    factory_price       INTEGER NOT NULL,
+    discount_price      INTEGER NOT NULL,
+    PRIMARY KEY (batch_id, price_id)
+);
@@ -0,0 +1,20 @@
+-- акции: пустые списки целей означают весь каталог
+CREATE TABLE IF NOT EXISTS promotions
+(
+    id              BIGSERIAL PRIMARY KEY,
+    name            TEXT NOT NULL,
+    type            TEXT NOT NULL CHECK (type IN ('percentage', 'fixed', 'buy_x_get_y')),
+    value           INTEGER NOT NULL DEFAULT 0,
+    buy_quantity    INTEGER NOT NULL DEFAULT 0,
+    get_quantity    INTEGER NOT NULL DEFAULT 0,
+    priority        INTEGER NOT NULL DEFAULT 0,
+    stackable       BOOL NOT NULL DEFAULT false,
+    starts_at       TIMESTAMPTZ NOT NULL,
+    ends_at         TIMESTAMPTZ NOT NULL CHECK (ends_at > starts_at),
+    category_ids    BIGINT[] NOT NULL DEFAULT '{}',
+    shop_ids        BIGINT[] NOT NULL DEFAULT '{}',
+    product_ids     UUID[] NOT NULL DEFAULT '{}',
+    is_active       BOOL NOT NULL DEFAULT true,
+    created         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS coupon_redemptions, coupons;
@@ -0,0 +1,25 @@
+-- купоны: код хранится в верхнем регистре, пустой список категорий означает весь каталог
+CREATE TABLE IF NOT EXISTS coupons
+(
+    id              BIGSERIAL PRIMARY KEY,
+    code            TEXT NOT NULL UNIQUE,
+    type            TEXT NOT NULL CHECK (type IN ('percentage', 'fixed')),
+    value           INTEGER NOT NULL,
+    min_order       INTEGER NOT NULL DEFAULT 0,
+    usage_limit     INTEGER NOT NULL DEFAULT 0,
+    per_user_limit  INTEGER NOT NULL DEFAULT 0,
+    starts_at       TIMESTAMPTZ NOT NULL,
+    ends_at         TIMESTAMPTZ NOT NULL CHECK (ends_at > starts_at),
+    category_ids    BIGINT[] NOT NULL DEFAULT '{}',
+    is_active       BOOL NOT NULL DEFAULT true,
+    created         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS coupon_redemptions
+(
+    id          BIGSERIAL PRIMARY KEY,
+    coupon_id   BIGINT NOT NULL REFERENCES coupons,
+    user_id     BIGINT NOT NULL,
+    created     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,19 @@
+-- корзины: одна на пользователя; unit_price — цена, с которой покупатель согласился последний раз
+CREATE TABLE IF NOT EXISTS carts
+(
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL UNIQUE,
+    created     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS cart_items
+(
+    cart_id     BIGINT NOT NULL REFERENCES carts ON DELETE CASCADE,
+    product_id  UUID NOT NULL REFERENCES products ON DELETE CASCADE,
+    quantity    INTEGER NOT NULL CHECK (quantity BETWEEN 1 AND 1000),
+    unit_price  INTEGER NOT NULL,
+    added       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (cart_id, product_id)
+);
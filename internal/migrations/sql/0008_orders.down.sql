@@ -0,0 +1,4 @@
+DELETE FROM userroles WHERE role_id IN (SELECT id FROM roles WHERE name = 'STAFF');
+DELETE FROM roles WHERE name = 'STAFF';
+ALTER TABLE coupon_redemptions DROP COLUMN IF EXISTS order_id;
+DROP TABLE IF EXISTS order_transitions, order_items, orders;
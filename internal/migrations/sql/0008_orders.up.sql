@@ -0,0 +1,49 @@
+-- заказы: позиции хранят названия и цены на момент оформления, поэтому без ссылок на каталог
+CREATE TABLE IF NOT EXISTS orders
+(
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         BIGINT NOT NULL,
+    status          TEXT NOT NULL CHECK (status IN ('created', 'paid', 'packed', 'shipped', 'delivered', 'cancelled', 'refunded')),
+    subtotal        INTEGER NOT NULL,
+    discount        INTEGER NOT NULL DEFAULT 0,
+    coupon_id       BIGINT REFERENCES coupons,
+    coupon_code     TEXT NOT NULL DEFAULT '',
+    coupon_discount INTEGER NOT NULL DEFAULT 0,
+    total           INTEGER NOT NULL,
+    created         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated         TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS orders_user_id ON orders (user_id, id);
+
+CREATE TABLE IF NOT EXISTS order_items
+(
+    order_id    BIGINT NOT NULL REFERENCES orders,
+    product_id  UUID NOT NULL,
+    sku         TEXT NOT NULL,
+    name        TEXT NOT NULL,
+    quantity    INTEGER NOT NULL,
+    unit_price  INTEGER NOT NULL,
+    total       INTEGER NOT NULL,
+    PRIMARY KEY (order_id, product_id)
+);
+
+-- история статусов: кто и когда перевёл заказ
+CREATE TABLE IF NOT EXISTS order_transitions
+(
+    id          BIGSERIAL PRIMARY KEY,
+    order_id    BIGINT NOT NULL REFERENCES orders,
+    from_status TEXT NOT NULL DEFAULT '',
+    to_status   TEXT NOT NULL,
+    actor_id    BIGINT NOT NULL,
+    note        TEXT NOT NULL DEFAULT '',
+    created     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE coupon_redemptions ADD COLUMN IF NOT EXISTS order_id BIGINT REFERENCES orders;
+
+-- STAFF собирает и отправляет заказы
+INSERT
+INTO roles (name)
+VALUES ('STAFF')
+ON CONFLICT (name) DO NOTHING;
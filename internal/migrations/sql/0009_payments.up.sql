@@ -0,0 +1,14 @@
+-- платежи: id выдаёт платёжный провайдер; у заказа не больше одного неотклонённого платежа
+CREATE TABLE IF NOT EXISTS payments
+(
+    id          TEXT PRIMARY KEY,
+    order_id    BIGINT NOT NULL REFERENCES orders,
+    provider    TEXT NOT NULL,
+    status      TEXT NOT NULL CHECK (status IN ('pending', 'authorized', 'captured', 'declined', 'refunded')),
+    amount      INTEGER NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    created     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS payments_order_open ON payments (order_id) WHERE status <> 'declined';
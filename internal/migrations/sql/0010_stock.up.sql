@@ -0,0 +1,28 @@
+-- остатки в магазинах: reserved — сколько держат неоплаченные заказы, больше остатка не зарезервировать
+CREATE TABLE IF NOT EXISTS stock
+(
+    shop_id     BIGINT NOT NULL REFERENCES shops,
+    product_id  UUID NOT NULL REFERENCES products,
+    quantity    INTEGER NOT NULL CHECK (quantity >= 0),
+    reserved    INTEGER NOT NULL DEFAULT 0 CHECK (reserved BETWEEN 0 AND quantity),
+    updated     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (shop_id, product_id)
+);
+
+-- резервы заказов: held держит остаток до expires_at, при оплате списывается, при отмене возвращается
+CREATE TABLE IF NOT EXISTS reservations
+(
+    id          BIGSERIAL PRIMARY KEY,
+    order_id    BIGINT NOT NULL REFERENCES orders,
+    shop_id     BIGINT NOT NULL,
+    product_id  UUID NOT NULL,
+    quantity    INTEGER NOT NULL CHECK (quantity > 0),
+    status      TEXT NOT NULL CHECK (status IN ('held', 'deducted', 'released')),
+    expires_at  TIMESTAMPTZ NOT NULL,
+    created     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (shop_id, product_id) REFERENCES stock
+);
+
+CREATE INDEX IF NOT EXISTS reservations_held ON reservations (expires_at) WHERE status = 'held';
+CREATE INDEX IF NOT EXISTS reservations_order_id ON reservations (order_id);
@@ -0,0 +1,28 @@
+-- журнал аудита: кто, что и с какой сущностью сделал, значения до и после
+CREATE TABLE audit_log
+(
+    id          BIGSERIAL PRIMARY KEY,
+    created     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    actor_id    BIGINT,
+    action      TEXT NOT NULL,
+    entity      TEXT NOT NULL,
+    entity_id   TEXT NOT NULL,
+    before      JSONB,
+    after       JSONB,
+    request_id  TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX audit_log_entity_idx ON audit_log (entity, entity_id, id);
+CREATE INDEX audit_log_actor_idx ON audit_log (actor_id, id);
+
+-- журнал только пополняется: правка и удаление записей запрещены
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE PROCEDURE audit_log_append_only();
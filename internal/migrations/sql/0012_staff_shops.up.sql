@@ -0,0 +1,9 @@
+-- магазины, заказы которых ведёт сотрудник с ролью STAFF
+CREATE TABLE IF NOT EXISTS staff_shops
+(
+    user_id BIGINT NOT NULL REFERENCES users ON DELETE CASCADE,
+    shop_id BIGINT NOT NULL REFERENCES shops ON DELETE CASCADE,
+    PRIMARY KEY (user_id, shop_id)
+);
+
+CREATE INDEX IF NOT EXISTS staff_shops_shop_id ON staff_shops (shop_id);
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audit actions.
+const (
+	AuditCreate      = "create"
+	AuditUpdate      = "update"
+	AuditDelete      = "delete"
+	AuditRestore     = "restore"
+	AuditPurge       = "purge"
+	AuditSetPassword = "set_password"
+	AuditGrantRole   = "grant_role"
+	AuditRevokeRole  = "revoke_role"
+	AuditSetShops    = "set_shops"
+)
+
+// AuditActions are all audit actions.
+var AuditActions = []string{AuditCreate, AuditUpdate, AuditDelete, AuditRestore, AuditPurge, AuditSetPassword, AuditGrantRole, AuditRevokeRole, AuditSetShops}
+
+// Audited entities.
+const (
+	AuditShop     = "shop"
+	AuditCategory = "category"
+	AuditProduct  = "product"
+	AuditPrice    = "price"
+	AuditUser     = "user"
+)
+
+// AuditEntities are all audited entities.
+var AuditEntities = []string{AuditShop, AuditCategory, AuditProduct, AuditPrice, AuditUser}
+
+// AuditEntry records one change: who made it, in which request, and the
+// entity as it was before and after. Before is empty for creations, After
+// for deletions. ActorID is 0 when no one was logged in.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	ActorID   int             `json:"actor_id,omitempty"`
+	Action    string          `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityID  string          `json:"entity_id"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	RequestID string          `json:"request_id,omitempty"`
+	Created   time.Time       `json:"created"`
+}
+
+// AuditFilter narrows audit listings, newest first. Zero values don't
+// filter. BeforeID pages back: only entries older than it are returned.
+type AuditFilter struct {
+	ActorID   int
+	Action    string
+	Entity    string
+	EntityID  string
+	RequestID string
+	From      time.Time
+	To        time.Time
+	BeforeID  int64
+	Limit     int
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// CartItem is a product in a user's cart. AddedPrice is the sale price the
+// user last agreed to, refreshed whenever the item is added or its quantity
+// changed; Price is the current one and nil when the product has no live
+// price. Available is false once the product is deactivated or deleted.
+type CartItem struct {
+	ProductID  string
+	SKU        string
+	Name       string
+	Quantity   int
+	AddedPrice int
+	Price      *int
+	Available  bool
+	Added      time.Time
+}
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+const (
+	// CouponPercentage takes Value percent off the eligible items.
+	CouponPercentage = "percentage"
+	// CouponFixed takes Value off the eligible items, at most their total.
+	CouponFixed = "fixed"
+)
+
+// Coupon is a redeemable code valid from StartsAt up to, but not including,
+// EndsAt. MinOrder is the basket subtotal it needs; UsageLimit and
+// PerUserLimit cap redemptions overall and per user, zero meaning no cap.
+// With CategoryIDs set only items filed in those categories are discounted.
+// Used counts the redemptions so far and is never written.
+type Coupon struct {
+	ID           int       `json:"id"`
+	Code         string    `json:"code"`
+	Type         string    `json:"type"`
+	Value        int       `json:"value"`
+	MinOrder     int       `json:"min_order"`
+	UsageLimit   int       `json:"usage_limit"`
+	PerUserLimit int       `json:"per_user_limit"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+	CategoryIDs  []int     `json:"category_ids"`
+	IsActive     bool      `json:"is_active"`
+	Used         int       `json:"used"`
+}
@@ -0,0 +1,92 @@
+package model
+
+import "time"
+
+// Order statuses. An order starts as created and moves along
+// OrderTransitions; cancelled and refunded are final.
+const (
+	OrderCreated   = "created"
+	OrderPaid      = "paid"
+	OrderPacked    = "packed"
+	OrderShipped   = "shipped"
+	OrderDelivered = "delivered"
+	OrderCancelled = "cancelled"
+	OrderRefunded  = "refunded"
+)
+
+// OrderTransitions lists the statuses each status may move to. Money that
+// was taken is given back by refunding, so only unpaid orders are
+// cancelled.
+var OrderTransitions = map[string][]string{
+	OrderCreated:   {OrderPaid, OrderCancelled},
+	OrderPaid:      {OrderPacked, OrderRefunded},
+	OrderPacked:    {OrderShipped, OrderRefunded},
+	OrderShipped:   {OrderDelivered, OrderRefunded},
+	OrderDelivered: {OrderRefunded},
+}
+
+// OrderStatuses are all statuses in their natural order.
+var OrderStatuses = []string{OrderCreated, OrderPaid, OrderPacked, OrderShipped, OrderDelivered, OrderCancelled, OrderRefunded}
+
+// CanTransition reports whether an order may move from one status to
+// another.
+func CanTransition(from, to string) bool {
+	for _, next := range OrderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Order is a checked-out cart. Items keep the names and prices of the
+// moment of checkout. Subtotal is at sale prices, Discount is what running
+// promotions took off and CouponDiscount what the coupon took off on top.
+// ReservedUntil is when the stock held for an unpaid order is released and
+// the order cancelled. ShopIDs are the shops its stock is reserved in, whose
+// staff work the order.
+type Order struct {
+	ID             int               `json:"id"`
+	UserID         int               `json:"user_id"`
+	Status         string            `json:"status"`
+	Items          []OrderItem       `json:"items"`
+	Subtotal       int               `json:"subtotal"`
+	Discount       int               `json:"discount"`
+	CouponID       int               `json:"-"`
+	CouponCode     string            `json:"coupon_code,omitempty"`
+	CouponDiscount int               `json:"coupon_discount"`
+	Total          int               `json:"total"`
+	Created        time.Time         `json:"created"`
+	Updated        time.Time         `json:"updated"`
+	History        []OrderTransition `json:"history,omitempty"`
+	ReservedUntil  *time.Time        `json:"reserved_until,omitempty"`
+	ShopIDs        []int             `json:"shop_ids"`
+}
+
+// OrderItem is a product as it was ordered. Total is after promotions.
+type OrderItem struct {
+	ProductID string `json:"product_id"`
+	SKU       string `json:"sku"`
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int    `json:"unit_price"`
+	Total     int    `json:"total"`
+}
+
+// OrderTransition records who moved an order to a status and when. From is
+// empty for the checkout itself.
+type OrderTransition struct {
+	From    string    `json:"from,omitempty"`
+	To      string    `json:"to"`
+	ActorID int       `json:"actor_id"`
+	Note    string    `json:"note,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// OrderFilter narrows order listings. Zero values don't filter. StaffID
+// keeps the orders of that user and those reserved in the shops they staff.
+type OrderFilter struct {
+	UserID  int
+	StaffID int
+	Status  string
+}
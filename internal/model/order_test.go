@@ -0,0 +1,28 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{OrderCreated, OrderPaid, true},
+		{OrderCreated, OrderCancelled, true},
+		{OrderCreated, OrderShipped, false},
+		{OrderPaid, OrderCancelled, false},
+		{OrderPaid, OrderRefunded, true},
+		{OrderShipped, OrderDelivered, true},
+		{OrderDelivered, OrderRefunded, true},
+		{OrderCancelled, OrderPaid, false},
+		{OrderRefunded, OrderRefunded, false},
+		{"", OrderCreated, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CanTransition(tt.from, tt.to), "%s → %s", tt.from, tt.to)
+	}
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Payment is one attempt to pay an order. ID is assigned by the provider;
+// Reason explains a decline.
+type Payment struct {
+	ID       string    `json:"id"`
+	OrderID  int       `json:"order_id"`
+	Provider string    `json:"provider"`
+	Status   string    `json:"status"`
+	Amount   int       `json:"amount"`
+	Reason   string    `json:"reason,omitempty"`
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+}
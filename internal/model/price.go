@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Price struct {
 	ID            int    `json:"id,omitempty"`
 	SalePrice     int    `json:"sale_price"`
@@ -8,3 +10,33 @@ type Price struct {
 	IsActive      bool   `json:"is_active,omitempty"`
 	ProductID     string `json:"product_id"`
 }
+
+// PriceSelector picks the live prices a bulk update applies to. All set
+// fields must match; MinPrice and MaxPrice bound the current sale price and
+// are ignored when zero.
+type PriceSelector struct {
+	CategoryID int      `json:"category_id,omitempty"`
+	ShopID     int      `json:"shop_id,omitempty"`
+	SKUs       []string `json:"skus,omitempty"`
+	MinPrice   int      `json:"min_price,omitempty"`
+	MaxPrice   int      `json:"max_price,omitempty"`
+}
+
+// PriceChange is one price of a bulk update before and after the rule.
+type PriceChange struct {
+	PriceID   int
+	ProductID string
+	SKU       string
+	Old       Price
+	New       Price
+}
+
+// PriceBatch is a bulk update as it is recorded: who applied which rule to
+// which prices.
+type PriceBatch struct {
+	ID      int
+	ActorID int
+	Rule    string
+	Changes []PriceChange
+	Created time.Time
+}
@@ -9,3 +9,30 @@ type Product struct {
 	Description string `json:"description,omitempty"`
 	IsActive    bool   `json:"is_active"`
 }
+
+// ProductImport is one row of a bulk import: the product, where it is sold
+// and, optionally, its price.
+type ProductImport struct {
+	Product    Product
+	ShopID     int
+	CategoryID int
+	Price      *Price
+}
+
+// ProductFilter narrows product listings and exports. Zero values don't
+// filter.
+type ProductFilter struct {
+	ShopID     int
+	CategoryID int
+	IsActive   *bool
+	Name       string
+}
+
+// ProductExport is a product joined with where it is sold and its price.
+// Price is nil for products without a live price.
+type ProductExport struct {
+	Product  Product
+	Shop     Shop
+	Category Category
+	Price    *Price
+}
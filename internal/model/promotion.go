@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+const (
+	// PromotionPercentage takes Value percent off the unit price.
+	PromotionPercentage = "percentage"
+	// PromotionFixed takes Value off the unit price.
+	PromotionFixed = "fixed"
+	// PromotionBuyXGetY gives GetQuantity units free for every BuyQuantity
+	// paid ones.
+	PromotionBuyXGetY = "buy_x_get_y"
+)
+
+// Promotion is a campaign running from StartsAt up to, but not including,
+// EndsAt. It targets the products listed, sold in the shops or filed in the
+// categories listed; with no targets at all it covers the whole catalog.
+// Higher priorities are applied first. A promotion that isn't Stackable
+// is never combined with another one.
+type Promotion struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Value       int       `json:"value,omitempty"`
+	BuyQuantity int       `json:"buy_quantity,omitempty"`
+	GetQuantity int       `json:"get_quantity,omitempty"`
+	Priority    int       `json:"priority"`
+	Stackable   bool      `json:"stackable"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	CategoryIDs []int     `json:"category_ids"`
+	ShopIDs     []int     `json:"shop_ids"`
+	ProductIDs  []string  `json:"product_ids"`
+	IsActive    bool      `json:"is_active"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// Stock is how many units of a product a shop has. Reserved units are held
+// by unpaid orders and can't be sold again.
+type Stock struct {
+	ShopID    int       `json:"shop_id"`
+	ProductID string    `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+	Reserved  int       `json:"reserved"`
+	Available int       `json:"available"`
+	Updated   time.Time `json:"updated"`
+}
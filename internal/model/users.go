@@ -7,6 +7,14 @@ type User struct {
 	Role     string `json:"role,omitempty"`
 }
 
+// Account is a user as administrators see it: with all roles and never
+// with the password.
+type Account struct {
+	ID    int      `json:"id"`
+	Login string   `json:"login"`
+	Roles []string `json:"roles"`
+}
+
 type Roles struct {
 	ID   int    `json:"id"`
 	Role string `json:"role"`
@@ -17,4 +25,6 @@ type UserRole string
 const (
 	ADMIN UserRole = "ADMIN"
 	USER  UserRole = "USER"
+	// STAFF works orders of every customer without being an administrator.
+	STAFF UserRole = "STAFF"
 )
@@ -0,0 +1,33 @@
+package payments
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrDisabled is returned when no payment provider is configured.
+var ErrDisabled = errors.New("payments: no provider is configured")
+
+// Disabled is the provider of deployments that take no payments: it
+// refuses every payment and trusts no webhook.
+type Disabled struct{}
+
+func (Disabled) Name() string {
+	return "none"
+}
+
+func (Disabled) Authorize(ctx context.Context, request Request) (Result, error) {
+	return Result{}, ErrDisabled
+}
+
+func (Disabled) Capture(ctx context.Context, paymentID string, amount int) (Result, error) {
+	return Result{}, ErrDisabled
+}
+
+func (Disabled) Refund(ctx context.Context, paymentID string, amount int) (Result, error) {
+	return Result{}, ErrDisabled
+}
+
+func (Disabled) VerifyWebhook(body []byte, signature string) (Event, error) {
+	return Event{}, ErrInvalidSignature
+}
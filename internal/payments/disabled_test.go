@@ -0,0 +1,22 @@
+package payments
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_Disabled(t *testing.T) {
+	ctx := context.Background()
+	var provider Provider = Disabled{}
+
+	_, err := provider.Authorize(ctx, Request{Reference: "order-1", Amount: 1000})
+	assert.ErrorIs(t, err, ErrDisabled)
+	_, err = provider.Refund(ctx, "payment-1", 1000)
+	assert.ErrorIs(t, err, ErrDisabled)
+	body := []byte(`{"payment_id":"payment-1","status":"CAPTURED"}`)
+	_, err = provider.VerifyWebhook(body, NewFake("secret", zap.NewNop()).Sign(body))
+	assert.ErrorIs(t, err, ErrInvalidSignature, "no webhook is trusted")
+}
@@ -0,0 +1,179 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Tokens that make the fake provider decline a payment or confirm it only
+// later through the webhook. Any other token is authorized right away.
+const (
+	TokenDecline = "decline"
+	TokenDelayed = "delayed"
+)
+
+// Fake is an in-process provider for local runs and tests. It keeps the
+// payments in memory and signs its webhooks with HMAC-SHA256 of the body.
+type Fake struct {
+	secret     []byte
+	webhookURL string
+	delay      time.Duration
+	client     *http.Client
+	lg         *zap.Logger
+
+	mu       sync.Mutex
+	seq      int
+	payments map[string]*fakePayment
+}
+
+type fakePayment struct {
+	amount int
+	status string
+}
+
+type FakeOption func(*Fake)
+
+// WithWebhook makes the fake confirm delayed payments by posting an event
+// to url after delay. Without it they stay pending until Confirm is called.
+func WithWebhook(url string, delay time.Duration) FakeOption {
+	return func(f *Fake) {
+		f.webhookURL = url
+		f.delay = delay
+	}
+}
+
+func NewFake(secret string, lg *zap.Logger, opts ...FakeOption) *Fake {
+	f := &Fake{
+		secret:   []byte(secret),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		lg:       lg,
+		payments: make(map[string]*fakePayment),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) Authorize(ctx context.Context, request Request) (Result, error) {
+	if request.Amount <= 0 {
+		return Result{}, fmt.Errorf("Authorize: amount must be positive, got %d", request.Amount)
+	}
+	f.mu.Lock()
+	f.seq++
+	result := Result{PaymentID: fmt.Sprintf("fake_%d", f.seq), Status: StatusAuthorized}
+	switch request.Token {
+	case TokenDecline:
+		result.Status, result.Reason = StatusDeclined, "card declined"
+	case TokenDelayed:
+		result.Status = StatusPending
+	}
+	f.payments[result.PaymentID] = &fakePayment{amount: request.Amount, status: result.Status}
+	f.mu.Unlock()
+
+	if result.Status == StatusPending && f.webhookURL != "" {
+		time.AfterFunc(f.delay, func() {
+			if err := f.Confirm(context.Background(), result.PaymentID); err != nil {
+				f.lg.Error("Fake.Confirm", zap.String("payment", result.PaymentID), zap.Error(err))
+			}
+		})
+	}
+	return result, nil
+}
+
+// Confirm authorizes a pending payment and notifies the webhook, if one is
+// configured.
+func (f *Fake) Confirm(ctx context.Context, paymentID string) error {
+	if _, err := f.move(paymentID, StatusPending, StatusAuthorized, 0); err != nil {
+		return err
+	}
+	if f.webhookURL == "" {
+		return nil
+	}
+	body, err := json.Marshal(Event{PaymentID: paymentID, Status: StatusAuthorized})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, f.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(SignatureHeader, f.Sign(body))
+	response, err := f.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook answered %s", response.Status)
+	}
+	return nil
+}
+
+func (f *Fake) Capture(ctx context.Context, paymentID string, amount int) (Result, error) {
+	return f.move(paymentID, StatusAuthorized, StatusCaptured, amount)
+}
+
+func (f *Fake) Refund(ctx context.Context, paymentID string, amount int) (Result, error) {
+	return f.move(paymentID, StatusCaptured, StatusRefunded, amount)
+}
+
+// move changes the status of a payment that is in the from status. A
+// non-zero amount must not exceed the authorized one.
+func (f *Fake) move(paymentID, from, to string, amount int) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.payments[paymentID]
+	if !ok {
+		return Result{}, fmt.Errorf("payment %s not found", paymentID)
+	}
+	if p.status != from {
+		return Result{}, fmt.Errorf("payment %s is %s, not %s", paymentID, p.status, from)
+	}
+	if amount > p.amount {
+		return Result{}, fmt.Errorf("payment %s: amount %d exceeds %d", paymentID, amount, p.amount)
+	}
+	p.status = to
+	return Result{PaymentID: paymentID, Status: to}, nil
+}
+
+// Sign returns the signature the fake puts in SignatureHeader.
+func (f *Fake) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (f *Fake) VerifyWebhook(body []byte, signature string) (Event, error) {
+	expected, err := hex.DecodeString(f.Sign(body))
+	if err != nil {
+		return Event{}, err
+	}
+	actual, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, actual) {
+		return Event{}, ErrInvalidSignature
+	}
+	var event Event
+	if err = json.Unmarshal(body, &event); err != nil {
+		return Event{}, fmt.Errorf("VerifyWebhook: %w", err)
+	}
+	if event.PaymentID == "" {
+		return Event{}, fmt.Errorf("VerifyWebhook: payment_id is missing")
+	}
+	return event, nil
+}
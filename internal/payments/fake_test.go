@@ -0,0 +1,95 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_Fake_Outcomes(t *testing.T) {
+	ctx := context.Background()
+	fake := NewFake("secret", zap.NewNop())
+
+	paid, err := fake.Authorize(ctx, Request{Reference: "order-1", Amount: 1000})
+	require.NoError(t, err)
+	assert.Equal(t, StatusAuthorized, paid.Status)
+	captured, err := fake.Capture(ctx, paid.PaymentID, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCaptured, captured.Status)
+	refunded, err := fake.Refund(ctx, paid.PaymentID, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRefunded, refunded.Status)
+	_, err = fake.Refund(ctx, paid.PaymentID, 1000)
+	assert.Error(t, err, "refunded twice")
+
+	declined, err := fake.Authorize(ctx, Request{Reference: "order-2", Amount: 1000, Token: TokenDecline})
+	require.NoError(t, err)
+	assert.Equal(t, StatusDeclined, declined.Status)
+	assert.Equal(t, "card declined", declined.Reason)
+	_, err = fake.Capture(ctx, declined.PaymentID, 1000)
+	assert.Error(t, err)
+
+	delayed, err := fake.Authorize(ctx, Request{Reference: "order-3", Amount: 1000, Token: TokenDelayed})
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, delayed.Status)
+	_, err = fake.Capture(ctx, delayed.PaymentID, 1000)
+	assert.Error(t, err, "pending payments can't be captured")
+	require.NoError(t, fake.Confirm(ctx, delayed.PaymentID))
+	_, err = fake.Capture(ctx, delayed.PaymentID, 2000)
+	assert.Error(t, err, "more than authorized")
+	_, err = fake.Capture(ctx, delayed.PaymentID, 1000)
+	assert.NoError(t, err)
+
+	_, err = fake.Authorize(ctx, Request{Reference: "order-4"})
+	assert.Error(t, err)
+}
+
+func Test_Fake_DelayedWebhook(t *testing.T) {
+	received := make(chan Event, 1)
+	fake := NewFake("secret", zap.NewNop())
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		event, err := fake.VerifyWebhook(body, request.Header.Get(SignatureHeader))
+		if err != nil {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		received <- event
+	}))
+	defer server.Close()
+	WithWebhook(server.URL, time.Millisecond)(fake)
+
+	result, err := fake.Authorize(context.Background(), Request{Reference: "order-1", Amount: 500, Token: TokenDelayed})
+	require.NoError(t, err)
+	select {
+	case event := <-received:
+		assert.Equal(t, Event{PaymentID: result.PaymentID, Status: StatusAuthorized}, event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the webhook was not called")
+	}
+}
+
+func Test_Fake_VerifyWebhook(t *testing.T) {
+	fake := NewFake("secret", zap.NewNop())
+	body, err := json.Marshal(Event{PaymentID: "fake_1", Status: StatusAuthorized})
+	require.NoError(t, err)
+
+	event, err := fake.VerifyWebhook(body, fake.Sign(body))
+	require.NoError(t, err)
+	assert.Equal(t, "fake_1", event.PaymentID)
+
+	_, err = fake.VerifyWebhook(body, NewFake("other", zap.NewNop()).Sign(body))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	_, err = fake.VerifyWebhook(body, "not hex")
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	_, err = fake.VerifyWebhook([]byte(`{"status":"authorized"}`), fake.Sign([]byte(`{"status":"authorized"}`)))
+	assert.Error(t, err)
+}
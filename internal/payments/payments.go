@@ -0,0 +1,66 @@
+// Package payments abstracts the payment gateway that takes money for
+// orders. A payment is authorized first, which reserves the amount, and
+// captured afterwards, which takes it; a captured payment may be refunded.
+// Some authorizations are confirmed later by the gateway, which then calls
+// the webhook with a signed event.
+package payments
+
+import (
+	"context"
+	"errors"
+)
+
+// Payment statuses as reported by providers.
+const (
+	StatusPending    = "pending"
+	StatusAuthorized = "authorized"
+	StatusCaptured   = "captured"
+	StatusDeclined   = "declined"
+	StatusRefunded   = "refunded"
+)
+
+// Statuses are all payment statuses.
+var Statuses = []string{StatusPending, StatusAuthorized, StatusCaptured, StatusDeclined, StatusRefunded}
+
+// SignatureHeader carries the signature of a webhook body.
+const SignatureHeader = "Payment-Signature"
+
+// ErrInvalidSignature is returned for webhooks that don't come from the
+// provider.
+var ErrInvalidSignature = errors.New("payments: invalid webhook signature")
+
+// Request asks to authorize Amount for an order. Token identifies the
+// payment method the customer chose on the provider's side.
+type Request struct {
+	Reference string
+	Amount    int
+	Token     string
+}
+
+// Result is what the provider answered. Reason explains a decline.
+type Result struct {
+	PaymentID string
+	Status    string
+	Reason    string
+}
+
+// Event is a verified webhook notification about a payment.
+type Event struct {
+	PaymentID string `json:"payment_id"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+type Provider interface {
+	// Name identifies the provider in stored payments.
+	Name() string
+	// Authorize reserves the amount. The result is authorized, declined or
+	// pending; a pending payment is settled later through the webhook.
+	Authorize(ctx context.Context, request Request) (Result, error)
+	// Capture takes an authorized amount.
+	Capture(ctx context.Context, paymentID string, amount int) (Result, error)
+	// Refund gives a captured amount back.
+	Refund(ctx context.Context, paymentID string, amount int) (Result, error)
+	// VerifyWebhook checks the signature of a webhook body and decodes it.
+	VerifyWebhook(body []byte, signature string) (Event, error)
+}
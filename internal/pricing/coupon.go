@@ -0,0 +1,87 @@
+package pricing
+
+import (
+	"fmt"
+	"market4/internal/model"
+	"time"
+)
+
+// Line is one product of a basket at its current unit price.
+type Line struct {
+	ProductID   string
+	CategoryIDs []int
+	Unit        int
+	Quantity    int
+	Eligible    bool
+}
+
+// CouponQuote is a coupon evaluated against a basket. Reason is empty when
+// the coupon can be redeemed and says why not otherwise, in which case
+// Discount is zero.
+type CouponQuote struct {
+	Lines    []Line
+	Subtotal int
+	Eligible int
+	Discount int
+	Total    int
+	Reason   string
+}
+
+// EvaluateCoupon checks the coupon against the basket at the given moment.
+// used and usedByUser are the redemptions so far, overall and by the
+// customer asking.
+func EvaluateCoupon(coupon model.Coupon, lines []Line, used, usedByUser int, at time.Time) CouponQuote {
+	quote := CouponQuote{Lines: make([]Line, len(lines))}
+	for i, line := range lines {
+		line.Eligible = len(coupon.CategoryIDs) == 0 || overlaps(coupon.CategoryIDs, line.CategoryIDs)
+		quote.Subtotal += line.Unit * line.Quantity
+		if line.Eligible {
+			quote.Eligible += line.Unit * line.Quantity
+		}
+		quote.Lines[i] = line
+	}
+	quote.Total = quote.Subtotal
+
+	switch {
+	case !coupon.IsActive:
+		quote.Reason = "coupon is not active"
+	case at.Before(coupon.StartsAt):
+		quote.Reason = "coupon is not valid yet"
+	case !at.Before(coupon.EndsAt):
+		quote.Reason = "coupon has expired"
+	case coupon.UsageLimit > 0 && used >= coupon.UsageLimit:
+		quote.Reason = "coupon has been used up"
+	case coupon.PerUserLimit > 0 && usedByUser >= coupon.PerUserLimit:
+		quote.Reason = "coupon has already been used the allowed number of times"
+	case quote.Subtotal < coupon.MinOrder:
+		quote.Reason = fmt.Sprintf("order subtotal is below the minimum of %d", coupon.MinOrder)
+	case quote.Eligible == 0:
+		quote.Reason = "no item in the basket is eligible"
+	}
+	if quote.Reason != "" {
+		return quote
+	}
+
+	switch coupon.Type {
+	case model.CouponPercentage:
+		quote.Discount = quote.Eligible * coupon.Value / 100
+	case model.CouponFixed:
+		quote.Discount = coupon.Value
+	}
+	if quote.Discount > quote.Eligible {
+		quote.Discount = quote.Eligible
+	}
+	quote.Total = quote.Subtotal - quote.Discount
+	return quote
+}
+
+func overlaps(a, b []int) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package pricing
+
+import (
+	"market4/internal/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EvaluateCoupon(t *testing.T) {
+	now := time.Now()
+	coupon := model.Coupon{
+		Code: "SPRING", Type: model.CouponPercentage, Value: 10, MinOrder: 3000,
+		UsageLimit: 100, PerUserLimit: 1, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour),
+		CategoryIDs: []int{1}, IsActive: true,
+	}
+	basket := []Line{
+		{ProductID: "a", CategoryIDs: []int{1, 2}, Unit: 2000, Quantity: 1},
+		{ProductID: "b", CategoryIDs: []int{3}, Unit: 500, Quantity: 4},
+	}
+	with := func(change func(c *model.Coupon)) model.Coupon {
+		c := coupon
+		change(&c)
+		return c
+	}
+
+	quote := EvaluateCoupon(coupon, basket, 5, 0, now)
+	assert.Equal(t, "", quote.Reason)
+	assert.Equal(t, 4000, quote.Subtotal)
+	assert.Equal(t, 2000, quote.Eligible, "only the first line is in category 1")
+	assert.Equal(t, 200, quote.Discount)
+	assert.Equal(t, 3800, quote.Total)
+	assert.True(t, quote.Lines[0].Eligible)
+	assert.False(t, quote.Lines[1].Eligible)
+
+	quote = EvaluateCoupon(with(func(c *model.Coupon) { c.Type, c.Value, c.CategoryIDs = model.CouponFixed, 5000, nil }), basket, 0, 0, now)
+	assert.Equal(t, 4000, quote.Discount, "a fixed discount is capped at the eligible total")
+	assert.Equal(t, 0, quote.Total)
+
+	for _, tt := range []struct {
+		name     string
+		coupon   model.Coupon
+		used     int
+		usedByMe int
+		at       time.Time
+		reason   string
+	}{
+		{"inactive", with(func(c *model.Coupon) { c.IsActive = false }), 0, 0, now, "coupon is not active"},
+		{"early", coupon, 0, 0, now.Add(-2 * time.Hour), "coupon is not valid yet"},
+		{"expired", coupon, 0, 0, now.Add(time.Hour), "coupon has expired"},
+		{"used up", coupon, 100, 0, now, "coupon has been used up"},
+		{"used by me", coupon, 1, 1, now, "coupon has already been used the allowed number of times"},
+		{"small order", with(func(c *model.Coupon) { c.MinOrder = 5000 }), 0, 0, now, "order subtotal is below the minimum of 5000"},
+		{"nothing eligible", with(func(c *model.Coupon) { c.CategoryIDs = []int{9} }), 0, 0, now, "no item in the basket is eligible"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := EvaluateCoupon(tt.coupon, basket, tt.used, tt.usedByMe, tt.at)
+			assert.Equal(t, tt.reason, quote.Reason)
+			assert.Equal(t, 0, quote.Discount)
+			assert.Equal(t, 4000, quote.Total)
+		})
+	}
+}
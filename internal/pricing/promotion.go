@@ -0,0 +1,51 @@
+package pricing
+
+import "market4/internal/model"
+
+// Quote is the price of a quantity of one product after promotions.
+type Quote struct {
+	Unit    int
+	Total   int
+	Applied []model.Promotion
+}
+
+// Promote prices qty units at base each under promotions, which must be
+// ordered by priority, highest first. The first promotion always applies;
+// if it is stackable, every further stackable one applies on top, otherwise
+// it is the only one. Percentage and fixed discounts compound on the unit
+// price, which never drops below zero; only the first buy-X-get-Y offer
+// counts. An offer is reported as applied even when qty is too small to
+// earn a free unit.
+func Promote(base, qty int, promotions []model.Promotion) Quote {
+	quote := Quote{Unit: base}
+	free := 0
+	offer := false
+	for _, promotion := range promotions {
+		if len(quote.Applied) > 0 && (!quote.Applied[0].Stackable || !promotion.Stackable) {
+			continue
+		}
+		switch promotion.Type {
+		case model.PromotionPercentage:
+			quote.Unit -= quote.Unit * promotion.Value / 100
+		case model.PromotionFixed:
+			quote.Unit -= promotion.Value
+		case model.PromotionBuyXGetY:
+			if offer || promotion.BuyQuantity < 1 || promotion.GetQuantity < 1 {
+				continue
+			}
+			offer = true
+			free = qty / (promotion.BuyQuantity + promotion.GetQuantity) * promotion.GetQuantity
+		default:
+			continue
+		}
+		if quote.Unit < 0 {
+			quote.Unit = 0
+		}
+		quote.Applied = append(quote.Applied, promotion)
+		if !promotion.Stackable {
+			break
+		}
+	}
+	quote.Total = quote.Unit * (qty - free)
+	return quote
+}
@@ -0,0 +1,48 @@
+package pricing
+
+import (
+	"market4/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Promote(t *testing.T) {
+	percent := func(id, value int, stackable bool) model.Promotion {
+		return model.Promotion{ID: id, Type: model.PromotionPercentage, Value: value, Stackable: stackable}
+	}
+	fixed := func(id, value int, stackable bool) model.Promotion {
+		return model.Promotion{ID: id, Type: model.PromotionFixed, Value: value, Stackable: stackable}
+	}
+	offer := model.Promotion{ID: 9, Type: model.PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1, Stackable: true}
+
+	tests := []struct {
+		name       string
+		qty        int
+		promotions []model.Promotion
+		unit       int
+		total      int
+		applied    []int
+	}{
+		{"no promotions", 2, nil, 2000, 4000, nil},
+		{"exclusive first wins", 1, []model.Promotion{percent(1, 10, false), fixed(2, 500, true)}, 1800, 1800, []int{1}},
+		{"stackable compound", 1, []model.Promotion{percent(1, 10, true), fixed(2, 300, true)}, 1500, 1500, []int{1, 2}},
+		{"exclusive after stackable is skipped", 1, []model.Promotion{percent(1, 10, true), fixed(2, 300, false), percent(3, 50, true)}, 900, 900, []int{1, 3}},
+		{"never below zero", 1, []model.Promotion{fixed(1, 5000, false)}, 0, 0, []int{1}},
+		{"buy two get one", 7, []model.Promotion{offer}, 2000, 10000, []int{9}},
+		{"offer with discount", 3, []model.Promotion{percent(1, 50, true), offer}, 1000, 2000, []int{1, 9}},
+		{"offer counts at any quantity", 1, []model.Promotion{offer}, 2000, 2000, []int{9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := Promote(2000, tt.qty, tt.promotions)
+			assert.Equal(t, tt.unit, quote.Unit)
+			assert.Equal(t, tt.total, quote.Total)
+			var applied []int
+			for _, p := range quote.Applied {
+				applied = append(applied, p.ID)
+			}
+			assert.Equal(t, tt.applied, applied)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package pricing computes prices: new ones from the rules used for bulk
+// repricing, effective ones from running promotions and coupon discounts
+// for a basket.
+package pricing
+
+import (
+	"market4/internal/model"
+	"math"
+)
+
+const (
+	// Set replaces the price with Value.
+	Set = "set"
+	// Percent changes the price by Value percent, e.g. -10 for a 10% cut.
+	Percent = "percent"
+	// Amount adds Value, which may be negative, to the price.
+	Amount = "amount"
+)
+
+var Ops = []string{Set, Percent, Amount}
+
+// Round99 moves the result up to the next price ending in 99, i.e. to ,99
+// of the same whole unit when prices are kept in kopecks: 1234 becomes 1299.
+const Round99 = "99"
+
+const (
+	SalePrice     = "sale_price"
+	FactoryPrice  = "factory_price"
+	DiscountPrice = "discount_price"
+)
+
+var Fields = []string{SalePrice, FactoryPrice, DiscountPrice}
+
+// Rule changes one field of a price; Field defaults to sale_price.
+type Rule struct {
+	Op    string `json:"op"`
+	Value int    `json:"value"`
+	Round string `json:"round,omitempty"`
+	Field string `json:"field,omitempty"`
+}
+
+// Apply returns p with the rule applied. The result is not checked: it may
+// be negative or break discount_price <= sale_price, which the caller
+// validates like any other price.
+func (r Rule) Apply(p model.Price) model.Price {
+	target := &p.SalePrice
+	switch r.Field {
+	case FactoryPrice:
+		target = &p.FactoryPrice
+	case DiscountPrice:
+		target = &p.DiscountPrice
+	}
+	value := *target
+	switch r.Op {
+	case Set:
+		value = r.Value
+	case Percent:
+		value = int(math.Round(float64(value) * float64(100+r.Value) / 100))
+	case Amount:
+		value += r.Value
+	}
+	if r.Round == Round99 && value >= 0 {
+		value = value/100*100 + 99
+	}
+	*target = value
+	return p
+}
@@ -0,0 +1,30 @@
+package pricing
+
+import (
+	"market4/internal/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Rule_Apply(t *testing.T) {
+	price := model.Price{ID: 1, SalePrice: 2000, FactoryPrice: 1000, DiscountPrice: 1600}
+	tests := []struct {
+		name string
+		rule Rule
+		want model.Price
+	}{
+		{"set", Rule{Op: Set, Value: 2500}, model.Price{ID: 1, SalePrice: 2500, FactoryPrice: 1000, DiscountPrice: 1600}},
+		{"percent down", Rule{Op: Percent, Value: -15}, model.Price{ID: 1, SalePrice: 1700, FactoryPrice: 1000, DiscountPrice: 1600}},
+		{"percent rounds half up", Rule{Op: Percent, Value: 1, Field: DiscountPrice}, model.Price{ID: 1, SalePrice: 2000, FactoryPrice: 1000, DiscountPrice: 1616}},
+		{"amount", Rule{Op: Amount, Value: -150, Field: FactoryPrice}, model.Price{ID: 1, SalePrice: 2000, FactoryPrice: 850, DiscountPrice: 1600}},
+		{"round to 99", Rule{Op: Percent, Value: 10, Round: Round99}, model.Price{ID: 1, SalePrice: 2299, FactoryPrice: 1000, DiscountPrice: 1600}},
+		{"round only", Rule{Op: Amount, Round: Round99}, model.Price{ID: 1, SalePrice: 2099, FactoryPrice: 1000, DiscountPrice: 1600}},
+		{"negative results are left for validation", Rule{Op: Amount, Value: -2100, Round: Round99}, model.Price{ID: 1, SalePrice: -100, FactoryPrice: 1000, DiscountPrice: 1600}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.rule.Apply(price))
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many takes pass between two sweeps of full buckets.
+const sweepEvery = 10000
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	full   time.Time
+}
+
+// Memory keeps the buckets of this instance only; with several instances
+// behind a load balancer every one of them grants the full limit.
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+	now     func() time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), at: now}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.at).Seconds()*limit.Rate)
+	b.at = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	r := result(limit, b.tokens, allowed)
+	b.full = now.Add(r.Reset)
+
+	m.takes++
+	if m.takes%sweepEvery == 0 {
+		m.sweep(now)
+	}
+	return r, nil
+}
+
+// sweep forgets the buckets that have filled up: a new one is the same.
+func (m *Memory) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if !b.full.After(now) {
+			delete(m.buckets, key)
+		}
+	}
+}
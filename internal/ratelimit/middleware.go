@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"market4/internal/api/auth"
+	"market4/internal/api/problem"
+	"market4/internal/config"
+	"market4/internal/logging"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unrolled/render"
+	"go.uber.org/zap"
+)
+
+// KeyPrefix starts the keys of all buckets.
+const KeyPrefix = "ratelimit:"
+
+// Identify returns the claims of a request's valid token, if any.
+type Identify func(request *http.Request) (*auth.Payload, bool)
+
+type group struct {
+	name   string
+	paths  []string
+	byIP   bool
+	limits map[string]Limit
+}
+
+// match reports whether path is one of the group's paths or below one.
+func (g group) match(path string) bool {
+	for _, prefix := range g.paths {
+		if path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Limiter refuses requests over the limits of config.RateLimit.
+type Limiter struct {
+	groups     []group
+	store      Store
+	identify   Identify
+	trustProxy bool
+	lg         *zap.Logger
+	renderer   *render.Render
+}
+
+func New(cfg config.RateLimit, store Store, identify Identify, lg *zap.Logger) *Limiter {
+	l := &Limiter{
+		store:      store,
+		identify:   identify,
+		trustProxy: cfg.TrustProxy,
+		lg:         lg,
+		renderer:   render.New(),
+	}
+	for _, g := range cfg.Groups {
+		limits := make(map[string]Limit, len(g.Limits))
+		for role, limit := range g.Limits {
+			limits[role] = limitFrom(limit)
+		}
+		l.groups = append(l.groups, group{name: g.Name, paths: g.Paths, byIP: g.ByIP, limits: limits})
+	}
+	return l
+}
+
+// Middleware takes a token from the caller's bucket and answers 429 when
+// there is none. It lets requests through when the store fails: an outage
+// of Redis shouldn't take the API down with it. A nil Limiter limits
+// nothing.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		key, limit, ok := l.bucket(request)
+		if !ok {
+			next.ServeHTTP(writer, request)
+			return
+		}
+		lg := logging.FromContext(request.Context(), l.lg)
+		result, err := l.store.Take(request.Context(), key, limit)
+		if err != nil {
+			lg.Warn("RateLimit: take", zap.String("key", key), zap.Error(err))
+			next.ServeHTTP(writer, request)
+			return
+		}
+		header := writer.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", ceilSeconds(result.Reset))
+		if result.Allowed {
+			next.ServeHTTP(writer, request)
+			return
+		}
+		lg.Info("RateLimit: too many requests", zap.String("key", key))
+		header.Set("Retry-After", ceilSeconds(result.RetryAfter))
+		p := problem.New(http.StatusTooManyRequests, "rate limit exceeded, retry after "+ceilSeconds(result.RetryAfter)+"s")
+		if err = problem.Write(l.renderer, writer, request, p); err != nil {
+			lg.Error("RateLimit: write problem", zap.Error(err))
+		}
+	})
+}
+
+// bucket picks the group of the request and the caller's bucket in it;
+// ok is false if the request isn't limited. A token only earns a bucket of
+// its own where one of its roles has a limit; otherwise it counts for as
+// little as no token, lest any account be a way around the IP limit.
+func (l *Limiter) bucket(request *http.Request) (key string, limit Limit, ok bool) {
+	for _, g := range l.groups {
+		if !g.match(request.URL.Path) {
+			continue
+		}
+		if !g.byIP {
+			if claims, found := l.identify(request); found {
+				if limit, ok = mostGenerous(g.limits, claims.Roles); ok {
+					return KeyPrefix + g.name + ":user:" + strconv.Itoa(claims.ID), limit, ok
+				}
+			}
+		}
+		limit, ok = g.limits[config.Anonymous]
+		return KeyPrefix + g.name + ":ip:" + l.clientIP(request), limit, ok
+	}
+	return "", Limit{}, false
+}
+
+func mostGenerous(limits map[string]Limit, roles []string) (best Limit, ok bool) {
+	for _, role := range roles {
+		limit, found := limits[role]
+		if !found {
+			continue
+		}
+		if !ok || limit.Rate > best.Rate || limit.Rate == best.Rate && limit.Burst > best.Burst {
+			best, ok = limit, true
+		}
+	}
+	return best, ok
+}
+
+// clientIP is the address the request came from, or with trustProxy the
+// one the proxy says the client has.
+func (l *Limiter) clientIP(request *http.Request) string {
+	if l.trustProxy {
+		if forwarded := request.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+		if real := request.Header.Get("X-Real-IP"); real != "" {
+			return strings.TrimSpace(real)
+		}
+	}
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,58 @@
+// Package ratelimit throttles callers with token buckets. Every caller has
+// one bucket per route group; a request takes a token and is refused with
+// 429 Too Many Requests when the bucket is empty. Buckets refill at a steady
+// rate up to their burst size.
+package ratelimit
+
+import (
+	"context"
+	"market4/internal/config"
+	"math"
+	"time"
+)
+
+// Limit is a bucket of Burst tokens refilled at Rate tokens per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func limitFrom(l config.Limit) Limit {
+	return Limit{Rate: float64(l.Rate) / time.Duration(l.Per).Seconds(), Burst: l.Burst}
+}
+
+// Result is the state of a bucket after a request took, or failed to take,
+// a token from it.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// Reset is when the bucket will be full again.
+	Reset time.Duration
+	// RetryAfter is when the next token is due; zero if Allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// result describes a bucket holding tokens after the request.
+func result(limit Limit, tokens float64, allowed bool) Result {
+	r := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+	}
+	if !allowed {
+		r.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	return r
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,194 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"market4/internal/api/auth"
+	"market4/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func Test_Memory_Refill(t *testing.T) {
+	c := &clock{now: time.Unix(1000, 0)}
+	m := NewMemory()
+	m.now = c.Now
+	limit := Limit{Rate: 1, Burst: 2}
+	ctx := context.Background()
+
+	r, _ := m.Take(ctx, "k", limit)
+	assert.Equal(t, Result{Allowed: true, Remaining: 1, Reset: time.Second}, r)
+	r, _ = m.Take(ctx, "k", limit)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 0, r.Remaining)
+	r, _ = m.Take(ctx, "k", limit)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, time.Second, r.RetryAfter)
+	assert.Equal(t, 2*time.Second, r.Reset)
+
+	r, _ = m.Take(ctx, "other", limit)
+	assert.True(t, r.Allowed, "buckets are per key")
+
+	c.now = c.now.Add(1500 * time.Millisecond)
+	r, _ = m.Take(ctx, "k", limit)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 0, r.Remaining)
+	r, _ = m.Take(ctx, "k", limit)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, 500*time.Millisecond, r.RetryAfter)
+
+	c.now = c.now.Add(time.Hour)
+	r, _ = m.Take(ctx, "k", limit)
+	assert.Equal(t, 1, r.Remaining, "a bucket never holds more than its burst")
+}
+
+func Test_Memory_Sweep(t *testing.T) {
+	c := &clock{now: time.Unix(1000, 0)}
+	m := NewMemory()
+	m.now = c.Now
+	for i := 0; i < sweepEvery-1; i++ {
+		m.Take(context.Background(), strconv.Itoa(i), Limit{Rate: 1, Burst: 1})
+	}
+	c.now = c.now.Add(time.Second)
+	m.Take(context.Background(), "last", Limit{Rate: 1, Burst: 1})
+	assert.Len(t, m.buckets, 1, "full buckets are forgotten")
+}
+
+var testConfig = config.RateLimit{Groups: []config.RateLimitGroup{
+	{Name: "auth", Paths: []string{"/api/v1/auth"}, ByIP: true, Limits: map[string]config.Limit{
+		config.Anonymous: {Rate: 1, Per: config.Duration(time.Minute), Burst: 1},
+		"ADMIN":          {Rate: 3, Per: config.Duration(time.Minute), Burst: 3},
+	}},
+	{Name: "search", Paths: []string{"/api/v1/search"}, Limits: map[string]config.Limit{
+		config.Anonymous: {Rate: 1, Per: config.Duration(time.Minute), Burst: 1},
+		"USER":           {Rate: 2, Per: config.Duration(time.Minute), Burst: 2},
+		"ADMIN":          {Rate: 3, Per: config.Duration(time.Minute), Burst: 3},
+	}},
+	{Name: "api", Paths: []string{"/api/v1/"}, Limits: map[string]config.Limit{
+		config.Anonymous: {Rate: 1, Per: config.Duration(time.Minute), Burst: 1},
+	}},
+}}
+
+// byHeader stands in for tokens: "user" and "admin" identify callers.
+func byHeader(request *http.Request) (*auth.Payload, bool) {
+	switch request.Header.Get("Authorization") {
+	case "user":
+		return &auth.Payload{ID: 1, Roles: []string{"USER"}}, true
+	case "admin":
+		return &auth.Payload{ID: 2, Roles: []string{"USER", "ADMIN"}}, true
+	}
+	return nil, false
+}
+
+func do(handler http.Handler, path, token, ip string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	request.RemoteAddr = ip + ":1234"
+	if token != "" {
+		request.Header.Set("Authorization", token)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func newHandler(cfg config.RateLimit, store Store) http.Handler {
+	ok := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {})
+	return New(cfg, store, byHeader, zap.NewNop()).Middleware(ok)
+}
+
+func Test_Middleware_TooManyRequests(t *testing.T) {
+	handler := newHandler(testConfig, NewMemory())
+
+	w := do(handler, "/api/v1/search/milk", "", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	w = do(handler, "/api/v1/search/milk", "", "10.0.0.1")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"status":429`)
+
+	assert.Equal(t, http.StatusOK, do(handler, "/api/v1/search/milk", "", "10.0.0.2").Code, "anonymous callers are limited per IP")
+	assert.Equal(t, http.StatusOK, do(handler, "/api/v1/shops", "", "10.0.0.1").Code, "groups have separate buckets")
+	assert.Equal(t, http.StatusOK, do(handler, "/healthz", "", "10.0.0.1").Code, "paths outside the groups aren't limited")
+}
+
+func Test_Middleware_Roles(t *testing.T) {
+	handler := newHandler(testConfig, NewMemory())
+
+	w := do(handler, "/api/v1/search/milk", "user", "10.0.0.1")
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	w = do(handler, "/api/v1/search/milk", "admin", "10.0.0.1")
+	assert.Equal(t, "3", w.Header().Get("RateLimit-Limit"), "the most generous role counts")
+
+	do(handler, "/api/v1/search/milk", "user", "10.0.0.2")
+	w = do(handler, "/api/v1/search/milk", "user", "10.0.0.3")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "users are limited across IPs")
+
+	w = do(handler, "/api/v1/shops", "user", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"), "roles without a limit get the anonymous one")
+	w = do(handler, "/api/v1/shops", "", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "a token without a limit shares the bucket of its IP")
+}
+
+func Test_Middleware_ByIP(t *testing.T) {
+	handler := newHandler(testConfig, NewMemory())
+
+	w := do(handler, "/api/v1/auth", "admin", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"), "tokens don't count on by_ip groups")
+	assert.Equal(t, http.StatusTooManyRequests, do(handler, "/api/v1/auth", "user", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do(handler, "/api/v1/auth", "", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusOK, do(handler, "/api/v1/auth", "admin", "10.0.0.2").Code)
+}
+
+func Test_Middleware_TrustProxy(t *testing.T) {
+	cfg := testConfig
+	cfg.TrustProxy = true
+	handler := newHandler(cfg, NewMemory())
+	forwarded := func(ip string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/shops", nil)
+		request.Header.Set("X-Forwarded-For", ip+", 10.0.0.254")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+	assert.Equal(t, http.StatusOK, forwarded("203.0.113.1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, forwarded("203.0.113.1").Code)
+	assert.Equal(t, http.StatusOK, forwarded("203.0.113.2").Code)
+}
+
+type failing struct{}
+
+func (failing) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	return Result{}, errors.New("connection refused")
+}
+
+func Test_Middleware_StoreDown(t *testing.T) {
+	handler := newHandler(testConfig, failing{})
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, do(handler, "/api/v1/shops", "", "10.0.0.1").Code)
+	}
+}
+
+func Test_Middleware_Nil(t *testing.T) {
+	var l *Limiter
+	handler := l.Middleware(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+	assert.Equal(t, http.StatusOK, do(handler, "/api/v1/shops", "", "10.0.0.1").Code)
+}
@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// takeSource refills and takes from a bucket atomically. The clock of Redis
+// is used, so instances with skewed clocks still agree. A bucket expires
+// once it would be full again.
+const takeSource = `
+local rate = tonumber(ARGV[1]) / 1000
+local burst = tonumber(ARGV[2])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = tonumber(state[1])
+local at = tonumber(state[2])
+if tokens == nil or at == nil then
+    tokens = burst
+    at = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - at) * rate)
+local allowed = 0
+if tokens >= 1 then
+    tokens = tokens - 1
+    allowed = 1
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'at', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate) + 1000)
+return {allowed, tostring(tokens)}
+`
+
+var take = redis.NewScript(1, takeSource)
+
+// timeout bounds one round trip to Redis.
+const timeout = 100 * time.Millisecond
+
+// Redis keeps the buckets in Redis, so that the limits hold for the whole
+// cluster.
+type Redis struct {
+	pool *redis.Pool
+}
+
+func NewRedis(pool *redis.Pool) *Redis {
+	return &Redis{pool: pool}
+}
+
+func (r *Redis) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	conn, err := r.pool.GetContext(ctx)
+	if err != nil {
+		return Result{}, fmt.Errorf("Take: %w", err)
+	}
+	defer conn.Close()
+	// Script.Do has no timeout, so it is spelled out: EVALSHA, and EVAL
+	// when Redis doesn't have the script yet.
+	reply, err := redis.DoWithTimeout(conn, timeout, "EVALSHA", take.Hash(), 1, key, limit.Rate, limit.Burst)
+	if e, ok := err.(redis.Error); ok && strings.HasPrefix(string(e), "NOSCRIPT ") {
+		reply, err = redis.DoWithTimeout(conn, timeout, "EVAL", takeSource, 1, key, limit.Rate, limit.Burst)
+	}
+	values, err := redis.Values(reply, err)
+	if err != nil {
+		return Result{}, fmt.Errorf("Take: %w", err)
+	}
+	var allowed int
+	var tokens string
+	if _, err = redis.Scan(values, &allowed, &tokens); err != nil {
+		return Result{}, fmt.Errorf("Take: %w", err)
+	}
+	left, err := strconv.ParseFloat(tokens, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("Take: %w", err)
+	}
+	return result(limit, left, allowed == 1), nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"market4/internal/model"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// PurgeReport counts the rows removed by a purge and lists those of the
+// audited entities.
+type PurgeReport struct {
+	Shops      int64
+	Categories int64
+	Products   int64
+	Prices     int64
+	Rows       []PurgedRow
+}
+
+// PurgedRow is a shop, category, product or price as it was stored before
+// Purge removed it. Entity is one of model.AuditEntities.
+type PurgedRow struct {
+	Entity string
+	ID     string
+	Row    json.RawMessage
+}
+
+type archiveRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewArchiveRepository(pool *pgxpool.Pool) Archive {
+	return &archiveRepo{pool: pool}
+}
+
+// purgedProducts and purgedShops select the rows Purge removes: archived
+// before $1 and not held by a reservation of an unpaid order. Held ones wait
+// for the next purge after the order is paid or cancelled.
+const (
+	purgedProducts = "SELECT id FROM products WHERE deleted_at < $1 " +
+		"AND id NOT IN (SELECT product_id FROM reservations WHERE status = 'held')"
+	purgedShops = "SELECT id FROM shops WHERE deleted_at < $1 " +
+		"AND id NOT IN (SELECT shop_id FROM reservations WHERE status = 'held')"
+)
+
+// Purge runs in one transaction, children first, so foreign keys never see
+// a half-purged catalog. Links, stock and settled reservations of purged
+// products, shops and categories go with them; prices of a purged product go
+// even if they are still live, those of a product kept in the archive stay
+// for RestoreProduct. The purged shops, categories, products and prices are
+// returned as they were stored.
+func (a *archiveRepo) Purge(ctx context.Context, before time.Time) (PurgeReport, error) {
+	var report PurgeReport
+	err := conn(ctx, a.pool).BeginFunc(ctx, func(tx pgx.Tx) error {
+		steps := []struct {
+			dbReq  string
+			entity string
+			count  *int64
+		}{
+			{"DELETE FROM reservations " +
+				"WHERE status <> 'held' " +
+				"AND (product_id IN (" + purgedProducts + ") OR shop_id IN (" + purgedShops + "))", "", nil},
+			{"DELETE FROM stock " +
+				"WHERE product_id IN (" + purgedProducts + ") " +
+				"OR shop_id IN (" + purgedShops + ")", "", nil},
+			{"DELETE FROM prices d " +
+				"WHERE product_id IN (" + purgedProducts + ") " +
+				"OR deleted_at < $1 AND product_id IN (SELECT id FROM products WHERE deleted_at IS NULL)",
+				model.AuditPrice, &report.Prices},
+			{"DELETE FROM productcategory " +
+				"WHERE product_id IN (" + purgedProducts + ") " +
+				"OR category_id IN (SELECT id FROM categories WHERE deleted_at < $1)", "", nil},
+			{"DELETE FROM productshop " +
+				"WHERE product_id IN (" + purgedProducts + ") " +
+				"OR shop_id IN (" + purgedShops + ")", "", nil},
+			{"DELETE FROM products d WHERE id IN (" + purgedProducts + ")", model.AuditProduct, &report.Products},
+			{"DELETE FROM categories d WHERE deleted_at < $1", model.AuditCategory, &report.Categories},
+			{"DELETE FROM shops d WHERE id IN (" + purgedShops + ")", model.AuditShop, &report.Shops},
+		}
+		for _, step := range steps {
+			if step.entity == "" {
+				if _, err := tx.Exec(ctx, step.dbReq, before); err != nil {
+					return classify(err)
+				}
+				continue
+			}
+			purged, err := purgeRows(ctx, tx, step.dbReq, step.entity, before)
+			if err != nil {
+				return classify(err)
+			}
+			*step.count = int64(len(purged))
+			report.Rows = append(report.Rows, purged...)
+		}
+		return nil
+	})
+	if err != nil {
+		return PurgeReport{}, fmt.Errorf("Purge: %w", err)
+	}
+	return report, nil
+}
+
+// purgeRows runs a DELETE of entity rows and returns them as they were.
+func purgeRows(ctx context.Context, tx pgx.Tx, dbReq, entity string, before time.Time) ([]PurgedRow, error) {
+	rows, err := tx.Query(ctx, dbReq+" RETURNING id::text, to_jsonb(d)", before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var purged []PurgedRow
+	for rows.Next() {
+		row := PurgedRow{Entity: entity}
+		if err = rows.Scan(&row.ID, (*[]byte)(&row.Row)); err != nil {
+			return nil, err
+		}
+		purged = append(purged, row)
+	}
+	return purged, rows.Err()
+}
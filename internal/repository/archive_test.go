@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	settledProduct = "2800d950-5c62-49e2-a705-c74ba77f57d0"
+	heldProduct    = "7c2c4a8e-8f0c-4a55-9f1b-0d6f7e1b2a33"
+)
+
+type ArchiveTestSuite struct {
+	suite.Suite
+	testRepo archiveRepo
+	Data     TestData
+}
+
+func Test_ArchiveSuite(t *testing.T) {
+	suite.Run(t, new(ArchiveTestSuite))
+}
+
+func (s *ArchiveTestSuite) SetupTest() {
+	fmt.Println("start setup")
+	var err error
+	s.testRepo.pool, err = pgxpool.Connect(context.Background(), testDSN)
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	s.Data, err = loadTestDataFromYaml("archive_test.yaml")
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	for _, r := range s.Data.Conf.Setup.Requests {
+		_, err = s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			return
+		}
+	}
+}
+
+func (s *ArchiveTestSuite) TearDownTest() {
+	fmt.Println("cleaning up")
+	for _, r := range s.Data.Conf.Teardown.Requests {
+		_, err := s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			s.Fail("cleaning failed")
+		}
+	}
+}
+
+func (s *ArchiveTestSuite) count(dbReq string, args ...interface{}) int {
+	var n int
+	s.Require().NoError(s.testRepo.pool.QueryRow(context.Background(), dbReq, args...).Scan(&n))
+	return n
+}
+
+// purged lists the entity and ID of the purged rows and leaves the counts.
+func purged(report *PurgeReport) []string {
+	var rows []string
+	for _, row := range report.Rows {
+		rows = append(rows, row.Entity+" "+row.ID)
+	}
+	report.Rows = nil
+	return rows
+}
+
+func (s *ArchiveTestSuite) Test_archiveRepo_Purge() {
+	ctx := context.Background()
+	report, err := s.testRepo.Purge(ctx, time.Now().UTC().Add(-48*time.Hour))
+	s.NoError(err)
+	s.Equal(PurgeReport{}, report, "recently deleted rows are kept")
+
+	report, err = s.testRepo.Purge(ctx, time.Now().UTC().Add(time.Hour))
+	s.Require().NoError(err)
+	rows := purged(&report)
+	s.Len(rows, 3, "purged rows are returned for the audit log")
+	s.Contains(rows, "product "+settledProduct)
+	s.Contains(rows, "shop 2")
+	s.Equal(PurgeReport{Shops: 1, Products: 1, Prices: 1}, report,
+		"stock and settled reservations go with the product and the shop")
+	s.Zero(s.count("SELECT count(*) FROM stock WHERE product_id = $1 OR shop_id = 2", settledProduct))
+	s.Zero(s.count("SELECT count(*) FROM reservations WHERE status <> 'held'"))
+
+	s.Equal(1, s.count("SELECT count(*) FROM products WHERE id = $1", heldProduct),
+		"a product held by an unpaid order stays")
+	s.Equal(1, s.count("SELECT count(*) FROM prices WHERE product_id = $1", heldProduct),
+		"and so do its prices")
+
+	_, err = s.testRepo.pool.Exec(ctx, "UPDATE reservations SET status = 'released' WHERE product_id = $1", heldProduct)
+	s.Require().NoError(err)
+	report, err = s.testRepo.Purge(ctx, time.Now().UTC().Add(time.Hour))
+	s.NoError(err)
+	s.Contains(purged(&report), "product "+heldProduct)
+	s.Equal(PurgeReport{Products: 1, Prices: 1}, report, "it goes once the order is settled")
+	s.Zero(s.count("SELECT count(*) FROM reservations"))
+}
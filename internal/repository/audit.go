@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"market4/internal/model"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// DefaultAuditLimit and MaxAuditLimit bound one page of ListAuditEntries.
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+type auditRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditRepository(pool *pgxpool.Pool) Audit {
+	return &auditRepo{pool: pool}
+}
+
+const auditColumns = "id, COALESCE(actor_id, 0), action, entity, entity_id, before, after, request_id, created"
+
+func scanAuditEntry(row pgx.Row) (model.AuditEntry, error) {
+	var e model.AuditEntry
+	err := row.Scan(&e.ID, &e.ActorID, &e.Action, &e.Entity, &e.EntityID,
+		(*[]byte)(&e.Before), (*[]byte)(&e.After), &e.RequestID, &e.Created)
+	return e, err
+}
+
+// AddAuditEntry appends an entry; ID and Created are set by the database.
+func (r *auditRepo) AddAuditEntry(ctx context.Context, e model.AuditEntry) (model.AuditEntry, error) {
+	dbReq := "INSERT INTO audit_log (actor_id, action, entity, entity_id, before, after, request_id) " +
+		"VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7) RETURNING " + auditColumns
+	// Empty values go in as SQL NULL rather than the JSON null.
+	entry, err := scanAuditEntry(conn(ctx, r.pool).QueryRow(ctx, dbReq, e.ActorID, e.Action, e.Entity, e.EntityID,
+		[]byte(e.Before), []byte(e.After), e.RequestID))
+	if err != nil {
+		return entry, fmt.Errorf("AddAuditEntry: %w", classify(err))
+	}
+	return entry, nil
+}
+
+// ListAuditEntries returns the matching entries, newest first.
+func (r *auditRepo) ListAuditEntries(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	entries := make([]model.AuditEntry, 0)
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ActorID != 0 {
+		where("actor_id = $%d", filter.ActorID)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if filter.Entity != "" {
+		where("entity = $%d", filter.Entity)
+	}
+	if filter.EntityID != "" {
+		where("entity_id = $%d", filter.EntityID)
+	}
+	if filter.RequestID != "" {
+		where("request_id = $%d", filter.RequestID)
+	}
+	if !filter.From.IsZero() {
+		where("created >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("created < $%d", filter.To)
+	}
+	if filter.BeforeID != 0 {
+		where("id < $%d", filter.BeforeID)
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultAuditLimit
+	}
+	if limit > MaxAuditLimit {
+		limit = MaxAuditLimit
+	}
+	dbReq := "SELECT " + auditColumns + " FROM audit_log"
+	if len(conditions) > 0 {
+		dbReq += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit)
+	dbReq += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := conn(ctx, r.pool).Query(ctx, dbReq, args...)
+	if err != nil {
+		return entries, fmt.Errorf("ListAuditEntries: %w", classify(err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return entries, fmt.Errorf("ListAuditEntries: %w", classify(err))
+		}
+		entries = append(entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		return entries, fmt.Errorf("ListAuditEntries: %w", classify(err))
+	}
+	return entries, nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"market4/internal/model"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuditTestSuite struct {
+	suite.Suite
+	testRepo auditRepo
+	Data     TestData
+}
+
+func Test_AuditSuite(t *testing.T) {
+	suite.Run(t, new(AuditTestSuite))
+}
+
+func (s *AuditTestSuite) SetupTest() {
+	fmt.Println("start setup")
+	var err error
+	s.testRepo.pool, err = pgxpool.Connect(context.Background(), testDSN)
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	s.Data, err = loadTestDataFromYaml("audit_test.yaml")
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	for _, r := range s.Data.Conf.Setup.Requests {
+		_, err = s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			return
+		}
+	}
+}
+
+func (s *AuditTestSuite) TearDownTest() {
+	fmt.Println("cleaning up")
+	for _, r := range s.Data.Conf.Teardown.Requests {
+		_, err := s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			s.Fail("cleaning failed")
+		}
+	}
+}
+
+func (s *AuditTestSuite) Test_auditRepo_AddAuditEntry() {
+	ctx := context.Background()
+	added, err := s.testRepo.AddAuditEntry(ctx, model.AuditEntry{
+		ActorID: 2, Action: model.AuditUpdate, Entity: model.AuditPrice, EntityID: "1",
+		Before: json.RawMessage(`{"sale_price": 2000}`), After: json.RawMessage(`{"sale_price": 2500}`),
+		RequestID: "req-1",
+	})
+	s.Require().NoError(err)
+	s.NotZero(added.ID)
+	s.NotZero(added.Created)
+	s.JSONEq(`{"sale_price": 2500}`, string(added.After))
+
+	anonymous, err := s.testRepo.AddAuditEntry(ctx, model.AuditEntry{Action: model.AuditCreate, Entity: model.AuditUser, EntityID: "3"})
+	s.Require().NoError(err)
+	s.Zero(anonymous.ActorID)
+	s.Nil(anonymous.Before, "empty values are stored as NULL, not as JSON null")
+
+	_, err = s.testRepo.pool.Exec(ctx, "DELETE FROM audit_log")
+	s.Error(err, "the log is append-only")
+	_, err = s.testRepo.pool.Exec(ctx, "UPDATE audit_log SET actor_id = 1")
+	s.Error(err, "the log is append-only")
+}
+
+func (s *AuditTestSuite) Test_auditRepo_ListAuditEntries() {
+	ctx := context.Background()
+	for i, e := range []model.AuditEntry{
+		{ActorID: 2, Action: model.AuditCreate, Entity: model.AuditShop, EntityID: "1", RequestID: "a"},
+		{ActorID: 2, Action: model.AuditUpdate, Entity: model.AuditShop, EntityID: "1", RequestID: "b"},
+		{ActorID: 3, Action: model.AuditUpdate, Entity: model.AuditPrice, EntityID: "1", RequestID: "c"},
+	} {
+		_, err := s.testRepo.AddAuditEntry(ctx, e)
+		s.Require().NoError(err, "entry %d", i)
+	}
+
+	entries, err := s.testRepo.ListAuditEntries(ctx, model.AuditFilter{Entity: model.AuditShop, EntityID: "1"})
+	s.Require().NoError(err)
+	s.Require().Len(entries, 2)
+	s.Equal("b", entries[0].RequestID, "newest first")
+
+	entries, err = s.testRepo.ListAuditEntries(ctx, model.AuditFilter{ActorID: 2, Action: model.AuditUpdate})
+	s.Require().NoError(err)
+	s.Require().Len(entries, 1)
+	s.Equal("b", entries[0].RequestID)
+
+	page, err := s.testRepo.ListAuditEntries(ctx, model.AuditFilter{Limit: 2})
+	s.Require().NoError(err)
+	s.Require().Len(page, 2)
+	rest, err := s.testRepo.ListAuditEntries(ctx, model.AuditFilter{Limit: 2, BeforeID: page[1].ID})
+	s.Require().NoError(err)
+	s.Require().Len(rest, 1)
+	s.Equal("a", rest[0].RequestID)
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"market4/internal/model"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type cartRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewCartRepository(pool *pgxpool.Pool) Cart {
+	return &cartRepo{pool: pool}
+}
+
+// livePrice is the current sale price of products.id: the newest active
+// price that isn't deleted.
+const livePrice = "SELECT sale_price FROM prices " +
+	"WHERE prices.product_id = products.id AND prices.deleted_at IS NULL AND prices.is_active " +
+	"ORDER BY prices.id DESC LIMIT 1"
+
+func (c *cartRepo) GetCart(ctx context.Context, userID int) ([]model.CartItem, error) {
+	items := make([]model.CartItem, 0)
+	dbReq := "SELECT products.id, products.sku, products.name, cart_items.quantity, cart_items.unit_price, " +
+		"(" + livePrice + "), products.is_active AND products.deleted_at IS NULL, cart_items.added " +
+		"FROM carts " +
+		"JOIN cart_items ON cart_items.cart_id = carts.id " +
+		"JOIN products ON products.id = cart_items.product_id " +
+		"WHERE carts.user_id = $1 " +
+		"ORDER BY cart_items.added, products.sku"
+	rows, err := c.pool.Query(ctx, dbReq, userID)
+	if err != nil {
+		return items, fmt.Errorf("GetCart: %w", classify(err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var item model.CartItem
+		err = rows.Scan(&item.ProductID, &item.SKU, &item.Name, &item.Quantity, &item.AddedPrice,
+			&item.Price, &item.Available, &item.Added)
+		if err != nil {
+			return items, fmt.Errorf("GetCart: %w", classify(err))
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return items, fmt.Errorf("GetCart: %w", classify(err))
+	}
+	return items, nil
+}
+
+// AddCartItem puts quantity more of the product into the user's cart,
+// creating the cart on first use. Only active products with a live price
+// can be added; the item takes that price.
+func (c *cartRepo) AddCartItem(ctx context.Context, userID int, productID string, quantity int) error {
+	err := c.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		cartID, err := c.cartID(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		dbReq := "INSERT INTO cart_items (cart_id, product_id, quantity, unit_price) " +
+			"SELECT $1, products.id, $3, price.sale_price " +
+			"FROM products JOIN LATERAL (" + livePrice + ") AS price ON true " +
+			"WHERE products.id = $2 AND products.is_active AND products.deleted_at IS NULL " +
+			"ON CONFLICT (cart_id, product_id) DO UPDATE " +
+			"SET quantity = cart_items.quantity + EXCLUDED.quantity, " +
+			"unit_price = EXCLUDED.unit_price, updated = CURRENT_TIMESTAMP"
+		tag, err := tx.Exec(ctx, dbReq, cartID, productID, quantity)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return NewForeignKeyError("product_id", "product is not available")
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("AddCartItem: %w", classify(err))
+	}
+	return nil
+}
+
+func (c *cartRepo) cartID(ctx context.Context, tx pgx.Tx, userID int) (int, error) {
+	var cartID int
+	err := tx.QueryRow(ctx,
+		"INSERT INTO carts (user_id) VALUES ($1) "+
+			"ON CONFLICT (user_id) DO UPDATE SET updated = CURRENT_TIMESTAMP "+
+			"RETURNING id",
+		userID).Scan(&cartID)
+	return cartID, err
+}
+
+// SetCartItemQuantity changes the quantity of an item already in the cart
+// and brings its price up to date, keeping the old one if the product has
+// lost its price.
+func (c *cartRepo) SetCartItemQuantity(ctx context.Context, userID int, productID string, quantity int) error {
+	dbReq := "UPDATE cart_items " +
+		"SET quantity = $3, unit_price = COALESCE((" + livePrice + "), cart_items.unit_price), " +
+		"updated = CURRENT_TIMESTAMP " +
+		"FROM carts, products " +
+		"WHERE carts.id = cart_items.cart_id AND products.id = cart_items.product_id " +
+		"AND carts.user_id = $1 AND cart_items.product_id = $2"
+	tag, err := c.pool.Exec(ctx, dbReq, userID, productID, quantity)
+	if err != nil {
+		return fmt.Errorf("SetCartItemQuantity: %w", classify(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("SetCartItemQuantity: %w", NewNotFoundError(fmt.Sprintf("product %s in the cart", productID)))
+	}
+	return nil
+}
+
+func (c *cartRepo) RemoveCartItem(ctx context.Context, userID int, productID string) error {
+	dbReq := "DELETE FROM cart_items USING carts " +
+		"WHERE carts.id = cart_items.cart_id AND carts.user_id = $1 AND cart_items.product_id = $2"
+	tag, err := c.pool.Exec(ctx, dbReq, userID, productID)
+	if err != nil {
+		return fmt.Errorf("RemoveCartItem: %w", classify(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("RemoveCartItem: %w", NewNotFoundError(fmt.Sprintf("product %s in the cart", productID)))
+	}
+	return nil
+}
+
+// ClearCart empties the cart; clearing an empty or missing cart is not an
+// error.
+func (c *cartRepo) ClearCart(ctx context.Context, userID int) error {
+	dbReq := "DELETE FROM cart_items USING carts " +
+		"WHERE carts.id = cart_items.cart_id AND carts.user_id = $1"
+	if _, err := c.pool.Exec(ctx, dbReq, userID); err != nil {
+		return fmt.Errorf("ClearCart: %w", classify(err))
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/suite"
+)
+
+const unpricedProduct = "5f0e3d3a-1c1b-4c7e-9d5a-3b2f6e8a9c10"
+
+type CartsTestSuite struct {
+	suite.Suite
+	testRepo cartRepo
+	Data     TestData
+}
+
+func Test_CartsSuite(t *testing.T) {
+	suite.Run(t, new(CartsTestSuite))
+}
+
+func (s *CartsTestSuite) SetupTest() {
+	fmt.Println("start setup")
+	var err error
+	s.testRepo.pool, err = pgxpool.Connect(context.Background(), testDSN)
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	s.Data, err = loadTestDataFromYaml("carts_test.yaml")
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	for _, r := range s.Data.Conf.Setup.Requests {
+		_, err = s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			return
+		}
+	}
+}
+
+func (s *CartsTestSuite) TearDownTest() {
+	fmt.Println("cleaning up")
+	for _, r := range s.Data.Conf.Teardown.Requests {
+		_, err := s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			s.Fail("cleaning failed")
+		}
+	}
+}
+
+func (s *CartsTestSuite) Test_cartRepo_AddCartItem() {
+	ctx := context.Background()
+	s.Require().NoError(s.testRepo.AddCartItem(ctx, 1, promotedProduct, 1))
+	s.Require().NoError(s.testRepo.AddCartItem(ctx, 1, promotedProduct, 2))
+	s.Require().NoError(s.testRepo.AddCartItem(ctx, 1, shopProduct, 1))
+
+	items, err := s.testRepo.GetCart(ctx, 1)
+	s.Require().NoError(err)
+	s.Require().Len(items, 2)
+	s.Equal(3, items[0].Quantity, "adding again adds up")
+	s.Equal(2000, items[0].AddedPrice)
+	s.Require().NotNil(items[0].Price)
+	s.Equal(2000, *items[0].Price)
+	s.True(items[0].Available)
+
+	err = s.testRepo.AddCartItem(ctx, 1, unpricedProduct, 1)
+	s.True(errors.Is(err, ErrForeignKey), "products without a price can't be added: %v", err)
+	err = s.testRepo.AddCartItem(ctx, 1, shopProduct, 1000)
+	s.True(errors.Is(err, ErrValidation), "quantity is capped: %v", err)
+
+	other, err := s.testRepo.GetCart(ctx, 2)
+	s.NoError(err)
+	s.Empty(other)
+}
+
+func (s *CartsTestSuite) Test_cartRepo_Repricing() {
+	ctx := context.Background()
+	s.Require().NoError(s.testRepo.AddCartItem(ctx, 1, promotedProduct, 1))
+	s.Require().NoError(s.testRepo.AddCartItem(ctx, 1, shopProduct, 1))
+	_, err := s.testRepo.pool.Exec(ctx, "UPDATE prices SET sale_price = 2200 WHERE product_id = $1", promotedProduct)
+	s.Require().NoError(err)
+	_, err = s.testRepo.pool.Exec(ctx, "UPDATE products SET is_active = false WHERE id = $1", shopProduct)
+	s.Require().NoError(err)
+
+	items, err := s.testRepo.GetCart(ctx, 1)
+	s.Require().NoError(err)
+	s.Equal(2000, items[0].AddedPrice)
+	s.Equal(2200, *items[0].Price)
+	s.False(items[1].Available)
+
+	s.NoError(s.testRepo.SetCartItemQuantity(ctx, 1, promotedProduct, 2))
+	items, err = s.testRepo.GetCart(ctx, 1)
+	s.Require().NoError(err)
+	s.Equal(2, items[0].Quantity)
+	s.Equal(2200, items[0].AddedPrice, "changing the quantity takes the current price")
+
+	err = s.testRepo.SetCartItemQuantity(ctx, 1, unpricedProduct, 2)
+	s.True(errors.Is(err, ErrNotFound), err)
+}
+
+func (s *CartsTestSuite) Test_cartRepo_Remove() {
+	ctx := context.Background()
+	s.Require().NoError(s.testRepo.AddCartItem(ctx, 1, promotedProduct, 1))
+	s.Require().NoError(s.testRepo.AddCartItem(ctx, 1, shopProduct, 1))
+
+	s.NoError(s.testRepo.RemoveCartItem(ctx, 1, promotedProduct))
+	s.True(errors.Is(s.testRepo.RemoveCartItem(ctx, 1, promotedProduct), ErrNotFound))
+	s.True(errors.Is(s.testRepo.RemoveCartItem(ctx, 2, shopProduct), ErrNotFound), "other users' carts are out of reach")
+
+	s.NoError(s.testRepo.ClearCart(ctx, 1))
+	items, err := s.testRepo.GetCart(ctx, 1)
+	s.NoError(err)
+	s.Empty(items)
+	s.NoError(s.testRepo.ClearCart(ctx, 3))
+}
@@ -18,9 +18,9 @@ func NewCategoryRepository(pool *pgxpool.Pool) Category {
 	return &categoryRepo{pool: pool}
 }
 func (c *categoryRepo) IfCategoryExists(ctx context.Context, category int) bool {
-	dbReq := "SELECT id FROM categories WHERE id=$1"
+	dbReq := "SELECT id FROM categories WHERE id=$1 AND deleted_at IS NULL"
 	var id = 0
-	err := c.pool.QueryRow(ctx, dbReq, category).Scan(&id)
+	err := conn(ctx, c.pool).QueryRow(ctx, dbReq, category).Scan(&id)
 	if err != nil {
 		log.Println(fmt.Errorf("IfCategoryExists: %w", err))
 		return false
@@ -35,13 +35,14 @@ func (c *categoryRepo) ListAllCategories(ctx context.Context) ([]model.Category,
 	categories := make([]model.Category, 0)
 
 	dbReq := "SELECT id, name, uri_name " +
-		"FROM categories"
-	rows, err := c.pool.Query(ctx, dbReq)
+		"FROM categories " +
+		"WHERE deleted_at IS NULL"
+	rows, err := conn(ctx, c.pool).Query(ctx, dbReq)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return categories, nil
 		}
-		return categories, fmt.Errorf("ListAllCategories: %w", err)
+		return categories, fmt.Errorf("ListAllCategories: %w", classify(err))
 	}
 	defer rows.Close()
 
@@ -49,7 +50,7 @@ func (c *categoryRepo) ListAllCategories(ctx context.Context) ([]model.Category,
 		var category model.Category
 		err = rows.Scan(&category.ID, &category.Name, &category.URI_name)
 		if err != nil {
-			return categories, fmt.Errorf("ListAllCategories: %w", err)
+			return categories, fmt.Errorf("ListAllCategories: %w", classify(err))
 		}
 		categories = append(categories, category)
 	}
@@ -60,28 +61,94 @@ func (c *categoryRepo) AddCategory(ctx context.Context, category *model.Category
 		"VALUES ($1) " +
 		"RETURNING id"
 	var id int
-	err := c.pool.QueryRow(ctx, dbReq, category.Name).Scan(&id)
+	err := conn(ctx, c.pool).QueryRow(ctx, dbReq, category.Name).Scan(&id)
 	if err != nil {
-		return 0, fmt.Errorf("AddCategory: %w", err)
+		return 0, fmt.Errorf("AddCategory: %w", classify(err))
 	}
 
 	dbReq = "UPDATE categories " +
 		"SET uri_name = $1 " +
 		"WHERE id = $2"
 	uri_name := fmt.Sprintf("%s-%d", category.Name, id)
-	_, err = c.pool.Exec(ctx, dbReq, uri_name, id)
+	_, err = conn(ctx, c.pool).Exec(ctx, dbReq, uri_name, id)
 	if err != nil {
-		return 0, fmt.Errorf("AddCategory: %w", err)
+		return 0, fmt.Errorf("AddCategory: %w", classify(err))
 	}
 	return id, nil
 }
 func (c *categoryRepo) EditCategory(ctx context.Context, category *model.Category) error {
 	dbReq := fmt.Sprintf("UPDATE categories SET name = '%s', "+
-		"uri_name = '%s-%d', updated = CURRENT_TIMESTAMP WHERE id = %d",
+		"uri_name = '%s-%d', updated = CURRENT_TIMESTAMP WHERE id = %d AND deleted_at IS NULL",
 		category.Name, category.Name, category.ID, category.ID)
-	_, err := c.pool.Exec(ctx, dbReq)
+	tag, err := conn(ctx, c.pool).Exec(ctx, dbReq)
 	if err != nil {
-		return fmt.Errorf("UpdateCategoryParameter: %w", err)
+		return fmt.Errorf("UpdateCategoryParameter: %w", classify(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("UpdateCategoryParameter: %w", NewNotFoundError(fmt.Sprintf("category %d", category.ID)))
+	}
+	return nil
+}
+
+func (c *categoryRepo) GetCategoryByID(ctx context.Context, categoryID int) (model.Category, error) {
+	dbReq := "SELECT id, name, uri_name " +
+		"FROM categories " +
+		"WHERE id = $1 AND deleted_at IS NULL"
+	var category model.Category
+	err := conn(ctx, c.pool).QueryRow(ctx, dbReq, categoryID).Scan(&category.ID, &category.Name, &category.URI_name)
+	if err != nil {
+		return category, fmt.Errorf("GetCategoryByID: %w", classify(err))
+	}
+	return category, nil
+}
+
+func (c *categoryRepo) GetCategoryByURIName(ctx context.Context, uriName string) (model.Category, error) {
+	dbReq := "SELECT id, name, uri_name " +
+		"FROM categories " +
+		"WHERE uri_name = $1 AND deleted_at IS NULL"
+	var category model.Category
+	err := conn(ctx, c.pool).QueryRow(ctx, dbReq, uriName).Scan(&category.ID, &category.Name, &category.URI_name)
+	if err != nil {
+		return category, fmt.Errorf("GetCategoryByURIName: %w", classify(err))
+	}
+	return category, nil
+}
+
+// DeleteCategory archives a category. Categories that still hold live
+// products can't be archived.
+func (c *categoryRepo) DeleteCategory(ctx context.Context, categoryID int) error {
+	dbReq := "SELECT count(*) " +
+		"FROM productcategory " +
+		"JOIN products ON products.id = productcategory.product_id " +
+		"WHERE productcategory.category_id = $1 AND products.deleted_at IS NULL"
+	var products int
+	err := conn(ctx, c.pool).QueryRow(ctx, dbReq, categoryID).Scan(&products)
+	if err != nil {
+		return fmt.Errorf("DeleteCategory: %w", classify(err))
+	}
+	if products > 0 {
+		return fmt.Errorf("DeleteCategory: %w", NewConflictError(fmt.Sprintf("category %d still has %d products", categoryID, products)))
+	}
+
+	dbReq = "UPDATE categories SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL"
+	tag, err := conn(ctx, c.pool).Exec(ctx, dbReq, categoryID)
+	if err != nil {
+		return fmt.Errorf("DeleteCategory: %w", classify(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("DeleteCategory: %w", NewNotFoundError(fmt.Sprintf("category %d", categoryID)))
+	}
+	return nil
+}
+
+func (c *categoryRepo) RestoreCategory(ctx context.Context, categoryID int) error {
+	dbReq := "UPDATE categories SET deleted_at = NULL, updated = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NOT NULL"
+	tag, err := conn(ctx, c.pool).Exec(ctx, dbReq, categoryID)
+	if err != nil {
+		return fmt.Errorf("RestoreCategory: %w", classify(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("RestoreCategory: %w", NewNotFoundError(fmt.Sprintf("archived category %d", categoryID)))
 	}
 	return nil
 }
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"market4/internal/model"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type couponRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewCouponRepository(pool *pgxpool.Pool) Coupon {
+	return &couponRepo{pool: pool}
+}
+
+const couponColumns = "coupons.id, coupons.code, coupons.type, coupons.value, coupons.min_order, " +
+	"coupons.usage_limit, coupons.per_user_limit, coupons.starts_at, coupons.ends_at, " +
+	"coupons.category_ids, coupons.is_active, " +
+	"(SELECT count(*) FROM coupon_redemptions WHERE coupon_id = coupons.id)"
+
+func scanCoupon(row pgx.Row) (model.Coupon, error) {
+	var c model.Coupon
+	err := row.Scan(&c.ID, &c.Code, &c.Type, &c.Value, &c.MinOrder,
+		&c.UsageLimit, &c.PerUserLimit, &c.StartsAt, &c.EndsAt,
+		&c.CategoryIDs, &c.IsActive, &c.Used)
+	return c, err
+}
+
+func couponCategories(c model.Coupon) []int {
+	if c.CategoryIDs == nil {
+		return []int{}
+	}
+	return c.CategoryIDs
+}
+
+func (r *couponRepo) AddCoupon(ctx context.Context, c model.Coupon) (model.Coupon, error) {
+	dbReq := "INSERT INTO coupons (code, type, value, min_order, usage_limit, per_user_limit, " +
+		"starts_at, ends_at, category_ids, is_active) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) " +
+		"RETURNING " + couponColumns
+	result, err := scanCoupon(r.pool.QueryRow(ctx, dbReq,
+		c.Code, c.Type, c.Value, c.MinOrder, c.UsageLimit, c.PerUserLimit,
+		c.StartsAt, c.EndsAt, couponCategories(c), c.IsActive))
+	if err != nil {
+		return result, fmt.Errorf("AddCoupon: %w", classify(err))
+	}
+	return result, nil
+}
+
+func (r *couponRepo) EditCoupon(ctx context.Context, c model.Coupon) (model.Coupon, error) {
+	dbReq := "UPDATE coupons " +
+		"SET code=$1, type=$2, value=$3, min_order=$4, usage_limit=$5, per_user_limit=$6, " +
+		"starts_at=$7, ends_at=$8, category_ids=$9, is_active=$10, updated=CURRENT_TIMESTAMP " +
+		"WHERE id=$11 " +
+		"RETURNING " + couponColumns
+	result, err := scanCoupon(r.pool.QueryRow(ctx, dbReq,
+		c.Code, c.Type, c.Value, c.MinOrder, c.UsageLimit, c.PerUserLimit,
+		c.StartsAt, c.EndsAt, couponCategories(c), c.IsActive, c.ID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			err = NewNotFoundError(fmt.Sprintf("coupon %d", c.ID))
+		}
+		return result, fmt.Errorf("EditCoupon: %w", classify(err))
+	}
+	return result, nil
+}
+
+func (r *couponRepo) GetCouponByID(ctx context.Context, couponID int) (model.Coupon, error) {
+	result, err := scanCoupon(r.pool.QueryRow(ctx, "SELECT "+couponColumns+" FROM coupons WHERE id = $1", couponID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			err = NewNotFoundError(fmt.Sprintf("coupon %d", couponID))
+		}
+		return result, fmt.Errorf("GetCouponByID: %w", classify(err))
+	}
+	return result, nil
+}
+
+// GetCouponByCode looks codes up case-insensitively; they are stored in
+// upper case.
+func (r *couponRepo) GetCouponByCode(ctx context.Context, code string) (model.Coupon, error) {
+	result, err := scanCoupon(r.pool.QueryRow(ctx, "SELECT "+couponColumns+" FROM coupons WHERE code = upper($1)", code))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			err = NewNotFoundError(fmt.Sprintf("coupon %q", code))
+		}
+		return result, fmt.Errorf("GetCouponByCode: %w", classify(err))
+	}
+	return result, nil
+}
+
+func (r *couponRepo) ListCoupons(ctx context.Context) ([]model.Coupon, error) {
+	coupons := make([]model.Coupon, 0)
+	rows, err := r.pool.Query(ctx, "SELECT "+couponColumns+" FROM coupons ORDER BY code")
+	if err != nil {
+		return coupons, fmt.Errorf("ListCoupons: %w", classify(err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c, err := scanCoupon(rows)
+		if err != nil {
+			return coupons, fmt.Errorf("ListCoupons: %w", classify(err))
+		}
+		coupons = append(coupons, c)
+	}
+	if err = rows.Err(); err != nil {
+		return coupons, fmt.Errorf("ListCoupons: %w", classify(err))
+	}
+	return coupons, nil
+}
+
+// DeleteCoupon removes a coupon that was never redeemed. Redeemed coupons
+// keep their history and can only be deactivated.
+func (r *couponRepo) DeleteCoupon(ctx context.Context, couponID int) error {
+	tag, err := r.pool.Exec(ctx, "DELETE FROM coupons WHERE id = $1", couponID)
+	if err != nil {
+		err = classify(err)
+		if errors.Is(err, ErrForeignKey) {
+			err = NewConflictError(fmt.Sprintf("coupon %d has been redeemed; deactivate it instead", couponID))
+		}
+		return fmt.Errorf("DeleteCoupon: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("DeleteCoupon: %w", NewNotFoundError(fmt.Sprintf("coupon %d", couponID)))
+	}
+	return nil
+}
+
+// CouponUsage counts the redemptions of a coupon, overall and by one user.
+func (r *couponRepo) CouponUsage(ctx context.Context, couponID, userID int) (int, int, error) {
+	var total, byUser int
+	err := r.pool.QueryRow(ctx,
+		"SELECT count(*), count(*) FILTER (WHERE user_id = $2) FROM coupon_redemptions WHERE coupon_id = $1",
+		couponID, userID).Scan(&total, &byUser)
+	if err != nil {
+		return 0, 0, fmt.Errorf("CouponUsage: %w", classify(err))
+	}
+	return total, byUser, nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"market4/internal/model"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/suite"
+)
+
+type CouponsTestSuite struct {
+	suite.Suite
+	testRepo couponRepo
+	Data     TestData
+}
+
+func Test_CouponsSuite(t *testing.T) {
+	suite.Run(t, new(CouponsTestSuite))
+}
+
+func (s *CouponsTestSuite) SetupTest() {
+	fmt.Println("start setup")
+	var err error
+	s.testRepo.pool, err = pgxpool.Connect(context.Background(), testDSN)
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	s.Data, err = loadTestDataFromYaml("coupons_test.yaml")
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	for _, r := range s.Data.Conf.Setup.Requests {
+		_, err = s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			return
+		}
+	}
+}
+
+func (s *CouponsTestSuite) TearDownTest() {
+	fmt.Println("cleaning up")
+	for _, r := range s.Data.Conf.Teardown.Requests {
+		_, err := s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			s.Fail("cleaning failed")
+		}
+	}
+}
+
+func (s *CouponsTestSuite) coupon(code string) model.Coupon {
+	now := time.Now()
+	return model.Coupon{
+		Code:     code,
+		Type:     model.CouponPercentage,
+		Value:    10,
+		StartsAt: now.Add(-time.Hour),
+		EndsAt:   now.Add(time.Hour),
+		IsActive: true,
+	}
+}
+
+func (s *CouponsTestSuite) Test_couponRepo_CRUD() {
+	ctx := context.Background()
+	c := s.coupon("SPRING10")
+	c.CategoryIDs = []int{1}
+	added, err := s.testRepo.AddCoupon(ctx, c)
+	s.Require().NoError(err)
+	s.NotZero(added.ID)
+	s.Equal([]int{1}, added.CategoryIDs)
+	s.Zero(added.Used)
+
+	_, err = s.testRepo.AddCoupon(ctx, c)
+	s.True(errors.Is(err, ErrConflict), "codes are unique: %v", err)
+
+	added.Value = 20
+	edited, err := s.testRepo.EditCoupon(ctx, added)
+	s.NoError(err)
+	s.Equal(20, edited.Value)
+
+	got, err := s.testRepo.GetCouponByCode(ctx, "spring10")
+	s.NoError(err)
+	s.Equal(added.ID, got.ID)
+
+	list, err := s.testRepo.ListCoupons(ctx)
+	s.NoError(err)
+	s.Len(list, 1)
+
+	s.NoError(s.testRepo.DeleteCoupon(ctx, added.ID))
+	_, err = s.testRepo.GetCouponByID(ctx, added.ID)
+	s.True(errors.Is(err, ErrNotFound), err)
+	s.True(errors.Is(s.testRepo.DeleteCoupon(ctx, added.ID), ErrNotFound))
+
+	c.EndsAt = c.StartsAt
+	_, err = s.testRepo.AddCoupon(ctx, c)
+	s.True(errors.Is(err, ErrValidation), "ends_at must be after starts_at: %v", err)
+}
+
+func (s *CouponsTestSuite) Test_couponRepo_CouponUsage() {
+	ctx := context.Background()
+	added, err := s.testRepo.AddCoupon(ctx, s.coupon("ONCE"))
+	s.Require().NoError(err)
+	_, err = s.testRepo.pool.Exec(ctx,
+		"INSERT INTO coupon_redemptions (coupon_id, user_id) VALUES ($1, 1), ($1, 1), ($1, 2)", added.ID)
+	s.Require().NoError(err)
+
+	total, byUser, err := s.testRepo.CouponUsage(ctx, added.ID, 1)
+	s.NoError(err)
+	s.Equal(3, total)
+	s.Equal(2, byUser)
+
+	got, err := s.testRepo.GetCouponByID(ctx, added.ID)
+	s.NoError(err)
+	s.Equal(3, got.Used)
+
+	err = s.testRepo.DeleteCoupon(ctx, added.ID)
+	s.True(errors.Is(err, ErrConflict), "redeemed coupons are kept: %v", err)
+}
+
+func (s *CouponsTestSuite) Test_productRepo_ProductCategories() {
+	products := productRepo{pool: s.testRepo.pool}
+	got, err := products.ProductCategories(context.Background(), []string{promotedProduct, shopProduct})
+	s.NoError(err)
+	s.Equal(map[string][]int{promotedProduct: {1, 2}}, got)
+}
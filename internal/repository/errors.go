@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	ErrForeignKey = errors.New("foreign key violation")
+)
+
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgNotNullViolation    = "23502"
+	pgCheckViolation      = "23514"
+	pgInvalidText         = "22P02"
+	pgNumericOutOfRange   = "22003"
+)
+
+type FieldError struct {
+	Field  string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Error is a domain error returned by the repositories. Kind is one of the
+// Err* values above, so callers match it with errors.Is.
+type Error struct {
+	Kind   error
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Kind.Error())
+	if e.Detail != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Detail)
+	}
+	for _, f := range e.Fields {
+		fmt.Fprintf(&b, "; %s %s", f.Field, f.Reason)
+	}
+	return b.String()
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NewNotFoundError(detail string) error {
+	return &Error{Kind: ErrNotFound, Detail: detail}
+}
+
+func NewConflictError(detail string) error {
+	return &Error{Kind: ErrConflict, Detail: detail}
+}
+
+func NewValidationError(fields ...FieldError) error {
+	return &Error{Kind: ErrValidation, Fields: fields}
+}
+
+func NewForeignKeyError(field, reason string) error {
+	return &Error{Kind: ErrForeignKey, Fields: []FieldError{{Field: field, Reason: reason}}}
+}
+
+// classify maps pgx and Postgres errors onto the domain errors above.
+// Errors it does not recognise are returned unchanged.
+func classify(err error) error {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &Error{Kind: ErrNotFound, Err: err}
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	field := pgErr.ColumnName
+	if field == "" {
+		field = pgErr.ConstraintName
+	}
+	// Postgres' own messages name tables, constraints and values; clients
+	// get these instead.
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		return &Error{Kind: ErrConflict, Detail: uniqueDetail(pgErr), Err: err}
+	case pgForeignKeyViolation:
+		detail := "it refers to a record that doesn't exist"
+		if strings.HasPrefix(pgErr.Message, "update or delete") {
+			detail = "other records still refer to it"
+		}
+		return &Error{Kind: ErrForeignKey, Detail: detail, Err: err}
+	case pgNotNullViolation:
+		return &Error{Kind: ErrValidation, Fields: []FieldError{{Field: field, Reason: "is mandatory"}}, Err: err}
+	case pgCheckViolation:
+		return &Error{Kind: ErrValidation, Fields: []FieldError{{Field: field, Reason: "is not allowed"}}, Err: err}
+	case pgInvalidText:
+		return &Error{Kind: ErrValidation, Fields: []FieldError{{Field: field, Reason: "is malformed"}}, Err: err}
+	case pgNumericOutOfRange:
+		return &Error{Kind: ErrValidation, Fields: []FieldError{{Field: field, Reason: "is out of range"}}, Err: err}
+	}
+	return err
+}
+
+// uniqueDetail names the column of a unique constraint named the way
+// Postgres names them, table_column_key.
+func uniqueDetail(pgErr *pgconn.PgError) string {
+	column := strings.TrimPrefix(pgErr.ConstraintName, pgErr.TableName+"_")
+	if column == pgErr.ConstraintName || !strings.HasSuffix(column, "_key") {
+		return "the record already exists"
+	}
+	return "a record with this " + strings.TrimSuffix(column, "_key") + " already exists"
+}
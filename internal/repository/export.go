@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"market4/internal/model"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// exportFetchSize is how many rows each FETCH pulls from the export cursor.
+const exportFetchSize = 500
+
+// ExportProducts walks the filtered catalog with a server-side cursor and
+// hands every row to fn, so memory use doesn't grow with the catalog. An
+// archived shop or category is exported as none. An error from fn stops the
+// export and is returned as is.
+func (p *productRepo) ExportProducts(ctx context.Context, filter model.ProductFilter, fn func(model.ProductExport) error) error {
+	where, args := productFilter(filter)
+	dbReq := "DECLARE export_products NO SCROLL CURSOR FOR " +
+		"SELECT products.id, products.sku, products.name, products.uri, products.description, products.is_active, " +
+		"COALESCE(shops.id, 0), COALESCE(shops.name, ''), " +
+		"COALESCE(categories.id, 0), COALESCE(categories.name, ''), COALESCE(categories.uri_name, ''), " +
+		"prices.id, prices.sale_price, prices.factory_price, prices.discount_price, prices.is_active " +
+		"FROM products " +
+		"LEFT JOIN productshop ON productshop.product_id = products.id " +
+		"LEFT JOIN shops ON shops.id = productshop.shop_id AND shops.deleted_at IS NULL " +
+		"LEFT JOIN productcategory ON productcategory.product_id = products.id " +
+		"LEFT JOIN categories ON categories.id = productcategory.category_id AND categories.deleted_at IS NULL " +
+		"LEFT JOIN prices ON prices.product_id = products.id AND prices.deleted_at IS NULL " +
+		"WHERE products.deleted_at IS NULL" + where + " " +
+		"ORDER BY products.sku"
+
+	err := p.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, dbReq, args...)
+		if err != nil {
+			return classify(err)
+		}
+		for {
+			fetched, err := fetchExport(ctx, tx, fn)
+			if err != nil {
+				return err
+			}
+			if fetched < exportFetchSize {
+				return nil
+			}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("ExportProducts: %w", err)
+	}
+	return nil
+}
+
+func fetchExport(ctx context.Context, tx pgx.Tx, fn func(model.ProductExport) error) (int, error) {
+	rows, err := tx.Query(ctx, fmt.Sprintf("FETCH %d FROM export_products", exportFetchSize))
+	if err != nil {
+		return 0, classify(err)
+	}
+	defer rows.Close()
+
+	fetched := 0
+	for rows.Next() {
+		var row model.ProductExport
+		var priceID *int
+		var salePrice, factoryPrice, discountPrice *int
+		var priceActive *bool
+		err = rows.Scan(
+			&row.Product.ID,
+			&row.Product.SKU,
+			&row.Product.Name,
+			&row.Product.URI,
+			&row.Product.Description,
+			&row.Product.IsActive,
+			&row.Shop.ID,
+			&row.Shop.Name,
+			&row.Category.ID,
+			&row.Category.Name,
+			&row.Category.URI_name,
+			&priceID,
+			&salePrice,
+			&factoryPrice,
+			&discountPrice,
+			&priceActive)
+		if err != nil {
+			return fetched, classify(err)
+		}
+		row.Product.Type = productType(row.Product.URI, row.Product.SKU)
+		if priceID != nil {
+			row.Price = &model.Price{
+				ID:            *priceID,
+				SalePrice:     *salePrice,
+				FactoryPrice:  *factoryPrice,
+				DiscountPrice: *discountPrice,
+				IsActive:      *priceActive,
+				ProductID:     row.Product.ID,
+			}
+		}
+		if err = fn(row); err != nil {
+			return fetched, err
+		}
+		fetched++
+	}
+	return fetched, classify(rows.Err())
+}
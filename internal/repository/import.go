@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"market4/internal/model"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// ErrRolledBack marks rows that were valid but not written because another
+// row of the same transaction failed.
+var ErrRolledBack = errors.New("rolled back")
+
+// UpsertResult reports what happened to one row of UpsertProducts.
+type UpsertResult struct {
+	ProductID string
+	Created   bool
+	Err       error
+}
+
+// UpsertProducts creates or updates products by SKU in one transaction. If
+// any row fails nothing is written: the failing row carries its error and
+// the others ErrRolledBack. An archived product with the same SKU is brought
+// back. The returned error is reserved for failures not tied to a row.
+func (p *productRepo) UpsertProducts(ctx context.Context, rows []model.ProductImport) ([]UpsertResult, error) {
+	results := make([]UpsertResult, len(rows))
+	failed := false
+	err := conn(ctx, p.pool).BeginFunc(ctx, func(tx pgx.Tx) error {
+		for i := range rows {
+			results[i].ProductID, results[i].Created, results[i].Err = upsertProduct(ctx, tx, rows[i])
+			if results[i].Err != nil {
+				failed = true
+				break
+			}
+		}
+		if failed {
+			return ErrRolledBack
+		}
+		return nil
+	})
+	if failed {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = UpsertResult{Err: ErrRolledBack}
+			}
+		}
+		return results, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("UpsertProducts: %w", classify(err))
+	}
+	return results, nil
+}
+
+func upsertProduct(ctx context.Context, tx pgx.Tx, row model.ProductImport) (string, bool, error) {
+	product := row.Product
+	dbReq := "INSERT INTO products (sku, name, uri, description, is_active) " +
+		"VALUES ($1, $2, $3, $4, $5) " +
+		"ON CONFLICT (sku) DO UPDATE SET " +
+		"name = EXCLUDED.name, uri = EXCLUDED.uri, description = EXCLUDED.description, " +
+		"is_active = EXCLUDED.is_active, updated = CURRENT_TIMESTAMP, deleted_at = NULL " +
+		"RETURNING id, xmax = 0"
+	uri := fmt.Sprintf("/product/%s-%s", product.Type, product.SKU)
+	var id string
+	var created bool
+	err := tx.QueryRow(ctx, dbReq, product.SKU, product.Name, uri, product.Description, product.IsActive).Scan(&id, &created)
+	if err != nil {
+		return "", false, classify(err)
+	}
+
+	links := []struct {
+		table, column, parent, noun string
+		parentID                    int
+	}{
+		{"productshop", "shop_id", "shops", "shop", row.ShopID},
+		{"productcategory", "category_id", "categories", "category", row.CategoryID},
+	}
+	for _, link := range links {
+		_, err = tx.Exec(ctx, "DELETE FROM "+link.table+" WHERE product_id = $1", id)
+		if err != nil {
+			return "", false, classify(err)
+		}
+		dbReq = "INSERT INTO " + link.table + " (" + link.column + ", product_id) " +
+			"SELECT id, $2 FROM " + link.parent + " WHERE id = $1 AND deleted_at IS NULL"
+		tag, err := tx.Exec(ctx, dbReq, link.parentID, id)
+		if err != nil {
+			return "", false, classify(err)
+		}
+		if tag.RowsAffected() == 0 {
+			return "", false, NewForeignKeyError(link.column, link.noun+" doesn't exist")
+		}
+	}
+
+	if row.Price == nil {
+		return id, created, nil
+	}
+	price := row.Price
+	dbReq = "UPDATE prices " +
+		"SET sale_price = $1, factory_price = $2, discount_price = $3, is_active = $4, updated = CURRENT_TIMESTAMP " +
+		"WHERE product_id = $5 AND deleted_at IS NULL"
+	tag, err := tx.Exec(ctx, dbReq, price.SalePrice, price.FactoryPrice, price.DiscountPrice, price.IsActive, id)
+	if err != nil {
+		return "", false, classify(err)
+	}
+	if tag.RowsAffected() == 0 {
+		dbReq = "INSERT INTO prices (sale_price, factory_price, discount_price, product_id, is_active) " +
+			"VALUES ($1, $2, $3, $4, $5)"
+		_, err = tx.Exec(ctx, dbReq, price.SalePrice, price.FactoryPrice, price.DiscountPrice, id, price.IsActive)
+		if err != nil {
+			return "", false, classify(err)
+		}
+	}
+	return id, created, nil
+}
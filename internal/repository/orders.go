@@ -0,0 +1,387 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"market4/internal/model"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type orderRepo struct {
+	pool           *pgxpool.Pool
+	reservationTTL time.Duration
+}
+
+// NewOrderRepository returns orders whose stock is held for reservationTTL
+// after checkout.
+func NewOrderRepository(pool *pgxpool.Pool, reservationTTL time.Duration) Order {
+	return &orderRepo{pool: pool, reservationTTL: reservationTTL}
+}
+
+const orderColumns = "orders.id, orders.user_id, orders.status, orders.subtotal, orders.discount, " +
+	"COALESCE(orders.coupon_id, 0), orders.coupon_code, orders.coupon_discount, orders.total, " +
+	"orders.created, orders.updated, " +
+	"(SELECT min(expires_at) FROM reservations WHERE reservations.order_id = orders.id AND reservations.status = 'held'), " +
+	"ARRAY(SELECT DISTINCT shop_id FROM reservations WHERE reservations.order_id = orders.id ORDER BY shop_id)"
+
+func scanOrder(row pgx.Row) (model.Order, error) {
+	var o model.Order
+	err := row.Scan(&o.ID, &o.UserID, &o.Status, &o.Subtotal, &o.Discount,
+		&o.CouponID, &o.CouponCode, &o.CouponDiscount, &o.Total,
+		&o.Created, &o.Updated, &o.ReservedUntil, &o.ShopIDs)
+	return o, err
+}
+
+// CreateOrder stores a checked-out cart in one transaction: the order and
+// its items, the stock reservations, the coupon redemption and the first
+// history entry. The ordered products leave the user's cart. The coupon row
+// is locked while its usage is recounted, so two checkouts can't both take
+// its last redemption.
+func (r *orderRepo) CreateOrder(ctx context.Context, order model.Order) (model.Order, error) {
+	err := r.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if order.CouponID != 0 {
+			if err := redeemable(ctx, tx, order.CouponID, order.UserID); err != nil {
+				return err
+			}
+		}
+
+		var couponID interface{}
+		if order.CouponID != 0 {
+			couponID = order.CouponID
+		}
+		dbReq := "INSERT INTO orders (user_id, status, subtotal, discount, coupon_id, coupon_code, coupon_discount, total) " +
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8) " +
+			"RETURNING id, created, updated"
+		err := tx.QueryRow(ctx, dbReq,
+			order.UserID, model.OrderCreated, order.Subtotal, order.Discount,
+			couponID, order.CouponCode, order.CouponDiscount, order.Total).Scan(&order.ID, &order.Created, &order.Updated)
+		if err != nil {
+			return err
+		}
+		order.Status = model.OrderCreated
+
+		productIDs := make([]string, 0, len(order.Items))
+		for _, item := range order.Items {
+			_, err = tx.Exec(ctx,
+				"INSERT INTO order_items (order_id, product_id, sku, name, quantity, unit_price, total) "+
+					"VALUES ($1, $2, $3, $4, $5, $6, $7)",
+				order.ID, item.ProductID, item.SKU, item.Name, item.Quantity, item.UnitPrice, item.Total)
+			if err != nil {
+				return err
+			}
+			productIDs = append(productIDs, item.ProductID)
+		}
+		if order.ShopIDs, err = reserve(ctx, tx, order.ID, order.Items, r.reservationTTL); err != nil {
+			return err
+		}
+
+		if order.CouponID != 0 {
+			_, err = tx.Exec(ctx,
+				"INSERT INTO coupon_redemptions (coupon_id, user_id, order_id) VALUES ($1, $2, $3)",
+				order.CouponID, order.UserID, order.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		transition := model.OrderTransition{To: model.OrderCreated, ActorID: order.UserID}
+		err = tx.QueryRow(ctx,
+			"INSERT INTO order_transitions (order_id, to_status, actor_id) VALUES ($1, $2, $3) RETURNING created",
+			order.ID, transition.To, transition.ActorID).Scan(&transition.Created)
+		if err != nil {
+			return err
+		}
+		order.History = []model.OrderTransition{transition}
+
+		_, err = tx.Exec(ctx,
+			"DELETE FROM cart_items USING carts "+
+				"WHERE carts.id = cart_items.cart_id AND carts.user_id = $1 AND cart_items.product_id = ANY($2::uuid[])",
+			order.UserID, productIDs)
+		return err
+	})
+	if err != nil {
+		return order, fmt.Errorf("CreateOrder: %w", classify(err))
+	}
+	return order, nil
+}
+
+// reserve holds stock for every item in one shop that has enough of it and
+// returns the shops, in order.
+// Stock rows are updated in product order, so two checkouts of the same
+// products can't deadlock, and only while enough is left, so concurrent
+// checkouts can't reserve more than there is.
+func reserve(ctx context.Context, tx pgx.Tx, orderID int, items []model.OrderItem, ttl time.Duration) ([]int, error) {
+	reservedIn := make(map[int]bool)
+	sorted := make([]model.OrderItem, len(items))
+	copy(sorted, items)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ProductID < sorted[j].ProductID })
+	for _, item := range sorted {
+		var shopIDs []int
+		rows, err := tx.Query(ctx,
+			"SELECT shop_id FROM stock WHERE product_id = $1 AND quantity - reserved >= $2 "+
+				"ORDER BY quantity - reserved DESC, shop_id",
+			item.ProductID, item.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var shopID int
+			if err = rows.Scan(&shopID); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			shopIDs = append(shopIDs, shopID)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+
+		reserved := false
+		for _, shopID := range shopIDs {
+			tag, err := tx.Exec(ctx,
+				"UPDATE stock SET reserved = reserved + $3, updated = CURRENT_TIMESTAMP "+
+					"WHERE shop_id = $1 AND product_id = $2 AND quantity - reserved >= $3",
+				shopID, item.ProductID, item.Quantity)
+			if err != nil {
+				return nil, err
+			}
+			if tag.RowsAffected() == 0 {
+				// Another checkout took it since the candidates were read.
+				continue
+			}
+			_, err = tx.Exec(ctx,
+				"INSERT INTO reservations (order_id, shop_id, product_id, quantity, status, expires_at) "+
+					"VALUES ($1, $2, $3, $4, 'held', CURRENT_TIMESTAMP + $5::interval)",
+				orderID, shopID, item.ProductID, item.Quantity, ttl)
+			if err != nil {
+				return nil, err
+			}
+			reserved = true
+			reservedIn[shopID] = true
+			break
+		}
+		if !reserved {
+			return nil, NewConflictError(fmt.Sprintf("not enough stock of %s", item.SKU))
+		}
+	}
+	shopIDs := make([]int, 0, len(reservedIn))
+	for shopID := range reservedIn {
+		shopIDs = append(shopIDs, shopID)
+	}
+	sort.Ints(shopIDs)
+	return shopIDs, nil
+}
+
+// redeemable locks the coupon and checks its limits against the
+// redemptions made so far.
+func redeemable(ctx context.Context, tx pgx.Tx, couponID, userID int) error {
+	var usageLimit, perUserLimit, used, usedByUser int
+	err := tx.QueryRow(ctx, "SELECT usage_limit, per_user_limit FROM coupons WHERE id = $1 FOR UPDATE", couponID).
+		Scan(&usageLimit, &perUserLimit)
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRow(ctx,
+		"SELECT count(*), count(*) FILTER (WHERE user_id = $2) FROM coupon_redemptions WHERE coupon_id = $1",
+		couponID, userID).Scan(&used, &usedByUser)
+	if err != nil {
+		return err
+	}
+	if usageLimit > 0 && used >= usageLimit || perUserLimit > 0 && usedByUser >= perUserLimit {
+		return NewConflictError(fmt.Sprintf("coupon %d can't be redeemed any more", couponID))
+	}
+	return nil
+}
+
+// GetOrder returns the order with its items and history.
+func (r *orderRepo) GetOrder(ctx context.Context, orderID int) (model.Order, error) {
+	order, err := scanOrder(r.pool.QueryRow(ctx, "SELECT "+orderColumns+" FROM orders WHERE id = $1", orderID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			err = NewNotFoundError(fmt.Sprintf("order %d", orderID))
+		}
+		return order, fmt.Errorf("GetOrder: %w", classify(err))
+	}
+	orders := []model.Order{order}
+	if err = r.items(ctx, orders); err != nil {
+		return order, fmt.Errorf("GetOrder: %w", classify(err))
+	}
+	order = orders[0]
+
+	rows, err := r.pool.Query(ctx,
+		"SELECT from_status, to_status, actor_id, note, created FROM order_transitions WHERE order_id = $1 ORDER BY id",
+		orderID)
+	if err != nil {
+		return order, fmt.Errorf("GetOrder: %w", classify(err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t model.OrderTransition
+		if err = rows.Scan(&t.From, &t.To, &t.ActorID, &t.Note, &t.Created); err != nil {
+			return order, fmt.Errorf("GetOrder: %w", classify(err))
+		}
+		order.History = append(order.History, t)
+	}
+	if err = rows.Err(); err != nil {
+		return order, fmt.Errorf("GetOrder: %w", classify(err))
+	}
+	return order, nil
+}
+
+// ListOrders returns the matching orders with their items, newest first.
+func (r *orderRepo) ListOrders(ctx context.Context, filter model.OrderFilter) ([]model.Order, error) {
+	orders := make([]model.Order, 0)
+	var conditions []string
+	var args []interface{}
+	if filter.UserID != 0 {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if filter.StaffID != 0 {
+		args = append(args, filter.StaffID)
+		conditions = append(conditions, fmt.Sprintf("(user_id = $%[1]d OR id IN ("+
+			"SELECT reservations.order_id FROM reservations "+
+			"JOIN staff_shops ON staff_shops.shop_id = reservations.shop_id "+
+			"WHERE staff_shops.user_id = $%[1]d))", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	dbReq := "SELECT " + orderColumns + " FROM orders"
+	if len(conditions) > 0 {
+		dbReq += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	dbReq += " ORDER BY id DESC"
+
+	rows, err := r.pool.Query(ctx, dbReq, args...)
+	if err != nil {
+		return orders, fmt.Errorf("ListOrders: %w", classify(err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return orders, fmt.Errorf("ListOrders: %w", classify(err))
+		}
+		orders = append(orders, o)
+	}
+	if err = rows.Err(); err != nil {
+		return orders, fmt.Errorf("ListOrders: %w", classify(err))
+	}
+	rows.Close()
+	if err = r.items(ctx, orders); err != nil {
+		return orders, fmt.Errorf("ListOrders: %w", classify(err))
+	}
+	return orders, nil
+}
+
+// items fills in the items of all the orders with one query.
+func (r *orderRepo) items(ctx context.Context, orders []model.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	byID := make(map[int]*model.Order, len(orders))
+	orderIDs := make([]int, 0, len(orders))
+	for i := range orders {
+		orders[i].Items = make([]model.OrderItem, 0)
+		byID[orders[i].ID] = &orders[i]
+		orderIDs = append(orderIDs, orders[i].ID)
+	}
+	rows, err := r.pool.Query(ctx,
+		"SELECT order_id, product_id, sku, name, quantity, unit_price, total "+
+			"FROM order_items WHERE order_id = ANY($1::bigint[]) ORDER BY order_id, sku",
+		orderIDs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var orderID int
+		var item model.OrderItem
+		err = rows.Scan(&orderID, &item.ProductID, &item.SKU, &item.Name, &item.Quantity, &item.UnitPrice, &item.Total)
+		if err != nil {
+			return err
+		}
+		byID[orderID].Items = append(byID[orderID].Items, item)
+	}
+	return rows.Err()
+}
+
+// TransitionOrder moves an order to another status and records who did
+// it.
+func (r *orderRepo) TransitionOrder(ctx context.Context, orderID int, transition model.OrderTransition) (model.Order, error) {
+	err := r.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		return transitionOrder(ctx, tx, orderID, transition)
+	})
+	if err != nil {
+		return model.Order{}, fmt.Errorf("TransitionOrder: %w", classify(err))
+	}
+	return r.GetOrder(ctx, orderID)
+}
+
+// transitionOrder locks the order while its current status is checked
+// against the state machine, so concurrent transitions can't both succeed.
+// Paying an order deducts its reserved stock; cancelling it gives the stock
+// and its coupon redemption back.
+func transitionOrder(ctx context.Context, tx pgx.Tx, orderID int, transition model.OrderTransition) error {
+	err := tx.QueryRow(ctx, "SELECT status FROM orders WHERE id = $1 FOR UPDATE", orderID).Scan(&transition.From)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return NewNotFoundError(fmt.Sprintf("order %d", orderID))
+		}
+		return err
+	}
+	if !model.CanTransition(transition.From, transition.To) {
+		return NewConflictError(fmt.Sprintf("order %d is %s and can't become %s", orderID, transition.From, transition.To))
+	}
+	_, err = tx.Exec(ctx, "UPDATE orders SET status = $2, updated = CURRENT_TIMESTAMP WHERE id = $1",
+		orderID, transition.To)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx,
+		"INSERT INTO order_transitions (order_id, from_status, to_status, actor_id, note) VALUES ($1, $2, $3, $4, $5)",
+		orderID, transition.From, transition.To, transition.ActorID, transition.Note)
+	if err != nil {
+		return err
+	}
+	switch transition.To {
+	case model.OrderPaid:
+		return settleReservations(ctx, tx, orderID, true)
+	case model.OrderCancelled:
+		_, err = tx.Exec(ctx, "DELETE FROM coupon_redemptions WHERE order_id = $1", orderID)
+		if err != nil {
+			return err
+		}
+		return settleReservations(ctx, tx, orderID, false)
+	}
+	return nil
+}
+
+// settleReservations ends the held reservations of an order. The reserved
+// units are freed either way; a deduction also takes them off the stock.
+func settleReservations(ctx context.Context, tx pgx.Tx, orderID int, deduct bool) error {
+	set, status := "reserved = reserved - r.quantity", "released"
+	if deduct {
+		set, status = "quantity = quantity - r.quantity, reserved = reserved - r.quantity", "deducted"
+	}
+	_, err := tx.Exec(ctx,
+		"UPDATE stock SET "+set+", updated = CURRENT_TIMESTAMP "+
+			"FROM reservations r "+
+			"WHERE r.order_id = $1 AND r.status = 'held' AND stock.shop_id = r.shop_id AND stock.product_id = r.product_id",
+		orderID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx,
+		"UPDATE reservations SET status = $2, updated = CURRENT_TIMESTAMP WHERE order_id = $1 AND status = 'held'",
+		orderID, status)
+	return err
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"market4/internal/model"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/suite"
+)
+
+type OrdersTestSuite struct {
+	suite.Suite
+	testRepo orderRepo
+	Data     TestData
+}
+
+func Test_OrdersSuite(t *testing.T) {
+	suite.Run(t, new(OrdersTestSuite))
+}
+
+func (s *OrdersTestSuite) SetupTest() {
+	fmt.Println("start setup")
+	var err error
+	s.testRepo.pool, err = pgxpool.Connect(context.Background(), testDSN)
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	s.testRepo.reservationTTL = time.Hour
+	s.Data, err = loadTestDataFromYaml("orders_test.yaml")
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	for _, r := range s.Data.Conf.Setup.Requests {
+		_, err = s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			return
+		}
+	}
+}
+
+func (s *OrdersTestSuite) TearDownTest() {
+	fmt.Println("cleaning up")
+	for _, r := range s.Data.Conf.Teardown.Requests {
+		_, err := s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			s.Fail("cleaning failed")
+		}
+	}
+}
+
+func (s *OrdersTestSuite) order(userID, couponID int) model.Order {
+	order := model.Order{
+		UserID:   userID,
+		Subtotal: 2000,
+		Total:    2000,
+		Items: []model.OrderItem{
+			{ProductID: promotedProduct, SKU: "3001", Name: "пушка", Quantity: 1, UnitPrice: 2000, Total: 2000},
+		},
+	}
+	if couponID != 0 {
+		order.CouponID, order.CouponCode, order.CouponDiscount, order.Total = couponID, "ONCE", 100, 1900
+	}
+	return order
+}
+
+func (s *OrdersTestSuite) Test_orderRepo_CreateOrder() {
+	ctx := context.Background()
+	carts := cartRepo{pool: s.testRepo.pool}
+	s.Require().NoError(carts.AddCartItem(ctx, 1, promotedProduct, 1))
+	s.Require().NoError(carts.AddCartItem(ctx, 1, shopProduct, 1))
+
+	created, err := s.testRepo.CreateOrder(ctx, s.order(1, 1))
+	s.Require().NoError(err)
+	s.NotZero(created.ID)
+	s.Equal(model.OrderCreated, created.Status)
+
+	items, err := carts.GetCart(ctx, 1)
+	s.NoError(err)
+	s.Require().Len(items, 1, "only the ordered products leave the cart")
+	s.Equal(shopProduct, items[0].ProductID)
+
+	got, err := s.testRepo.GetOrder(ctx, created.ID)
+	s.Require().NoError(err)
+	s.Equal(1900, got.Total)
+	s.Equal(1, got.CouponID)
+	s.Equal(s.order(1, 1).Items, got.Items)
+	s.Equal([]int{1}, got.ShopIDs)
+	s.Equal(got.ShopIDs, created.ShopIDs)
+	s.Require().Len(got.History, 1)
+	s.Equal(model.OrderTransition{To: model.OrderCreated, ActorID: 1, Created: got.History[0].Created}, got.History[0])
+
+	_, err = s.testRepo.CreateOrder(ctx, s.order(1, 1))
+	s.True(errors.Is(err, ErrConflict), "the coupon is once per user: %v", err)
+	_, err = s.testRepo.CreateOrder(ctx, s.order(2, 1))
+	s.NoError(err, "other users still may use it")
+}
+
+func (s *OrdersTestSuite) Test_orderRepo_TransitionOrder() {
+	ctx := context.Background()
+	created, err := s.testRepo.CreateOrder(ctx, s.order(1, 1))
+	s.Require().NoError(err)
+
+	paid, err := s.testRepo.TransitionOrder(ctx, created.ID, model.OrderTransition{To: model.OrderPaid, ActorID: 2, Note: "наличными"})
+	s.Require().NoError(err)
+	s.Equal(model.OrderPaid, paid.Status)
+	s.Require().Len(paid.History, 2)
+	s.Equal(model.OrderCreated, paid.History[1].From)
+	s.Equal(2, paid.History[1].ActorID)
+	s.Equal("наличными", paid.History[1].Note)
+
+	_, err = s.testRepo.TransitionOrder(ctx, created.ID, model.OrderTransition{To: model.OrderCancelled, ActorID: 1})
+	s.True(errors.Is(err, ErrConflict), "paid orders are refunded: %v", err)
+	_, err = s.testRepo.TransitionOrder(ctx, 100, model.OrderTransition{To: model.OrderPaid, ActorID: 2})
+	s.True(errors.Is(err, ErrNotFound), err)
+
+	second, err := s.testRepo.CreateOrder(ctx, s.order(2, 1))
+	s.Require().NoError(err)
+	_, err = s.testRepo.TransitionOrder(ctx, second.ID, model.OrderTransition{To: model.OrderCancelled, ActorID: 2})
+	s.Require().NoError(err)
+	_, err = s.testRepo.CreateOrder(ctx, s.order(2, 1))
+	s.NoError(err, "cancelling gives the coupon back")
+}
+
+func (s *OrdersTestSuite) Test_orderRepo_ListOrders() {
+	ctx := context.Background()
+	first, err := s.testRepo.CreateOrder(ctx, s.order(1, 0))
+	s.Require().NoError(err)
+	second, err := s.testRepo.CreateOrder(ctx, s.order(1, 0))
+	s.Require().NoError(err)
+	_, err = s.testRepo.CreateOrder(ctx, s.order(2, 0))
+	s.Require().NoError(err)
+	_, err = s.testRepo.TransitionOrder(ctx, first.ID, model.OrderTransition{To: model.OrderPaid, ActorID: 2})
+	s.Require().NoError(err)
+
+	all, err := s.testRepo.ListOrders(ctx, model.OrderFilter{})
+	s.NoError(err)
+	s.Len(all, 3)
+
+	mine, err := s.testRepo.ListOrders(ctx, model.OrderFilter{UserID: 1})
+	s.NoError(err)
+	s.Require().Len(mine, 2)
+	s.Equal(second.ID, mine[0].ID, "newest first")
+	s.Len(mine[0].Items, 1)
+
+	paid, err := s.testRepo.ListOrders(ctx, model.OrderFilter{UserID: 1, Status: model.OrderPaid})
+	s.NoError(err)
+	s.Require().Len(paid, 1)
+	s.Equal(first.ID, paid[0].ID)
+
+	staffed, err := s.testRepo.ListOrders(ctx, model.OrderFilter{StaffID: 7})
+	s.NoError(err)
+	s.Len(staffed, 3, "staff see the orders reserved in their shops")
+	elsewhere, err := s.testRepo.ListOrders(ctx, model.OrderFilter{StaffID: 8})
+	s.NoError(err)
+	s.Empty(elsewhere, "but not those of other shops")
+	own, err := s.testRepo.ListOrders(ctx, model.OrderFilter{StaffID: 2})
+	s.NoError(err)
+	s.Len(own, 1, "and their own orders as customers")
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"market4/internal/model"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type paymentRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPaymentRepository(pool *pgxpool.Pool) Payment {
+	return &paymentRepo{pool: pool}
+}
+
+const paymentColumns = "id, order_id, provider, status, amount, reason, created, updated"
+
+func scanPayment(row pgx.Row) (model.Payment, error) {
+	var p model.Payment
+	err := row.Scan(&p.ID, &p.OrderID, &p.Provider, &p.Status, &p.Amount, &p.Reason, &p.Created, &p.Updated)
+	return p, err
+}
+
+// AddPayment stores a payment the provider answered. An order has at most
+// one payment that wasn't declined, so a second one is a conflict.
+func (r *paymentRepo) AddPayment(ctx context.Context, p model.Payment) (model.Payment, error) {
+	dbReq := "INSERT INTO payments (id, order_id, provider, status, amount, reason) " +
+		"VALUES ($1, $2, $3, $4, $5, $6) RETURNING " + paymentColumns
+	payment, err := scanPayment(r.pool.QueryRow(ctx, dbReq, p.ID, p.OrderID, p.Provider, p.Status, p.Amount, p.Reason))
+	if err != nil {
+		return payment, fmt.Errorf("AddPayment: %w", classify(err))
+	}
+	return payment, nil
+}
+
+func (r *paymentRepo) GetPayment(ctx context.Context, paymentID string) (model.Payment, error) {
+	payment, err := scanPayment(r.pool.QueryRow(ctx, "SELECT "+paymentColumns+" FROM payments WHERE id = $1", paymentID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			err = NewNotFoundError("payment " + paymentID)
+		}
+		return payment, fmt.Errorf("GetPayment: %w", classify(err))
+	}
+	return payment, nil
+}
+
+// ListPayments returns the payments of an order, oldest first.
+func (r *paymentRepo) ListPayments(ctx context.Context, orderID int) ([]model.Payment, error) {
+	payments := make([]model.Payment, 0)
+	rows, err := r.pool.Query(ctx, "SELECT "+paymentColumns+" FROM payments WHERE order_id = $1 ORDER BY created, id", orderID)
+	if err != nil {
+		return payments, fmt.Errorf("ListPayments: %w", classify(err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return payments, fmt.Errorf("ListPayments: %w", classify(err))
+		}
+		payments = append(payments, p)
+	}
+	if err = rows.Err(); err != nil {
+		return payments, fmt.Errorf("ListPayments: %w", classify(err))
+	}
+	return payments, nil
+}
+
+// SetPaymentStatus moves a payment from one status to another. When
+// transition is given, the payment's order moves in the same transaction,
+// so a captured payment and a paid order are never seen apart. A payment
+// that is no longer in the from status is a conflict: somebody else, e.g.
+// a repeated webhook, has already moved it.
+func (r *paymentRepo) SetPaymentStatus(ctx context.Context,
+	paymentID, from, to, reason string,
+	transition *model.OrderTransition) (model.Payment, error) {
+	var payment model.Payment
+	err := r.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var err error
+		payment, err = scanPayment(tx.QueryRow(ctx,
+			"UPDATE payments SET status = $3, reason = $4, updated = CURRENT_TIMESTAMP "+
+				"WHERE id = $1 AND status = $2 RETURNING "+paymentColumns,
+			paymentID, from, to, reason))
+		if err == pgx.ErrNoRows {
+			return NewConflictError(fmt.Sprintf("payment %s is no longer %s", paymentID, from))
+		}
+		if err != nil || transition == nil {
+			return err
+		}
+		return transitionOrder(ctx, tx, payment.OrderID, *transition)
+	})
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("SetPaymentStatus: %w", classify(err))
+	}
+	return payment, nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"market4/internal/model"
+	"testing"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/suite"
+)
+
+type PaymentsTestSuite struct {
+	suite.Suite
+	testRepo paymentRepo
+	Data     TestData
+}
+
+func Test_PaymentsSuite(t *testing.T) {
+	suite.Run(t, new(PaymentsTestSuite))
+}
+
+func (s *PaymentsTestSuite) SetupTest() {
+	fmt.Println("start setup")
+	var err error
+	s.testRepo.pool, err = pgxpool.Connect(context.Background(), testDSN)
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	s.Data, err = loadTestDataFromYaml("payments_test.yaml")
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	for _, r := range s.Data.Conf.Setup.Requests {
+		_, err = s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			return
+		}
+	}
+}
+
+func (s *PaymentsTestSuite) TearDownTest() {
+	fmt.Println("cleaning up")
+	for _, r := range s.Data.Conf.Teardown.Requests {
+		_, err := s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			s.Fail("cleaning failed")
+		}
+	}
+}
+
+func (s *PaymentsTestSuite) Test_paymentRepo_AddPayment() {
+	ctx := context.Background()
+	declined, err := s.testRepo.AddPayment(ctx, model.Payment{ID: "fake_1", OrderID: 1, Provider: "fake", Status: "declined", Amount: 2000, Reason: "card declined"})
+	s.Require().NoError(err)
+	s.Equal("card declined", declined.Reason)
+	s.NotZero(declined.Created)
+
+	_, err = s.testRepo.AddPayment(ctx, model.Payment{ID: "fake_2", OrderID: 1, Provider: "fake", Status: "pending", Amount: 2000})
+	s.Require().NoError(err, "a declined payment may be retried")
+	_, err = s.testRepo.AddPayment(ctx, model.Payment{ID: "fake_3", OrderID: 1, Provider: "fake", Status: "authorized", Amount: 2000})
+	s.True(errors.Is(err, ErrConflict), "one open payment per order: %v", err)
+	_, err = s.testRepo.AddPayment(ctx, model.Payment{ID: "fake_4", OrderID: 100, Provider: "fake", Status: "authorized", Amount: 2000})
+	s.True(errors.Is(err, ErrForeignKey), err)
+
+	payments, err := s.testRepo.ListPayments(ctx, 1)
+	s.NoError(err)
+	s.Require().Len(payments, 2)
+	s.Equal("fake_1", payments[0].ID)
+
+	_, err = s.testRepo.GetPayment(ctx, "fake_3")
+	s.True(errors.Is(err, ErrNotFound), err)
+}
+
+func (s *PaymentsTestSuite) Test_paymentRepo_SetPaymentStatus() {
+	ctx := context.Background()
+	_, err := s.testRepo.AddPayment(ctx, model.Payment{ID: "fake_1", OrderID: 1, Provider: "fake", Status: "authorized", Amount: 2000})
+	s.Require().NoError(err)
+
+	captured, err := s.testRepo.SetPaymentStatus(ctx, "fake_1", "authorized", "captured", "",
+		&model.OrderTransition{To: model.OrderPaid, ActorID: 1, Note: "payment fake_1"})
+	s.Require().NoError(err)
+	s.Equal("captured", captured.Status)
+
+	orders := orderRepo{pool: s.testRepo.pool}
+	order, err := orders.GetOrder(ctx, 1)
+	s.Require().NoError(err)
+	s.Equal(model.OrderPaid, order.Status)
+
+	_, err = s.testRepo.SetPaymentStatus(ctx, "fake_1", "authorized", "captured", "", nil)
+	s.True(errors.Is(err, ErrConflict), "a repeated capture is noticed: %v", err)
+
+	_, err = s.testRepo.SetPaymentStatus(ctx, "fake_1", "captured", "refunded", "",
+		&model.OrderTransition{To: model.OrderCancelled, ActorID: 1})
+	s.True(errors.Is(err, ErrConflict), "paid orders aren't cancelled: %v", err)
+	got, err := s.testRepo.GetPayment(ctx, "fake_1")
+	s.Require().NoError(err)
+	s.Equal("captured", got.Status, "the payment stays as it was when the order can't move")
+}
@@ -22,7 +22,7 @@ func (price *priceRepo) AddPrice(ctx context.Context, p *model.Price) (model.Pri
 		"VALUES ($1, $2, $3, $4, $5)" +
 		"RETURNING sale_price, factory_price, discount_price"
 	var newPrice model.Price
-	err := price.pool.QueryRow(ctx,
+	err := conn(ctx, price.pool).QueryRow(ctx,
 		dbReq,
 		p.SalePrice,
 		p.FactoryPrice,
@@ -32,7 +32,7 @@ func (price *priceRepo) AddPrice(ctx context.Context, p *model.Price) (model.Pri
 		&newPrice.FactoryPrice,
 		&newPrice.DiscountPrice)
 	if err != nil {
-		return newPrice, fmt.Errorf("AddPrice: %w", err)
+		return newPrice, fmt.Errorf("AddPrice: %w", classify(err))
 	}
 	return newPrice, nil
 }
@@ -40,10 +40,10 @@ func (price *priceRepo) AddPrice(ctx context.Context, p *model.Price) (model.Pri
 func (price *priceRepo) EditPrice(ctx context.Context, p *model.Price) (model.Price, error) {
 	var dbReq = "UPDATE prices " +
 		"SET sale_price=$1, factory_price=$2, discount_price=$3, is_active=$4, updated=CURRENT_TIMESTAMP " +
-		"WHERE id = $5" +
+		"WHERE id = $5 AND deleted_at IS NULL " +
 		"RETURNING id, sale_price, factory_price, discount_price, is_active, product_id"
 	var result model.Price
-	err := price.pool.QueryRow(
+	err := conn(ctx, price.pool).QueryRow(
 		ctx,
 		dbReq,
 		p.SalePrice,
@@ -57,10 +57,7 @@ func (price *priceRepo) EditPrice(ctx context.Context, p *model.Price) (model.Pr
 		&result.IsActive,
 		&result.ProductID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return result, nil
-		}
-		return result, fmt.Errorf("EditPrice: %w", err)
+		return result, fmt.Errorf("EditPrice: %w", classify(err))
 	}
 	return result, nil
 }
@@ -68,10 +65,10 @@ func (price *priceRepo) EditPrice(ctx context.Context, p *model.Price) (model.Pr
 func (price *priceRepo) EditPriceByProductID(ctx context.Context, p *model.Price) (model.Price, error) {
 	var dbReq = "UPDATE prices " +
 		"SET sale_price=$1, factory_price=$2, discount_price=$3, is_active=$4, updated=CURRENT_TIMESTAMP " +
-		"WHERE product_id = $5" +
+		"WHERE product_id = $5 AND deleted_at IS NULL " +
 		"RETURNING id, sale_price, factory_price, discount_price, is_active, product_id"
 	var result model.Price
-	err := price.pool.QueryRow(
+	err := conn(ctx, price.pool).QueryRow(
 		ctx,
 		dbReq,
 		p.SalePrice,
@@ -80,10 +77,7 @@ func (price *priceRepo) EditPriceByProductID(ctx context.Context, p *model.Price
 		p.IsActive,
 		p.ProductID).Scan(&result.ID, &result.SalePrice, &result.FactoryPrice, &result.DiscountPrice, &result.IsActive, &result.ProductID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return result, nil
-		}
-		return result, fmt.Errorf("EditPriceByProductID: %w", err)
+		return result, fmt.Errorf("EditPriceByProductID: %w", classify(err))
 	}
 	return result, nil
 }
@@ -92,13 +86,14 @@ func (price *priceRepo) ListAllPrices(ctx context.Context) ([]model.Price, error
 	prices := make([]model.Price, 0)
 
 	dbReq := "SELECT id, sale_price, factory_price, discount_price, is_active, product_id " +
-		"FROM prices"
-	rows, err := price.pool.Query(ctx, dbReq)
+		"FROM prices " +
+		"WHERE deleted_at IS NULL"
+	rows, err := conn(ctx, price.pool).Query(ctx, dbReq)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return prices, nil
 		}
-		return prices, fmt.Errorf("ListAllPrices: %w", err)
+		return prices, fmt.Errorf("ListAllPrices: %w", classify(err))
 	}
 	for rows.Next() {
 		var result model.Price
@@ -109,7 +104,7 @@ func (price *priceRepo) ListAllPrices(ctx context.Context) ([]model.Price, error
 			&result.IsActive,
 			&result.ProductID)
 		if err != nil {
-			return prices, fmt.Errorf("ListAllPrices: %w", err)
+			return prices, fmt.Errorf("ListAllPrices: %w", classify(err))
 		}
 
 		prices = append(prices, result)
@@ -119,10 +114,10 @@ func (price *priceRepo) ListAllPrices(ctx context.Context) ([]model.Price, error
 func (price *priceRepo) SearchPriceByProductID(ctx context.Context, productID string) (model.Price, error) {
 	dbReq := fmt.Sprintf("SELECT id, sale_price, factory_price, discount_price, product_id, is_active "+
 		"FROM prices "+
-		"WHERE product_id = '%s'",
+		"WHERE product_id = '%s' AND deleted_at IS NULL",
 		productID)
 	var productPrice model.Price
-	err := price.pool.QueryRow(ctx, dbReq).Scan(
+	err := conn(ctx, price.pool).QueryRow(ctx, dbReq).Scan(
 		&productPrice.ID,
 		&productPrice.SalePrice,
 		&productPrice.FactoryPrice,
@@ -133,7 +128,64 @@ func (price *priceRepo) SearchPriceByProductID(ctx context.Context, productID st
 		if err == pgx.ErrNoRows {
 			return productPrice, nil
 		}
-		return productPrice, fmt.Errorf("SearchPriceByProductID: %w", err)
+		return productPrice, fmt.Errorf("SearchPriceByProductID: %w", classify(err))
 	}
 	return productPrice, nil
 }
+
+func (price *priceRepo) GetPriceByID(ctx context.Context, priceID int) (model.Price, error) {
+	dbReq := "SELECT id, sale_price, factory_price, discount_price, product_id, is_active " +
+		"FROM prices " +
+		"WHERE id = $1 AND deleted_at IS NULL"
+	var result model.Price
+	err := conn(ctx, price.pool).QueryRow(ctx, dbReq, priceID).Scan(
+		&result.ID,
+		&result.SalePrice,
+		&result.FactoryPrice,
+		&result.DiscountPrice,
+		&result.ProductID,
+		&result.IsActive)
+	if err != nil {
+		return result, fmt.Errorf("GetPriceByID: %w", classify(err))
+	}
+	return result, nil
+}
+
+func (price *priceRepo) DeletePrice(ctx context.Context, priceID int) error {
+	dbReq := "UPDATE prices SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL"
+	tag, err := conn(ctx, price.pool).Exec(ctx, dbReq, priceID)
+	if err != nil {
+		return fmt.Errorf("DeletePrice: %w", classify(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("DeletePrice: %w", NewNotFoundError(fmt.Sprintf("price %d", priceID)))
+	}
+	return nil
+}
+
+// RestorePrice brings an archived price back unless its product is archived
+// as well; such prices come back together with the product.
+func (price *priceRepo) RestorePrice(ctx context.Context, priceID int) error {
+	dbReq := "SELECT count(*) " +
+		"FROM prices " +
+		"JOIN products ON products.id = prices.product_id " +
+		"WHERE prices.id = $1 AND products.deleted_at IS NOT NULL"
+	var archived int
+	err := conn(ctx, price.pool).QueryRow(ctx, dbReq, priceID).Scan(&archived)
+	if err != nil {
+		return fmt.Errorf("RestorePrice: %w", classify(err))
+	}
+	if archived > 0 {
+		return fmt.Errorf("RestorePrice: %w", NewForeignKeyError("product_id", "product is archived"))
+	}
+
+	dbReq = "UPDATE prices SET deleted_at = NULL, updated = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NOT NULL"
+	tag, err := conn(ctx, price.pool).Exec(ctx, dbReq, priceID)
+	if err != nil {
+		return fmt.Errorf("RestorePrice: %w", classify(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("RestorePrice: %w", NewNotFoundError(fmt.Sprintf("archived price %d", priceID)))
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"market4/internal/model"
 	"testing"
@@ -42,16 +43,16 @@ func (s *PricesTestSuite) SetupTest() {
 			s.Error(err)
 			return
 		}
-		if i == 1 {
+		if i == 7 {
 			break
 		}
 	}
-	err = s.testRepo.pool.QueryRow(context.Background(), s.Data.Conf.Setup.Requests[2].Request).Scan(&s.productID)
+	err = s.testRepo.pool.QueryRow(context.Background(), s.Data.Conf.Setup.Requests[8].Request).Scan(&s.productID)
 	if err != nil {
 		s.Error(err)
 		return
 	}
-	addPriceReq := fmt.Sprintf(s.Data.Conf.Setup.Requests[3].Request, s.productID)
+	addPriceReq := fmt.Sprintf(s.Data.Conf.Setup.Requests[9].Request, s.productID)
 	_, err = s.testRepo.pool.Exec(context.Background(), addPriceReq)
 	if err != nil {
 		s.Error(err)
@@ -377,3 +378,65 @@ func (s *PricesTestSuite) Test_priceRepo_SearchPriceByProductID() {
 		})
 	}
 }
+
+func (s *PricesTestSuite) Test_priceRepo_SelectPrices() {
+	for _, tt := range []struct {
+		name     string
+		selector model.PriceSelector
+		want     int
+	}{
+		{name: "by sku", selector: model.PriceSelector{SKUs: []string{"3001", "3002"}}, want: 1},
+		{name: "by unknown sku", selector: model.PriceSelector{SKUs: []string{"3002"}}, want: 0},
+		{name: "in range", selector: model.PriceSelector{MinPrice: 1000, MaxPrice: 2000}, want: 1},
+		{name: "above range", selector: model.PriceSelector{MaxPrice: 1999}, want: 0},
+		{name: "by shop without links", selector: model.PriceSelector{ShopID: 1}, want: 0},
+	} {
+		s.Run(tt.name, func() {
+			got, err := s.testRepo.SelectPrices(context.Background(), tt.selector)
+			s.NoError(err)
+			s.Len(got, tt.want)
+		})
+	}
+
+	ctx := context.Background()
+	_, err := s.testRepo.pool.Exec(ctx, "INSERT INTO shops (id, name, address) VALUES (1, 'Ашан', 'Москва')")
+	s.Require().NoError(err)
+	_, err = s.testRepo.pool.Exec(ctx, "INSERT INTO productshop (shop_id, product_id) VALUES (1, $1)", s.productID)
+	s.Require().NoError(err)
+	got, err := s.testRepo.SelectPrices(ctx, model.PriceSelector{ShopID: 1})
+	s.NoError(err)
+	s.Len(got, 1)
+	_, err = s.testRepo.pool.Exec(ctx, "UPDATE shops SET deleted_at = CURRENT_TIMESTAMP WHERE id = 1")
+	s.Require().NoError(err)
+	got, err = s.testRepo.SelectPrices(ctx, model.PriceSelector{ShopID: 1})
+	s.NoError(err)
+	s.Empty(got, "archived shops aren't repriced")
+}
+
+func (s *PricesTestSuite) Test_priceRepo_ApplyPriceChanges() {
+	changes, err := s.testRepo.SelectPrices(context.Background(), model.PriceSelector{SKUs: []string{"3001"}})
+	s.Require().NoError(err)
+	s.Require().Len(changes, 1)
+	s.Equal("3001", changes[0].SKU)
+	changes[0].New.SalePrice = 2199
+
+	batch, err := s.testRepo.ApplyPriceChanges(context.Background(), model.PriceBatch{
+		ActorID: 2,
+		Rule:    `{"rule": {"op": "set", "value": 2199}}`,
+		Changes: changes,
+	})
+	s.NoError(err)
+	s.NotZero(batch.ID)
+	price, err := s.testRepo.SearchPriceByProductID(context.Background(), s.productID)
+	s.NoError(err)
+	s.Equal(2199, price.SalePrice)
+	var actor, old int
+	err = s.testRepo.pool.QueryRow(context.Background(),
+		"SELECT changed_by, old_sale_price FROM price_batches JOIN price_history ON batch_id = id").Scan(&actor, &old)
+	s.NoError(err)
+	s.Equal(2, actor)
+	s.Equal(2000, old)
+
+	_, err = s.testRepo.ApplyPriceChanges(context.Background(), model.PriceBatch{ActorID: 2, Rule: "{}", Changes: changes})
+	s.True(errors.Is(err, ErrConflict), "a stale change is refused: %v", err)
+}
@@ -2,10 +2,10 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log"
 	"market4/internal/model"
+	"strings"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -23,9 +23,9 @@ func NewProductRepository(pool *pgxpool.Pool, categoryRepo Category, shopRepo Sh
 }
 
 func (p *productRepo) IfProductExists(ctx context.Context, productID string) bool {
-	dbReq := "SELECT id FROM products WHERE id=$1"
+	dbReq := "SELECT id FROM products WHERE id=$1 AND deleted_at IS NULL"
 	var id = ""
-	err := p.pool.QueryRow(ctx, dbReq, productID).Scan(&id)
+	err := conn(ctx, p.pool).QueryRow(ctx, dbReq, productID).Scan(&id)
 	if err != nil {
 		log.Println(fmt.Errorf("IfProductExists: %w", err))
 		return false
@@ -38,11 +38,11 @@ func (p *productRepo) IfProductExists(ctx context.Context, productID string) boo
 func (p *productRepo) AddProduct(ctx context.Context, product model.Product, shopId, categoryId int) (model.Product, error) {
 	var result model.Product
 	if !p.categoryRepo.IfCategoryExists(ctx, categoryId) {
-		err := errors.New("category doesn't exist")
+		err := NewForeignKeyError("category_id", "category doesn't exist")
 		return result, fmt.Errorf("AddProduct: %w", err)
 	}
 	if !p.shopRepo.IfShopExists(ctx, shopId) {
-		err := errors.New("shop doesn't exist")
+		err := NewForeignKeyError("shop_id", "shop doesn't exist")
 		return result, fmt.Errorf("AddProduct: %w", err)
 	}
 
@@ -51,7 +51,7 @@ func (p *productRepo) AddProduct(ctx context.Context, product model.Product, sho
 		"RETURNING id, sku, name, uri, description, is_active"
 	uri := fmt.Sprintf("/product/%s-%s", product.Type, product.SKU)
 
-	err := p.pool.QueryRow(ctx,
+	err := conn(ctx, p.pool).QueryRow(ctx,
 		dbReq,
 		product.SKU,
 		product.Name,
@@ -64,17 +64,17 @@ func (p *productRepo) AddProduct(ctx context.Context, product model.Product, sho
 		&result.Description,
 		&result.IsActive)
 	if err != nil {
-		return result, fmt.Errorf("AddProduct: %w", err)
+		return result, fmt.Errorf("AddProduct: %w", classify(err))
 	}
 
 	err = p.setProductCategory(ctx, categoryId, result.ID)
 	if err != nil {
-		return result, fmt.Errorf("AddProduct: %w", err)
+		return result, fmt.Errorf("AddProduct: %w", classify(err))
 	}
 
 	err = p.setProductShop(ctx, shopId, result.ID)
 	if err != nil {
-		return result, fmt.Errorf("AddProduct: %w", err)
+		return result, fmt.Errorf("AddProduct: %w", classify(err))
 	}
 
 	return result, nil
@@ -82,9 +82,9 @@ func (p *productRepo) AddProduct(ctx context.Context, product model.Product, sho
 func (p *productRepo) setProductCategory(ctx context.Context, categoryId int, productId string) error {
 	dbReq := "INSERT INTO productcategory (category_id, product_id)" +
 		" VALUES ($1, $2)"
-	_, err := p.pool.Exec(ctx, dbReq, categoryId, productId)
+	_, err := conn(ctx, p.pool).Exec(ctx, dbReq, categoryId, productId)
 	if err != nil {
-		return fmt.Errorf("SetProductCategory: %w", err)
+		return fmt.Errorf("SetProductCategory: %w", classify(err))
 	}
 	return nil
 }
@@ -92,9 +92,9 @@ func (p *productRepo) setProductCategory(ctx context.Context, categoryId int, pr
 func (p *productRepo) setProductShop(ctx context.Context, shopID int, productID string) error {
 	dbReq := "INSERT INTO productshop (shop_id, product_id)" +
 		"VALUES ($1, $2)"
-	_, err := p.pool.Exec(ctx, dbReq, shopID, productID)
+	_, err := conn(ctx, p.pool).Exec(ctx, dbReq, shopID, productID)
 	if err != nil {
-		return fmt.Errorf("SetProductShop: %w", err)
+		return fmt.Errorf("SetProductShop: %w", classify(err))
 	}
 	return nil
 }
@@ -112,56 +112,96 @@ func (p *productRepo) EditProduct(ctx context.Context, product model.Product, sh
 	}
 
 	dbReq = fmt.Sprintf("%s is_active = %t, "+
-		"updated = CURRENT_TIMESTAMP WHERE sku = '%s' "+
+		"updated = CURRENT_TIMESTAMP WHERE sku = '%s' AND deleted_at IS NULL "+
 		"RETURNING id, sku, name, uri, description, is_active",
 		dbReq, product.IsActive, product.SKU)
 
 	var result model.Product
-	err := p.pool.QueryRow(ctx, dbReq).Scan(&result.ID, &result.SKU, &result.Name, &result.URI, &result.Description, &result.IsActive)
+	err := conn(ctx, p.pool).QueryRow(ctx, dbReq).Scan(&result.ID, &result.SKU, &result.Name, &result.URI, &result.Description, &result.IsActive)
 	if err != nil {
-		return result, fmt.Errorf("EditProduct: %w", err)
+		return result, fmt.Errorf("EditProduct: %w", classify(err))
 	}
 
 	if shopID != 0 {
 		dbReq = "UPDATE productshop SET shop_id = $1 WHERE product_id = $2"
-		_, err = p.pool.Exec(ctx, dbReq, shopID, result.ID)
+		_, err = conn(ctx, p.pool).Exec(ctx, dbReq, shopID, result.ID)
 		if err != nil {
-			return result, fmt.Errorf("EditProduct: %w", err)
+			return result, fmt.Errorf("EditProduct: %w", classify(err))
 		}
 	}
 
 	if categoryID != 0 {
 		dbReq = "UPDATE productcategory SET category_id = $1 WHERE product_id = $2 "
-		_, err = p.pool.Exec(ctx, dbReq, categoryID, result.ID)
+		_, err = conn(ctx, p.pool).Exec(ctx, dbReq, categoryID, result.ID)
 		if err != nil {
-			return result, fmt.Errorf("EditProduct: %w", err)
+			return result, fmt.Errorf("EditProduct: %w", classify(err))
 		}
 	}
 	return result, nil
 }
 
 func (p *productRepo) ListAllProducts(ctx context.Context) ([]model.Product, error) {
+	products, err := p.ListProducts(ctx, model.ProductFilter{})
+	if err != nil {
+		return products, fmt.Errorf("ListAllProducts: %w", err)
+	}
+	return products, nil
+}
+
+func (p *productRepo) ListProducts(ctx context.Context, filter model.ProductFilter) ([]model.Product, error) {
 	products := make([]model.Product, 0)
 
+	where, args := productFilter(filter)
 	dbReq := "SELECT id, sku, name, uri, description, is_active " +
-		"FROM products "
-	rows, err := p.pool.Query(ctx, dbReq)
+		"FROM products " +
+		"WHERE deleted_at IS NULL" + where
+	rows, err := conn(ctx, p.pool).Query(ctx, dbReq, args...)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return products, nil
 		}
-		return products, fmt.Errorf("ListAllProducts: %w", err)
+		return products, fmt.Errorf("ListProducts: %w", classify(err))
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var product model.Product
 		err = rows.Scan(&product.ID, &product.SKU, &product.Name, &product.URI, &product.Description, &product.IsActive)
 		if err != nil {
-			return products, fmt.Errorf("ListAllProducts: %w", err)
+			return products, fmt.Errorf("ListProducts: %w", classify(err))
 		}
 		products = append(products, product)
 	}
 	return products, nil
 }
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// productFilter turns filter into conditions on the products table, each
+// starting with AND, and their arguments numbered from $1. Archived shops
+// and categories match nothing.
+func productFilter(filter model.ProductFilter) (string, []interface{}) {
+	var where strings.Builder
+	args := make([]interface{}, 0)
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		fmt.Fprintf(&where, " AND "+condition, len(args))
+	}
+	if filter.ShopID != 0 {
+		add("products.id IN (SELECT product_id FROM productshop JOIN shops ON shops.id = productshop.shop_id "+
+			"WHERE shop_id = $%d AND shops.deleted_at IS NULL)", filter.ShopID)
+	}
+	if filter.CategoryID != 0 {
+		add("products.id IN (SELECT product_id FROM productcategory JOIN categories ON categories.id = productcategory.category_id "+
+			"WHERE category_id = $%d AND categories.deleted_at IS NULL)", filter.CategoryID)
+	}
+	if filter.IsActive != nil {
+		add("products.is_active = $%d", *filter.IsActive)
+	}
+	if filter.Name != "" {
+		add("products.name ILIKE $%d", "%"+likeEscaper.Replace(filter.Name)+"%")
+	}
+	return where.String(), args
+}
 func (p *productRepo) SearchProductsByCategory(ctx context.Context, category int) ([]model.Product, error) {
 	products := make([]model.Product, 0)
 
@@ -169,19 +209,21 @@ func (p *productRepo) SearchProductsByCategory(ctx context.Context, category int
 		"FROM products " +
 		"JOIN productcategory " +
 		"ON products.id = productcategory.product_id " +
-		"WHERE productcategory.category_id = $1 "
-	rows, err := p.pool.Query(ctx, dbReq, category)
+		"JOIN categories " +
+		"ON categories.id = productcategory.category_id " +
+		"WHERE productcategory.category_id = $1 AND products.deleted_at IS NULL AND categories.deleted_at IS NULL"
+	rows, err := conn(ctx, p.pool).Query(ctx, dbReq, category)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return products, nil
 		}
-		return products, fmt.Errorf("SearchProductsByCategory: %w", err)
+		return products, fmt.Errorf("SearchProductsByCategory: %w", classify(err))
 	}
 	for rows.Next() {
 		var product model.Product
 		err = rows.Scan(&product.ID, &product.Name, &product.URI)
 		if err != nil {
-			return products, fmt.Errorf("ListAllProducts: %w", err)
+			return products, fmt.Errorf("ListAllProducts: %w", classify(err))
 		}
 		products = append(products, product)
 	}
@@ -190,14 +232,14 @@ func (p *productRepo) SearchProductsByCategory(ctx context.Context, category int
 func (p *productRepo) SearchProductsByName(ctx context.Context, productName string) (model.Product, error) {
 	dbReq := "SELECT sku, name, uri, description, id " +
 		"FROM products " +
-		"WHERE name = $1"
+		"WHERE name = $1 AND deleted_at IS NULL"
 	var product model.Product
-	err := p.pool.QueryRow(ctx, dbReq, productName).Scan(&product.SKU, &product.Name, &product.URI, &product.Description, &product.ID)
+	err := conn(ctx, p.pool).QueryRow(ctx, dbReq, productName).Scan(&product.SKU, &product.Name, &product.URI, &product.Description, &product.ID)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return product, nil
 		}
-		return product, fmt.Errorf("SearchProductsByName: %w", err)
+		return product, fmt.Errorf("SearchProductsByName: %w", classify(err))
 	}
 
 	return product, nil
@@ -208,14 +250,16 @@ func (p *productRepo) SearchProductsByShop(ctx context.Context, shopID int) ([]m
 		"FROM products " +
 		"JOIN productshop " +
 		"ON products.id = productshop.product_id " +
-		"WHERE productshop.shop_id = $1"
+		"JOIN shops " +
+		"ON shops.id = productshop.shop_id " +
+		"WHERE productshop.shop_id = $1 AND products.deleted_at IS NULL AND shops.deleted_at IS NULL"
 
-	rows, err := p.pool.Query(ctx, dbReq, shopID)
+	rows, err := conn(ctx, p.pool).Query(ctx, dbReq, shopID)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return products, nil
 		}
-		return products, fmt.Errorf("SearchActiveProductsByShop: %w", err)
+		return products, fmt.Errorf("SearchActiveProductsByShop: %w", classify(err))
 	}
 	for rows.Next() {
 		var product model.Product
@@ -228,9 +272,154 @@ func (p *productRepo) SearchProductsByShop(ctx context.Context, shopID int) ([]m
 			&product.IsActive,
 		)
 		if err != nil {
-			return products, fmt.Errorf("SearchActiveProductsByShop: %w", err)
+			return products, fmt.Errorf("SearchActiveProductsByShop: %w", classify(err))
 		}
 		products = append(products, product)
 	}
 	return products, nil
 }
+
+func (p *productRepo) GetProductByID(ctx context.Context, productID string) (model.Product, error) {
+	product, err := p.getProduct(ctx, "id", productID)
+	if err != nil {
+		return product, fmt.Errorf("GetProductByID: %w", err)
+	}
+	return product, nil
+}
+
+func (p *productRepo) GetProductBySKU(ctx context.Context, sku string) (model.Product, error) {
+	product, err := p.getProduct(ctx, "sku", sku)
+	if err != nil {
+		return product, fmt.Errorf("GetProductBySKU: %w", err)
+	}
+	return product, nil
+}
+
+func (p *productRepo) GetProductByURI(ctx context.Context, uri string) (model.Product, error) {
+	product, err := p.getProduct(ctx, "uri", uri)
+	if err != nil {
+		return product, fmt.Errorf("GetProductByURI: %w", err)
+	}
+	return product, nil
+}
+
+// getProduct looks a product up by one of its unique columns. column is
+// always a literal from this file, never user input.
+func (p *productRepo) getProduct(ctx context.Context, column, value string) (model.Product, error) {
+	dbReq := "SELECT id, sku, name, uri, description, is_active " +
+		"FROM products " +
+		"WHERE " + column + " = $1 AND deleted_at IS NULL"
+	var product model.Product
+	err := conn(ctx, p.pool).QueryRow(ctx, dbReq, value).Scan(
+		&product.ID,
+		&product.SKU,
+		&product.Name,
+		&product.URI,
+		&product.Description,
+		&product.IsActive)
+	if err != nil {
+		return product, classify(err)
+	}
+	product.Type = productType(product.URI, product.SKU)
+	return product, nil
+}
+
+// productType recovers the type from a URI built as /product/{type}-{sku}.
+func productType(uri, sku string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(uri, "/product/"), "-"+sku)
+}
+
+// DeleteProduct archives a product together with its live prices. Both get
+// the same deleted_at, which is how RestoreProduct finds the prices again.
+func (p *productRepo) DeleteProduct(ctx context.Context, productID string) error {
+	err := conn(ctx, p.pool).BeginFunc(ctx, func(tx pgx.Tx) error {
+		dbReq := "UPDATE products SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL"
+		tag, err := tx.Exec(ctx, dbReq, productID)
+		if err != nil {
+			return classify(err)
+		}
+		if tag.RowsAffected() == 0 {
+			return NewNotFoundError("product " + productID)
+		}
+
+		dbReq = "UPDATE prices SET deleted_at = CURRENT_TIMESTAMP WHERE product_id = $1 AND deleted_at IS NULL"
+		_, err = tx.Exec(ctx, dbReq, productID)
+		return classify(err)
+	})
+	if err != nil {
+		return fmt.Errorf("DeleteProduct: %w", err)
+	}
+	return nil
+}
+
+// RestoreProduct brings back an archived product and the prices archived
+// with it. The product's shop and category must not be archived.
+func (p *productRepo) RestoreProduct(ctx context.Context, productID string) error {
+	err := conn(ctx, p.pool).BeginFunc(ctx, func(tx pgx.Tx) error {
+		dbReq := "SELECT " +
+			"(SELECT count(*) FROM productshop JOIN shops ON shops.id = productshop.shop_id " +
+			"WHERE productshop.product_id = $1 AND shops.deleted_at IS NOT NULL), " +
+			"(SELECT count(*) FROM productcategory JOIN categories ON categories.id = productcategory.category_id " +
+			"WHERE productcategory.product_id = $1 AND categories.deleted_at IS NOT NULL)"
+		var shops, categories int
+		err := tx.QueryRow(ctx, dbReq, productID).Scan(&shops, &categories)
+		if err != nil {
+			return classify(err)
+		}
+		if shops > 0 {
+			return NewForeignKeyError("shop_id", "shop is archived")
+		}
+		if categories > 0 {
+			return NewForeignKeyError("category_id", "category is archived")
+		}
+
+		dbReq = "UPDATE prices SET deleted_at = NULL, updated = CURRENT_TIMESTAMP " +
+			"WHERE product_id = $1 AND deleted_at = (SELECT deleted_at FROM products WHERE id = $1)"
+		_, err = tx.Exec(ctx, dbReq, productID)
+		if err != nil {
+			return classify(err)
+		}
+
+		dbReq = "UPDATE products SET deleted_at = NULL, updated = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NOT NULL"
+		tag, err := tx.Exec(ctx, dbReq, productID)
+		if err != nil {
+			return classify(err)
+		}
+		if tag.RowsAffected() == 0 {
+			return NewNotFoundError("archived product " + productID)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("RestoreProduct: %w", err)
+	}
+	return nil
+}
+
+// ProductCategories returns the categories each product is filed in.
+// Products without any are left out of the map.
+func (p *productRepo) ProductCategories(ctx context.Context, productIDs []string) (map[string][]int, error) {
+	result := make(map[string][]int)
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+	dbReq := "SELECT product_id, category_id FROM productcategory " +
+		"WHERE product_id = ANY($1::uuid[]) ORDER BY product_id, category_id"
+	rows, err := conn(ctx, p.pool).Query(ctx, dbReq, productIDs)
+	if err != nil {
+		return result, fmt.Errorf("ProductCategories: %w", classify(err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var productID string
+		var categoryID int
+		if err = rows.Scan(&productID, &categoryID); err != nil {
+			return result, fmt.Errorf("ProductCategories: %w", classify(err))
+		}
+		result[productID] = append(result[productID], categoryID)
+	}
+	if err = rows.Err(); err != nil {
+		return result, fmt.Errorf("ProductCategories: %w", classify(err))
+	}
+	return result, nil
+}
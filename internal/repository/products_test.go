@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"market4/internal/model"
 	"testing"
@@ -47,22 +48,22 @@ func (s *ProductTestSuite) SetupTest() {
 			s.Error(err)
 			return
 		}
-		if i == 6 {
+		if i == 7 {
 			break
 		}
 	}
-	err = s.testRepo.pool.QueryRow(context.Background(), s.Data.Conf.Setup.Requests[7].Request).Scan(&s.productID)
+	err = s.testRepo.pool.QueryRow(context.Background(), s.Data.Conf.Setup.Requests[8].Request).Scan(&s.productID)
 	if err != nil {
 		s.Fail("setup failed: addProductReq", err)
 		return
 	}
-	addProductCategoryReq := fmt.Sprintf(s.Data.Conf.Setup.Requests[8].Request, s.productID)
+	addProductCategoryReq := fmt.Sprintf(s.Data.Conf.Setup.Requests[9].Request, s.productID)
 	_, err = s.testRepo.pool.Exec(context.Background(), addProductCategoryReq)
 	if err != nil {
 		s.Fail("setup failed", err)
 		return
 	}
-	addProductShopReq := fmt.Sprintf(s.Data.Conf.Setup.Requests[9].Request, s.productID)
+	addProductShopReq := fmt.Sprintf(s.Data.Conf.Setup.Requests[10].Request, s.productID)
 	_, err = s.testRepo.pool.Exec(context.Background(), addProductShopReq)
 	if err != nil {
 		s.Fail("setup failed", err)
@@ -511,3 +512,185 @@ func (s *ProductTestSuite) Test_productRepo_SearchProductsByShop() {
 		})
 	}
 }
+
+func (s *ProductTestSuite) Test_productRepo_DeleteProduct() {
+	tests := []struct {
+		name      string
+		productID string
+		wantErr   error
+	}{
+		{
+			name:      "delete product",
+			productID: s.productID,
+		},
+		{
+			name:      "delete deleted product",
+			productID: s.productID,
+			wantErr:   ErrNotFound,
+		},
+		{
+			name:      "delete non-existing product",
+			productID: "9efd8091-67ef-4c97-bb35-7cdfb1680c59",
+			wantErr:   ErrNotFound,
+		},
+	}
+	for i := range tests {
+		tt := tests[i]
+		s.Run(tt.name, func() {
+			err := s.testRepo.DeleteProduct(context.Background(), tt.productID)
+			if !errors.Is(err, tt.wantErr) {
+				fmt.Printf("DeleteProduct() error = %v, wantErr %v", err, tt.wantErr)
+				s.Fail("test DeleteProduct failed")
+			}
+		})
+	}
+
+	s.False(s.testRepo.IfProductExists(context.Background(), s.productID))
+	products, err := s.testRepo.ListAllProducts(context.Background())
+	s.NoError(err)
+	s.Empty(products)
+	products, err = s.testRepo.SearchProductsByShop(context.Background(), 1)
+	s.NoError(err)
+	s.Empty(products)
+}
+
+func (s *ProductTestSuite) Test_productRepo_RestoreProduct() {
+	err := s.testRepo.DeleteProduct(context.Background(), s.productID)
+	if err != nil {
+		s.Fail("DeleteProduct failed", err)
+		return
+	}
+	tests := []struct {
+		name      string
+		productID string
+		wantErr   error
+	}{
+		{
+			name:      "restore deleted product",
+			productID: s.productID,
+		},
+		{
+			name:      "restore live product",
+			productID: s.productID,
+			wantErr:   ErrNotFound,
+		},
+	}
+	for i := range tests {
+		tt := tests[i]
+		s.Run(tt.name, func() {
+			err := s.testRepo.RestoreProduct(context.Background(), tt.productID)
+			if !errors.Is(err, tt.wantErr) {
+				fmt.Printf("RestoreProduct() error = %v, wantErr %v", err, tt.wantErr)
+				s.Fail("test RestoreProduct failed")
+			}
+		})
+	}
+	s.True(s.testRepo.IfProductExists(context.Background(), s.productID))
+}
+
+func (s *ProductTestSuite) Test_productRepo_UpsertProducts() {
+	row := func(sku, name string, shopID int) model.ProductImport {
+		return model.ProductImport{
+			Product:    model.Product{SKU: sku, Name: name, Type: "тепловая", Description: "пушка детская", IsActive: true},
+			ShopID:     shopID,
+			CategoryID: 1,
+			Price:      &model.Price{SalePrice: 2000, FactoryPrice: 1000, DiscountPrice: 1600, IsActive: true},
+		}
+	}
+
+	results, err := s.testRepo.UpsertProducts(context.Background(), []model.ProductImport{
+		row("3001", "пушка водяная", 2),
+		row("3002", "пушка снежная", 1),
+	})
+	s.NoError(err)
+	s.Equal(s.productID, results[0].ProductID)
+	s.False(results[0].Created)
+	s.True(results[1].Created)
+	s.NoError(results[0].Err)
+	s.NoError(results[1].Err)
+	products, err := s.testRepo.SearchProductsByShop(context.Background(), 2)
+	s.NoError(err)
+	s.Len(products, 1, "the shop of an updated product is replaced")
+
+	results, err = s.testRepo.UpsertProducts(context.Background(), []model.ProductImport{
+		row("3003", "пушка лазерная", 1),
+		row("3004", "пушка космическая", 10),
+	})
+	s.NoError(err)
+	s.True(errors.Is(results[0].Err, ErrRolledBack), results[0].Err)
+	s.True(errors.Is(results[1].Err, ErrForeignKey), results[1].Err)
+	_, err = s.testRepo.GetProductBySKU(context.Background(), "3003")
+	s.True(errors.Is(err, ErrNotFound), "nothing is written when a row fails")
+}
+
+func (s *ProductTestSuite) Test_productRepo_ListProducts() {
+	inactive := false
+	for _, tt := range []struct {
+		name   string
+		filter model.ProductFilter
+		want   int
+	}{
+		{name: "by shop", filter: model.ProductFilter{ShopID: 1}, want: 1},
+		{name: "by another shop", filter: model.ProductFilter{ShopID: 2}, want: 0},
+		{name: "by category", filter: model.ProductFilter{CategoryID: 1}, want: 1},
+		{name: "inactive", filter: model.ProductFilter{IsActive: &inactive}, want: 0},
+		{name: "by name", filter: model.ProductFilter{Name: "ПУШ"}, want: 1},
+		{name: "name is not a pattern", filter: model.ProductFilter{Name: "п%"}, want: 0},
+	} {
+		s.Run(tt.name, func() {
+			got, err := s.testRepo.ListProducts(context.Background(), tt.filter)
+			s.NoError(err)
+			s.Len(got, tt.want)
+		})
+	}
+}
+
+func (s *ProductTestSuite) Test_productRepo_ArchivedShopAndCategory() {
+	ctx := context.Background()
+	_, err := s.testRepo.pool.Exec(ctx, "UPDATE shops SET deleted_at = CURRENT_TIMESTAMP WHERE id = 1")
+	s.Require().NoError(err)
+	_, err = s.testRepo.pool.Exec(ctx, "UPDATE categories SET deleted_at = CURRENT_TIMESTAMP WHERE id = 1")
+	s.Require().NoError(err)
+
+	products, err := s.testRepo.SearchProductsByShop(ctx, 1)
+	s.NoError(err)
+	s.Empty(products, "an archived shop sells nothing")
+	products, err = s.testRepo.SearchProductsByCategory(ctx, 1)
+	s.NoError(err)
+	s.Empty(products, "an archived category holds nothing")
+	products, err = s.testRepo.ListProducts(ctx, model.ProductFilter{ShopID: 1})
+	s.NoError(err)
+	s.Empty(products)
+
+	var rows []model.ProductExport
+	err = s.testRepo.ExportProducts(ctx, model.ProductFilter{}, func(row model.ProductExport) error {
+		rows = append(rows, row)
+		return nil
+	})
+	s.NoError(err)
+	if s.Len(rows, 1, "the product itself is live") {
+		s.Zero(rows[0].Shop.ID)
+		s.Zero(rows[0].Category.ID)
+	}
+}
+
+func (s *ProductTestSuite) Test_productRepo_ExportProducts() {
+	var rows []model.ProductExport
+	err := s.testRepo.ExportProducts(context.Background(), model.ProductFilter{ShopID: 1}, func(row model.ProductExport) error {
+		rows = append(rows, row)
+		return nil
+	})
+	s.NoError(err)
+	if s.Len(rows, 1) {
+		s.Equal(s.productID, rows[0].Product.ID)
+		s.Equal("тепловая", rows[0].Product.Type)
+		s.Equal(1, rows[0].Shop.ID)
+		s.Equal(1, rows[0].Category.ID)
+	}
+
+	stop := errors.New("stop")
+	err = s.testRepo.ExportProducts(context.Background(), model.ProductFilter{}, func(model.ProductExport) error {
+		return stop
+	})
+	s.True(errors.Is(err, stop), err)
+}
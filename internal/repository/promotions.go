@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"market4/internal/model"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type promotionRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewPromotionRepository(pool *pgxpool.Pool) Promotion {
+	return &promotionRepo{pool: pool}
+}
+
+const promotionColumns = "promotions.id, promotions.name, promotions.type, promotions.value, " +
+	"promotions.buy_quantity, promotions.get_quantity, promotions.priority, promotions.stackable, " +
+	"promotions.starts_at, promotions.ends_at, promotions.category_ids, promotions.shop_ids, " +
+	"promotions.product_ids, promotions.is_active"
+
+func scanPromotion(row pgx.Row, extra ...interface{}) (model.Promotion, error) {
+	var p model.Promotion
+	dest := append(extra,
+		&p.ID, &p.Name, &p.Type, &p.Value,
+		&p.BuyQuantity, &p.GetQuantity, &p.Priority, &p.Stackable,
+		&p.StartsAt, &p.EndsAt, &p.CategoryIDs, &p.ShopIDs,
+		&p.ProductIDs, &p.IsActive)
+	err := row.Scan(dest...)
+	return p, err
+}
+
+// targets returns the target lists ready to be stored; a nil slice would be
+// sent as NULL rather than as an empty array.
+func targets(p model.Promotion) ([]int, []int, []string) {
+	categories, shops, products := p.CategoryIDs, p.ShopIDs, p.ProductIDs
+	if categories == nil {
+		categories = []int{}
+	}
+	if shops == nil {
+		shops = []int{}
+	}
+	if products == nil {
+		products = []string{}
+	}
+	return categories, shops, products
+}
+
+func (r *promotionRepo) AddPromotion(ctx context.Context, p model.Promotion) (model.Promotion, error) {
+	categories, shops, products := targets(p)
+	dbReq := "INSERT INTO promotions (name, type, value, buy_quantity, get_quantity, priority, stackable, " +
+		"starts_at, ends_at, category_ids, shop_ids, product_ids, is_active) " +
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) " +
+		"RETURNING " + promotionColumns
+	result, err := scanPromotion(r.pool.QueryRow(ctx, dbReq,
+		p.Name, p.Type, p.Value, p.BuyQuantity, p.GetQuantity, p.Priority, p.Stackable,
+		p.StartsAt, p.EndsAt, categories, shops, products, p.IsActive))
+	if err != nil {
+		return result, fmt.Errorf("AddPromotion: %w", classify(err))
+	}
+	return result, nil
+}
+
+func (r *promotionRepo) EditPromotion(ctx context.Context, p model.Promotion) (model.Promotion, error) {
+	categories, shops, products := targets(p)
+	dbReq := "UPDATE promotions " +
+		"SET name=$1, type=$2, value=$3, buy_quantity=$4, get_quantity=$5, priority=$6, stackable=$7, " +
+		"starts_at=$8, ends_at=$9, category_ids=$10, shop_ids=$11, product_ids=$12, is_active=$13, " +
+		"updated=CURRENT_TIMESTAMP " +
+		"WHERE id=$14 " +
+		"RETURNING " + promotionColumns
+	result, err := scanPromotion(r.pool.QueryRow(ctx, dbReq,
+		p.Name, p.Type, p.Value, p.BuyQuantity, p.GetQuantity, p.Priority, p.Stackable,
+		p.StartsAt, p.EndsAt, categories, shops, products, p.IsActive, p.ID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			err = NewNotFoundError(fmt.Sprintf("promotion %d", p.ID))
+		}
+		return result, fmt.Errorf("EditPromotion: %w", classify(err))
+	}
+	return result, nil
+}
+
+func (r *promotionRepo) GetPromotionByID(ctx context.Context, promotionID int) (model.Promotion, error) {
+	dbReq := "SELECT " + promotionColumns + " FROM promotions WHERE id = $1"
+	result, err := scanPromotion(r.pool.QueryRow(ctx, dbReq, promotionID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			err = NewNotFoundError(fmt.Sprintf("promotion %d", promotionID))
+		}
+		return result, fmt.Errorf("GetPromotionByID: %w", classify(err))
+	}
+	return result, nil
+}
+
+func (r *promotionRepo) ListPromotions(ctx context.Context) ([]model.Promotion, error) {
+	promotions := make([]model.Promotion, 0)
+	dbReq := "SELECT " + promotionColumns + " FROM promotions ORDER BY starts_at DESC, id"
+	rows, err := r.pool.Query(ctx, dbReq)
+	if err != nil {
+		return promotions, fmt.Errorf("ListPromotions: %w", classify(err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		p, err := scanPromotion(rows)
+		if err != nil {
+			return promotions, fmt.Errorf("ListPromotions: %w", classify(err))
+		}
+		promotions = append(promotions, p)
+	}
+	if err = rows.Err(); err != nil {
+		return promotions, fmt.Errorf("ListPromotions: %w", classify(err))
+	}
+	return promotions, nil
+}
+
+func (r *promotionRepo) DeletePromotion(ctx context.Context, promotionID int) error {
+	tag, err := r.pool.Exec(ctx, "DELETE FROM promotions WHERE id = $1", promotionID)
+	if err != nil {
+		return fmt.Errorf("DeletePromotion: %w", classify(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("DeletePromotion: %w", NewNotFoundError(fmt.Sprintf("promotion %d", promotionID)))
+	}
+	return nil
+}
+
+// ActivePromotions returns, per product, the promotions running at the given
+// moment that target it, highest priority first. Products without any are
+// left out of the map.
+func (r *promotionRepo) ActivePromotions(ctx context.Context, productIDs []string, at time.Time) (map[string][]model.Promotion, error) {
+	result := make(map[string][]model.Promotion)
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+	dbReq := "SELECT target.id, " + promotionColumns + " " +
+		"FROM unnest($1::uuid[]) AS target(id) " +
+		"JOIN promotions ON promotions.is_active AND promotions.starts_at <= $2 AND promotions.ends_at > $2 " +
+		"AND ((cardinality(promotions.category_ids) = 0 AND cardinality(promotions.shop_ids) = 0 " +
+		"AND cardinality(promotions.product_ids) = 0) " +
+		"OR target.id = ANY(promotions.product_ids) " +
+		"OR promotions.category_ids && ARRAY(SELECT category_id FROM productcategory WHERE product_id = target.id) " +
+		"OR promotions.shop_ids && ARRAY(SELECT shop_id FROM productshop WHERE product_id = target.id)) " +
+		"ORDER BY target.id, promotions.priority DESC, promotions.id"
+	rows, err := r.pool.Query(ctx, dbReq, productIDs, at)
+	if err != nil {
+		return result, fmt.Errorf("ActivePromotions: %w", classify(err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var productID string
+		p, err := scanPromotion(rows, &productID)
+		if err != nil {
+			return result, fmt.Errorf("ActivePromotions: %w", classify(err))
+		}
+		result[productID] = append(result[productID], p)
+	}
+	if err = rows.Err(); err != nil {
+		return result, fmt.Errorf("ActivePromotions: %w", classify(err))
+	}
+	return result, nil
+}
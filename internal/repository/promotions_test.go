@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"market4/internal/model"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	promotedProduct = "2800d950-5c62-49e2-a705-c74ba77f57d0"
+	shopProduct     = "7c2c4a8e-8f0c-4a55-9f1b-0d6f7e1b2a33"
+)
+
+type PromotionsTestSuite struct {
+	suite.Suite
+	testRepo promotionRepo
+	Data     TestData
+}
+
+func Test_PromotionsSuite(t *testing.T) {
+	suite.Run(t, new(PromotionsTestSuite))
+}
+
+func (s *PromotionsTestSuite) SetupTest() {
+	fmt.Println("start setup")
+	var err error
+	s.testRepo.pool, err = pgxpool.Connect(context.Background(), testDSN)
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	s.Data, err = loadTestDataFromYaml("promotions_test.yaml")
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	for _, r := range s.Data.Conf.Setup.Requests {
+		_, err = s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			return
+		}
+	}
+}
+
+func (s *PromotionsTestSuite) TearDownTest() {
+	fmt.Println("cleaning up")
+	for _, r := range s.Data.Conf.Teardown.Requests {
+		_, err := s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			s.Fail("cleaning failed")
+		}
+	}
+}
+
+func (s *PromotionsTestSuite) promotion(name string, priority int) model.Promotion {
+	now := time.Now()
+	return model.Promotion{
+		Name:     name,
+		Type:     model.PromotionPercentage,
+		Value:    10,
+		Priority: priority,
+		StartsAt: now.Add(-time.Hour),
+		EndsAt:   now.Add(time.Hour),
+		IsActive: true,
+	}
+}
+
+func (s *PromotionsTestSuite) Test_promotionRepo_CRUD() {
+	ctx := context.Background()
+	p := s.promotion("весенняя распродажа", 1)
+	p.CategoryIDs = []int{1}
+	added, err := s.testRepo.AddPromotion(ctx, p)
+	s.Require().NoError(err)
+	s.NotZero(added.ID)
+	s.Equal([]int{1}, added.CategoryIDs)
+	s.Equal([]string{}, added.ProductIDs)
+
+	added.Value = 20
+	edited, err := s.testRepo.EditPromotion(ctx, added)
+	s.NoError(err)
+	s.Equal(20, edited.Value)
+
+	got, err := s.testRepo.GetPromotionByID(ctx, added.ID)
+	s.NoError(err)
+	s.Equal(20, got.Value)
+
+	list, err := s.testRepo.ListPromotions(ctx)
+	s.NoError(err)
+	s.Len(list, 1)
+
+	s.NoError(s.testRepo.DeletePromotion(ctx, added.ID))
+	_, err = s.testRepo.GetPromotionByID(ctx, added.ID)
+	s.True(errors.Is(err, ErrNotFound), err)
+	s.True(errors.Is(s.testRepo.DeletePromotion(ctx, added.ID), ErrNotFound))
+
+	p.EndsAt = p.StartsAt
+	_, err = s.testRepo.AddPromotion(ctx, p)
+	s.True(errors.Is(err, ErrValidation), "ends_at must be after starts_at: %v", err)
+}
+
+func (s *PromotionsTestSuite) Test_promotionRepo_ActivePromotions() {
+	ctx := context.Background()
+	byCategory := s.promotion("игрушки", 1)
+	byCategory.CategoryIDs = []int{1}
+	byShop := s.promotion("магазин", 2)
+	byShop.ShopIDs = []int{1}
+	byProduct := s.promotion("мяч", 3)
+	byProduct.ProductIDs = []string{shopProduct}
+	everything := s.promotion("всё", 0)
+	expired := s.promotion("прошлая", 5)
+	expired.StartsAt, expired.EndsAt = expired.StartsAt.Add(-48*time.Hour), expired.StartsAt.Add(-24*time.Hour)
+	disabled := s.promotion("выключена", 5)
+	disabled.IsActive = false
+	for _, p := range []model.Promotion{byCategory, byShop, byProduct, everything, expired, disabled} {
+		_, err := s.testRepo.AddPromotion(ctx, p)
+		s.Require().NoError(err)
+	}
+
+	got, err := s.testRepo.ActivePromotions(ctx, []string{promotedProduct, shopProduct}, time.Now())
+	s.Require().NoError(err)
+	names := func(promotions []model.Promotion) []string {
+		var result []string
+		for _, p := range promotions {
+			result = append(result, p.Name)
+		}
+		return result
+	}
+	s.Equal([]string{"игрушки", "всё"}, names(got[promotedProduct]))
+	s.Equal([]string{"мяч", "магазин", "всё"}, names(got[shopProduct]), "highest priority first")
+}
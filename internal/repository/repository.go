@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"market4/internal/model"
+	"time"
 )
 
 type Shop interface {
@@ -10,6 +11,9 @@ type Shop interface {
 	AddShop(ctx context.Context, s *model.Shop) (int, error)
 	EditShop(ctx context.Context, s *model.Shop) error
 	IfShopExists(ctx context.Context, shopID int) bool
+	GetShopByID(ctx context.Context, shopID int) (model.Shop, error)
+	DeleteShop(ctx context.Context, shopID int) error
+	RestoreShop(ctx context.Context, shopID int) error
 }
 
 type Category interface {
@@ -17,16 +21,29 @@ type Category interface {
 	AddCategory(ctx context.Context, c *model.Category) (int, error)
 	EditCategory(ctx context.Context, c *model.Category) error
 	IfCategoryExists(ctx context.Context, categoryID int) bool
+	GetCategoryByID(ctx context.Context, categoryID int) (model.Category, error)
+	GetCategoryByURIName(ctx context.Context, uriName string) (model.Category, error)
+	DeleteCategory(ctx context.Context, categoryID int) error
+	RestoreCategory(ctx context.Context, categoryID int) error
 }
 
 type Product interface {
 	AddProduct(ctx context.Context, p model.Product, shopId int, categoryId int) (model.Product, error)
 	EditProduct(ctx context.Context, p model.Product, shopId int, categoryId int) (model.Product, error)
 	ListAllProducts(ctx context.Context) ([]model.Product, error)
+	ListProducts(ctx context.Context, filter model.ProductFilter) ([]model.Product, error)
 	IfProductExists(ctx context.Context, productID string) bool
 	SearchProductsByCategory(ctx context.Context, category int) ([]model.Product, error)
 	SearchProductsByName(ctx context.Context, productName string) (model.Product, error)
 	SearchProductsByShop(ctx context.Context, shopID int) ([]model.Product, error)
+	GetProductByID(ctx context.Context, productID string) (model.Product, error)
+	GetProductBySKU(ctx context.Context, sku string) (model.Product, error)
+	GetProductByURI(ctx context.Context, uri string) (model.Product, error)
+	DeleteProduct(ctx context.Context, productID string) error
+	RestoreProduct(ctx context.Context, productID string) error
+	UpsertProducts(ctx context.Context, rows []model.ProductImport) ([]UpsertResult, error)
+	ExportProducts(ctx context.Context, filter model.ProductFilter, fn func(model.ProductExport) error) error
+	ProductCategories(ctx context.Context, productIDs []string) (map[string][]int, error)
 }
 
 type Price interface {
@@ -35,6 +52,73 @@ type Price interface {
 	ListAllPrices(ctx context.Context) ([]model.Price, error)
 	SearchPriceByProductID(ctx context.Context, productID string) (model.Price, error)
 	EditPriceByProductID(ctx context.Context, p *model.Price) (model.Price, error)
+	GetPriceByID(ctx context.Context, priceID int) (model.Price, error)
+	DeletePrice(ctx context.Context, priceID int) error
+	RestorePrice(ctx context.Context, priceID int) error
+	SelectPrices(ctx context.Context, selector model.PriceSelector) ([]model.PriceChange, error)
+	ApplyPriceChanges(ctx context.Context, batch model.PriceBatch) (model.PriceBatch, error)
+}
+
+type Promotion interface {
+	AddPromotion(ctx context.Context, p model.Promotion) (model.Promotion, error)
+	EditPromotion(ctx context.Context, p model.Promotion) (model.Promotion, error)
+	GetPromotionByID(ctx context.Context, promotionID int) (model.Promotion, error)
+	ListPromotions(ctx context.Context) ([]model.Promotion, error)
+	DeletePromotion(ctx context.Context, promotionID int) error
+	ActivePromotions(ctx context.Context, productIDs []string, at time.Time) (map[string][]model.Promotion, error)
+}
+
+type Coupon interface {
+	AddCoupon(ctx context.Context, c model.Coupon) (model.Coupon, error)
+	EditCoupon(ctx context.Context, c model.Coupon) (model.Coupon, error)
+	GetCouponByID(ctx context.Context, couponID int) (model.Coupon, error)
+	GetCouponByCode(ctx context.Context, code string) (model.Coupon, error)
+	ListCoupons(ctx context.Context) ([]model.Coupon, error)
+	DeleteCoupon(ctx context.Context, couponID int) error
+	CouponUsage(ctx context.Context, couponID, userID int) (total int, byUser int, err error)
+}
+
+// Cart holds one cart per user.
+type Cart interface {
+	GetCart(ctx context.Context, userID int) ([]model.CartItem, error)
+	AddCartItem(ctx context.Context, userID int, productID string, quantity int) error
+	SetCartItemQuantity(ctx context.Context, userID int, productID string, quantity int) error
+	RemoveCartItem(ctx context.Context, userID int, productID string) error
+	ClearCart(ctx context.Context, userID int) error
+}
+
+type Order interface {
+	CreateOrder(ctx context.Context, order model.Order) (model.Order, error)
+	GetOrder(ctx context.Context, orderID int) (model.Order, error)
+	ListOrders(ctx context.Context, filter model.OrderFilter) ([]model.Order, error)
+	TransitionOrder(ctx context.Context, orderID int, transition model.OrderTransition) (model.Order, error)
+}
+
+// Payment stores the payments of orders made through a payment provider.
+type Payment interface {
+	AddPayment(ctx context.Context, p model.Payment) (model.Payment, error)
+	GetPayment(ctx context.Context, paymentID string) (model.Payment, error)
+	ListPayments(ctx context.Context, orderID int) ([]model.Payment, error)
+	SetPaymentStatus(ctx context.Context, paymentID, from, to, reason string, transition *model.OrderTransition) (model.Payment, error)
+}
+
+// Stock holds how many units of each product the shops have. Orders
+// reserve stock at checkout; see Order.
+type Stock interface {
+	SetStock(ctx context.Context, s model.Stock) (model.Stock, error)
+	ListStock(ctx context.Context, productID string) ([]model.Stock, error)
+	ExpireReservations(ctx context.Context) ([]int, error)
+}
+
+// Archive hard-deletes rows that were soft deleted before the given moment.
+type Archive interface {
+	Purge(ctx context.Context, before time.Time) (PurgeReport, error)
+}
+
+// Audit is the append-only log of changes made through the API.
+type Audit interface {
+	AddAuditEntry(ctx context.Context, e model.AuditEntry) (model.AuditEntry, error)
+	ListAuditEntries(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error)
 }
 
 type Users interface {
@@ -46,4 +130,7 @@ type Users interface {
 	GetRoleByID(ctx context.Context, roleID int) (string, error)
 	AddRole(ctx context.Context, login string, role string) error
 	RemoveRole(ctx context.Context, login string, role string) error
+	ListUsers(ctx context.Context) ([]model.Account, error)
+	GetStaffShops(ctx context.Context, userID int) ([]int, error)
+	SetStaffShops(ctx context.Context, login string, shopIDs []int) error
 }
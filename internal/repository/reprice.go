@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"market4/internal/model"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// SelectPrices returns the live prices of live products matching selector
+// as changes whose New is still equal to Old. A selector naming an archived
+// shop or category matches nothing.
+func (price *priceRepo) SelectPrices(ctx context.Context, selector model.PriceSelector) ([]model.PriceChange, error) {
+	filter, args := productFilter(model.ProductFilter{ShopID: selector.ShopID, CategoryID: selector.CategoryID})
+	var where strings.Builder
+	where.WriteString(filter)
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		fmt.Fprintf(&where, " AND "+condition, len(args))
+	}
+	if len(selector.SKUs) > 0 {
+		add("products.sku = ANY($%d)", selector.SKUs)
+	}
+	if selector.MinPrice != 0 {
+		add("prices.sale_price >= $%d", selector.MinPrice)
+	}
+	if selector.MaxPrice != 0 {
+		add("prices.sale_price <= $%d", selector.MaxPrice)
+	}
+
+	dbReq := "SELECT prices.id, prices.product_id, products.sku, " +
+		"prices.sale_price, prices.factory_price, prices.discount_price, prices.is_active " +
+		"FROM prices " +
+		"JOIN products ON products.id = prices.product_id " +
+		"WHERE prices.deleted_at IS NULL AND products.deleted_at IS NULL" + where.String() + " " +
+		"ORDER BY products.sku, prices.id"
+	rows, err := conn(ctx, price.pool).Query(ctx, dbReq, args...)
+	if err != nil {
+		return nil, fmt.Errorf("SelectPrices: %w", classify(err))
+	}
+	defer rows.Close()
+
+	changes := make([]model.PriceChange, 0)
+	for rows.Next() {
+		var change model.PriceChange
+		err = rows.Scan(&change.PriceID,
+			&change.ProductID,
+			&change.SKU,
+			&change.Old.SalePrice,
+			&change.Old.FactoryPrice,
+			&change.Old.DiscountPrice,
+			&change.Old.IsActive)
+		if err != nil {
+			return nil, fmt.Errorf("SelectPrices: %w", classify(err))
+		}
+		change.Old.ID = change.PriceID
+		change.Old.ProductID = change.ProductID
+		change.New = change.Old
+		changes = append(changes, change)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("SelectPrices: %w", classify(err))
+	}
+	return changes, nil
+}
+
+// ApplyPriceChanges writes all changes of the batch and their history in one
+// transaction. A price that was edited or archived since it was selected
+// fails the whole batch with a conflict, so a stale preview is never
+// applied.
+func (price *priceRepo) ApplyPriceChanges(ctx context.Context, batch model.PriceBatch) (model.PriceBatch, error) {
+	err := conn(ctx, price.pool).BeginFunc(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			"INSERT INTO price_batches (changed_by, rule) VALUES ($1, $2) RETURNING id, created",
+			batch.ActorID, batch.Rule).Scan(&batch.ID, &batch.Created)
+		if err != nil {
+			return classify(err)
+		}
+		for _, change := range batch.Changes {
+			tag, err := tx.Exec(ctx, "UPDATE prices "+
+				"SET sale_price=$1, factory_price=$2, discount_price=$3, updated=CURRENT_TIMESTAMP "+
+				"WHERE id=$4 AND deleted_at IS NULL AND sale_price=$5 AND factory_price=$6 AND discount_price=$7",
+				change.New.SalePrice, change.New.FactoryPrice, change.New.DiscountPrice,
+				change.PriceID, change.Old.SalePrice, change.Old.FactoryPrice, change.Old.DiscountPrice)
+			if err != nil {
+				return classify(err)
+			}
+			if tag.RowsAffected() == 0 {
+				return NewConflictError(fmt.Sprintf("price %d of product %s has changed since it was read", change.PriceID, change.SKU))
+			}
+			_, err = tx.Exec(ctx, "INSERT INTO price_history "+
+				"(batch_id, price_id, old_sale_price, old_factory_price, old_discount_price, sale_price, factory_price, discount_price) "+
+				"VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+				batch.ID, change.PriceID,
+				change.Old.SalePrice, change.Old.FactoryPrice, change.Old.DiscountPrice,
+				change.New.SalePrice, change.New.FactoryPrice, change.New.DiscountPrice)
+			if err != nil {
+				return classify(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return model.PriceBatch{}, fmt.Errorf("ApplyPriceChanges: %w", err)
+	}
+	return batch, nil
+}
@@ -18,9 +18,9 @@ func NewShopRepository(pool *pgxpool.Pool) Shop {
 	return &shopRepo{pool: pool}
 }
 func (s *shopRepo) IfShopExists(ctx context.Context, shop int) bool {
-	dbReq := "SELECT id FROM shops WHERE id=$1"
+	dbReq := "SELECT id FROM shops WHERE id=$1 AND deleted_at IS NULL"
 	var id = 0
-	err := s.pool.QueryRow(ctx, dbReq, shop).Scan(&id)
+	err := conn(ctx, s.pool).QueryRow(ctx, dbReq, shop).Scan(&id)
 	if err != nil {
 		log.Println(fmt.Errorf("ifShopExists: %w", err))
 		return false
@@ -32,15 +32,16 @@ func (s *shopRepo) IfShopExists(ctx context.Context, shop int) bool {
 }
 func (s *shopRepo) ListAllShops(ctx context.Context) ([]model.Shop, error) {
 	dbReq := "SELECT id, name, address, lon, lat, working_hours " +
-		"FROM shops"
+		"FROM shops " +
+		"WHERE deleted_at IS NULL"
 
 	shops := make([]model.Shop, 0)
-	rows, err := s.pool.Query(ctx, dbReq)
+	rows, err := conn(ctx, s.pool).Query(ctx, dbReq)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return shops, nil
 		}
-		return shops, fmt.Errorf("ListAllShops: %w", err)
+		return shops, fmt.Errorf("ListAllShops: %w", classify(err))
 	}
 	defer rows.Close()
 
@@ -48,7 +49,7 @@ func (s *shopRepo) ListAllShops(ctx context.Context) ([]model.Shop, error) {
 		var shop model.Shop
 		err = rows.Scan(&shop.ID, &shop.Name, &shop.Address, &shop.LON, &shop.LAT, &shop.WorkingHours)
 		if err != nil {
-			return shops, fmt.Errorf("ListAllShops: %w", err)
+			return shops, fmt.Errorf("ListAllShops: %w", classify(err))
 		}
 		shops = append(shops, shop)
 	}
@@ -61,11 +62,11 @@ func (s *shopRepo) AddShop(ctx context.Context, shop *model.Shop) (int, error) {
 		"VALUES ($1, $2, $3, $4, $5) " +
 		"RETURNING id"
 	var id int
-	err := s.pool.QueryRow(ctx,
+	err := conn(ctx, s.pool).QueryRow(ctx,
 		dbReq,
 		shop.Name, shop.Address, shop.LON, shop.LAT, shop.WorkingHours).Scan(&id)
 	if err != nil {
-		return 0, fmt.Errorf("AddShop: %w", err)
+		return 0, fmt.Errorf("AddShop: %w", classify(err))
 	}
 	return id, nil
 }
@@ -93,10 +94,64 @@ func (s *shopRepo) EditShop(ctx context.Context, shop *model.Shop) error {
 		dbReq = fmt.Sprintf("%s working_hours = '%s',", dbReq, shop.WorkingHours)
 	}
 
-	dbReq = fmt.Sprintf("%s updated = CURRENT_TIMESTAMP WHERE id = %d", dbReq, shop.ID)
-	_, err := s.pool.Exec(ctx, dbReq)
+	dbReq = fmt.Sprintf("%s updated = CURRENT_TIMESTAMP WHERE id = %d AND deleted_at IS NULL", dbReq, shop.ID)
+	tag, err := conn(ctx, s.pool).Exec(ctx, dbReq)
 	if err != nil {
-		return fmt.Errorf("UpdateShopParameter: %w", err)
+		return fmt.Errorf("UpdateShopParameter: %w", classify(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("UpdateShopParameter: %w", NewNotFoundError(fmt.Sprintf("shop %d", shop.ID)))
+	}
+	return nil
+}
+
+func (s *shopRepo) GetShopByID(ctx context.Context, shopID int) (model.Shop, error) {
+	dbReq := "SELECT id, name, address, lon, lat, working_hours " +
+		"FROM shops " +
+		"WHERE id = $1 AND deleted_at IS NULL"
+	var shop model.Shop
+	err := conn(ctx, s.pool).QueryRow(ctx, dbReq, shopID).Scan(&shop.ID, &shop.Name, &shop.Address, &shop.LON, &shop.LAT, &shop.WorkingHours)
+	if err != nil {
+		return shop, fmt.Errorf("GetShopByID: %w", classify(err))
+	}
+	return shop, nil
+}
+
+// DeleteShop archives a shop. Shops that still sell live products can't be
+// archived, the products have to be moved or deleted first.
+func (s *shopRepo) DeleteShop(ctx context.Context, shopID int) error {
+	dbReq := "SELECT count(*) " +
+		"FROM productshop " +
+		"JOIN products ON products.id = productshop.product_id " +
+		"WHERE productshop.shop_id = $1 AND products.deleted_at IS NULL"
+	var products int
+	err := conn(ctx, s.pool).QueryRow(ctx, dbReq, shopID).Scan(&products)
+	if err != nil {
+		return fmt.Errorf("DeleteShop: %w", classify(err))
+	}
+	if products > 0 {
+		return fmt.Errorf("DeleteShop: %w", NewConflictError(fmt.Sprintf("shop %d still has %d products", shopID, products)))
+	}
+
+	dbReq = "UPDATE shops SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL"
+	tag, err := conn(ctx, s.pool).Exec(ctx, dbReq, shopID)
+	if err != nil {
+		return fmt.Errorf("DeleteShop: %w", classify(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("DeleteShop: %w", NewNotFoundError(fmt.Sprintf("shop %d", shopID)))
+	}
+	return nil
+}
+
+func (s *shopRepo) RestoreShop(ctx context.Context, shopID int) error {
+	dbReq := "UPDATE shops SET deleted_at = NULL, updated = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NOT NULL"
+	tag, err := conn(ctx, s.pool).Exec(ctx, dbReq, shopID)
+	if err != nil {
+		return fmt.Errorf("RestoreShop: %w", classify(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("RestoreShop: %w", NewNotFoundError(fmt.Sprintf("archived shop %d", shopID)))
 	}
 	return nil
 }
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"market4/internal/model"
 	"testing"
@@ -245,3 +246,33 @@ func (s *ShopsTestSuite) Test_EditShop() {
 		})
 	}
 }
+
+func (s *ShopsTestSuite) Test_InTx() {
+	ctx := context.Background()
+	tx := NewTransactor(s.testRepo.pool)
+	failed := errors.New("audit failed")
+	err := tx.InTx(ctx, func(ctx context.Context) error {
+		_, err := s.testRepo.AddShop(ctx, &model.Shop{Name: "Магазин у дома", Address: "Тверь"})
+		s.Require().NoError(err)
+		return failed
+	})
+	s.ErrorIs(err, failed)
+	shops, err := s.testRepo.ListAllShops(ctx)
+	s.Require().NoError(err)
+	for _, shop := range shops {
+		s.NotEqual("Магазин у дома", shop.Name, "the shop is rolled back with the failed change")
+	}
+
+	err = tx.InTx(ctx, func(ctx context.Context) error {
+		_, err := s.testRepo.AddShop(ctx, &model.Shop{Name: "Магазин у дома", Address: "Тверь"})
+		return err
+	})
+	s.Require().NoError(err)
+	shops, err = s.testRepo.ListAllShops(ctx)
+	s.Require().NoError(err)
+	names := make([]string, 0, len(shops))
+	for _, shop := range shops {
+		names = append(names, shop.Name)
+	}
+	s.Contains(names, "Магазин у дома")
+}
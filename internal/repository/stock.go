@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"market4/internal/model"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type stockRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewStockRepository(pool *pgxpool.Pool) Stock {
+	return &stockRepo{pool: pool}
+}
+
+const stockColumns = "shop_id, product_id, quantity, reserved, quantity - reserved, updated"
+
+func scanStock(row pgx.Row) (model.Stock, error) {
+	var s model.Stock
+	err := row.Scan(&s.ShopID, &s.ProductID, &s.Quantity, &s.Reserved, &s.Available, &s.Updated)
+	return s, err
+}
+
+// SetStock sets how many units a shop has. It can't go below what unpaid
+// orders have reserved.
+func (r *stockRepo) SetStock(ctx context.Context, s model.Stock) (model.Stock, error) {
+	dbReq := "INSERT INTO stock (shop_id, product_id, quantity) VALUES ($1, $2, $3) " +
+		"ON CONFLICT (shop_id, product_id) DO UPDATE SET quantity = EXCLUDED.quantity, updated = CURRENT_TIMESTAMP " +
+		"RETURNING " + stockColumns
+	stock, err := scanStock(r.pool.QueryRow(ctx, dbReq, s.ShopID, s.ProductID, s.Quantity))
+	if err != nil {
+		return stock, fmt.Errorf("SetStock: %w", classify(err))
+	}
+	return stock, nil
+}
+
+// ListStock returns the stock of a product in every shop that has it.
+func (r *stockRepo) ListStock(ctx context.Context, productID string) ([]model.Stock, error) {
+	stock := make([]model.Stock, 0)
+	rows, err := r.pool.Query(ctx, "SELECT "+stockColumns+" FROM stock WHERE product_id = $1 ORDER BY shop_id", productID)
+	if err != nil {
+		return stock, fmt.Errorf("ListStock: %w", classify(err))
+	}
+	defer rows.Close()
+	for rows.Next() {
+		s, err := scanStock(rows)
+		if err != nil {
+			return stock, fmt.Errorf("ListStock: %w", classify(err))
+		}
+		stock = append(stock, s)
+	}
+	if err = rows.Err(); err != nil {
+		return stock, fmt.Errorf("ListStock: %w", classify(err))
+	}
+	return stock, nil
+}
+
+// ExpireReservations cancels the unpaid orders whose reservations have
+// expired, which releases their stock, and returns their IDs. Orders with a
+// payment still in progress are left until it settles. Each order is
+// cancelled in its own transaction; one that was paid or cancelled in the
+// meantime is skipped.
+func (r *stockRepo) ExpireReservations(ctx context.Context) ([]int, error) {
+	orderIDs := make([]int, 0)
+	rows, err := r.pool.Query(ctx,
+		"SELECT DISTINCT orders.id FROM orders "+
+			"JOIN reservations ON reservations.order_id = orders.id "+
+			"WHERE reservations.status = 'held' AND reservations.expires_at <= CURRENT_TIMESTAMP "+
+			"AND orders.status = $1 "+
+			"AND NOT EXISTS (SELECT 1 FROM payments "+
+			"WHERE payments.order_id = orders.id AND payments.status IN ('pending', 'authorized')) "+
+			"ORDER BY orders.id",
+		model.OrderCreated)
+	if err != nil {
+		return orderIDs, fmt.Errorf("ExpireReservations: %w", classify(err))
+	}
+	var candidates []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return orderIDs, fmt.Errorf("ExpireReservations: %w", classify(err))
+		}
+		candidates = append(candidates, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return orderIDs, fmt.Errorf("ExpireReservations: %w", classify(err))
+	}
+
+	for _, id := range candidates {
+		err = r.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+			return transitionOrder(ctx, tx, id, model.OrderTransition{To: model.OrderCancelled, Note: "reservation expired"})
+		})
+		if errors.Is(err, ErrConflict) {
+			continue
+		}
+		if err != nil {
+			return orderIDs, fmt.Errorf("ExpireReservations: %w", classify(err))
+		}
+		orderIDs = append(orderIDs, id)
+	}
+	return orderIDs, nil
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"market4/internal/model"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/suite"
+)
+
+type StockTestSuite struct {
+	suite.Suite
+	testRepo stockRepo
+	orders   orderRepo
+	Data     TestData
+}
+
+func Test_StockSuite(t *testing.T) {
+	suite.Run(t, new(StockTestSuite))
+}
+
+func (s *StockTestSuite) SetupTest() {
+	fmt.Println("start setup")
+	var err error
+	s.testRepo.pool, err = pgxpool.Connect(context.Background(), testDSN)
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	s.orders = orderRepo{pool: s.testRepo.pool, reservationTTL: time.Hour}
+	s.Data, err = loadTestDataFromYaml("stock_test.yaml")
+	if err != nil {
+		s.Error(err)
+		s.Fail("setup failed")
+		return
+	}
+	for _, r := range s.Data.Conf.Setup.Requests {
+		_, err = s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			return
+		}
+	}
+}
+
+func (s *StockTestSuite) TearDownTest() {
+	fmt.Println("cleaning up")
+	for _, r := range s.Data.Conf.Teardown.Requests {
+		_, err := s.testRepo.pool.Exec(context.Background(), r.Request)
+		if err != nil {
+			s.Error(err)
+			s.Fail("cleaning failed")
+		}
+	}
+}
+
+func (s *StockTestSuite) order(userID, quantity int) model.Order {
+	return model.Order{
+		UserID:   userID,
+		Subtotal: 2000 * quantity,
+		Total:    2000 * quantity,
+		Items: []model.OrderItem{
+			{ProductID: promotedProduct, SKU: "3001", Name: "пушка", Quantity: quantity, UnitPrice: 2000, Total: 2000 * quantity},
+		},
+	}
+}
+
+func (s *StockTestSuite) available() int {
+	stock, err := s.testRepo.ListStock(context.Background(), promotedProduct)
+	s.Require().NoError(err)
+	total := 0
+	for _, st := range stock {
+		total += st.Available
+	}
+	return total
+}
+
+func (s *StockTestSuite) Test_stockRepo_SetStock() {
+	ctx := context.Background()
+	stock, err := s.testRepo.SetStock(ctx, model.Stock{ShopID: 2, ProductID: promotedProduct, Quantity: 3})
+	s.Require().NoError(err)
+	s.Equal(3, stock.Available)
+
+	_, err = s.orders.CreateOrder(ctx, s.order(1, 8))
+	s.Require().NoError(err)
+	_, err = s.testRepo.SetStock(ctx, model.Stock{ShopID: 1, ProductID: promotedProduct, Quantity: 5})
+	s.True(errors.Is(err, ErrValidation), "stock can't go below what is reserved: %v", err)
+
+	stock, err = s.testRepo.SetStock(ctx, model.Stock{ShopID: 1, ProductID: promotedProduct, Quantity: 12})
+	s.Require().NoError(err)
+	s.Equal(8, stock.Reserved)
+	s.Equal(4, stock.Available)
+
+	list, err := s.testRepo.ListStock(ctx, promotedProduct)
+	s.NoError(err)
+	s.Require().Len(list, 2)
+	s.Equal(1, list[0].ShopID)
+}
+
+func (s *StockTestSuite) Test_stockRepo_NoOversell() {
+	ctx := context.Background()
+	_, err := s.testRepo.SetStock(ctx, model.Stock{ShopID: 1, ProductID: promotedProduct, Quantity: 5})
+	s.Require().NoError(err)
+
+	const buyers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, buyers)
+	for i := 1; i <= buyers; i++ {
+		wg.Add(1)
+		go func(userID int) {
+			defer wg.Done()
+			_, err := s.orders.CreateOrder(ctx, s.order(userID, 1))
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	var sold, conflicts int
+	for err := range errs {
+		switch {
+		case err == nil:
+			sold++
+		case errors.Is(err, ErrConflict):
+			conflicts++
+		default:
+			s.NoError(err)
+		}
+	}
+	s.Equal(5, sold, "exactly the stock is sold")
+	s.Equal(buyers-5, conflicts)
+	s.Zero(s.available())
+}
+
+func (s *StockTestSuite) Test_stockRepo_SettleOnTransition() {
+	ctx := context.Background()
+	paid, err := s.orders.CreateOrder(ctx, s.order(1, 2))
+	s.Require().NoError(err)
+	s.NotNil(paid.ReservedUntil)
+	cancelled, err := s.orders.CreateOrder(ctx, s.order(2, 3))
+	s.Require().NoError(err)
+	s.Equal(5, s.available())
+
+	paid, err = s.orders.TransitionOrder(ctx, paid.ID, model.OrderTransition{To: model.OrderPaid, ActorID: 2})
+	s.Require().NoError(err)
+	s.Nil(paid.ReservedUntil, "nothing is held for a paid order")
+	_, err = s.orders.TransitionOrder(ctx, cancelled.ID, model.OrderTransition{To: model.OrderCancelled, ActorID: 2})
+	s.Require().NoError(err)
+
+	stock, err := s.testRepo.ListStock(ctx, promotedProduct)
+	s.Require().NoError(err)
+	s.Equal(8, stock[0].Quantity, "paying deducts")
+	s.Zero(stock[0].Reserved, "cancelling releases")
+}
+
+func (s *StockTestSuite) Test_stockRepo_ExpireReservations() {
+	ctx := context.Background()
+	expiring := orderRepo{pool: s.testRepo.pool, reservationTTL: -time.Minute}
+	expired, err := expiring.CreateOrder(ctx, s.order(1, 1))
+	s.Require().NoError(err)
+	paying, err := expiring.CreateOrder(ctx, s.order(2, 1))
+	s.Require().NoError(err)
+	_, err = s.testRepo.pool.Exec(ctx,
+		"INSERT INTO payments (id, order_id, provider, status, amount) VALUES ('fake_1', $1, 'fake', 'pending', 2000)", paying.ID)
+	s.Require().NoError(err)
+	_, err = s.orders.CreateOrder(ctx, s.order(3, 1))
+	s.Require().NoError(err)
+
+	cancelled, err := s.testRepo.ExpireReservations(ctx)
+	s.Require().NoError(err)
+	s.Equal([]int{expired.ID}, cancelled, "orders being paid and fresh ones stay")
+	s.Equal(8, s.available())
+
+	order, err := s.orders.GetOrder(ctx, expired.ID)
+	s.Require().NoError(err)
+	s.Equal(model.OrderCancelled, order.Status)
+	s.Equal("reservation expired", order.History[len(order.History)-1].Note)
+
+	cancelled, err = s.testRepo.ExpireReservations(ctx)
+	s.NoError(err)
+	s.Empty(cancelled)
+}
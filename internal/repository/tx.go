@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Transactor runs several repository calls as one change. Calls made with
+// the context fn gets share its transaction, which commits if fn returns
+// nil and rolls back otherwise. A context that carries a transaction
+// already makes fn join it.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type transactor struct {
+	pool *pgxpool.Pool
+}
+
+func NewTransactor(pool *pgxpool.Pool) Transactor {
+	return &transactor{pool: pool}
+}
+
+type txKey struct{}
+
+func (t *transactor) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+	var fnErr error
+	err := t.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		fnErr = fn(context.WithValue(ctx, txKey{}, tx))
+		return fnErr
+	})
+	if err != nil && err != fnErr {
+		return fmt.Errorf("InTx: %w", classify(err))
+	}
+	return err
+}
+
+// querier is what the repositories run statements on: the pool or a
+// transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	BeginFunc(ctx context.Context, f func(pgx.Tx) error) error
+}
+
+// conn is the transaction ctx carries, see Transactor, or else pool.
+// BeginFunc on a transaction opens a savepoint, so repository methods that
+// make their own transactions nest in it.
+func conn(ctx context.Context, pool *pgxpool.Pool) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	return pool
+}
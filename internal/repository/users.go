@@ -28,14 +28,14 @@ func (u *usersRepo) AddUser(ctx context.Context, user *model.User) (*model.User,
 	if err != nil {
 		return nil, fmt.Errorf("AddUser: %w", err)
 	}
-	err = u.pool.QueryRow(ctx, dbReq, user.Login, hash).Scan(&addedUser.ID)
+	err = conn(ctx, u.pool).QueryRow(ctx, dbReq, user.Login, hash).Scan(&addedUser.ID)
 	if err != nil {
-		return nil, fmt.Errorf("AddUser: %w", err)
+		return nil, fmt.Errorf("AddUser: %w", classify(err))
 	}
 
 	err = u.AddRole(ctx, user.Login, user.Role)
 	if err != nil {
-		return nil, fmt.Errorf("AddUser: %w", err)
+		return nil, fmt.Errorf("AddUser: %w", classify(err))
 	}
 	return &addedUser, nil
 }
@@ -49,21 +49,18 @@ func (u *usersRepo) EditUser(ctx context.Context, user *model.User) (*model.User
 	if err != nil {
 		return nil, fmt.Errorf("AddUser: %w", err)
 	}
-	err = u.pool.QueryRow(ctx, dbReq, hash, user.Login).Scan(&editedUser.ID)
+	err = conn(ctx, u.pool).QueryRow(ctx, dbReq, hash, user.Login).Scan(&editedUser.ID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return &editedUser, nil
-		}
-		return &editedUser, fmt.Errorf("EditUser: %w", err)
+		return &editedUser, fmt.Errorf("EditUser: %w", classify(err))
 	}
 	return &editedUser, nil
 }
 func (u *usersRepo) AddRole(ctx context.Context, login, role string) error {
 	dbReq := "INSERT INTO userroles (user_id, role_id) " +
 		"VALUES ((SELECT id FROM users WHERE login = $1), (SELECT id FROM roles WHERE name = $2))"
-	_, err := u.pool.Exec(ctx, dbReq, login, role)
+	_, err := conn(ctx, u.pool).Exec(ctx, dbReq, login, role)
 	if err != nil {
-		return fmt.Errorf("AddRole: %w", err)
+		return fmt.Errorf("AddRole: %w", classify(err))
 	}
 	return nil
 }
@@ -72,9 +69,9 @@ func (u *usersRepo) RemoveRole(ctx context.Context, login, role string) error {
 	dbReq := "DELETE FROM userroles " +
 		"WHERE user_id = (SELECT id FROM users WHERE login = $1) " +
 		"AND role_id = (SELECT id FROM roles WHERE name = $2)"
-	_, err := u.pool.Exec(ctx, dbReq, login, role)
+	_, err := conn(ctx, u.pool).Exec(ctx, dbReq, login, role)
 	if err != nil {
-		return fmt.Errorf("RemoveRole: %w", err)
+		return fmt.Errorf("RemoveRole: %w", classify(err))
 	}
 	return nil
 }
@@ -82,12 +79,12 @@ func (u *usersRepo) RemoveRole(ctx context.Context, login, role string) error {
 func (u *usersRepo) GetUserRolesByID(ctx context.Context, id int) ([]string, error) {
 	dbReq := "SELECT role_id FROM userroles WHERE user_id = $1"
 	var roles = make([]string, 0)
-	rows, err := u.pool.Query(ctx, dbReq, id)
+	rows, err := conn(ctx, u.pool).Query(ctx, dbReq, id)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return roles, nil
 		}
-		return roles, fmt.Errorf("GetUserRoleByID: %w", err)
+		return roles, fmt.Errorf("GetUserRoleByID: %w", classify(err))
 	}
 	defer rows.Close()
 	for rows.Next() {
@@ -95,14 +92,14 @@ func (u *usersRepo) GetUserRolesByID(ctx context.Context, id int) ([]string, err
 		var roleName string
 		err = rows.Scan(&roleID)
 		if err != nil {
-			return roles, fmt.Errorf("GetUserRoleByID: %w", err)
+			return roles, fmt.Errorf("GetUserRoleByID: %w", classify(err))
 		}
 		roleName, err = u.GetRoleByID(ctx, roleID)
 		if err != nil {
 			if err == pgx.ErrNoRows {
 				continue
 			}
-			return roles, fmt.Errorf("GetUserRoleByID: %w", err)
+			return roles, fmt.Errorf("GetUserRoleByID: %w", classify(err))
 		}
 		roles = append(roles, roleName)
 	}
@@ -112,7 +109,7 @@ func (u *usersRepo) GetUserRolesByID(ctx context.Context, id int) ([]string, err
 func (u *usersRepo) CheckCreds(ctx context.Context, user model.User) bool {
 	dbReq := "SELECT password FROM users WHERE login = $1"
 	var hash []byte
-	err := u.pool.QueryRow(ctx, dbReq, user.Login).Scan(&hash)
+	err := conn(ctx, u.pool).QueryRow(ctx, dbReq, user.Login).Scan(&hash)
 	if err != nil {
 		log.Println(fmt.Errorf("CheckCreds: %w", err))
 		return false
@@ -129,12 +126,12 @@ func (u *usersRepo) CheckCreds(ctx context.Context, user model.User) bool {
 func (u *usersRepo) GetUserID(ctx context.Context, login string) (int, error) {
 	dbReq := "SELECT id FROM users WHERE login = $1"
 	var id int
-	err := u.pool.QueryRow(ctx, dbReq, login).Scan(&id)
+	err := conn(ctx, u.pool).QueryRow(ctx, dbReq, login).Scan(&id)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return 0, nil
 		}
-		return 0, fmt.Errorf("GetUserID: %w", err)
+		return 0, fmt.Errorf("GetUserID: %w", classify(err))
 	}
 	return id, nil
 }
@@ -142,12 +139,89 @@ func (u *usersRepo) GetUserID(ctx context.Context, login string) (int, error) {
 func (u usersRepo) GetRoleByID(ctx context.Context, roleID int) (string, error) {
 	dbReq := "SELECT name FROM roles WHERE id = $1"
 	var role string
-	err := u.pool.QueryRow(ctx, dbReq, roleID).Scan(&role)
+	err := conn(ctx, u.pool).QueryRow(ctx, dbReq, roleID).Scan(&role)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return "", nil
 		}
-		return "", fmt.Errorf("GetRoleByID: %w", err)
+		return "", fmt.Errorf("GetRoleByID: %w", classify(err))
 	}
 	return role, nil
 }
+
+// GetStaffShops returns the shops whose orders the user works as staff,
+// in order.
+func (u *usersRepo) GetStaffShops(ctx context.Context, userID int) ([]int, error) {
+	dbReq := "SELECT ARRAY(SELECT shop_id FROM staff_shops WHERE user_id = $1 ORDER BY shop_id)"
+	shopIDs := make([]int, 0)
+	err := conn(ctx, u.pool).QueryRow(ctx, dbReq, userID).Scan(&shopIDs)
+	if err != nil {
+		return nil, fmt.Errorf("GetStaffShops: %w", classify(err))
+	}
+	return shopIDs, nil
+}
+
+// SetStaffShops replaces the shops the user staffs. The shops must exist
+// and not be archived.
+func (u *usersRepo) SetStaffShops(ctx context.Context, login string, shopIDs []int) error {
+	err := conn(ctx, u.pool).BeginFunc(ctx, func(tx pgx.Tx) error {
+		var userID int
+		err := tx.QueryRow(ctx, "SELECT id FROM users WHERE login = $1", login).Scan(&userID)
+		if err == pgx.ErrNoRows {
+			return NewNotFoundError("user " + login)
+		}
+		if err != nil {
+			return classify(err)
+		}
+		if _, err = tx.Exec(ctx, "DELETE FROM staff_shops WHERE user_id = $1", userID); err != nil {
+			return classify(err)
+		}
+		tag, err := tx.Exec(ctx,
+			"INSERT INTO staff_shops (user_id, shop_id) "+
+				"SELECT $1, id FROM shops WHERE id = ANY($2::bigint[]) AND deleted_at IS NULL",
+			userID, shopIDs)
+		if err != nil {
+			return classify(err)
+		}
+		wanted := make(map[int]bool, len(shopIDs))
+		for _, shopID := range shopIDs {
+			wanted[shopID] = true
+		}
+		if int(tag.RowsAffected()) != len(wanted) {
+			return NewForeignKeyError("shop_ids", "shop doesn't exist")
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("SetStaffShops: %w", err)
+	}
+	return nil
+}
+
+// ListUsers returns every user with their roles, ordered by login.
+func (u *usersRepo) ListUsers(ctx context.Context) ([]model.Account, error) {
+	dbReq := "SELECT users.id, users.login, " +
+		"COALESCE(array_agg(roles.name ORDER BY roles.name) FILTER (WHERE roles.name IS NOT NULL), '{}') " +
+		"FROM users " +
+		"LEFT JOIN userroles ON userroles.user_id = users.id " +
+		"LEFT JOIN roles ON roles.id = userroles.role_id " +
+		"GROUP BY users.id " +
+		"ORDER BY users.login"
+	rows, err := conn(ctx, u.pool).Query(ctx, dbReq)
+	if err != nil {
+		return nil, fmt.Errorf("ListUsers: %w", classify(err))
+	}
+	defer rows.Close()
+	accounts := make([]model.Account, 0)
+	for rows.Next() {
+		var a model.Account
+		if err = rows.Scan(&a.ID, &a.Login, &a.Roles); err != nil {
+			return nil, fmt.Errorf("ListUsers: %w", classify(err))
+		}
+		accounts = append(accounts, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ListUsers: %w", classify(err))
+	}
+	return accounts, nil
+}
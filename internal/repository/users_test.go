@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"market4/internal/model"
 	"sync"
@@ -552,3 +553,52 @@ func (s *UsersTestSuite) Test_GetRoleByID() {
 		})
 	}
 }
+
+func (s *UsersTestSuite) Test_ListUsers() {
+	ctx := context.Background()
+	accounts, err := s.testRepo.ListUsers(ctx)
+	s.Require().NoError(err)
+	s.Empty(accounts)
+
+	_, err = s.testRepo.AddUser(ctx, &model.User{Login: "zoe", Password: "secret", Role: "USER"})
+	s.Require().NoError(err)
+	_, err = s.testRepo.AddUser(ctx, &model.User{Login: "adam", Password: "secret", Role: "USER"})
+	s.Require().NoError(err)
+	s.Require().NoError(s.testRepo.AddRole(ctx, "adam", "ADMIN"))
+	_, err = s.testRepo.pool.Exec(ctx, "INSERT INTO users (login, password) VALUES ('nobody', 'x')")
+	s.Require().NoError(err)
+
+	accounts, err = s.testRepo.ListUsers(ctx)
+	s.Require().NoError(err)
+	s.Equal([]model.Account{
+		{ID: 2, Login: "adam", Roles: []string{"ADMIN", "USER"}},
+		{ID: 3, Login: "nobody", Roles: []string{}},
+		{ID: 1, Login: "zoe", Roles: []string{"USER"}},
+	}, accounts)
+}
+
+func (s *UsersTestSuite) Test_StaffShops() {
+	ctx := context.Background()
+	_, err := s.testRepo.AddUser(ctx, &model.User{Login: "kate", Password: "secret", Role: "USER"})
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.testRepo.SetStaffShops(ctx, "kate", []int{2, 1, 2}))
+	shops, err := s.testRepo.GetStaffShops(ctx, 1)
+	s.NoError(err)
+	s.Equal([]int{1, 2}, shops)
+
+	err = s.testRepo.SetStaffShops(ctx, "kate", []int{1, 3})
+	s.True(errors.Is(err, ErrForeignKey), "archived shops can't be staffed: %v", err)
+	err = s.testRepo.SetStaffShops(ctx, "kate", []int{4})
+	s.True(errors.Is(err, ErrForeignKey), err)
+	err = s.testRepo.SetStaffShops(ctx, "nobody", []int{1})
+	s.True(errors.Is(err, ErrNotFound), err)
+	shops, err = s.testRepo.GetStaffShops(ctx, 1)
+	s.NoError(err)
+	s.Equal([]int{1, 2}, shops, "failed changes keep the shops")
+
+	s.Require().NoError(s.testRepo.SetStaffShops(ctx, "kate", nil))
+	shops, err = s.testRepo.GetStaffShops(ctx, 1)
+	s.NoError(err)
+	s.Empty(shops)
+}
@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"market4/internal/cache"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type tracedCache struct {
+	next cache.Cache
+}
+
+// Cache wraps next with a span per call.
+func Cache(next cache.Cache) cache.Cache {
+	return tracedCache{next: next}
+}
+
+func (t tracedCache) ToCache(ctx context.Context, key string, value []byte) error {
+	ctx, span := start(ctx, "cache.ToCache", attribute.String("cache.key", key), attribute.Int("cache.size", len(value)))
+	defer span.End()
+	err := t.next.ToCache(ctx, key, value)
+	record(span, err)
+	return err
+}
+
+func (t tracedCache) FromCache(ctx context.Context, key string) ([]byte, error) {
+	ctx, span := start(ctx, "cache.FromCache", attribute.String("cache.key", key))
+	defer span.End()
+	value, err := t.next.FromCache(ctx, key)
+	miss := value == nil && (err == nil || errors.Is(err, cache.ErrMiss))
+	span.SetAttributes(attribute.Bool("cache.hit", !miss && err == nil))
+	if !miss {
+		record(span, err)
+	}
+	return value, err
+}
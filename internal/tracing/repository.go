@@ -0,0 +1,779 @@
+package tracing
+
+import (
+	"context"
+	"market4/internal/model"
+	"market4/internal/repository"
+	"time"
+)
+
+// The types below wrap the repositories with a span per method, named
+// after the interface and the method, e.g. Product.GetProductByID.
+
+type tracedShop struct {
+	next repository.Shop
+}
+
+func Shop(next repository.Shop) repository.Shop {
+	return tracedShop{next: next}
+}
+
+func (t tracedShop) ListAllShops(ctx context.Context) ([]model.Shop, error) {
+	ctx, span := start(ctx, "Shop.ListAllShops")
+	defer span.End()
+	result, err := t.next.ListAllShops(ctx)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedShop) AddShop(ctx context.Context, s *model.Shop) (int, error) {
+	ctx, span := start(ctx, "Shop.AddShop")
+	defer span.End()
+	result, err := t.next.AddShop(ctx, s)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedShop) EditShop(ctx context.Context, s *model.Shop) error {
+	ctx, span := start(ctx, "Shop.EditShop")
+	defer span.End()
+	err := t.next.EditShop(ctx, s)
+	record(span, err)
+	return err
+}
+
+func (t tracedShop) IfShopExists(ctx context.Context, shopID int) bool {
+	ctx, span := start(ctx, "Shop.IfShopExists")
+	defer span.End()
+	return t.next.IfShopExists(ctx, shopID)
+}
+
+func (t tracedShop) GetShopByID(ctx context.Context, shopID int) (model.Shop, error) {
+	ctx, span := start(ctx, "Shop.GetShopByID")
+	defer span.End()
+	result, err := t.next.GetShopByID(ctx, shopID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedShop) DeleteShop(ctx context.Context, shopID int) error {
+	ctx, span := start(ctx, "Shop.DeleteShop")
+	defer span.End()
+	err := t.next.DeleteShop(ctx, shopID)
+	record(span, err)
+	return err
+}
+
+func (t tracedShop) RestoreShop(ctx context.Context, shopID int) error {
+	ctx, span := start(ctx, "Shop.RestoreShop")
+	defer span.End()
+	err := t.next.RestoreShop(ctx, shopID)
+	record(span, err)
+	return err
+}
+
+type tracedCategory struct {
+	next repository.Category
+}
+
+func Category(next repository.Category) repository.Category {
+	return tracedCategory{next: next}
+}
+
+func (t tracedCategory) ListAllCategories(ctx context.Context) ([]model.Category, error) {
+	ctx, span := start(ctx, "Category.ListAllCategories")
+	defer span.End()
+	result, err := t.next.ListAllCategories(ctx)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedCategory) AddCategory(ctx context.Context, c *model.Category) (int, error) {
+	ctx, span := start(ctx, "Category.AddCategory")
+	defer span.End()
+	result, err := t.next.AddCategory(ctx, c)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedCategory) EditCategory(ctx context.Context, c *model.Category) error {
+	ctx, span := start(ctx, "Category.EditCategory")
+	defer span.End()
+	err := t.next.EditCategory(ctx, c)
+	record(span, err)
+	return err
+}
+
+func (t tracedCategory) IfCategoryExists(ctx context.Context, categoryID int) bool {
+	ctx, span := start(ctx, "Category.IfCategoryExists")
+	defer span.End()
+	return t.next.IfCategoryExists(ctx, categoryID)
+}
+
+func (t tracedCategory) GetCategoryByID(ctx context.Context, categoryID int) (model.Category, error) {
+	ctx, span := start(ctx, "Category.GetCategoryByID")
+	defer span.End()
+	result, err := t.next.GetCategoryByID(ctx, categoryID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedCategory) GetCategoryByURIName(ctx context.Context, uriName string) (model.Category, error) {
+	ctx, span := start(ctx, "Category.GetCategoryByURIName")
+	defer span.End()
+	result, err := t.next.GetCategoryByURIName(ctx, uriName)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedCategory) DeleteCategory(ctx context.Context, categoryID int) error {
+	ctx, span := start(ctx, "Category.DeleteCategory")
+	defer span.End()
+	err := t.next.DeleteCategory(ctx, categoryID)
+	record(span, err)
+	return err
+}
+
+func (t tracedCategory) RestoreCategory(ctx context.Context, categoryID int) error {
+	ctx, span := start(ctx, "Category.RestoreCategory")
+	defer span.End()
+	err := t.next.RestoreCategory(ctx, categoryID)
+	record(span, err)
+	return err
+}
+
+type tracedProduct struct {
+	next repository.Product
+}
+
+func Product(next repository.Product) repository.Product {
+	return tracedProduct{next: next}
+}
+
+func (t tracedProduct) AddProduct(ctx context.Context, p model.Product, shopId int, categoryId int) (model.Product, error) {
+	ctx, span := start(ctx, "Product.AddProduct")
+	defer span.End()
+	result, err := t.next.AddProduct(ctx, p, shopId, categoryId)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedProduct) EditProduct(ctx context.Context, p model.Product, shopId int, categoryId int) (model.Product, error) {
+	ctx, span := start(ctx, "Product.EditProduct")
+	defer span.End()
+	result, err := t.next.EditProduct(ctx, p, shopId, categoryId)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedProduct) ListAllProducts(ctx context.Context) ([]model.Product, error) {
+	ctx, span := start(ctx, "Product.ListAllProducts")
+	defer span.End()
+	result, err := t.next.ListAllProducts(ctx)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedProduct) ListProducts(ctx context.Context, filter model.ProductFilter) ([]model.Product, error) {
+	ctx, span := start(ctx, "Product.ListProducts")
+	defer span.End()
+	result, err := t.next.ListProducts(ctx, filter)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedProduct) IfProductExists(ctx context.Context, productID string) bool {
+	ctx, span := start(ctx, "Product.IfProductExists")
+	defer span.End()
+	return t.next.IfProductExists(ctx, productID)
+}
+
+func (t tracedProduct) SearchProductsByCategory(ctx context.Context, category int) ([]model.Product, error) {
+	ctx, span := start(ctx, "Product.SearchProductsByCategory")
+	defer span.End()
+	result, err := t.next.SearchProductsByCategory(ctx, category)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedProduct) SearchProductsByName(ctx context.Context, productName string) (model.Product, error) {
+	ctx, span := start(ctx, "Product.SearchProductsByName")
+	defer span.End()
+	result, err := t.next.SearchProductsByName(ctx, productName)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedProduct) SearchProductsByShop(ctx context.Context, shopID int) ([]model.Product, error) {
+	ctx, span := start(ctx, "Product.SearchProductsByShop")
+	defer span.End()
+	result, err := t.next.SearchProductsByShop(ctx, shopID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedProduct) GetProductByID(ctx context.Context, productID string) (model.Product, error) {
+	ctx, span := start(ctx, "Product.GetProductByID")
+	defer span.End()
+	result, err := t.next.GetProductByID(ctx, productID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedProduct) GetProductBySKU(ctx context.Context, sku string) (model.Product, error) {
+	ctx, span := start(ctx, "Product.GetProductBySKU")
+	defer span.End()
+	result, err := t.next.GetProductBySKU(ctx, sku)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedProduct) GetProductByURI(ctx context.Context, uri string) (model.Product, error) {
+	ctx, span := start(ctx, "Product.GetProductByURI")
+	defer span.End()
+	result, err := t.next.GetProductByURI(ctx, uri)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedProduct) DeleteProduct(ctx context.Context, productID string) error {
+	ctx, span := start(ctx, "Product.DeleteProduct")
+	defer span.End()
+	err := t.next.DeleteProduct(ctx, productID)
+	record(span, err)
+	return err
+}
+
+func (t tracedProduct) RestoreProduct(ctx context.Context, productID string) error {
+	ctx, span := start(ctx, "Product.RestoreProduct")
+	defer span.End()
+	err := t.next.RestoreProduct(ctx, productID)
+	record(span, err)
+	return err
+}
+
+func (t tracedProduct) UpsertProducts(ctx context.Context, rows []model.ProductImport) ([]repository.UpsertResult, error) {
+	ctx, span := start(ctx, "Product.UpsertProducts")
+	defer span.End()
+	result, err := t.next.UpsertProducts(ctx, rows)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedProduct) ExportProducts(ctx context.Context, filter model.ProductFilter, fn func(model.ProductExport) error) error {
+	ctx, span := start(ctx, "Product.ExportProducts")
+	defer span.End()
+	err := t.next.ExportProducts(ctx, filter, fn)
+	record(span, err)
+	return err
+}
+
+func (t tracedProduct) ProductCategories(ctx context.Context, productIDs []string) (map[string][]int, error) {
+	ctx, span := start(ctx, "Product.ProductCategories")
+	defer span.End()
+	result, err := t.next.ProductCategories(ctx, productIDs)
+	record(span, err)
+	return result, err
+}
+
+type tracedPrice struct {
+	next repository.Price
+}
+
+func Price(next repository.Price) repository.Price {
+	return tracedPrice{next: next}
+}
+
+func (t tracedPrice) AddPrice(ctx context.Context, p *model.Price) (model.Price, error) {
+	ctx, span := start(ctx, "Price.AddPrice")
+	defer span.End()
+	result, err := t.next.AddPrice(ctx, p)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedPrice) EditPrice(ctx context.Context, p *model.Price) (model.Price, error) {
+	ctx, span := start(ctx, "Price.EditPrice")
+	defer span.End()
+	result, err := t.next.EditPrice(ctx, p)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedPrice) ListAllPrices(ctx context.Context) ([]model.Price, error) {
+	ctx, span := start(ctx, "Price.ListAllPrices")
+	defer span.End()
+	result, err := t.next.ListAllPrices(ctx)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedPrice) SearchPriceByProductID(ctx context.Context, productID string) (model.Price, error) {
+	ctx, span := start(ctx, "Price.SearchPriceByProductID")
+	defer span.End()
+	result, err := t.next.SearchPriceByProductID(ctx, productID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedPrice) EditPriceByProductID(ctx context.Context, p *model.Price) (model.Price, error) {
+	ctx, span := start(ctx, "Price.EditPriceByProductID")
+	defer span.End()
+	result, err := t.next.EditPriceByProductID(ctx, p)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedPrice) GetPriceByID(ctx context.Context, priceID int) (model.Price, error) {
+	ctx, span := start(ctx, "Price.GetPriceByID")
+	defer span.End()
+	result, err := t.next.GetPriceByID(ctx, priceID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedPrice) DeletePrice(ctx context.Context, priceID int) error {
+	ctx, span := start(ctx, "Price.DeletePrice")
+	defer span.End()
+	err := t.next.DeletePrice(ctx, priceID)
+	record(span, err)
+	return err
+}
+
+func (t tracedPrice) RestorePrice(ctx context.Context, priceID int) error {
+	ctx, span := start(ctx, "Price.RestorePrice")
+	defer span.End()
+	err := t.next.RestorePrice(ctx, priceID)
+	record(span, err)
+	return err
+}
+
+func (t tracedPrice) SelectPrices(ctx context.Context, selector model.PriceSelector) ([]model.PriceChange, error) {
+	ctx, span := start(ctx, "Price.SelectPrices")
+	defer span.End()
+	result, err := t.next.SelectPrices(ctx, selector)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedPrice) ApplyPriceChanges(ctx context.Context, batch model.PriceBatch) (model.PriceBatch, error) {
+	ctx, span := start(ctx, "Price.ApplyPriceChanges")
+	defer span.End()
+	result, err := t.next.ApplyPriceChanges(ctx, batch)
+	record(span, err)
+	return result, err
+}
+
+type tracedPromotion struct {
+	next repository.Promotion
+}
+
+func Promotion(next repository.Promotion) repository.Promotion {
+	return tracedPromotion{next: next}
+}
+
+func (t tracedPromotion) AddPromotion(ctx context.Context, p model.Promotion) (model.Promotion, error) {
+	ctx, span := start(ctx, "Promotion.AddPromotion")
+	defer span.End()
+	result, err := t.next.AddPromotion(ctx, p)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedPromotion) EditPromotion(ctx context.Context, p model.Promotion) (model.Promotion, error) {
+	ctx, span := start(ctx, "Promotion.EditPromotion")
+	defer span.End()
+	result, err := t.next.EditPromotion(ctx, p)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedPromotion) GetPromotionByID(ctx context.Context, promotionID int) (model.Promotion, error) {
+	ctx, span := start(ctx, "Promotion.GetPromotionByID")
+	defer span.End()
+	result, err := t.next.GetPromotionByID(ctx, promotionID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedPromotion) ListPromotions(ctx context.Context) ([]model.Promotion, error) {
+	ctx, span := start(ctx, "Promotion.ListPromotions")
+	defer span.End()
+	result, err := t.next.ListPromotions(ctx)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedPromotion) DeletePromotion(ctx context.Context, promotionID int) error {
+	ctx, span := start(ctx, "Promotion.DeletePromotion")
+	defer span.End()
+	err := t.next.DeletePromotion(ctx, promotionID)
+	record(span, err)
+	return err
+}
+
+func (t tracedPromotion) ActivePromotions(ctx context.Context, productIDs []string, at time.Time) (map[string][]model.Promotion, error) {
+	ctx, span := start(ctx, "Promotion.ActivePromotions")
+	defer span.End()
+	result, err := t.next.ActivePromotions(ctx, productIDs, at)
+	record(span, err)
+	return result, err
+}
+
+type tracedCoupon struct {
+	next repository.Coupon
+}
+
+func Coupon(next repository.Coupon) repository.Coupon {
+	return tracedCoupon{next: next}
+}
+
+func (t tracedCoupon) AddCoupon(ctx context.Context, c model.Coupon) (model.Coupon, error) {
+	ctx, span := start(ctx, "Coupon.AddCoupon")
+	defer span.End()
+	result, err := t.next.AddCoupon(ctx, c)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedCoupon) EditCoupon(ctx context.Context, c model.Coupon) (model.Coupon, error) {
+	ctx, span := start(ctx, "Coupon.EditCoupon")
+	defer span.End()
+	result, err := t.next.EditCoupon(ctx, c)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedCoupon) GetCouponByID(ctx context.Context, couponID int) (model.Coupon, error) {
+	ctx, span := start(ctx, "Coupon.GetCouponByID")
+	defer span.End()
+	result, err := t.next.GetCouponByID(ctx, couponID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedCoupon) GetCouponByCode(ctx context.Context, code string) (model.Coupon, error) {
+	ctx, span := start(ctx, "Coupon.GetCouponByCode")
+	defer span.End()
+	result, err := t.next.GetCouponByCode(ctx, code)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedCoupon) ListCoupons(ctx context.Context) ([]model.Coupon, error) {
+	ctx, span := start(ctx, "Coupon.ListCoupons")
+	defer span.End()
+	result, err := t.next.ListCoupons(ctx)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedCoupon) DeleteCoupon(ctx context.Context, couponID int) error {
+	ctx, span := start(ctx, "Coupon.DeleteCoupon")
+	defer span.End()
+	err := t.next.DeleteCoupon(ctx, couponID)
+	record(span, err)
+	return err
+}
+
+func (t tracedCoupon) CouponUsage(ctx context.Context, couponID, userID int) (int, int, error) {
+	ctx, span := start(ctx, "Coupon.CouponUsage")
+	defer span.End()
+	total, byUser, err := t.next.CouponUsage(ctx, couponID, userID)
+	record(span, err)
+	return total, byUser, err
+}
+
+type tracedCart struct {
+	next repository.Cart
+}
+
+func Cart(next repository.Cart) repository.Cart {
+	return tracedCart{next: next}
+}
+
+func (t tracedCart) GetCart(ctx context.Context, userID int) ([]model.CartItem, error) {
+	ctx, span := start(ctx, "Cart.GetCart")
+	defer span.End()
+	result, err := t.next.GetCart(ctx, userID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedCart) AddCartItem(ctx context.Context, userID int, productID string, quantity int) error {
+	ctx, span := start(ctx, "Cart.AddCartItem")
+	defer span.End()
+	err := t.next.AddCartItem(ctx, userID, productID, quantity)
+	record(span, err)
+	return err
+}
+
+func (t tracedCart) SetCartItemQuantity(ctx context.Context, userID int, productID string, quantity int) error {
+	ctx, span := start(ctx, "Cart.SetCartItemQuantity")
+	defer span.End()
+	err := t.next.SetCartItemQuantity(ctx, userID, productID, quantity)
+	record(span, err)
+	return err
+}
+
+func (t tracedCart) RemoveCartItem(ctx context.Context, userID int, productID string) error {
+	ctx, span := start(ctx, "Cart.RemoveCartItem")
+	defer span.End()
+	err := t.next.RemoveCartItem(ctx, userID, productID)
+	record(span, err)
+	return err
+}
+
+func (t tracedCart) ClearCart(ctx context.Context, userID int) error {
+	ctx, span := start(ctx, "Cart.ClearCart")
+	defer span.End()
+	err := t.next.ClearCart(ctx, userID)
+	record(span, err)
+	return err
+}
+
+type tracedOrder struct {
+	next repository.Order
+}
+
+func Order(next repository.Order) repository.Order {
+	return tracedOrder{next: next}
+}
+
+func (t tracedOrder) CreateOrder(ctx context.Context, order model.Order) (model.Order, error) {
+	ctx, span := start(ctx, "Order.CreateOrder")
+	defer span.End()
+	result, err := t.next.CreateOrder(ctx, order)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedOrder) GetOrder(ctx context.Context, orderID int) (model.Order, error) {
+	ctx, span := start(ctx, "Order.GetOrder")
+	defer span.End()
+	result, err := t.next.GetOrder(ctx, orderID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedOrder) ListOrders(ctx context.Context, filter model.OrderFilter) ([]model.Order, error) {
+	ctx, span := start(ctx, "Order.ListOrders")
+	defer span.End()
+	result, err := t.next.ListOrders(ctx, filter)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedOrder) TransitionOrder(ctx context.Context, orderID int, transition model.OrderTransition) (model.Order, error) {
+	ctx, span := start(ctx, "Order.TransitionOrder")
+	defer span.End()
+	result, err := t.next.TransitionOrder(ctx, orderID, transition)
+	record(span, err)
+	return result, err
+}
+
+type tracedPayment struct {
+	next repository.Payment
+}
+
+func Payment(next repository.Payment) repository.Payment {
+	return tracedPayment{next: next}
+}
+
+func (t tracedPayment) AddPayment(ctx context.Context, p model.Payment) (model.Payment, error) {
+	ctx, span := start(ctx, "Payment.AddPayment")
+	defer span.End()
+	result, err := t.next.AddPayment(ctx, p)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedPayment) GetPayment(ctx context.Context, paymentID string) (model.Payment, error) {
+	ctx, span := start(ctx, "Payment.GetPayment")
+	defer span.End()
+	result, err := t.next.GetPayment(ctx, paymentID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedPayment) ListPayments(ctx context.Context, orderID int) ([]model.Payment, error) {
+	ctx, span := start(ctx, "Payment.ListPayments")
+	defer span.End()
+	result, err := t.next.ListPayments(ctx, orderID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedPayment) SetPaymentStatus(ctx context.Context, paymentID, from, to, reason string, transition *model.OrderTransition) (model.Payment, error) {
+	ctx, span := start(ctx, "Payment.SetPaymentStatus")
+	defer span.End()
+	result, err := t.next.SetPaymentStatus(ctx, paymentID, from, to, reason, transition)
+	record(span, err)
+	return result, err
+}
+
+type tracedStock struct {
+	next repository.Stock
+}
+
+func Stock(next repository.Stock) repository.Stock {
+	return tracedStock{next: next}
+}
+
+func (t tracedStock) SetStock(ctx context.Context, s model.Stock) (model.Stock, error) {
+	ctx, span := start(ctx, "Stock.SetStock")
+	defer span.End()
+	result, err := t.next.SetStock(ctx, s)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedStock) ListStock(ctx context.Context, productID string) ([]model.Stock, error) {
+	ctx, span := start(ctx, "Stock.ListStock")
+	defer span.End()
+	result, err := t.next.ListStock(ctx, productID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedStock) ExpireReservations(ctx context.Context) ([]int, error) {
+	ctx, span := start(ctx, "Stock.ExpireReservations")
+	defer span.End()
+	result, err := t.next.ExpireReservations(ctx)
+	record(span, err)
+	return result, err
+}
+
+type tracedArchive struct {
+	next repository.Archive
+}
+
+func Archive(next repository.Archive) repository.Archive {
+	return tracedArchive{next: next}
+}
+
+func (t tracedArchive) Purge(ctx context.Context, before time.Time) (repository.PurgeReport, error) {
+	ctx, span := start(ctx, "Archive.Purge")
+	defer span.End()
+	result, err := t.next.Purge(ctx, before)
+	record(span, err)
+	return result, err
+}
+
+type tracedAudit struct {
+	next repository.Audit
+}
+
+func Audit(next repository.Audit) repository.Audit {
+	return tracedAudit{next: next}
+}
+
+func (t tracedAudit) AddAuditEntry(ctx context.Context, e model.AuditEntry) (model.AuditEntry, error) {
+	ctx, span := start(ctx, "Audit.AddAuditEntry")
+	defer span.End()
+	result, err := t.next.AddAuditEntry(ctx, e)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedAudit) ListAuditEntries(ctx context.Context, filter model.AuditFilter) ([]model.AuditEntry, error) {
+	ctx, span := start(ctx, "Audit.ListAuditEntries")
+	defer span.End()
+	result, err := t.next.ListAuditEntries(ctx, filter)
+	record(span, err)
+	return result, err
+}
+
+type tracedUsers struct {
+	next repository.Users
+}
+
+func Users(next repository.Users) repository.Users {
+	return tracedUsers{next: next}
+}
+
+func (t tracedUsers) AddUser(ctx context.Context, u *model.User) (*model.User, error) {
+	ctx, span := start(ctx, "Users.AddUser")
+	defer span.End()
+	result, err := t.next.AddUser(ctx, u)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedUsers) EditUser(ctx context.Context, u *model.User) (*model.User, error) {
+	ctx, span := start(ctx, "Users.EditUser")
+	defer span.End()
+	result, err := t.next.EditUser(ctx, u)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedUsers) GetUserRolesByID(ctx context.Context, id int) ([]string, error) {
+	ctx, span := start(ctx, "Users.GetUserRolesByID")
+	defer span.End()
+	result, err := t.next.GetUserRolesByID(ctx, id)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedUsers) CheckCreds(ctx context.Context, u model.User) bool {
+	ctx, span := start(ctx, "Users.CheckCreds")
+	defer span.End()
+	return t.next.CheckCreds(ctx, u)
+}
+
+func (t tracedUsers) GetUserID(ctx context.Context, login string) (int, error) {
+	ctx, span := start(ctx, "Users.GetUserID")
+	defer span.End()
+	result, err := t.next.GetUserID(ctx, login)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedUsers) GetRoleByID(ctx context.Context, roleID int) (string, error) {
+	ctx, span := start(ctx, "Users.GetRoleByID")
+	defer span.End()
+	result, err := t.next.GetRoleByID(ctx, roleID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedUsers) AddRole(ctx context.Context, login string, role string) error {
+	ctx, span := start(ctx, "Users.AddRole")
+	defer span.End()
+	err := t.next.AddRole(ctx, login, role)
+	record(span, err)
+	return err
+}
+
+func (t tracedUsers) RemoveRole(ctx context.Context, login string, role string) error {
+	ctx, span := start(ctx, "Users.RemoveRole")
+	defer span.End()
+	err := t.next.RemoveRole(ctx, login, role)
+	record(span, err)
+	return err
+}
+
+func (t tracedUsers) ListUsers(ctx context.Context) ([]model.Account, error) {
+	ctx, span := start(ctx, "Users.ListUsers")
+	defer span.End()
+	result, err := t.next.ListUsers(ctx)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedUsers) GetStaffShops(ctx context.Context, userID int) ([]int, error) {
+	ctx, span := start(ctx, "Users.GetStaffShops")
+	defer span.End()
+	result, err := t.next.GetStaffShops(ctx, userID)
+	record(span, err)
+	return result, err
+}
+
+func (t tracedUsers) SetStaffShops(ctx context.Context, login string, shopIDs []int) error {
+	ctx, span := start(ctx, "Users.SetStaffShops")
+	defer span.End()
+	err := t.next.SetStaffShops(ctx, login, shopIDs)
+	record(span, err)
+	return err
+}
@@ -0,0 +1,115 @@
+// Package tracing records OpenTelemetry spans for incoming requests,
+// repository methods and cache calls. Trace context arrives and leaves in
+// the W3C traceparent and tracestate headers.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"market4/internal/config"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentation = "market4"
+	serviceName     = "market4"
+)
+
+// Setup installs the global tracer provider and propagator described by
+// cfg. The returned function flushes the spans still buffered and closes
+// the exporter; it must be called before the process exits.
+func Setup(cfg config.Tracing) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.Exporter == "none" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var out io.Writer = os.Stdout
+	var file *os.File
+	if cfg.Exporter == "file" {
+		var err error
+		file, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("tracing: %w", err)
+		}
+		out = file
+	}
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(out))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}, nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// start opens a span under the one in ctx.
+func start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// record marks the span failed when err is set.
+func record(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// Middleware continues the trace of the caller, if any, and wraps the
+// request in a server span. The span is named after the chi route pattern
+// once routing is done, so requests for different products share a name.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+		ctx, span := tracer().Start(ctx, request.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(serviceName, "", request)...))
+		defer span.End()
+
+		ww := middleware.NewWrapResponseWriter(writer, request.ProtoMajor)
+		next.ServeHTTP(ww, request.WithContext(ctx))
+
+		if rctx := chi.RouteContext(request.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(request.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(semconv.HTTPRouteKey.String(rctx.RoutePattern()))
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(status)...)
+		span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(status, trace.SpanKindServer))
+	})
+}
@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"market4/internal/cache"
+	"market4/internal/config"
+	"market4/internal/model"
+	"market4/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recorder installs a provider that keeps the finished spans in memory.
+func recorder(t *testing.T) *tracetest.SpanRecorder {
+	spans := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return spans
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+type stubShops struct {
+	repository.Shop
+	err error
+}
+
+func (s stubShops) GetShopByID(ctx context.Context, shopID int) (model.Shop, error) {
+	return model.Shop{ID: shopID}, s.err
+}
+
+func Test_Middleware_ContinuesTrace(t *testing.T) {
+	spans := recorder(t)
+	shops := Shop(stubShops{})
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/shops/{shopID}", func(writer http.ResponseWriter, request *http.Request) {
+		_, err := shops.GetShopByID(request.Context(), 1)
+		require.NoError(t, err)
+		writer.WriteHeader(http.StatusNoContent)
+	})
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	request := httptest.NewRequest(http.MethodGet, "/shops/1", nil)
+	request.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), request)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 2)
+	repo, server := ended[0], ended[1]
+	assert.Equal(t, "Shop.GetShopByID", repo.Name())
+	assert.Equal(t, "GET /shops/{shopID}", server.Name())
+	assert.Equal(t, traceID, server.SpanContext().TraceID().String(), "the caller's trace goes on")
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.Equal(t, server.SpanContext().SpanID(), repo.Parent().SpanID())
+	assert.Equal(t, int64(http.StatusNoContent), attr(server, "http.status_code").AsInt64())
+	assert.Equal(t, "/shops/{shopID}", attr(server, "http.route").AsString())
+}
+
+func Test_Repository_RecordsErrors(t *testing.T) {
+	spans := recorder(t)
+	_, err := Shop(stubShops{err: repository.NewNotFoundError("shop 1")}).GetShopByID(context.Background(), 1)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "errors pass through unchanged")
+
+	ended := spans.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, codes.Error, ended[0].Status().Code)
+	require.Len(t, ended[0].Events(), 1)
+	assert.Equal(t, "exception", ended[0].Events()[0].Name)
+}
+
+type stubCache struct {
+	value []byte
+	err   error
+}
+
+func (s stubCache) ToCache(ctx context.Context, key string, value []byte) error { return s.err }
+
+func (s stubCache) FromCache(ctx context.Context, key string) ([]byte, error) { return s.value, s.err }
+
+func Test_Cache(t *testing.T) {
+	spans := recorder(t)
+	ctx := context.Background()
+	Cache(stubCache{value: []byte("x")}).FromCache(ctx, "/products")
+	Cache(stubCache{err: fmt.Errorf("FromCache: %w", cache.ErrMiss)}).FromCache(ctx, "/products")
+	Cache(stubCache{err: errors.New("connection refused")}).ToCache(ctx, "/products", []byte("x"))
+
+	ended := spans.Ended()
+	require.Len(t, ended, 3)
+	assert.Equal(t, "cache.FromCache", ended[0].Name())
+	assert.True(t, attr(ended[0], "cache.hit").AsBool())
+	assert.False(t, attr(ended[1], "cache.hit").AsBool())
+	assert.Equal(t, codes.Unset, ended[1].Status().Code, "a miss is not an error")
+	assert.Equal(t, "/products", attr(ended[1], "cache.key").AsString())
+	assert.Equal(t, codes.Error, ended[2].Status().Code)
+}
+
+func Test_Setup_File(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+	path := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err := Setup(config.Tracing{Exporter: "file", File: path, SampleRatio: 1})
+	require.NoError(t, err)
+
+	_, span := start(context.Background(), "test")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	body, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"Name":"test"`)
+	assert.Contains(t, string(body), `"Value":"market4"`)
+}
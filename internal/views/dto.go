@@ -1,6 +1,9 @@
 package views
 
-import "market4/internal/model"
+import (
+	"market4/internal/model"
+	"market4/internal/repository"
+)
 
 type ShopListDTO struct {
 	Total int           `json:"total"`
@@ -13,9 +16,18 @@ type CategoriesListDTO struct {
 }
 
 type Price struct {
-	SalePrice     int `json:"sale_price"`
-	FactoryPrice  int `json:"factory_price"`
-	DiscountPrice int `json:"discount_price"`
+	SalePrice      int                    `json:"sale_price"`
+	FactoryPrice   int                    `json:"factory_price"`
+	DiscountPrice  int                    `json:"discount_price"`
+	EffectivePrice *int                   `json:"effective_price,omitempty"`
+	Promotions     []*AppliedPromotionDTO `json:"promotions,omitempty"`
+}
+
+// AppliedPromotionDTO names a promotion that went into an effective price.
+type AppliedPromotionDTO struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
 }
 
 type Product struct {
@@ -38,3 +50,127 @@ type PricesListDTO struct {
 	Total int            `json:"total"`
 	Items []*model.Price `json:"items"`
 }
+
+type ImportRowDTO struct {
+	Row           int                     `json:"row"`
+	SKU           string                  `json:"sku"`
+	Status        string                  `json:"status"`
+	ProductID     string                  `json:"product_id,omitempty"`
+	Detail        string                  `json:"detail,omitempty"`
+	InvalidParams []repository.FieldError `json:"invalid_params,omitempty"`
+}
+
+type ImportReportDTO struct {
+	Total   int             `json:"total"`
+	Created int             `json:"created"`
+	Updated int             `json:"updated"`
+	Failed  int             `json:"failed"`
+	Skipped int             `json:"skipped"`
+	Rows    []*ImportRowDTO `json:"rows"`
+}
+
+type PriceChangeDTO struct {
+	PriceID       int                     `json:"price_id"`
+	ProductID     string                  `json:"product_id"`
+	SKU           string                  `json:"sku"`
+	Old           Price                   `json:"old"`
+	New           Price                   `json:"new"`
+	InvalidParams []repository.FieldError `json:"invalid_params,omitempty"`
+}
+
+type PriceBatchDTO struct {
+	BatchID int               `json:"batch_id,omitempty"`
+	DryRun  bool              `json:"dry_run"`
+	Matched int               `json:"matched"`
+	Changed int               `json:"changed"`
+	Invalid int               `json:"invalid"`
+	Changes []*PriceChangeDTO `json:"changes"`
+}
+
+type PromotionsListDTO struct {
+	Total int                `json:"total"`
+	Items []*model.Promotion `json:"items"`
+}
+
+type CouponsListDTO struct {
+	Total int             `json:"total"`
+	Items []*model.Coupon `json:"items"`
+}
+
+// CouponCheckDTO is a coupon evaluated against a basket. Reason says why an
+// invalid coupon can't be redeemed.
+type CouponCheckDTO struct {
+	Code     string                `json:"code"`
+	Valid    bool                  `json:"valid"`
+	Reason   string                `json:"reason,omitempty"`
+	Subtotal int                   `json:"subtotal"`
+	Eligible int                   `json:"eligible"`
+	Discount int                   `json:"discount"`
+	Total    int                   `json:"total"`
+	Items    []*CouponCheckItemDTO `json:"items"`
+}
+
+type CouponCheckItemDTO struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int    `json:"unit_price"`
+	Eligible  bool   `json:"eligible"`
+}
+
+// CartDTO is a user's cart at current prices. Unavailable items are listed
+// but left out of the totals.
+type CartDTO struct {
+	Items    []*CartItemDTO    `json:"items"`
+	Count    int               `json:"count"`
+	Subtotal int               `json:"subtotal"`
+	Discount int               `json:"discount"`
+	Total    int               `json:"total"`
+	Warnings []*CartWarningDTO `json:"warnings"`
+}
+
+type CartItemDTO struct {
+	ProductID  string                 `json:"product_id"`
+	SKU        string                 `json:"sku"`
+	Name       string                 `json:"name"`
+	Quantity   int                    `json:"quantity"`
+	UnitPrice  int                    `json:"unit_price"`
+	LineTotal  int                    `json:"line_total"`
+	Available  bool                   `json:"available"`
+	Promotions []*AppliedPromotionDTO `json:"promotions,omitempty"`
+}
+
+// CartWarningDTO tells the user something about an item changed since it
+// was put into the cart.
+type CartWarningDTO struct {
+	ProductID string `json:"product_id"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	OldPrice  int    `json:"old_price,omitempty"`
+	NewPrice  int    `json:"new_price,omitempty"`
+}
+
+type OrdersListDTO struct {
+	Total int            `json:"total"`
+	Items []*model.Order `json:"items"`
+}
+
+type PaymentsListDTO struct {
+	Total int              `json:"total"`
+	Items []*model.Payment `json:"items"`
+}
+
+// StockListDTO lists the stock of a product by shop; Available sums up what
+// can still be ordered.
+type StockListDTO struct {
+	Total     int            `json:"total"`
+	Available int            `json:"available"`
+	Items     []*model.Stock `json:"items"`
+}
+
+// AuditListDTO is one page of the audit log, newest first. NextBeforeID
+// fetches the next page and is 0 on the last one.
+type AuditListDTO struct {
+	Total        int                 `json:"total"`
+	Items        []*model.AuditEntry `json:"items"`
+	NextBeforeID int64               `json:"next_before_id,omitempty"`
+}
@@ -1,15 +1,12 @@
 package views
 
 import (
-	"errors"
-	"fmt"
 	"market4/internal/model"
 )
 
 func MakePricesList(prices []model.Price) (*PricesListDTO, error) {
 	if len(prices) == 0 {
-		err := errors.New("Price list is empty")
-		return nil, fmt.Errorf("PricesList: %w", err)
+		return &PricesListDTO{Items: []*model.Price{}}, nil
 	}
 
 	var pricesList PricesListDTO
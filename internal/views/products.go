@@ -1,15 +1,12 @@
 package views
 
 import (
-	"errors"
-	"fmt"
 	"market4/internal/model"
 )
 
 func MakeProductsList(products []model.Product) (*ProductsListDTO, error) {
 	if len(products) == 0 {
-		err := errors.New("Product list is empty")
-		return nil, fmt.Errorf("ProductList: %w", err)
+		return &ProductsListDTO{Items: []*Product{}}, nil
 	}
 
 	var productsList ProductsListDTO
@@ -30,8 +27,7 @@ func MakeProductsList(products []model.Product) (*ProductsListDTO, error) {
 }
 func MakeProductsListWithPrices(products []model.Product, prices []model.Price) (*ProductsListDTO, error) {
 	if len(products) == 0 {
-		err := errors.New("Product list is empty")
-		return nil, fmt.Errorf("ProductList: %w", err)
+		return &ProductsListDTO{Items: []*Product{}}, nil
 	}
 
 	var productsList ProductsListDTO
@@ -61,3 +57,23 @@ func MakeProductsListWithPrices(products []model.Product, prices []model.Price)
 	}
 	return &productsList, nil
 }
+
+func MakeProduct(product model.Product, price model.Price) *Product {
+	item := Product{
+		ID:          product.ID,
+		SKU:         product.SKU,
+		Name:        product.Name,
+		Type:        product.Type,
+		URI:         product.URI,
+		Description: product.Description,
+		IsActive:    product.IsActive,
+	}
+	if price.ID != 0 {
+		item.Prices = append(item.Prices, &Price{
+			SalePrice:     price.SalePrice,
+			FactoryPrice:  price.FactoryPrice,
+			DiscountPrice: price.DiscountPrice,
+		})
+	}
+	return &item
+}
@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"context"
+	"market4/internal/repository"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Purger periodically hard-deletes catalog rows that have been soft deleted
+// for longer than the retention period.
+type Purger struct {
+	archive   repository.Archive
+	retention time.Duration
+	interval  time.Duration
+	lg        *zap.Logger
+	now       func() time.Time
+}
+
+func NewPurger(archive repository.Archive, retention, interval time.Duration, lg *zap.Logger) *Purger {
+	return &Purger{archive: archive, retention: retention, interval: interval, lg: lg, now: time.Now}
+}
+
+// Run purges once right away and then every interval until ctx is done.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Purge(ctx); err != nil {
+			p.lg.Error("Purger", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) Purge(ctx context.Context) error {
+	before := p.now().UTC().Add(-p.retention)
+	report, err := p.archive.Purge(ctx, before)
+	if err != nil {
+		return err
+	}
+	p.lg.Info("Purger",
+		zap.Time("before", before),
+		zap.Int64("shops", report.Shops),
+		zap.Int64("categories", report.Categories),
+		zap.Int64("products", report.Products),
+		zap.Int64("prices", report.Prices))
+	return nil
+}
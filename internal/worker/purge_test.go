@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"market4/internal/repository"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeArchive struct {
+	mu     sync.Mutex
+	before []time.Time
+	err    error
+}
+
+func (f *fakeArchive) Purge(ctx context.Context, before time.Time) (repository.PurgeReport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.before = append(f.before, before)
+	return repository.PurgeReport{Products: 1}, f.err
+}
+
+func (f *fakeArchive) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.before)
+}
+
+func Test_PurgeCutoff(t *testing.T) {
+	archive := &fakeArchive{}
+	purger := NewPurger(archive, 48*time.Hour, time.Hour, zap.NewNop())
+	now := time.Date(2021, 6, 10, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	purger.now = func() time.Time { return now }
+
+	assert.NoError(t, purger.Purge(context.Background()))
+	assert.Equal(t, []time.Time{time.Date(2021, 6, 8, 9, 0, 0, 0, time.UTC)}, archive.before)
+
+	archive.err = errors.New("connection refused")
+	assert.Error(t, purger.Purge(context.Background()))
+}
+
+func Test_RunStopsWithContext(t *testing.T) {
+	archive := &fakeArchive{err: errors.New("connection refused")}
+	purger := NewPurger(archive, time.Hour, time.Millisecond, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		purger.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return archive.calls() >= 3 }, time.Second, time.Millisecond,
+		"errors don't stop the purger")
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run didn't return after cancel")
+	}
+}
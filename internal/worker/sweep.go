@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"market4/internal/repository"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Sweeper periodically cancels unpaid orders whose stock reservations have
+// expired, so the stock they held can be sold again.
+type Sweeper struct {
+	stock    repository.Stock
+	interval time.Duration
+	lg       *zap.Logger
+}
+
+func NewSweeper(stock repository.Stock, interval time.Duration, lg *zap.Logger) *Sweeper {
+	return &Sweeper{stock: stock, interval: interval, lg: lg}
+}
+
+// Run sweeps once right away and then every interval until ctx is done.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Sweep(ctx); err != nil {
+			s.lg.Error("Sweeper", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sweeper) Sweep(ctx context.Context) error {
+	orderIDs, err := s.stock.ExpireReservations(ctx)
+	if err != nil {
+		return err
+	}
+	if len(orderIDs) > 0 {
+		s.lg.Info("Sweeper", zap.Ints("cancelled", orderIDs))
+	}
+	return nil
+}
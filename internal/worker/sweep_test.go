@@ -0,0 +1,81 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"market4/internal/model"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeStock struct {
+	mu      sync.Mutex
+	expired [][]int
+	sweeps  int
+	err     error
+}
+
+func (f *fakeStock) SetStock(ctx context.Context, s model.Stock) (model.Stock, error) {
+	return s, nil
+}
+
+func (f *fakeStock) ListStock(ctx context.Context, productID string) ([]model.Stock, error) {
+	return nil, nil
+}
+
+func (f *fakeStock) ExpireReservations(ctx context.Context) ([]int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sweeps++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if len(f.expired) == 0 {
+		return []int{}, nil
+	}
+	next := f.expired[0]
+	f.expired = f.expired[1:]
+	return next, nil
+}
+
+func (f *fakeStock) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sweeps
+}
+
+func Test_Sweep(t *testing.T) {
+	stock := &fakeStock{expired: [][]int{{3, 7}}}
+	sweeper := NewSweeper(stock, time.Minute, zap.NewNop())
+
+	assert.NoError(t, sweeper.Sweep(context.Background()))
+	assert.NoError(t, sweeper.Sweep(context.Background()), "nothing to expire")
+	assert.Equal(t, 2, stock.calls())
+
+	stock.err = errors.New("connection refused")
+	assert.Error(t, sweeper.Sweep(context.Background()))
+}
+
+func Test_SweeperRunStopsWithContext(t *testing.T) {
+	stock := &fakeStock{err: errors.New("connection refused")}
+	sweeper := NewSweeper(stock, time.Millisecond, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sweeper.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return stock.calls() >= 3 }, time.Second, time.Millisecond,
+		"errors don't stop the sweeper")
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run didn't return after cancel")
+	}
+}